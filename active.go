@@ -0,0 +1,280 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Name of the file, in the package directory, telling which version
+// of each package is active.
+const activeFile = ".active.json"
+
+// Activator is implemented by the backends that keep several
+// versions of a package side by side, one of them being active.
+// Switching the active version is atomic: the host always sees
+// either the old or the new one.
+type Activator interface {
+	SetActive(*Package) error
+
+	// ActiveVersion returns the version marked active for the
+	// named package, or the empty string if none is.
+	ActiveVersion(name string) (string, error)
+}
+
+// Switcher is implemented by the activators that can put the active
+// version live themselves: its load hooks are fired first, and only
+// once they succeeded the unload hooks of the version it replaces.
+type Switcher interface {
+	Switch(*Package) error
+}
+
+type ActivateOptions struct {
+	// Called once the new version passed the integrity and
+	// manifest checks, e.g. to handshake with its connectors.
+	// The switch doesn't happen if it fails.  The manifest is nil
+	// if the backend can't read it.
+	Validate func(*Manifest, *Package) error
+
+	// Keep the previously active version installed after the
+	// switch, instead of removing it.
+	KeepPrevious bool
+}
+
+// Activate makes the given version, installed alongside the current
+// one with AddOptions.AllowMultipleVersions, the active one.  It's
+// validated first, then switched to: when the backend is a
+// [Switcher], the new version is loaded before the previous one is
+// unloaded, so there is no moment where no version of the package is
+// available.
+func (p *Manager) Activate(name, version string, opts *ActivateOptions) error {
+	if opts == nil {
+		opts = &ActivateOptions{}
+	}
+
+	a, ok := p.store.(Activator)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	var (
+		next     *Package
+		previous []*Package
+	)
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return err
+		}
+		if pkg.Version == version {
+			next = pkg
+		} else {
+			previous = append(previous, pkg)
+		}
+	}
+	if next == nil {
+		return fmt.Errorf("%s %s: %w", name, version, ErrNotInstalled)
+	}
+
+	if err := p.store.Verify(next); err != nil {
+		return err
+	}
+
+	m, err := p.manifest(next)
+	if err != nil {
+		return fmt.Errorf("%s: %w: %w", next.Filename(), ErrCorrupted, err)
+	}
+
+	if opts.Validate != nil {
+		if err := opts.Validate(m, next); err != nil {
+			return err
+		}
+	}
+
+	if s, ok := a.(Switcher); ok {
+		err = s.Switch(next)
+	} else {
+		err = a.SetActive(next)
+	}
+	if err != nil {
+		return err
+	}
+
+	if opts.KeepPrevious {
+		return nil
+	}
+
+	var merr MultiError
+	for _, pkg := range previous {
+		merr.add(pkg, p.Del(name, &DelOptions{Version: pkg.Version}))
+	}
+	return merr.err()
+}
+
+// Use selects which of the installed versions of the named package
+// is the active one, i.e. the one [FlatBackend.LoadAll] loads.  Unlike
+// Activate it leaves the other versions installed and the selection
+// only takes effect at the next LoadAll.
+func (p *Manager) Use(name, version string) error {
+	a, ok := p.store.(Activator)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return err
+		}
+		if pkg.Version == version {
+			return a.SetActive(pkg)
+		}
+	}
+	return fmt.Errorf("%s %s: %w", name, version, ErrNotInstalled)
+}
+
+// Active returns the active version of the named package: the one
+// switched to with Activate or, failing that, the newest installed.
+func (p *Manager) Active(name string) (*Package, error) {
+	var version string
+	if a, ok := p.store.(Activator); ok {
+		v, err := a.ActiveVersion(name)
+		if err != nil {
+			return nil, err
+		}
+		version = v
+	}
+
+	var newest *Package
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return nil, err
+		}
+		if pkg.Version == version {
+			return pkg, nil
+		}
+		if newest == nil || p.versions.Compare(pkg.Version, newest.Version) > 0 {
+			newest = pkg
+		}
+	}
+	if newest == nil {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+	return newest, nil
+}
+
+func (f *FlatBackend) actives() (map[string]string, error) {
+	data, err := readFile(f.fsys, filepath.Join(f.pkgdir, activeFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	actives := map[string]string{}
+	if err := json.Unmarshal(data, &actives); err != nil {
+		return nil, err
+	}
+	return actives, nil
+}
+
+// SetActive records the package as the active version.  It only needs
+// to be installed: the version is extracted on demand by the next
+// LoadAll if its tree isn't in the cache.
+func (f *FlatBackend) SetActive(pkg *Package) error {
+	if _, err := f.fsys.Stat(f.ptarPath(pkg)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+		}
+		return err
+	}
+
+	return f.writeActive(pkg)
+}
+
+// writeActive records the package as the active version.
+func (f *FlatBackend) writeActive(pkg *Package) error {
+	actives, err := f.actives()
+	if err != nil {
+		return err
+	}
+	actives[pkg.Name] = pkg.Version
+
+	data, err := json.Marshal(actives)
+	if err != nil {
+		return err
+	}
+	if err := writeFile(f.fsys, filepath.Join(f.pkgdir, activeFile), data); err != nil {
+		return err
+	}
+	f.updateCurrent(pkg.Name)
+	return nil
+}
+
+// Switch makes the package the active version and loads it in place
+// of the other versions loaded: its load hooks are fired before the
+// unload hooks of the others, which are left installed but idle.
+func (f *FlatBackend) Switch(pkg *Package) error {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	if _, err := f.fsys.Stat(f.ptarPath(pkg)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+		}
+		return err
+	}
+
+	if !f.isLoaded(pkg) {
+		if err := f.reload(pkg); err != nil {
+			return err
+		}
+	}
+	if err := f.writeActive(pkg); err != nil {
+		return err
+	}
+
+	var merr MultiError
+	for other, err := range f.List(pkg.Name) {
+		if err != nil {
+			return err
+		}
+		if other.Version == pkg.Version || !f.isLoaded(other) {
+			continue
+		}
+		if err := f.runUnloadHooks(other, f.extractedPath(other)); err != nil {
+			merr.add(other, err)
+			continue
+		}
+		f.markUnloaded(other)
+		f.markIdle(other)
+	}
+	return merr.err()
+}
+
+func (f *FlatBackend) ActiveVersion(name string) (string, error) {
+	actives, err := f.actives()
+	if err != nil {
+		return "", err
+	}
+	return actives[name], nil
+}
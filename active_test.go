@@ -0,0 +1,309 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+// activeBackend is a fakeBackend keeping track of the active
+// versions.
+type activeBackend struct {
+	*fakeBackend
+	active map[string]string
+}
+
+func (a *activeBackend) SetActive(pkg *Package) error {
+	a.active[pkg.Name] = pkg.Version
+	return nil
+}
+
+func (a *activeBackend) ActiveVersion(name string) (string, error) {
+	return a.active[name], nil
+}
+
+func newActiveBackend(pkgs ...*Package) *activeBackend {
+	return &activeBackend{
+		fakeBackend: newFakeBackend(pkgs...),
+		active:      map[string]string{},
+	}
+}
+
+func TestActivate(t *testing.T) {
+	be := newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"))
+	m, _ := New(be, nil)
+
+	var validated []string
+	err := m.Activate("s3", "v2.0.0", &ActivateOptions{
+		Validate: func(_ *Manifest, pkg *Package) error {
+			// the previous version is still there while validating.
+			if len(be.unloaded) != 0 {
+				t.Error("previous version removed before the switch")
+			}
+			validated = append(validated, pkg.Version)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+
+	if !slices.Equal(validated, []string{"v2.0.0"}) {
+		t.Errorf("validated = %v", validated)
+	}
+	if be.active["s3"] != "v2.0.0" {
+		t.Errorf("active = %q, want v2.0.0", be.active["s3"])
+	}
+	if len(be.unloaded) != 1 || be.unloaded[0].Version != "v1.0.0" {
+		t.Errorf("unloaded = %v, want v1.0.0", be.unloaded)
+	}
+
+	active, err := m.Active("s3")
+	if err != nil || active.Version != "v2.0.0" {
+		t.Errorf("Active = %v, %v", active, err)
+	}
+}
+
+func TestActivateValidationFailure(t *testing.T) {
+	be := newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"))
+	be.active["s3"] = "v1.0.0"
+	m, _ := New(be, nil)
+
+	errHandshake := errors.New("handshake failed")
+	err := m.Activate("s3", "v2.0.0", &ActivateOptions{
+		Validate: func(*Manifest, *Package) error { return errHandshake },
+	})
+	if !errors.Is(err, errHandshake) {
+		t.Fatalf("Activate = %v, want %v", err, errHandshake)
+	}
+	if be.active["s3"] != "v1.0.0" || len(be.unloaded) != 0 {
+		t.Errorf("switched on failure: active=%q unloaded=%v", be.active["s3"], be.unloaded)
+	}
+
+	be.corrupted = []string{"s3"}
+	if err := m.Activate("s3", "v2.0.0", nil); !errors.Is(err, ErrCorrupted) {
+		t.Fatalf("Activate = %v, want %v", err, ErrCorrupted)
+	}
+	if be.active["s3"] != "v1.0.0" {
+		t.Errorf("active = %q, want v1.0.0", be.active["s3"])
+	}
+}
+
+func TestActivateKeepPrevious(t *testing.T) {
+	be := newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"))
+	m, _ := New(be, nil)
+
+	if err := m.Activate("s3", "v1.0.0", &ActivateOptions{KeepPrevious: true}); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if len(be.unloaded) != 0 {
+		t.Errorf("unloaded = %v, want none", be.unloaded)
+	}
+
+	// the pointer wins over the newest version.
+	active, err := m.Active("s3")
+	if err != nil || active.Version != "v1.0.0" {
+		t.Errorf("Active = %v, %v", active, err)
+	}
+}
+
+func TestActivateNotInstalled(t *testing.T) {
+	m, _ := New(newActiveBackend(pkgVer("s3", "v1.0.0")), nil)
+	if err := m.Activate("s3", "v2.0.0", nil); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("Activate = %v, want %v", err, ErrNotInstalled)
+	}
+	if err := m.Activate("s3", "v1.0.0", nil); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+
+	m, _ = New(newFakeBackend(pkgVer("s3", "v1.0.0")), nil)
+	if err := m.Activate("s3", "v1.0.0", nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Activate = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestFlatBackendActive(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, nil)
+
+	pkg := pkgVer("s3", "v1.0.0")
+	if err := be.SetActive(pkg); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("SetActive = %v, want %v", err, ErrNotInstalled)
+	}
+
+	installExtracted(t, pkgdir, cachedir, pkg)
+	if err := be.SetActive(pkg); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	if v, err := be.ActiveVersion("s3"); err != nil || v != "v1.0.0" {
+		t.Errorf("ActiveVersion = %q, %v", v, err)
+	}
+	if v, err := be.ActiveVersion("ftp"); err != nil || v != "" {
+		t.Errorf("ActiveVersion(ftp) = %q, %v", v, err)
+	}
+}
+
+func TestUse(t *testing.T) {
+	be := newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"))
+	m, _ := New(be, nil)
+
+	if err := m.Use("s3", "v3.0.0"); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("Use = %v, want %v", err, ErrNotInstalled)
+	}
+	if err := m.Use("s3", "v1.0.0"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if be.active["s3"] != "v1.0.0" {
+		t.Errorf("active = %q, want v1.0.0", be.active["s3"])
+	}
+	if len(be.unloaded) != 0 {
+		t.Errorf("unloaded = %v, want none", be.unloaded)
+	}
+
+	m, _ = New(newFakeBackend(pkgVer("s3", "v1.0.0")), nil)
+	if err := m.Use("s3", "v1.0.0"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Use = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestFlatBackendLoadAllActive(t *testing.T) {
+	var loaded []string
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			loaded = append(loaded, p.Name+"@"+p.Version)
+			return nil
+		},
+	})
+	for _, pkg := range []*Package{
+		pkgVer("s3", "v1.0.0"),
+		pkgVer("s3", "v2.0.0"),
+		pkgVer("sftp", "v1.0.0"),
+		pkgVer("sftp", "v1.1.0"),
+	} {
+		installExtracted(t, pkgdir, cachedir, pkg)
+	}
+	m, _ := New(be, nil)
+	if err := m.Use("s3", "v1.0.0"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	slices.Sort(loaded)
+	if want := []string{"s3@v1.0.0", "sftp@v1.1.0"}; !slices.Equal(loaded, want) {
+		t.Errorf("loaded = %v, want %v", loaded, want)
+	}
+}
+
+func TestActivateSparesNewConnectors(t *testing.T) {
+	reg := NewProcessRegistry()
+	be := newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"))
+	m, _ := New(be, &Options{Processes: reg, StopTimeout: 10 * time.Millisecond})
+
+	// a stuck process of the new version would make the removal
+	// of the old one fail if it was stopped along.
+	reg.TrackVersion("s3", "v2.0.0", "s3-importer", &os.Process{Pid: -1})
+
+	if err := m.Activate("s3", "v2.0.0", nil); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if got := reg.Running("s3"); len(got) != 1 || got[0].Version != "v2.0.0" {
+		t.Errorf("Running = %v", got)
+	}
+}
+
+// A version installed alongside the loaded one stays idle until
+// activated, and then goes live before the previous one is unloaded.
+func TestFlatBackendSwitchOrder(t *testing.T) {
+	var hooks []string
+	be, _, _ := newReloadBackend(t, &hooks)
+
+	v1, v2 := pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0")
+	for _, pkg := range []*Package{v1, v2} {
+		if err := be.Load(pkg, strings.NewReader("PTAR")); err != nil {
+			t.Fatalf("Load %s: %v", pkg.Version, err)
+		}
+	}
+	if want := []string{"load v1.0.0"}; !slices.Equal(hooks, want) {
+		t.Fatalf("hooks = %v, want %v", hooks, want)
+	}
+	if v, _ := be.ActiveVersion("s3"); v != "v1.0.0" {
+		t.Errorf("ActiveVersion = %q, want the live v1.0.0", v)
+	}
+
+	// what Activate does once the new version is validated.
+	if err := be.Switch(v2); err != nil {
+		t.Fatalf("Switch: %v", err)
+	}
+	if err := be.Unload(v1); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	want := []string{"load v1.0.0", "load v2.0.0", "unload v1.0.0"}
+	if !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %v, want %v", hooks, want)
+	}
+	if v, _ := be.ActiveVersion("s3"); v != "v2.0.0" {
+		t.Errorf("ActiveVersion = %q, want v2.0.0", v)
+	}
+}
+
+// switchingBackend is an activeBackend recording the switches.
+type switchingBackend struct {
+	*activeBackend
+	switched []string
+}
+
+func (b *switchingBackend) Switch(pkg *Package) error {
+	b.switched = append(b.switched, pkg.Version)
+	b.active[pkg.Name] = pkg.Version
+	return nil
+}
+
+func TestActivateSwitches(t *testing.T) {
+	be := &switchingBackend{
+		activeBackend: newActiveBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0")),
+	}
+	m, _ := New(be, nil)
+
+	if err := m.Activate("s3", "v2.0.0", nil); err != nil {
+		t.Fatalf("Activate: %v", err)
+	}
+	if !slices.Equal(be.switched, []string{"v2.0.0"}) {
+		t.Errorf("switched = %v", be.switched)
+	}
+	if len(be.unloaded) != 1 || be.unloaded[0].Version != "v1.0.0" {
+		t.Errorf("unloaded = %v, want v1.0.0", be.unloaded)
+	}
+}
+
+// An older version whose tree was never extracted can still be
+// switched to, and is extracted by the next LoadAll.
+func TestFlatBackendUseNotExtracted(t *testing.T) {
+	var hooks []string
+	be, ext, pkgdir := newReloadBackend(t, &hooks)
+
+	touch(t, pkgdir, pkgVer("s3", "v1.0.0").Filename())
+	touch(t, pkgdir, pkgVer("s3", "v2.0.0").Filename())
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	m, _ := New(be, nil)
+	if err := m.Use("s3", "v1.0.0"); err != nil {
+		t.Fatalf("Use: %v", err)
+	}
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	if want := []string{"load v2.0.0", "load v1.0.0"}; !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %v, want %v", hooks, want)
+	}
+	if len(ext.extracted) != 2 {
+		t.Errorf("extracted %d times, want 2", len(ext.extracted))
+	}
+}
@@ -20,10 +20,33 @@ package pkg
 
 import "time"
 
+// InstallationStatus is the state of an integration on this host.
+type InstallationStatus string
+
+const (
+	StatusNotInstalled InstallationStatus = "not-installed"
+	StatusInstalling   InstallationStatus = "installing"
+	StatusInstalled    InstallationStatus = "installed"
+	StatusRemoving     InstallationStatus = "removing"
+	StatusFailed       InstallationStatus = "failed"
+)
+
 type IntegrationInstallation struct {
-	Status    string `json:"status"`
-	Version   string `json:"version,omitempty"`
-	Available bool   `json:"available"`
+	Status    InstallationStatus `json:"status"`
+	Version   string             `json:"version,omitempty"`
+	Available bool               `json:"available"`
+
+	// Percentage of the download done while installing, or -1
+	// if the size is not known.
+	Progress int `json:"progress,omitempty"`
+
+	// Why the last operation failed, with StatusFailed.
+	Error string `json:"error,omitempty"`
+
+	// When the ongoing or the failed operation started, and when
+	// the status was last updated.
+	StartedAt time.Time `json:"started_at,omitzero"`
+	UpdatedAt time.Time `json:"updated_at,omitzero"`
 }
 
 type IntegrationTypes struct {
@@ -46,6 +69,13 @@ type Connector struct {
 	Validator any        `json:"validator"` // json schema
 }
 
+// Artifact is a binary published for a given version and platform.
+type Artifact struct {
+	Version         string `json:"version"`
+	OperatingSystem string `json:"os"`
+	Architecture    string `json:"arch"`
+}
+
 type Integration struct {
 	Edition string `json:"edition"`
 	API     string `json:"api"`
@@ -64,6 +94,18 @@ type Integration struct {
 	Documentation string      `json:"documentation"` // README.md
 	Icon          string      `json:"icon"`          // assets/icon.{png,svg}
 	Featured      string      `json:"featured"`      // assets/featured.{png,svg}
+	Artifacts     []Artifact  `json:"artifacts,omitempty"`
+	Publisher     Publisher   `json:"publisher"`
+	Provides      []string    `json:"provides,omitempty"` // virtual capabilities
+
+	// Versions withdrawn from the repository.
+	Yanked []YankedVersion `json:"yanked,omitempty"`
+
+	// Security advisories affecting some of its versions.
+	Advisories []Advisory `json:"advisories,omitempty"`
+
+	// Popularity figures, if the index provides them.
+	Stats *IntegrationStats `json:"stats,omitempty"`
 
 	Id            string                  `json:"id"`
 	Types         IntegrationTypes        `json:"types"`
@@ -72,6 +114,14 @@ type Integration struct {
 	LatestVersion string                  `json:"latest_version"`
 }
 
+// IntegrationStats are the popularity figures of an integration.  Any
+// of them may be missing from the index, and is left zero then.
+type IntegrationStats struct {
+	Downloads   int64     `json:"downloads,omitempty"`
+	Stars       int64     `json:"stars,omitempty"`
+	LastRelease time.Time `json:"last_release,omitzero"`
+}
+
 type IntegrationIndex struct {
 	Version      string        `json:"version"`
 	Timestamp    time.Time     `json:"timestamp"`
@@ -86,3 +136,31 @@ func (int *Integration) HasConnectorType(ct string) bool {
 	}
 	return false
 }
+
+// Platforms returns the "os/arch" pairs for which the given version
+// has been published.
+func (int *Integration) Platforms(version string) []string {
+	var ret []string
+	for _, a := range int.Artifacts {
+		if a.Version == version {
+			ret = append(ret, a.OperatingSystem+"/"+a.Architecture)
+		}
+	}
+	return ret
+}
+
+// Supports returns whether the given version is available for the
+// os/arch.  Indexes that don't list the artifacts are assumed to
+// provide every platform.
+func (int *Integration) Supports(version, goos, goarch string) bool {
+	if len(int.Artifacts) == 0 {
+		return true
+	}
+	for _, a := range int.Artifacts {
+		if a.Version == version && a.OperatingSystem == goos &&
+			a.Architecture == goarch {
+			return true
+		}
+	}
+	return false
+}
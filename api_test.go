@@ -70,3 +70,48 @@ func TestIntegrationIndexJSONDecode(t *testing.T) {
 		t.Error("expected storage connector")
 	}
 }
+
+func TestIntegrationPlatforms(t *testing.T) {
+	i := &Integration{
+		Artifacts: []Artifact{
+			{Version: "v1.0.0", OperatingSystem: "linux", Architecture: "amd64"},
+			{Version: "v1.0.0", OperatingSystem: "darwin", Architecture: "arm64"},
+			{Version: "v0.9.0", OperatingSystem: "darwin", Architecture: "amd64"},
+		},
+	}
+
+	got := i.Platforms("v1.0.0")
+	if len(got) != 2 || got[0] != "linux/amd64" || got[1] != "darwin/arm64" {
+		t.Errorf("Platforms(v1.0.0) = %v", got)
+	}
+
+	if !i.Supports("v1.0.0", "darwin", "arm64") {
+		t.Error("Supports(v1.0.0, darwin, arm64) = false, want true")
+	}
+	if i.Supports("v1.0.0", "darwin", "amd64") {
+		t.Error("Supports(v1.0.0, darwin, amd64) = true, want false")
+	}
+}
+
+func TestIntegrationSupportsWithoutArtifacts(t *testing.T) {
+	var i Integration
+	if !i.Supports("v1.0.0", "plan9", "sparc64") {
+		t.Error("Supports without artifacts = false, want true")
+	}
+}
+
+func TestIntegrationArtifactsJSONDecode(t *testing.T) {
+	const doc = `{
+		"name": "s3",
+		"version": "v1.0.0",
+		"artifacts": [{"version": "v1.0.0", "os": "linux", "arch": "amd64"}]
+	}`
+
+	var in Integration
+	if err := json.Unmarshal([]byte(doc), &in); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(in.Artifacts) != 1 || in.Artifacts[0] != (Artifact{"v1.0.0", "linux", "amd64"}) {
+		t.Errorf("Artifacts = %+v", in.Artifacts)
+	}
+}
@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"golang.org/x/mod/semver"
+)
+
+var (
+	ErrNoCommonAPIVersion = errors.New("no plugin API version in common with the repository")
+)
+
+// The document served by the repository at the root of InstallURL
+// listing the plugin API versions it provides.
+const apiVersionsEndpoint = "api-versions.json"
+
+type apiVersionsIndex struct {
+	Versions []string `json:"versions"`
+}
+
+// parseAPIVersions validates the given plugin API versions and
+// returns them sorted from the newest to the oldest.  An empty list
+// defaults to [PLUGIN_API_VERSION].
+func parseAPIVersions(versions []string) ([]string, error) {
+	if len(versions) == 0 {
+		return []string{PLUGIN_API_VERSION}, nil
+	}
+
+	ret := slices.Clone(versions)
+	for _, v := range ret {
+		if !semver.IsValid(v) {
+			return nil, fmt.Errorf("%w: bad API version %q", ErrInvalidOptions, v)
+		}
+	}
+
+	slices.SortFunc(ret, func(a, b string) int {
+		return semver.Compare(b, a)
+	})
+	return slices.Compact(ret), nil
+}
+
+// APIVersion returns the plugin API version used to talk to the
+// repository: the newest one that is both supported by the manager
+// and served by the repository.  With a single supported version no
+// negotiation takes place.
+func (p *Manager) APIVersion() (string, error) {
+	// held while negotiating, so that concurrent callers wait for
+	// the outcome instead of asking the repository again.
+	p.apimu.Lock()
+	defer p.apimu.Unlock()
+
+	if p.apiversion != "" {
+		return p.apiversion, nil
+	}
+
+	if len(p.apiversions) == 1 || p.repository == nil {
+		p.apiversion = p.apiversions[0]
+		return p.apiversion, nil
+	}
+
+	resp, err := p.fetch(p.repository, apiVersionsEndpoint, false)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var index apiVersionsIndex
+	if err := json.NewDecoder(resp.Body).Decode(&index); err != nil {
+		return "", fmt.Errorf("failed to decode the API versions: %w", err)
+	}
+
+	for _, v := range p.apiversions {
+		if slices.Contains(index.Versions, v) {
+			p.apiversion = v
+			return v, nil
+		}
+	}
+
+	return "", ErrNoCommonAPIVersion
+}
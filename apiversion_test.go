@@ -0,0 +1,134 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestParseAPIVersions(t *testing.T) {
+	got, err := parseAPIVersions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{PLUGIN_API_VERSION}) {
+		t.Errorf("default = %v", got)
+	}
+
+	got, err = parseAPIVersions([]string{"v1.0.0", "v2.0.0", "v1.1.0", "v1.0.0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(got, []string{"v2.0.0", "v1.1.0", "v1.0.0"}) {
+		t.Errorf("sorted = %v", got)
+	}
+
+	if _, err := parseAPIVersions([]string{"1.0"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("bad version err = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestAPIVersionSingleDoesNotNegotiate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request to %s", r.URL.Path)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL})
+	v, err := m.APIVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v != PLUGIN_API_VERSION {
+		t.Errorf("APIVersion = %q, want %q", v, PLUGIN_API_VERSION)
+	}
+}
+
+func TestAPIVersionNegotiation(t *testing.T) {
+	var hits int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/"+apiVersionsEndpoint:
+			hits++
+			io.WriteString(w, `{"versions": ["v1.0.0", "v1.1.0", "v3.0.0"]}`)
+		case r.URL.Path == "/v1.1.0/s3/recipe.yaml":
+			io.WriteString(w, "name: s3\nversion: v1.2.3\n")
+		default:
+			http.Error(w, "unexpected "+r.URL.Path, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	m, err := New(newFakeBackend(), &Options{
+		InstallURL:  srv.URL,
+		APIVersions: []string{"v1.0.0", "v1.1.0", "v2.0.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := m.FetchRecipe("s3"); err != nil {
+		t.Fatalf("FetchRecipe: %v", err)
+	}
+	if v, _ := m.APIVersion(); v != "v1.1.0" {
+		t.Errorf("APIVersion = %q, want v1.1.0", v)
+	}
+	if hits != 1 {
+		t.Errorf("negotiated %d times, want 1", hits)
+	}
+}
+
+func TestAPIVersionNoCommonVersion(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, apiVersionsEndpoint) {
+			t.Errorf("unexpected request to %s", r.URL.Path)
+		}
+		io.WriteString(w, `{"versions": ["v0.1.0"]}`)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{
+		InstallURL:  srv.URL,
+		APIVersions: []string{"v1.0.0", "v1.1.0"},
+	})
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrNoCommonAPIVersion) {
+		t.Errorf("FetchRecipe err = %v, want ErrNoCommonAPIVersion", err)
+	}
+}
+
+func TestAPIVersionConcurrent(t *testing.T) {
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		io.WriteString(w, `{"versions": ["v1.0.0", "v1.1.0"]}`)
+	}))
+	defer srv.Close()
+
+	m, err := New(newFakeBackend(), &Options{
+		InstallURL:  srv.URL,
+		APIVersions: []string{"v1.0.0", "v1.1.0"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Go(func() {
+			if v, err := m.APIVersion(); err != nil || v != "v1.1.0" {
+				t.Errorf("APIVersion = %q, %v", v, err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if n := hits.Load(); n != 1 {
+		t.Errorf("negotiated %d times, want 1", n)
+	}
+}
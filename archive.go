@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// Name of the directory, in the package directory, where the packages
+// deleted with DelOptions.KeepArchive are kept.
+const archiveDir = ".archive"
+
+// Archiver is implemented by the backends that can keep the stored
+// package around after uninstalling it, so that it can be installed
+// again without downloading it.
+type Archiver interface {
+	// Archive is like Unload, but keeps the stored package in
+	// the archive area.
+	Archive(*Package) error
+
+	// OpenArchive returns the archived package.  It fails with
+	// ErrNotFound if it's not there.  Loading the package drops
+	// it from the archive.
+	OpenArchive(*Package) (io.ReadCloser, error)
+
+	// DropArchive removes the archived package, if any.
+	DropArchive(*Package) error
+}
+
+// openArchived opens the archived copy of the package, if the backend
+// has one.
+func (p *Manager) openArchived(pkg *Package) (io.ReadCloser, error) {
+	a, ok := p.store.(Archiver)
+	if !ok {
+		return nil, nil
+	}
+	rd, err := a.OpenArchive(pkg)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	}
+	return rd, err
+}
+
+// archivePath returns where the package is archived.
+func (f *FlatBackend) archivePath(pkg *Package) string {
+	return filepath.Join(f.pkgdir, archiveDir, pkg.Filename())
+}
+
+func (f *FlatBackend) Archive(pkg *Package) error {
+	return f.unloadPackage(pkg, true)
+}
+
+func (f *FlatBackend) OpenArchive(pkg *Package) (io.ReadCloser, error) {
+	fp, err := f.fsys.Open(f.archivePath(pkg))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNotFound)
+		}
+		return nil, err
+	}
+	return fp, nil
+}
+
+func (f *FlatBackend) DropArchive(pkg *Package) error {
+	err := f.fsys.Remove(f.archivePath(pkg))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+// archive moves the stored package to the archive area.
+func (f *FlatBackend) archive(pkg *Package, pkgfile string) error {
+	dst := f.archivePath(pkg)
+	if err := f.fsys.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	return move(f.fsys, pkgfile, dst)
+}
@@ -0,0 +1,67 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDelKeepArchive(t *testing.T) {
+	downloads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ptar") {
+			downloads++
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	opts := &AddOptions{ImplicitFetch: true, Version: "v1.0.0", Checksum: sha256sum("PTARDATA")}
+	if err := m.Add("s3", opts); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := m.Del("s3", &DelOptions{KeepArchive: true}); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	pkg := pkgVer("s3", "v1.0.0")
+	if v := m.installedVersion("s3"); v != "" {
+		t.Errorf("s3 %s still installed", v)
+	}
+	if _, err := os.Stat(cachePath(cachedir, pkg)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("extracted tree left behind: %v", err)
+	}
+	archived := filepath.Join(pkgdir, archiveDir, pkg.Filename())
+	if _, err := os.Stat(archived); err != nil {
+		t.Fatalf("archived package: %v", err)
+	}
+
+	if err := m.Add("s3", opts); err != nil {
+		t.Fatalf("Add again: %v", err)
+	}
+	if downloads != 1 {
+		t.Errorf("%d downloads, want 1", downloads)
+	}
+	if _, err := os.Stat(archived); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("archived package left behind: %v", err)
+	}
+}
+
+func TestDelKeepArchiveUnsupported(t *testing.T) {
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, nil)
+	if err := m.Del("s3", &DelOptions{KeepArchive: true}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Del = %v, want %v", err, errors.ErrUnsupported)
+	}
+	if len(be.unloaded) != 0 {
+		t.Errorf("unloaded = %v", be.unloaded)
+	}
+}
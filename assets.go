@@ -0,0 +1,244 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Name of the file, in the asset cache directory, mapping the URLs
+// of the assets to the files they were saved to.
+const assetIndexFile = "assets.json"
+
+// Largest asset cached.
+const maxAssetSize = 16 << 20
+
+// indexURL resolves a reference found in the integration index, such
+// as a documentation or an icon path, against the location of the
+// index itself.
+func (p *Manager) indexURL(ref string) (*url.URL, error) {
+	u, err := url.Parse(ref)
+	if err != nil {
+		return nil, err
+	}
+	if u.IsAbs() {
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return nil, fmt.Errorf("unsupported asset URL %q", ref)
+		}
+		return u, nil
+	}
+	if p.api == nil {
+		return nil, fmt.Errorf("%s: no API URL", ref)
+	}
+	return p.api.JoinPath("v1/integrations/").ResolveReference(u), nil
+}
+
+// integration returns the entry of the integration index for the
+// given id, preferring the one for the negotiated API version.
+func (p *Manager) integration(id string) (*Integration, error) {
+	index, err := p.cachedIndex()
+	if err != nil {
+		return nil, err
+	}
+	apiversion, err := p.APIVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	var found *Integration
+	for i := range index.Integrations {
+		plug := &index.Integrations[i]
+		if plug.Name != id {
+			continue
+		}
+		if found == nil || plug.API == apiversion {
+			found = plug
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("integration %s: %w", id, ErrNotFound)
+	}
+	return found, nil
+}
+
+func (p *Manager) assets() (map[string]string, error) {
+	data, err := os.ReadFile(filepath.Join(p.assetdir, assetIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]string{}, nil
+		}
+		return nil, err
+	}
+
+	assets := map[string]string{}
+	if err := json.Unmarshal(data, &assets); err != nil {
+		return nil, err
+	}
+	return assets, nil
+}
+
+// cacheAsset returns the path of the local copy of the asset at the
+// given URL, downloading it if it's not cached yet or, with refresh,
+// if it can be fetched again.  The copies are named after their
+// content, so that the assets shared by several integrations are
+// stored once.
+func (p *Manager) cacheAsset(u *url.URL, refresh bool) (string, error) {
+	if p.assetdir == "" {
+		return "", fmt.Errorf("%w: no asset cache directory", ErrInvalidOptions)
+	}
+
+	p.assetmu.Lock()
+	defer p.assetmu.Unlock()
+
+	assets, err := p.assets()
+	if err != nil {
+		return "", err
+	}
+
+	var cached string
+	if name, ok := assets[u.String()]; ok {
+		file := filepath.Join(p.assetdir, name)
+		if _, err := os.Stat(file); err == nil {
+			cached = file
+		}
+	}
+	if cached != "" && !refresh {
+		return cached, nil
+	}
+
+	data, err := p.fetchAsset(u)
+	if err != nil {
+		if cached != "" {
+			return cached, nil
+		}
+		return "", err
+	}
+
+	sum := sha256.Sum256(data)
+	name := hex.EncodeToString(sum[:])
+	if ext := strings.ToLower(path.Ext(u.Path)); len(ext) <= 8 &&
+		strings.Trim(ext[min(1, len(ext)):], "abcdefghijklmnopqrstuvwxyz0123456789") == "" {
+		name += ext
+	}
+	file := filepath.Join(p.assetdir, name)
+	if err := os.MkdirAll(p.assetdir, 0755); err != nil {
+		return "", err
+	}
+	if err := writeFile(OSFS{}, file, data); err != nil {
+		return "", err
+	}
+
+	assets[u.String()] = name
+	index, err := json.Marshal(assets)
+	if err != nil {
+		return "", err
+	}
+	if err := writeFile(OSFS{}, filepath.Join(p.assetdir, assetIndexFile), index); err != nil {
+		return "", err
+	}
+	return file, nil
+}
+
+// fetchAsset downloads the asset at the given URL.
+func (p *Manager) fetchAsset(u *url.URL) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", p.useragent)
+	resp, err := p.do(req, http.StatusOK)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxAssetSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxAssetSize {
+		return nil, fmt.Errorf("%s: asset larger than %d bytes", u, maxAssetSize)
+	}
+	return data, nil
+}
+
+// Icon returns the path of the local copy of the icon of the
+// integration with the given id, downloading it into
+// [Options.AssetCacheDir] the first time.
+func (p *Manager) Icon(id string) (string, error) {
+	return p.integrationAsset(id, "icon", func(i *Integration) string { return i.Icon })
+}
+
+// Featured is Icon for the featured image of the integration.
+func (p *Manager) Featured(id string) (string, error) {
+	return p.integrationAsset(id, "featured image", func(i *Integration) string { return i.Featured })
+}
+
+func (p *Manager) integrationAsset(id, what string, ref func(*Integration) string) (string, error) {
+	plug, err := p.integration(id)
+	if err != nil {
+		return "", err
+	}
+	if ref(plug) == "" {
+		return "", fmt.Errorf("%s of %s: %w", what, id, ErrNotFound)
+	}
+
+	u, err := p.indexURL(ref(plug))
+	if err != nil {
+		return "", err
+	}
+	return p.cacheAsset(u, false)
+}
+
+// CacheAssets downloads the icons and featured images of all the
+// integrations of the index not cached yet, e.g. before going
+// offline.
+func (p *Manager) CacheAssets() error {
+	index, err := p.cachedIndex()
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, plug := range index.Integrations {
+		for _, ref := range []string{plug.Icon, plug.Featured} {
+			if ref == "" {
+				continue
+			}
+			u, err := p.indexURL(ref)
+			if err == nil {
+				_, err = p.cacheAsset(u, false)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", plug.Name, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
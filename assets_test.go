@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const assetsIndex = `{
+	"version":"v1",
+	"integrations":[
+		{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0","icon":"s3/icon.png","featured":"s3/featured.svg"},
+		{"name":"gcs","edition":"community","api":"v1.1.0","version":"v1.0.0","icon":"gcs/icon.png"},
+		{"name":"ftp","edition":"community","api":"v1.1.0","version":"v1.0.0"}
+	]
+}`
+
+func newAssetsAPI(t *testing.T, hits map[string]int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".json"):
+			io.WriteString(w, assetsIndex)
+		case strings.HasSuffix(r.URL.Path, "/icon.png"):
+			// the same icon for everyone.
+			io.WriteString(w, "PNG")
+		case strings.HasSuffix(r.URL.Path, "/featured.svg"):
+			io.WriteString(w, "<svg/>")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestIcon(t *testing.T) {
+	hits := map[string]int{}
+	srv := newAssetsAPI(t, hits)
+	dir := t.TempDir()
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, AssetCacheDir: dir})
+
+	icon, err := m.Icon("s3")
+	if err != nil {
+		t.Fatalf("Icon: %v", err)
+	}
+	if want := filepath.Join(dir, sha256sum("PNG")[len("sha256:"):]+".png"); icon != want {
+		t.Errorf("Icon = %s, want %s", icon, want)
+	}
+	if _, err := m.Icon("s3"); err != nil {
+		t.Fatalf("Icon: %v", err)
+	}
+	if n := hits["/v1/integrations/s3/icon.png"]; n != 1 {
+		t.Errorf("icon fetched %d times, want 1", n)
+	}
+
+	// content-addressed: the same image is stored once.
+	if gcs, err := m.Icon("gcs"); err != nil || gcs != icon {
+		t.Errorf("Icon(gcs) = %s, %v, want %s", gcs, err, icon)
+	}
+
+	featured, err := m.Featured("s3")
+	if err != nil {
+		t.Fatalf("Featured: %v", err)
+	}
+	if data, err := os.ReadFile(featured); err != nil || string(data) != "<svg/>" {
+		t.Errorf("featured image = %q, %v", data, err)
+	}
+
+	if _, err := m.Icon("ftp"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Icon(ftp) = %v, want %v", err, ErrNotFound)
+	}
+}
+
+func TestIconWithoutCache(t *testing.T) {
+	srv := newAssetsAPI(t, map[string]int{})
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	if _, err := m.Icon("s3"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Icon = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+func TestCacheAssets(t *testing.T) {
+	hits := map[string]int{}
+	srv := newAssetsAPI(t, hits)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, AssetCacheDir: t.TempDir()})
+
+	if err := m.CacheAssets(); err != nil {
+		t.Fatalf("CacheAssets: %v", err)
+	}
+
+	// everything is served from the cache afterwards.
+	srv.Close()
+	for _, id := range []string{"s3", "gcs"} {
+		if _, err := m.Icon(id); err != nil {
+			t.Errorf("Icon(%s) offline: %v", id, err)
+		}
+	}
+	if _, err := m.Featured("s3"); err != nil {
+		t.Errorf("Featured offline: %v", err)
+	}
+}
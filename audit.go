@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"iter"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	AuditAdd     = "add"
+	AuditUpgrade = "upgrade"
+	AuditDel     = "del"
+)
+
+// Name of the audit log in the package directory.
+const auditLogFile = ".audit.log"
+
+// AuditRecord is an entry of the audit log.
+type AuditRecord struct {
+	Time      time.Time `json:"time"`
+	User      string    `json:"user,omitempty"`
+	Operation string    `json:"operation"` // AuditAdd, AuditUpgrade or AuditDel
+	Name      string    `json:"name"`
+	From      string    `json:"from,omitempty"` // version replaced or removed
+	To        string    `json:"to,omitempty"`   // version installed
+
+	// Whether the operation failed, and why.
+	Failed bool   `json:"failed,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// What the install policy objected to, if it did.
+	Violation *PolicyViolation `json:"violation,omitempty"`
+}
+
+// AuditLog is implemented by the backends that keep a record of the
+// operations done on the packages.  Records are only ever appended.
+type AuditLog interface {
+	Append(*AuditRecord) error
+	History() iter.Seq2[*AuditRecord, error]
+}
+
+// audit appends a record of the operation to the backend log, if
+// it has one, and notifies the webhooks.  Recording is best effort:
+// the operation has already happened at this point and it's not
+// undone if the log can't be written.
+func (p *Manager) audit(rec *AuditRecord, err error) {
+	if rec.Name == "" {
+		return
+	}
+
+	rec.Time = time.Now().UTC()
+	rec.User = p.user
+	if err != nil {
+		rec.Failed = true
+		rec.Error = err.Error()
+		errors.As(err, &rec.Violation)
+	}
+
+	if log, ok := p.store.(AuditLog); ok {
+		log.Append(rec)
+	}
+	p.notify(rec)
+}
+
+// History returns the recorded operations on the named package, or
+// on all of them if name is empty, from the oldest to the newest.
+func (p *Manager) History(name string) iter.Seq2[*AuditRecord, error] {
+	return func(yield func(*AuditRecord, error) bool) {
+		log, ok := p.store.(AuditLog)
+		if !ok {
+			yield(nil, errors.ErrUnsupported)
+			return
+		}
+
+		for rec, err := range log.History() {
+			if err == nil && name != "" && rec.Name != name {
+				continue
+			}
+			if !yield(rec, err) {
+				return
+			}
+		}
+	}
+}
+
+// Append adds a record at the end of the audit log.
+func (f *FlatBackend) Append(rec *AuditRecord) error {
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	fp, err := f.fsys.OpenFile(filepath.Join(f.pkgdir, auditLogFile),
+		os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+	if err != nil {
+		return err
+	}
+
+	// a single write so that concurrent appends don't interleave.
+	_, err = fp.Write(append(data, '\n'))
+	if cerr := fp.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// History iterates over the audit log.
+func (f *FlatBackend) History() iter.Seq2[*AuditRecord, error] {
+	return func(yield func(*AuditRecord, error) bool) {
+		fp, err := f.fsys.Open(filepath.Join(f.pkgdir, auditLogFile))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				yield(nil, err)
+			}
+			return
+		}
+		defer fp.Close()
+
+		scan := bufio.NewScanner(fp)
+		for scan.Scan() {
+			var rec AuditRecord
+			if err := json.Unmarshal(scan.Bytes(), &rec); err != nil {
+				yield(nil, err)
+				return
+			}
+			if !yield(&rec, nil) {
+				return
+			}
+		}
+		if err := scan.Err(); err != nil {
+			yield(nil, err)
+		}
+	}
+}
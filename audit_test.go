@@ -0,0 +1,125 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"iter"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// auditBackend is a fakeBackend keeping its audit log in memory.
+type auditBackend struct {
+	*fakeBackend
+	records []AuditRecord
+}
+
+func (a *auditBackend) Append(rec *AuditRecord) error {
+	a.records = append(a.records, *rec)
+	return nil
+}
+
+func (a *auditBackend) History() iter.Seq2[*AuditRecord, error] {
+	return func(yield func(*AuditRecord, error) bool) {
+		for i := range a.records {
+			if !yield(&a.records[i], nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestAuditRecordsOperations(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := &auditBackend{fakeBackend: newFakeBackend()}
+	m, _ := New(be, &Options{InstallURL: srv.URL, User: "alice"})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.1.0", Upgrade: true}); err != nil {
+		t.Fatalf("Add (upgrade): %v", err)
+	}
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.1.0"}); err == nil {
+		t.Fatal("Add of an installed package succeeded")
+	}
+	if err := m.Del("s3", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	want := []AuditRecord{
+		{Operation: AuditAdd, Name: "s3", To: "v1.0.0"},
+		{Operation: AuditUpgrade, Name: "s3", From: "v1.0.0", To: "v1.1.0"},
+		{Operation: AuditUpgrade, Name: "s3", From: "v1.1.0", To: "v1.1.0", Failed: true},
+		{Operation: AuditDel, Name: "s3", From: "v1.1.0"},
+	}
+
+	var got []*AuditRecord
+	for rec, err := range m.History("s3") {
+		if err != nil {
+			t.Fatalf("History: %v", err)
+		}
+		got = append(got, rec)
+	}
+	if len(got) != len(want) {
+		t.Fatalf("got %d records, want %d", len(got), len(want))
+	}
+	for i, rec := range got {
+		w := want[i]
+		if rec.Operation != w.Operation || rec.Name != w.Name ||
+			rec.From != w.From || rec.To != w.To || rec.Failed != w.Failed {
+			t.Errorf("record %d = %+v, want %+v", i, rec, w)
+		}
+		if rec.User != "alice" || rec.Time.IsZero() {
+			t.Errorf("record %d user/time = %q/%v", i, rec.User, rec.Time)
+		}
+	}
+
+	for range m.History("other") {
+		t.Error("History(other) returned records")
+	}
+}
+
+func TestHistoryUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	for _, err := range m.History("") {
+		if !errors.Is(err, errors.ErrUnsupported) {
+			t.Errorf("History err = %v, want ErrUnsupported", err)
+		}
+	}
+}
+
+func TestFlatBackendAuditLog(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+
+	for range be.History() {
+		t.Fatal("History of an empty log returned records")
+	}
+
+	for _, name := range []string{"s3", "sftp"} {
+		if err := be.Append(&AuditRecord{Operation: AuditAdd, Name: name}); err != nil {
+			t.Fatalf("Append: %v", err)
+		}
+	}
+
+	var names []string
+	for rec, err := range be.History() {
+		if err != nil {
+			t.Fatalf("History: %v", err)
+		}
+		names = append(names, rec.Name)
+	}
+	if len(names) != 2 || names[0] != "s3" || names[1] != "sftp" {
+		t.Errorf("History names = %v", names)
+	}
+
+	// the log must not show up as a package.
+	for pkg := range be.List("") {
+		t.Errorf("List returned %+v", pkg)
+	}
+}
@@ -34,4 +34,12 @@ type Backend interface {
 
 	// Unload a plugin
 	Unload(*Package) error
+
+	// Open returns the stored ptar of an installed plugin.  It
+	// fails with ErrNotInstalled if the plugin is not there.
+	Open(*Package) (io.ReadCloser, error)
+
+	// Verify checks the integrity of an installed plugin, failing
+	// with ErrCorrupted if it was damaged.
+	Verify(*Package) error
 }
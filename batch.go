@@ -0,0 +1,155 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrUnknownOperation = errors.New("unknown operation")
+	ErrAborted          = errors.New("aborted after a previous failure")
+)
+
+// Operation is a change requested to [Manager.Apply].
+type Operation struct {
+	Type    string // SyncInstall, SyncUpgrade or SyncRemove
+	Name    string
+	Version string // constraint as in Spec.Version, or the version to remove
+}
+
+type ApplyOptions struct {
+	// Only compute the plan, don't change anything.
+	DryRun bool
+}
+
+// Apply performs a batch of operations.  They are all planned
+// first, and nothing is done unless every one of them can be: the
+// returned plan then carries the error of each step that can't.
+// Otherwise the steps are done in order, and if one fails the ones
+// already done are undone, as far as possible.
+func (p *Manager) Apply(ops []Operation, opts *ApplyOptions) ([]SyncAction, error) {
+	if opts == nil {
+		opts = &ApplyOptions{}
+	}
+
+	held, err := p.held()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		plan []SyncAction
+		errs []error
+	)
+	for i := range ops {
+		for _, a := range p.planOperation(&ops[i], held) {
+			if a.Err != nil {
+				errs = append(errs, fmt.Errorf("%s: %w", a.Name, a.Err))
+			}
+			plan = append(plan, a)
+		}
+	}
+	if len(errs) != 0 || opts.DryRun {
+		return plan, errors.Join(errs...)
+	}
+
+	for i := range plan {
+		a := &plan[i]
+		if a.Err = p.apply(a); a.Err == nil {
+			continue
+		}
+
+		errs = append(errs, fmt.Errorf("%s: %w", a.Name, a.Err))
+		for j := i + 1; j < len(plan); j++ {
+			plan[j].Err = ErrAborted
+		}
+		for _, done := range slices.Backward(plan[:i]) {
+			if err := p.undo(&done); err != nil {
+				errs = append(errs, fmt.Errorf("failed to undo %s of %s: %w",
+					done.Action, done.Name, err))
+			}
+		}
+		break
+	}
+	return plan, errors.Join(errs...)
+}
+
+// planOperation returns the steps of an operation.
+func (p *Manager) planOperation(op *Operation, held []string) []SyncAction {
+	versions := p.installedVersions(op.Name)
+
+	switch op.Type {
+	case SyncInstall:
+		if len(versions) != 0 {
+			return []SyncAction{{Action: op.Type, Name: op.Name,
+				Err: ErrAlreadyInstalled}}
+		}
+		return []SyncAction{p.planSpec(&Spec{Name: op.Name, Version: op.Version}, versions)}
+
+	case SyncUpgrade:
+		if len(versions) == 0 {
+			return []SyncAction{{Action: op.Type, Name: op.Name,
+				Err: ErrNotInstalled}}
+		}
+		if slices.Contains(held, op.Name) {
+			return []SyncAction{p.holdAction(op.Name, versions)}
+		}
+		return []SyncAction{p.planSpec(&Spec{Name: op.Name, Version: op.Version}, versions)}
+
+	case SyncRemove:
+		var ret []SyncAction
+		for _, v := range versions {
+			if op.Version == "" || v == op.Version {
+				ret = append(ret, SyncAction{Action: SyncRemove, Name: op.Name, From: v})
+			}
+		}
+		if len(ret) == 0 {
+			return []SyncAction{{Action: op.Type, Name: op.Name,
+				Err: ErrNotInstalled}}
+		}
+		return ret
+	}
+
+	return []SyncAction{{Action: op.Type, Name: op.Name,
+		Err: fmt.Errorf("%w %q", ErrUnknownOperation, op.Type)}}
+}
+
+// undo reverts a step that was done.
+func (p *Manager) undo(a *SyncAction) error {
+	switch a.Action {
+	case SyncInstall:
+		return p.Del(a.Name, &DelOptions{Version: a.To})
+	case SyncUpgrade, SyncDowngrade:
+		return p.Add(a.Name, &AddOptions{
+			ImplicitFetch: true,
+			Version:       a.From,
+			Replace:       true,
+		})
+	case SyncRemove:
+		return p.Add(a.Name, &AddOptions{
+			ImplicitFetch:         true,
+			Version:               a.From,
+			AllowMultipleVersions: true,
+		})
+	}
+	return nil
+}
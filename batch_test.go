@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestApplyDryRun(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0", "sftp": "v2.0.0"})
+	be := newFakeBackend(pkgVer("sftp", "v1.0.0"), pkgVer("fs", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	plan, err := m.Apply([]Operation{
+		{Type: SyncInstall, Name: "s3"},
+		{Type: SyncUpgrade, Name: "sftp"},
+		{Type: SyncRemove, Name: "fs"},
+	}, &ApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Apply: %v", err)
+	}
+
+	want := []SyncAction{
+		{Action: SyncInstall, Name: "s3", To: "v1.3.0"},
+		{Action: SyncUpgrade, Name: "sftp", From: "v1.0.0", To: "v2.0.0"},
+		{Action: SyncRemove, Name: "fs", From: "v1.0.0"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("plan = %+v", plan)
+	}
+	for i := range want {
+		got := plan[i]
+		if got.Action != want[i].Action || got.Name != want[i].Name ||
+			got.From != want[i].From || got.To != want[i].To {
+			t.Errorf("step %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+	if len(be.loaded) != 0 || len(be.unloaded) != 0 {
+		t.Errorf("dry run changed the packages")
+	}
+}
+
+func TestApplyPlanningFailure(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	plan, err := m.Apply([]Operation{
+		{Type: SyncInstall, Name: "s3"},
+		{Type: SyncRemove, Name: "fs"},
+		{Type: "frobnicate", Name: "ftp"},
+	}, nil)
+	if !errors.Is(err, ErrNotInstalled) || !errors.Is(err, ErrUnknownOperation) {
+		t.Fatalf("Apply err = %v", err)
+	}
+	if plan[0].Err != nil || plan[1].Err == nil || plan[2].Err == nil {
+		t.Errorf("plan = %+v", plan)
+	}
+	if len(be.loaded) != 0 {
+		t.Errorf("a step was done despite the planning failure")
+	}
+}
+
+func TestApplyUndo(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := &failingBackend{
+		fakeBackend: newFakeBackend(pkgVer("fs", "v1.0.0"), pkgVer("ftp", "v1.0.0")),
+		fail:        "fs",
+	}
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	plan, err := m.Apply([]Operation{
+		{Type: SyncInstall, Name: "s3"},
+		{Type: SyncRemove, Name: "fs"},
+		{Type: SyncRemove, Name: "ftp"},
+	}, nil)
+	if err == nil {
+		t.Fatal("Apply succeeded")
+	}
+	if plan[0].Err != nil || plan[1].Err == nil || !errors.Is(plan[2].Err, ErrAborted) {
+		t.Errorf("plan = %+v", plan)
+	}
+
+	if got := m.installedVersions("s3"); len(got) != 0 {
+		t.Errorf("s3 still installed after the undo: %v", got)
+	}
+	if got := m.installedVersions("ftp"); len(got) != 1 {
+		t.Errorf("ftp removed despite the abort")
+	}
+}
@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrNoProvider = errors.New("no package provides the capability")
+)
+
+// Targets starting with this prefix name a capability, like
+// "capability:storage:s3-compatible", rather than a package.
+const capabilityPrefix = "capability:"
+
+// ProviderPolicy chooses which of the candidates, all available for
+// the current platform, to install for the given capability.
+type ProviderPolicy func(capability string, candidates []*Integration) (*Integration, error)
+
+// PreferOfficial is the default ProviderPolicy.  It picks the
+// official integrations first, then the ones from a verified
+// publisher, and breaks ties by name.
+func PreferOfficial(capability string, candidates []*Integration) (*Integration, error) {
+	rank := func(in *Integration) int {
+		switch {
+		case in.Publisher.Official():
+			return 0
+		case in.Publisher.Verified:
+			return 1
+		}
+		return 2
+	}
+
+	best := slices.MinFunc(candidates, func(a, b *Integration) int {
+		if r := rank(a) - rank(b); r != 0 {
+			return r
+		}
+		return strings.Compare(a.Name, b.Name)
+	})
+	return best, nil
+}
+
+// resolveCapability returns the name of the package to install for
+// the capability.  It fails with ErrAlreadyInstalled if an installed
+// package already provides it.
+func (p *Manager) resolveCapability(capability string) (string, error) {
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return "", err
+		}
+		m, err := p.manifest(pkg)
+		if err != nil {
+			return "", err
+		}
+		if m != nil && slices.Contains(m.Provides, capability) {
+			return "", fmt.Errorf("%w: %s provides %q", ErrAlreadyInstalled,
+				pkg.Name, capability)
+		}
+	}
+
+	integrations, err := p.Query(nil)
+	if err != nil {
+		return "", err
+	}
+
+	var candidates []*Integration
+	for _, in := range integrations {
+		if in.Installation.Available && slices.Contains(in.Provides, capability) {
+			candidates = append(candidates, in)
+		}
+	}
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w %q", ErrNoProvider, capability)
+	}
+
+	in, err := p.providerpolicy(capability, candidates)
+	if err != nil {
+		return "", err
+	}
+	if in == nil {
+		return "", fmt.Errorf("%w %q", ErrNoProvider, capability)
+	}
+	return in.Name, nil
+}
@@ -0,0 +1,97 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+func TestPreferOfficial(t *testing.T) {
+	candidates := []*Integration{
+		{Name: "a-community"},
+		{Name: "z-official", Publisher: Publisher{Organization: OfficialPublisher, Verified: true}},
+		{Name: "b-verified", Publisher: Publisher{Organization: "acme", Verified: true}},
+	}
+	got, err := PreferOfficial("storage:s3-compatible", candidates)
+	if err != nil || got.Name != "z-official" {
+		t.Errorf("PreferOfficial = %v, %v, want z-official", got, err)
+	}
+
+	got, _ = PreferOfficial("storage:s3-compatible", candidates[:1:1])
+	if got.Name != "a-community" {
+		t.Errorf("PreferOfficial = %v, want a-community", got)
+	}
+}
+
+const capabilityIndex = `{
+	"version":"v1",
+	"integrations":[
+		{"name":"minio","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "provides":["storage:s3-compatible"]},
+		{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "provides":["storage:s3-compatible"]},
+		{"name":"sftp","edition":"community","api":"v1.1.0","version":"v1.0.0"}
+	]
+}`
+
+func newCapabilityIndex(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, capabilityIndex)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAddCapability(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"minio": "v1.0.0", "s3": "v1.0.0"})
+	api := newCapabilityIndex(t)
+
+	var offered []string
+	be := newManifestBackend(nil)
+	m, _ := New(be, &Options{
+		InstallURL: repo.URL,
+		ApiURL:     api.URL,
+		ProviderPolicy: func(c string, candidates []*Integration) (*Integration, error) {
+			for _, in := range candidates {
+				offered = append(offered, in.Name)
+			}
+			return candidates[len(candidates)-1], nil
+		},
+	})
+
+	if err := m.Add("capability:storage:s3-compatible", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if !slices.Equal(offered, []string{"minio", "s3"}) {
+		t.Errorf("candidates = %v", offered)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"s3"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestAddCapabilityAlreadyProvided(t *testing.T) {
+	be := newManifestBackend(map[string]string{
+		"minio": "name: minio\nprovides: [storage:s3-compatible]\n",
+	}, pkgVer("minio", "v1.0.0"))
+	m, _ := New(be, nil)
+
+	err := m.Add("capability:storage:s3-compatible", &AddOptions{ImplicitFetch: true})
+	if !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("Add err = %v, want ErrAlreadyInstalled", err)
+	}
+}
+
+func TestAddCapabilityNoProvider(t *testing.T) {
+	api := newCapabilityIndex(t)
+	m, _ := New(newManifestBackend(nil), &Options{ApiURL: api.URL})
+
+	err := m.Add("capability:storage:tape", &AddOptions{ImplicitFetch: true})
+	if !errors.Is(err, ErrNoProvider) {
+		t.Errorf("Add err = %v, want ErrNoProvider", err)
+	}
+}
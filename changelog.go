@@ -0,0 +1,125 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"time"
+
+	"go.yaml.in/yaml/v3"
+)
+
+// Release describes a published version of a package, as listed in
+// the changelog.yaml file the repository serves next to the recipe.
+type Release struct {
+	Version string    `yaml:"version" json:"version"`
+	Date    time.Time `yaml:"date" json:"date,omitzero"`
+	Notes   string    `yaml:"notes" json:"notes"`
+}
+
+type changelog struct {
+	Releases []Release `yaml:"releases"`
+}
+
+// Changelog fetches the release notes of every published version of
+// the named package.  It returns nil if the repository doesn't
+// provide a changelog for it.
+func (p *Manager) Changelog(name string) ([]Release, error) {
+	apiversion, err := p.APIVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := p.fetchBytes(p.repository, path.Join(apiversion, name, "changelog.yaml"), false)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var cl changelog
+	if err := yaml.Unmarshal(data, &cl); err != nil {
+		return nil, fmt.Errorf("failed to decode the changelog: %w", err)
+	}
+	return cl.Releases, nil
+}
+
+// ReleaseNotes returns the changelog entry for the given version of
+// the named package, or nil if there is none.
+func (p *Manager) ReleaseNotes(name, version string) (*Release, error) {
+	releases, err := p.Changelog(name)
+	if err != nil {
+		return nil, err
+	}
+	for i := range releases {
+		if releases[i].Version == version {
+			return &releases[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// PackageInfo is what's known about a version of a package.
+type PackageInfo struct {
+	Inspection
+
+	// Whether this version is installed.
+	Installed bool
+
+	// The changelog entry of this version, if the repository has
+	// one.
+	Release *Release
+}
+
+// Info describes a version of the named package: its manifest, its
+// files and its release notes.  The version defaults to the one
+// installed or, failing that, to the latest one.  Versions that are
+// not installed are fetched from the repository.
+func (p *Manager) Info(name, version string) (*PackageInfo, error) {
+	installed := false
+	if pkg, err := p.lookup(name, version); err == nil {
+		version, installed = pkg.Version, true
+	} else if version == "" {
+		r, err := p.FetchRecipe(name)
+		if err != nil {
+			return nil, err
+		}
+		version = r.Semver()
+	}
+
+	ins, err := p.inspectVersion(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &PackageInfo{
+		Inspection: *ins,
+		Installed:  installed,
+	}
+
+	if p.repository != nil {
+		info.Release, err = p.ReleaseNotes(name, version)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return info, nil
+}
@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"testing"
+)
+
+const testChangelog = `releases:
+  - version: v1.0.7
+    date: 2026-03-01T00:00:00Z
+    notes: |
+      Support for S3 object lock.
+  - version: v1.0.6
+    notes: Bug fixes.
+`
+
+func newChangelogRepository(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(path.Dir(r.URL.Path)) != "s3" {
+			http.NotFound(w, r)
+			return
+		}
+		switch path.Base(r.URL.Path) {
+		case "changelog.yaml":
+			io.WriteString(w, testChangelog)
+		case "recipe.yaml":
+			io.WriteString(w, "name: s3\nversion: v1.0.7\n")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestChangelog(t *testing.T) {
+	srv := newChangelogRepository(t)
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL})
+
+	releases, err := m.Changelog("s3")
+	if err != nil {
+		t.Fatalf("Changelog: %v", err)
+	}
+	if len(releases) != 2 || releases[0].Version != "v1.0.7" ||
+		releases[0].Notes != "Support for S3 object lock.\n" || releases[0].Date.IsZero() {
+		t.Errorf("releases = %+v", releases)
+	}
+
+	rel, err := m.ReleaseNotes("s3", "v1.0.6")
+	if err != nil || rel == nil || rel.Notes != "Bug fixes." {
+		t.Errorf("ReleaseNotes = %+v, %v", rel, err)
+	}
+	if rel, err := m.ReleaseNotes("s3", "v0.1.0"); err != nil || rel != nil {
+		t.Errorf("ReleaseNotes(v0.1.0) = %+v, %v", rel, err)
+	}
+
+	// packages without a changelog have no release notes.
+	if releases, err := m.Changelog("ftp"); err != nil || releases != nil {
+		t.Errorf("Changelog(ftp) = %v, %v", releases, err)
+	}
+}
+
+func TestInfo(t *testing.T) {
+	srv := newChangelogRepository(t)
+	be := &diffBackend{
+		fakeBackend: newFakeBackend(pkgVer("s3", "v1.0.7")),
+		manifests:   map[string]string{"v1.0.7": "name: s3\nlicense: ISC\n"},
+		files: map[string][]FileEntry{
+			"v1.0.7": {{Path: "manifest.yaml", Size: 24, Mode: 0644}},
+		},
+	}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	info, err := m.Info("s3", "")
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if !info.Installed || info.Package.Version != "v1.0.7" {
+		t.Errorf("package = %+v, installed = %v", info.Package, info.Installed)
+	}
+	if info.Manifest.License != "ISC" || len(info.Files) != 1 {
+		t.Errorf("manifest = %+v, files = %v", info.Manifest, info.Files)
+	}
+	if info.Release == nil || info.Release.Version != "v1.0.7" {
+		t.Errorf("release = %+v", info.Release)
+	}
+}
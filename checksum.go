@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+var (
+	ErrBadChecksum      = errors.New("invalid checksum")
+	ErrChecksumMismatch = errors.New("checksum mismatch")
+	ErrWeakChecksum     = errors.New("checksum algorithm too weak")
+)
+
+type hashAlgorithm struct {
+	new      func() hash.Hash
+	strength int // security level, in bits
+}
+
+// The algorithms usable in checksums.  New ones can be added without
+// breaking the existing checksums, since they always carry the
+// algorithm they were computed with.
+var hashAlgorithms = map[string]hashAlgorithm{
+	"sha256": {sha256.New, 128},
+	"sha512": {sha512.New, 256},
+	"blake3": {func() hash.Hash { return blake3.New() }, 128},
+}
+
+// ChecksumStrength returns the security level, in bits, of the
+// algorithm the checksum was computed with.
+func ChecksumStrength(checksum string) (int, error) {
+	algo, _, _ := strings.Cut(checksum, ":")
+	h, ok := hashAlgorithms[algo]
+	if !ok {
+		return 0, fmt.Errorf("%w %q: unsupported algorithm", ErrBadChecksum, checksum)
+	}
+	return h.strength, nil
+}
+
+// parseChecksum splits a checksum in the "algorithm:hexdigest" form
+// and returns a new hash for the algorithm with the decoded digest.
+func parseChecksum(checksum string) (hash.Hash, []byte, error) {
+	algo, digest, ok := strings.Cut(checksum, ":")
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q: missing algorithm", ErrBadChecksum, checksum)
+	}
+
+	alg, ok := hashAlgorithms[algo]
+	if !ok {
+		return nil, nil, fmt.Errorf("%w %q: unsupported algorithm", ErrBadChecksum, checksum)
+	}
+	h := alg.new()
+
+	sum, err := hex.DecodeString(digest)
+	if err != nil || len(sum) != h.Size() {
+		return nil, nil, fmt.Errorf("%w %q: malformed digest", ErrBadChecksum, checksum)
+	}
+
+	return h, sum, nil
+}
+
+// verifyingReader hashes what is read through it and, once the
+// underlying reader is exhausted, fails with ErrChecksumMismatch
+// instead of io.EOF if the digest doesn't match.  This way a consumer
+// like [Backend.Load] never sees a complete stream for bad content.
+type verifyingReader struct {
+	rd   io.Reader
+	hash hash.Hash
+	sum  []byte
+}
+
+// newVerifyingReader returns a reader checking the content of rd
+// against the given checksum, which must be computed with an
+// algorithm at least as strong as minstrength bits.
+func newVerifyingReader(rd io.Reader, checksum string, minstrength int) (io.Reader, error) {
+	strength, err := ChecksumStrength(checksum)
+	if err != nil {
+		return nil, err
+	}
+	if strength < minstrength {
+		return nil, fmt.Errorf("%w: %q provides %d bits, %d required",
+			ErrWeakChecksum, checksum, strength, minstrength)
+	}
+
+	h, sum, err := parseChecksum(checksum)
+	if err != nil {
+		return nil, err
+	}
+	return &verifyingReader{rd: rd, hash: h, sum: sum}, nil
+}
+
+func (v *verifyingReader) Read(p []byte) (int, error) {
+	n, err := v.rd.Read(p)
+	v.hash.Write(p[:n])
+	if err == io.EOF {
+		if got := v.hash.Sum(nil); !bytes.Equal(got, v.sum) {
+			return n, fmt.Errorf("%w: got %x, want %x",
+				ErrChecksumMismatch, got, v.sum)
+		}
+	}
+	return n, err
+}
@@ -0,0 +1,137 @@
+package pkg
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zeebo/blake3"
+)
+
+func sha256sum(s string) string {
+	return fmt.Sprintf("sha256:%x", sha256.Sum256([]byte(s)))
+}
+
+func TestParseChecksumErrors(t *testing.T) {
+	for _, in := range []string{
+		"",
+		"deadbeef",
+		"md5:d41d8cd98f00b204e9800998ecf8427e",
+		"sha512:" + strings.Repeat("ab", 32),
+		"sha256:nothex",
+		"sha256:deadbeef",
+	} {
+		if _, _, err := parseChecksum(in); !errors.Is(err, ErrBadChecksum) {
+			t.Errorf("parseChecksum(%q) err = %v, want ErrBadChecksum", in, err)
+		}
+	}
+}
+
+func TestVerifyingReader(t *testing.T) {
+	rd, err := newVerifyingReader(strings.NewReader("PTARDATA"), sha256sum("PTARDATA"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatalf("ReadAll: %v", err)
+	}
+	if string(b) != "PTARDATA" {
+		t.Errorf("read %q", b)
+	}
+
+	rd, err = newVerifyingReader(strings.NewReader("TAMPERED"), sha256sum("PTARDATA"), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := io.ReadAll(rd); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("ReadAll err = %v, want ErrChecksumMismatch", err)
+	}
+}
+
+func TestFetchBinaryVerifiesRecipeChecksum(t *testing.T) {
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+
+	for _, tt := range []struct {
+		name    string
+		payload string
+		wantErr error
+	}{
+		{"match", "PTARDATA", nil},
+		{"mismatch", "TAMPERED", ErrChecksumMismatch},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if strings.HasSuffix(r.URL.Path, "recipe.yaml") {
+					fmt.Fprintf(w, "name: s3\nversion: v1.2.3\nchecksums:\n  %s: %s\n",
+						platform, sha256sum("PTARDATA"))
+					return
+				}
+				io.WriteString(w, tt.payload)
+			}))
+			defer srv.Close()
+
+			be := newFakeBackend()
+			m, _ := New(be, &Options{InstallURL: srv.URL})
+			err := m.Add("s3", &AddOptions{ImplicitFetch: true})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Add err = %v, want %v", err, tt.wantErr)
+			}
+			if tt.wantErr != nil && len(be.loaded) != 0 {
+				t.Errorf("package loaded despite checksum mismatch")
+			}
+		})
+	}
+}
+
+func TestChecksumAlgorithms(t *testing.T) {
+	const msg = "PTARDATA"
+	sha512sum := fmt.Sprintf("sha512:%x", sha512.Sum512([]byte(msg)))
+	b3 := blake3.Sum256([]byte(msg))
+	blake3sum := fmt.Sprintf("blake3:%x", b3)
+
+	for _, sum := range []string{sha256sum(msg), sha512sum, blake3sum} {
+		rd, err := newVerifyingReader(strings.NewReader(msg), sum, 0)
+		if err != nil {
+			t.Fatalf("newVerifyingReader(%q): %v", sum, err)
+		}
+		if _, err := io.ReadAll(rd); err != nil {
+			t.Errorf("%s: %v", sum, err)
+		}
+	}
+}
+
+func TestChecksumMinStrength(t *testing.T) {
+	if n, err := ChecksumStrength(sha256sum("x")); err != nil || n != 128 {
+		t.Errorf("ChecksumStrength(sha256) = %d, %v", n, err)
+	}
+
+	_, err := newVerifyingReader(strings.NewReader("x"), sha256sum("x"), 256)
+	if !errors.Is(err, ErrWeakChecksum) {
+		t.Errorf("sha256 with 256 bits required: err = %v, want ErrWeakChecksum", err)
+	}
+
+	sha512sum := fmt.Sprintf("sha512:%x", sha512.Sum512([]byte("x")))
+	if _, err := newVerifyingReader(strings.NewReader("x"), sha512sum, 256); err != nil {
+		t.Errorf("sha512 with 256 bits required: %v", err)
+	}
+}
+
+func TestChecksumMinStrengthUnpinned(t *testing.T) {
+	m, _ := New(newFakeBackend(), &Options{
+		InstallURL:          "http://127.0.0.1:1",
+		MinChecksumStrength: 128,
+	})
+	_, _, err := m.openbinary(context.Background(), pkgVer("s3", "v1.0.0"), "")
+	if !errors.Is(err, ErrWeakChecksum) {
+		t.Errorf("openbinary without a checksum: err = %v, want ErrWeakChecksum", err)
+	}
+}
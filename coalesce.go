@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+)
+
+// flight is an operation shared by the callers that requested it
+// concurrently.
+type flight struct {
+	done    chan struct{}
+	err     error
+	waiters int
+	cancel  context.CancelFunc
+}
+
+// addKey identifies an install request: the same target with the
+// same options.
+func addKey(target string, opts *AddOptions) string {
+	if opts == nil {
+		opts = &AddOptions{}
+	}
+	if abs, err := filepath.Abs(target); err == nil && filepath.Ext(target) == ".ptar" {
+		target = abs
+	}
+	return fmt.Sprintf("add %s %+v", NormalizeName(target), *opts)
+}
+
+// coalesce runs fn, unless a call with the same key is in progress
+// in which case its result is waited for and returned instead.  The
+// shared call is only cancelled once all the callers waiting for it
+// gave up.
+func (p *Manager) coalesce(ctx context.Context, key string, fn func(context.Context) error) error {
+	p.flightmu.Lock()
+	f, ok := p.flights[key]
+	if !ok {
+		fctx, cancel := context.WithCancel(withOp(context.Background(), key))
+		f = &flight{done: make(chan struct{}), cancel: cancel}
+		if p.flights == nil {
+			p.flights = make(map[string]*flight)
+		}
+		p.flights[key] = f
+
+		go func() {
+			f.err = fn(fctx)
+			cancel()
+
+			p.flightmu.Lock()
+			if p.flights[key] == f {
+				delete(p.flights, key)
+			}
+			p.flightmu.Unlock()
+			close(f.done)
+		}()
+	}
+	f.waiters++
+	p.flightmu.Unlock()
+
+	select {
+	case <-f.done:
+		return f.err
+	case <-ctx.Done():
+		p.flightmu.Lock()
+		f.waiters--
+		last := f.waiters == 0
+		if last {
+			// a new call must not join the cancelled one.
+			delete(p.flights, key)
+		}
+		p.flightmu.Unlock()
+
+		if last {
+			// wait for the operation to stop, so that what it
+			// removed is restored before returning.
+			f.cancel()
+			<-f.done
+		}
+		return ctx.Err()
+	}
+}
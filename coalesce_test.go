@@ -0,0 +1,169 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+func TestAddCoalesced(t *testing.T) {
+	var downloads atomic.Int32
+	release := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "recipe.yaml" {
+			io.WriteString(w, "name: s3\nversion: v1.3.0\n")
+			return
+		}
+		downloads.Add(1)
+		<-release
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	var wg sync.WaitGroup
+	errs := make([]error, 2)
+	for i := range errs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			errs[i] = m.Add("s3", &AddOptions{ImplicitFetch: true})
+		}()
+	}
+
+	// let both requests join the same flight before it completes.
+	for {
+		m.flightmu.Lock()
+		var waiters int
+		for _, f := range m.flights {
+			waiters = f.waiters
+		}
+		m.flightmu.Unlock()
+		if waiters == 2 {
+			break
+		}
+	}
+	close(release)
+	wg.Wait()
+
+	for i, err := range errs {
+		if err != nil {
+			t.Errorf("Add #%d: %v", i, err)
+		}
+	}
+	if n := downloads.Load(); n != 1 {
+		t.Errorf("downloaded %d times, want once", n)
+	}
+	if len(be.loaded) != 1 {
+		t.Errorf("loaded %d packages, want 1", len(be.loaded))
+	}
+}
+
+func TestCoalesceCancel(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+
+	started := make(chan struct{})
+	var shared context.Context
+	fn := func(ctx context.Context) error {
+		shared = ctx
+		close(started)
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	ctx2, cancel2 := context.WithCancel(context.Background())
+
+	res := make(chan error, 2)
+	go func() { res <- m.coalesce(ctx1, "key", fn) }()
+	<-started
+	go func() { res <- m.coalesce(ctx2, "key", fn) }()
+	for {
+		m.flightmu.Lock()
+		f := m.flights["key"]
+		n := f.waiters
+		m.flightmu.Unlock()
+		if n == 2 {
+			break
+		}
+	}
+
+	cancel1()
+	if err := <-res; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller err = %v, want context.Canceled", err)
+	}
+	if shared.Err() != nil {
+		t.Fatal("shared call cancelled while still awaited")
+	}
+
+	cancel2()
+	if err := <-res; !errors.Is(err, context.Canceled) {
+		t.Errorf("second caller err = %v, want context.Canceled", err)
+	}
+	if shared.Err() == nil {
+		t.Error("shared call not cancelled")
+	}
+}
+
+// A call made while a cancelled one is still stopping starts afresh
+// instead of joining it.
+func TestCoalesceCancelRejoin(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+
+	started, stopping := make(chan struct{}), make(chan struct{})
+	release1, release2 := make(chan struct{}), make(chan struct{})
+	fn1 := func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		close(stopping)
+		<-release1
+		return ctx.Err()
+	}
+	fn2 := func(ctx context.Context) error {
+		<-release2
+		return nil
+	}
+
+	ctx1, cancel1 := context.WithCancel(context.Background())
+	res1 := make(chan error, 1)
+	go func() { res1 <- m.coalesce(ctx1, "key", fn1) }()
+	<-started
+	cancel1()
+	<-stopping
+
+	res2 := make(chan error, 1)
+	go func() { res2 <- m.coalesce(context.Background(), "key", fn2) }()
+	for {
+		m.flightmu.Lock()
+		f := m.flights["key"]
+		m.flightmu.Unlock()
+		if f != nil {
+			break
+		}
+	}
+
+	// the cancelled call is over, and mustn't forget the new one.
+	close(release1)
+	if err := <-res1; !errors.Is(err, context.Canceled) {
+		t.Errorf("first caller err = %v, want context.Canceled", err)
+	}
+	m.flightmu.Lock()
+	f := m.flights["key"]
+	m.flightmu.Unlock()
+	if f == nil {
+		t.Error("new call dropped by the cancelled one")
+	}
+
+	close(release2)
+	if err := <-res2; err != nil {
+		t.Errorf("second caller err = %v, want the new call's result", err)
+	}
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Collection is a curated list of integrations, e.g. "Getting
+// started" or "Cloud storage", as published by the registry.
+type Collection struct {
+	Id           string   `json:"id"`
+	Title        string   `json:"title"`
+	Description  string   `json:"description"`
+	Icon         string   `json:"icon,omitempty"`
+	Integrations []string `json:"integrations"` // names, in display order
+}
+
+type collectionIndex struct {
+	Version     string       `json:"version"`
+	Collections []Collection `json:"collections"`
+}
+
+// Collections returns the curated collections of the registry at
+// ApiURL, in display order.  It returns nil if the registry doesn't
+// publish any.
+func (p *Manager) Collections() ([]Collection, error) {
+	if p.api == nil {
+		return nil, nil
+	}
+
+	endp := "v1/integrations/collections-" + PLUGIN_BUNDLE_VERSION + ".json"
+	res, err := p.fetch(p.api, endp, false)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	var index collectionIndex
+	if err := json.NewDecoder(res.Body).Decode(&index); err != nil {
+		return nil, fmt.Errorf("failed to decode the collections: %w", err)
+	}
+	return index.Collections, nil
+}
@@ -0,0 +1,63 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCollections(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/v1/integrations/collections-") {
+			http.NotFound(w, r)
+			return
+		}
+		io.WriteString(w, `{"version":"v1","collections":[
+			{"id":"getting-started","title":"Getting started","integrations":["fs","s3"]},
+			{"id":"cloud","title":"Cloud storage","integrations":["s3","gcs","azure"]}
+		]}`)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+	got, err := m.Collections()
+	if err != nil {
+		t.Fatalf("Collections: %v", err)
+	}
+	if len(got) != 2 || got[0].Id != "getting-started" || got[1].Title != "Cloud storage" ||
+		len(got[1].Integrations) != 3 {
+		t.Errorf("collections = %+v", got)
+	}
+}
+
+func TestCollectionsMissing(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+	if got, err := m.Collections(); err != nil || got != nil {
+		t.Errorf("Collections = %v, %v, want none", got, err)
+	}
+
+	m, _ = New(newFakeBackend(), nil)
+	if got, err := m.Collections(); err != nil || got != nil {
+		t.Errorf("Collections without ApiURL = %v, %v, want none", got, err)
+	}
+}
+
+func TestHTTPHandlerCollections(t *testing.T) {
+	srv := newTestHTTPHandler(t, newFakeBackend())
+
+	resp, err := http.Get(srv.URL + "/collections")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || strings.TrimSpace(string(body)) != "[]" {
+		t.Errorf("collections = %s %s, want an empty list", resp.Status, body)
+	}
+}
@@ -0,0 +1,94 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+)
+
+// Compression is a compressed variant of the packages, published by
+// the repository next to the plain ptar.  The packages are always
+// stored decompressed, and the recipe checksums apply to the plain
+// ptar.
+type Compression struct {
+	// Appended to the package file name, e.g. ".zst".
+	Suffix string
+
+	// Returns a reader decompressing rd.
+	NewReader func(rd io.Reader) (io.ReadCloser, error)
+}
+
+// GzipCompression is the gzip variant of the packages, ".ptar.gz".
+// Decoders for other formats, like zstd, can be plugged in the same
+// way.
+var GzipCompression = Compression{
+	Suffix: ".gz",
+	NewReader: func(rd io.Reader) (io.ReadCloser, error) {
+		return gzip.NewReader(rd)
+	},
+}
+
+// compression returns the file name without the suffix of its
+// compressed variant, and the variant, or nil if it's not one.
+func (p *Manager) compression(name string) (string, *Compression) {
+	for i := range p.compressions {
+		c := &p.compressions[i]
+		if base, ok := strings.CutSuffix(name, ".ptar"+c.Suffix); ok {
+			return base + ".ptar", c
+		}
+	}
+	return name, nil
+}
+
+// decompress wraps body so that it's decompressed as the variant says.
+func decompress(body io.ReadCloser, c *Compression) (io.ReadCloser, error) {
+	rd, err := c.NewReader(body)
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{rd, closers{rd, body}}, nil
+}
+
+// closers closes all of them, in order.
+type closers []io.Closer
+
+func (c closers) Close() error {
+	var errs []error
+	for _, cl := range c {
+		errs = append(errs, cl.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// openPackageFile opens the local package file, decompressing it if
+// it's a compressed variant.
+func (p *Manager) openPackageFile(file string, c *Compression) (io.ReadCloser, error) {
+	rc, err := p.openLocal(file)
+	if err != nil || c == nil {
+		return rc, err
+	}
+	return decompress(rc, c)
+}
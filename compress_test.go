@@ -0,0 +1,87 @@
+package pkg
+
+import (
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func gzipped(t *testing.T, data string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	w := gzip.NewWriter(&buf)
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestFetchCompressedVariant(t *testing.T) {
+	var served []string
+	compressed := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		served = append(served, filepath.Base(r.URL.Path))
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".ptar.gz"):
+			if !compressed {
+				http.NotFound(w, r)
+				return
+			}
+			w.Write(gzipped(t, "PTARDATA"))
+		default:
+			w.Write([]byte("PTARDATA"))
+		}
+	}))
+	defer srv.Close()
+
+	pkg := pkgVer("s3", "v1.0.0")
+	for _, tt := range []struct {
+		compressed bool
+		want       []string
+	}{
+		{true, []string{pkg.Filename() + ".gz"}},
+		{false, []string{pkg.Filename() + ".gz", pkg.Filename()}},
+	} {
+		compressed, served = tt.compressed, nil
+		be := newFakeBackend()
+		m, _ := New(be, &Options{InstallURL: srv.URL, Compressions: []Compression{GzipCompression}})
+
+		// the checksum is the one of the plain ptar.
+		err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0", Checksum: sha256sum("PTARDATA")})
+		if err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+		if string(be.loadData[pkg.Filename()]) != "PTARDATA" {
+			t.Errorf("loaded data = %q, want PTARDATA", be.loadData[pkg.Filename()])
+		}
+		if strings.Join(served, " ") != strings.Join(tt.want, " ") {
+			t.Errorf("served = %v, want %v", served, tt.want)
+		}
+	}
+}
+
+func TestAddCompressedFile(t *testing.T) {
+	ptar := filepath.Join(t.TempDir(), "s3_v1.0.0_"+runtime.GOOS+"_"+runtime.GOARCH+".ptar.gz")
+	if err := os.WriteFile(ptar, gzipped(t, "PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{Compressions: []Compression{GzipCompression}})
+	if err := m.Add(ptar, nil); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	pkg := pkgVer("s3", "v1.0.0")
+	if string(be.loadData[pkg.Filename()]) != "PTARDATA" {
+		t.Errorf("loaded data = %q, want PTARDATA", be.loadData[pkg.Filename()])
+	}
+}
@@ -0,0 +1,227 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+var (
+	ErrUnknownProtocol = errors.New("no connector for the protocol")
+	ErrBadOption       = errors.New("invalid connector option")
+)
+
+// optionsSchema is the subset of JSON Schema understood for the
+// connector validators: an object whose properties are the location
+// options, all of them given as strings.
+type optionsSchema struct {
+	Type                 string                   `json:"type"`
+	Properties           map[string]*optionSchema `json:"properties"`
+	Required             []string                 `json:"required"`
+	AdditionalProperties *bool                    `json:"additionalProperties"`
+}
+
+type optionSchema struct {
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Default     any      `json:"default"`
+	Enum        []any    `json:"enum"`
+	Pattern     string   `json:"pattern"`
+	MinLength   *int     `json:"minLength"`
+	MaxLength   *int     `json:"maxLength"`
+	Minimum     *float64 `json:"minimum"`
+	Maximum     *float64 `json:"maximum"`
+}
+
+// schema parses the validator of the connector, or returns nil if it
+// has none.
+func (conn *ManifestConnector) schema() (*optionsSchema, error) {
+	if strings.TrimSpace(conn.Validator) == "" {
+		return nil, nil
+	}
+
+	var s optionsSchema
+	if err := json.Unmarshal([]byte(conn.Validator), &s); err != nil {
+		return nil, fmt.Errorf("bad validator for the %s connector: %w", conn.Type, err)
+	}
+	if s.Type != "" && s.Type != "object" {
+		return nil, fmt.Errorf("bad validator for the %s connector: type %q", conn.Type, s.Type)
+	}
+	for name, o := range s.Properties {
+		if o == nil {
+			return nil, fmt.Errorf("bad validator for the %s connector: no schema for %q", conn.Type, name)
+		}
+		if o.Pattern != "" {
+			if _, err := regexp.Compile(o.Pattern); err != nil {
+				return nil, fmt.Errorf("bad validator for the %s connector: %q: %w", conn.Type, name, err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+// connectors returns the connectors handling the given protocol.
+func (m *Manifest) connectors(proto string) []*ManifestConnector {
+	var ret []*ManifestConnector
+	for i := range m.Connectors {
+		if slices.Contains(m.Connectors[i].Protocols, proto) {
+			ret = append(ret, &m.Connectors[i])
+		}
+	}
+	return ret
+}
+
+// ValidateConnectorOptions checks the location options given by the
+// user for the protocol against the schema the plugin declares, so
+// that the mistakes are caught before spawning the connector.  When
+// several connectors handle the protocol, the options have to suit
+// one of them.  Connectors without a validator accept anything.
+func (m *Manifest) ValidateConnectorOptions(proto string, opts map[string]string) error {
+	conns := m.connectors(proto)
+	if len(conns) == 0 {
+		return fmt.Errorf("%w %q", ErrUnknownProtocol, proto)
+	}
+
+	var first error
+	for _, conn := range conns {
+		s, err := conn.schema()
+		if err != nil {
+			return err
+		}
+		if s == nil {
+			return nil
+		}
+		err = s.validate(opts)
+		if err == nil {
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// ConnectorOptionDefaults returns the default values the schema of
+// the connectors handling the protocol declares for their options.
+func (m *Manifest) ConnectorOptionDefaults(proto string) (map[string]string, error) {
+	conns := m.connectors(proto)
+	if len(conns) == 0 {
+		return nil, fmt.Errorf("%w %q", ErrUnknownProtocol, proto)
+	}
+
+	defaults := map[string]string{}
+	for _, conn := range conns {
+		s, err := conn.schema()
+		if err != nil {
+			return nil, err
+		}
+		if s == nil {
+			continue
+		}
+		for name, o := range s.Properties {
+			if _, ok := defaults[name]; ok || o.Default == nil {
+				continue
+			}
+			defaults[name] = fmt.Sprint(o.Default)
+		}
+	}
+	return defaults, nil
+}
+
+func (s *optionsSchema) validate(opts map[string]string) error {
+	for _, name := range s.Required {
+		if _, ok := opts[name]; !ok {
+			return fmt.Errorf("%w: %q is required", ErrBadOption, name)
+		}
+	}
+
+	for name, value := range opts {
+		o, ok := s.Properties[name]
+		if !ok {
+			if s.AdditionalProperties != nil && !*s.AdditionalProperties {
+				return fmt.Errorf("%w: unknown option %q", ErrBadOption, name)
+			}
+			continue
+		}
+		if err := o.validate(value); err != nil {
+			return fmt.Errorf("%w: %q: %w", ErrBadOption, name, err)
+		}
+	}
+	return nil
+}
+
+func (o *optionSchema) validate(value string) error {
+	var num float64
+	switch o.Type {
+	case "", "string":
+	case "boolean":
+		if _, err := strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("%q is not a boolean", value)
+		}
+	case "integer":
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("%q is not an integer", value)
+		}
+		num = float64(n)
+	case "number":
+		n, err := strconv.ParseFloat(value, 64)
+		if err != nil || math.IsNaN(n) || math.IsInf(n, 0) {
+			return fmt.Errorf("%q is not a number", value)
+		}
+		num = n
+	default:
+		return fmt.Errorf("unsupported type %q", o.Type)
+	}
+
+	if len(o.Enum) != 0 && !slices.ContainsFunc(o.Enum, func(v any) bool {
+		return fmt.Sprint(v) == value
+	}) {
+		return fmt.Errorf("%q is not one of %v", value, o.Enum)
+	}
+	if o.Pattern != "" {
+		if ok, _ := regexp.MatchString(o.Pattern, value); !ok {
+			return fmt.Errorf("%q doesn't match %q", value, o.Pattern)
+		}
+	}
+
+	if n := utf8.RuneCountInString(value); o.MinLength != nil && n < *o.MinLength {
+		return fmt.Errorf("shorter than %d characters", *o.MinLength)
+	} else if o.MaxLength != nil && n > *o.MaxLength {
+		return fmt.Errorf("longer than %d characters", *o.MaxLength)
+	}
+
+	if o.Type == "integer" || o.Type == "number" {
+		if o.Minimum != nil && num < *o.Minimum {
+			return fmt.Errorf("%s is less than %v", value, *o.Minimum)
+		}
+		if o.Maximum != nil && num > *o.Maximum {
+			return fmt.Errorf("%s is more than %v", value, *o.Maximum)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+const s3Validator = `{
+	"type": "object",
+	"properties": {
+		"bucket": {"type": "string", "minLength": 3},
+		"region": {"type": "string", "enum": ["eu-west-1", "us-east-1"], "default": "us-east-1"},
+		"use_tls": {"type": "boolean", "default": true},
+		"port": {"type": "integer", "minimum": 1, "maximum": 65535},
+		"prefix": {"type": "string", "pattern": "^[a-z/]+$"}
+	},
+	"required": ["bucket"],
+	"additionalProperties": false
+}`
+
+func TestValidateConnectorOptions(t *testing.T) {
+	m := &Manifest{
+		Connectors: []ManifestConnector{
+			{Type: ConnectorTypeStorage, Protocols: []string{"s3"}, Validator: s3Validator},
+			{Type: ConnectorTypeImporter, Protocols: []string{"fs"}},
+		},
+	}
+
+	valid := []map[string]string{
+		{"bucket": "backups"},
+		{"bucket": "backups", "region": "eu-west-1", "use_tls": "false", "port": "9000", "prefix": "a/b"},
+	}
+	for _, opts := range valid {
+		if err := m.ValidateConnectorOptions("s3", opts); err != nil {
+			t.Errorf("ValidateConnectorOptions(%v) = %v", opts, err)
+		}
+	}
+
+	invalid := []map[string]string{
+		{},
+		{"bucket": "ab"},
+		{"bucket": "backups", "region": "mars-1"},
+		{"bucket": "backups", "use_tls": "maybe"},
+		{"bucket": "backups", "port": "0"},
+		{"bucket": "backups", "port": "http"},
+		{"bucket": "backups", "prefix": "A"},
+		{"bucket": "backups", "regoin": "eu-west-1"},
+	}
+	for _, opts := range invalid {
+		if err := m.ValidateConnectorOptions("s3", opts); !errors.Is(err, ErrBadOption) {
+			t.Errorf("ValidateConnectorOptions(%v) = %v, want %v", opts, err, ErrBadOption)
+		}
+	}
+
+	// no validator: anything goes.
+	if err := m.ValidateConnectorOptions("fs", map[string]string{"x": "y"}); err != nil {
+		t.Errorf("ValidateConnectorOptions(fs) = %v", err)
+	}
+	if err := m.ValidateConnectorOptions("ftp", nil); !errors.Is(err, ErrUnknownProtocol) {
+		t.Errorf("ValidateConnectorOptions(ftp) = %v, want %v", err, ErrUnknownProtocol)
+	}
+}
+
+func TestValidateConnectorOptionsBadSchema(t *testing.T) {
+	m := &Manifest{
+		Connectors: []ManifestConnector{
+			{Type: ConnectorTypeStorage, Protocols: []string{"s3"}, Validator: "{oops"},
+		},
+	}
+	err := m.ValidateConnectorOptions("s3", nil)
+	if err == nil || errors.Is(err, ErrBadOption) {
+		t.Errorf("ValidateConnectorOptions = %v, want a schema error", err)
+	}
+}
+
+func TestConnectorOptionDefaults(t *testing.T) {
+	m := &Manifest{
+		Connectors: []ManifestConnector{
+			{Type: ConnectorTypeStorage, Protocols: []string{"s3"}, Validator: s3Validator},
+		},
+	}
+	defaults, err := m.ConnectorOptionDefaults("s3")
+	if err != nil {
+		t.Fatalf("ConnectorOptionDefaults: %v", err)
+	}
+	if len(defaults) != 2 || defaults["region"] != "us-east-1" || defaults["use_tls"] != "true" {
+		t.Errorf("defaults = %v", defaults)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PlakarKorp/kloset/snapshot"
+	"github.com/PlakarKorp/kloset/snapshot/vfs"
+)
+
+// maxSymlinks bounds how many symbolic links are followed when
+// resolving a single path, as the kernels do.
+const maxSymlinks = 40
+
+var (
+	ErrPathEscape = errors.New("path escapes the package directory")
+)
+
+// within tells whether name is root or lies under it.  Both are
+// expected to be clean; symbolic links are not followed.
+func within(root, name string) bool {
+	rel, err := filepath.Rel(root, name)
+	if err != nil {
+		return false
+	}
+	return rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) &&
+		!filepath.IsAbs(rel)
+}
+
+// readlinkFunc reports the target of the symbolic link at the given
+// slash-separated path, relative to the package root, and whether
+// there is one at all.
+type readlinkFunc func(name string) (target string, ok bool, err error)
+
+// resolveIn resolves name, relative to the package root, following
+// the symbolic links along the way.  It fails with ErrPathEscape as
+// soon as a step leads out of the package, and returns the resolved
+// slash-separated path otherwise.  Missing components are taken as
+// is.
+func resolveIn(name string, readlink readlinkFunc) (string, error) {
+	// the name isn't cleaned: a ".." following a symbolic link
+	// applies to its target, not to the link itself.
+	slashed := filepath.ToSlash(name)
+	if slashed == "" || path.IsAbs(slashed) || filepath.IsAbs(name) ||
+		filepath.VolumeName(name) != "" {
+		return "", fmt.Errorf("%w %q", ErrBadFilePath, name)
+	}
+
+	var resolved []string
+	todo := strings.Split(slashed, "/")
+	links := 0
+	for len(todo) > 0 {
+		elem := todo[0]
+		todo = todo[1:]
+
+		switch elem {
+		case "", ".":
+			continue
+		case "..":
+			if len(resolved) == 0 {
+				return "", fmt.Errorf("%w: %q", ErrPathEscape, name)
+			}
+			resolved = resolved[:len(resolved)-1]
+			continue
+		}
+
+		target, ok, err := readlink(path.Join(path.Join(resolved...), elem))
+		if err != nil {
+			return "", err
+		}
+		if !ok {
+			resolved = append(resolved, elem)
+			continue
+		}
+
+		if links++; links > maxSymlinks {
+			return "", fmt.Errorf("%w %q: too many symbolic links", ErrBadFilePath, name)
+		}
+		if target == "" || path.IsAbs(target) || filepath.IsAbs(target) ||
+			filepath.VolumeName(target) != "" {
+			return "", fmt.Errorf("%w: %q", ErrPathEscape, name)
+		}
+		todo = append(strings.Split(target, "/"), todo...)
+	}
+
+	if len(resolved) == 0 {
+		return ".", nil
+	}
+	return path.Join(resolved...), nil
+}
+
+// fsReadlink reads the symbolic links of the tree at root.
+func fsReadlink(fsys FS, root string) readlinkFunc {
+	return func(name string) (string, bool, error) {
+		p := filepath.Join(root, filepath.FromSlash(name))
+		fi, err := fsys.Lstat(p)
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", false, nil
+		}
+		if err != nil {
+			return "", false, err
+		}
+		if fi.Mode()&fs.ModeSymlink == 0 {
+			return "", false, nil
+		}
+		target, err := fsys.Readlink(p)
+		if err != nil {
+			return "", false, err
+		}
+		return filepath.ToSlash(target), true, nil
+	}
+}
+
+// containedPath returns the path in the tree at root the entry name
+// refers to, once its symbolic links are resolved.
+func containedPath(fsys FS, root, name string) (string, error) {
+	rel, err := resolveIn(name, fsReadlink(fsys, root))
+	if err != nil {
+		return "", err
+	}
+	p := filepath.Join(root, filepath.FromSlash(rel))
+	if !within(root, p) {
+		return "", fmt.Errorf("%w: %q", ErrPathEscape, name)
+	}
+	return p, nil
+}
+
+// checkTree makes sure that an extracted tree only holds regular
+// files, directories and symbolic links that stay inside of it.
+func checkTree(fsys FS, root string) error {
+	readlink := fsReadlink(fsys, root)
+	return walkDir(fsys, root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+
+		switch t := d.Type(); {
+		case t.IsDir(), t.IsRegular():
+			return nil
+		case t&fs.ModeSymlink != 0:
+			_, err := resolveIn(rel, readlink)
+			return err
+		default:
+			return fmt.Errorf("%w %q: unexpected file type %v", ErrBadFilePath, rel, t)
+		}
+	})
+}
+
+// checkSnapshot is checkTree for the content of a snapshot, before it
+// is extracted, so that no file gets written through a symbolic link
+// pointing outside of the package.
+func checkSnapshot(snap *snapshot.Snapshot, base string) error {
+	fsys, err := snap.Filesystem()
+	if err != nil {
+		return err
+	}
+
+	links := map[string]string{}
+	var entries []string
+	err = fsys.WalkDir(base, func(p string, e *vfs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel := strings.TrimPrefix(strings.TrimPrefix(p, base), "/")
+		if rel == "" {
+			return nil
+		}
+		if _, err := packagePath(rel); err != nil {
+			return err
+		}
+
+		mode := e.Stat().Mode()
+		switch {
+		case mode.IsDir(), mode.IsRegular():
+		case mode&fs.ModeSymlink != 0:
+			links[rel] = e.SymlinkTarget
+			entries = append(entries, rel)
+		default:
+			return fmt.Errorf("%w %q: unexpected file type %v", ErrBadFilePath, rel, mode.Type())
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	readlink := func(name string) (string, bool, error) {
+		target, ok := links[name]
+		return target, ok, nil
+	}
+	for _, rel := range entries {
+		if _, err := resolveIn(rel, readlink); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestWithin(t *testing.T) {
+	root := filepath.FromSlash("/cache/s3")
+	for name, want := range map[string]bool{
+		"/cache/s3":             true,
+		"/cache/s3/bin/s3":      true,
+		"/cache/s3-evil/bin/s3": false,
+		"/cache":                false,
+		"/etc/passwd":           false,
+	} {
+		if got := within(root, filepath.FromSlash(name)); got != want {
+			t.Errorf("within(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestResolveIn(t *testing.T) {
+	links := map[string]string{
+		"bin":       "dist/bin",
+		"lib/up":    "../bin/tool",
+		"abs":       "/etc",
+		"out":       "../x",
+		"self":      ".",
+		"loop/a":    "b",
+		"loop/b":    "a",
+		"dist/back": "../../..",
+	}
+	readlink := func(name string) (string, bool, error) {
+		target, ok := links[name]
+		return target, ok, nil
+	}
+
+	for _, tc := range []struct {
+		in, want string
+		err      error
+	}{
+		{in: "manifest.yaml", want: "manifest.yaml"},
+		{in: "bin/s3", want: "dist/bin/s3"},
+		{in: "lib/up", want: "dist/bin/tool"},
+		{in: "self/bin/s3", want: "dist/bin/s3"},
+		{in: "missing/file", want: "missing/file"},
+		{in: "abs/passwd", err: ErrPathEscape},
+		{in: "out", err: ErrPathEscape},
+		{in: "self/../x", err: ErrPathEscape},
+		{in: "dist/back", err: ErrPathEscape},
+		{in: "../x", err: ErrPathEscape},
+		{in: "lib/../../x", err: ErrPathEscape},
+		{in: "self/../self/bin/s3", err: ErrPathEscape},
+		{in: "/etc/passwd", err: ErrBadFilePath},
+		{in: "loop/a", err: ErrBadFilePath},
+	} {
+		got, err := resolveIn(tc.in, readlink)
+		if tc.err != nil {
+			if !errors.Is(err, tc.err) {
+				t.Errorf("resolveIn(%q) = %q, %v, want %v", tc.in, got, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("resolveIn(%q) = %q, %v, want %q", tc.in, got, err, tc.want)
+		}
+	}
+}
+
+func writeManifest(t *testing.T, dir, manifest string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	mpath := filepath.Join(dir, "manifest.yaml")
+	if err := os.WriteFile(mpath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return mpath
+}
+
+// A sibling directory sharing the package directory as a prefix used
+// to pass the check.
+func TestLoadManifestRejectsSiblingPrefix(t *testing.T) {
+	be, _, cachedir := newTestFlatBackend(t, nil)
+
+	mpath := writeManifest(t, filepath.Join(cachedir, "s3"), `
+name: s3
+connectors:
+  - type: storage
+    executable: ../s3-evil/tool
+`)
+	if err := os.MkdirAll(filepath.Join(cachedir, "s3-evil"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, filepath.Join(cachedir, "s3-evil"), "tool")
+
+	if _, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath); err == nil {
+		t.Fatal("loadmanifest accepted an executable in a sibling directory")
+	}
+}
+
+func TestLoadManifestRejectsSymlinkEscape(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+
+	for name, manifest := range map[string]string{
+		"executable": `
+name: s3
+connectors:
+  - type: storage
+    executable: bin/tool
+`,
+		"extra file": `
+name: s3
+connectors:
+  - type: storage
+    executable: s3-storage
+    extra_files: [bin/tool]
+`,
+		"extra file glob": `
+name: s3
+connectors:
+  - type: storage
+    executable: s3-storage
+    extra_files: ["lib/*"]
+`,
+	} {
+		t.Run(name, func(t *testing.T) {
+			be, _, cachedir := newTestFlatBackend(t, nil)
+			outside := t.TempDir()
+			touch(t, outside, "tool")
+
+			dir := filepath.Join(cachedir, "s3")
+			mpath := writeManifest(t, dir, manifest)
+			touch(t, dir, "s3-storage")
+			if err := os.Symlink(outside, filepath.Join(dir, "bin")); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Mkdir(filepath.Join(dir, "lib"), 0755); err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Symlink("../../"+filepath.Base(cachedir), filepath.Join(dir, "lib", "up")); err != nil {
+				t.Fatal(err)
+			}
+
+			_, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath)
+			if !errors.Is(err, ErrPathEscape) {
+				t.Fatalf("loadmanifest = %v, want ErrPathEscape", err)
+			}
+		})
+	}
+}
+
+func TestLoadManifestAcceptsInnerSymlink(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+
+	be, _, cachedir := newTestFlatBackend(t, nil)
+	dir := filepath.Join(cachedir, "s3")
+	mpath := writeManifest(t, dir, `
+name: s3
+connectors:
+  - type: storage
+    executable: bin/s3-storage
+`)
+	if err := os.MkdirAll(filepath.Join(dir, "dist"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, filepath.Join(dir, "dist"), "s3-storage")
+	if err := os.Symlink("dist", filepath.Join(dir, "bin")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath); err != nil {
+		t.Fatalf("loadmanifest: %v", err)
+	}
+}
+
+// escapingExtractor leaves a symbolic link pointing outside of the
+// package, as a crafted ptar could.
+type escapingExtractor struct {
+	target string
+}
+
+func (e escapingExtractor) Extract(ptar, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("name: s3\n"), 0644); err != nil {
+		return err
+	}
+	return os.Symlink(e.target, filepath.Join(dir, "passwd"))
+}
+
+func TestFlatBackendRejectsEscapingTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+
+	for _, target := range []string{"/etc/passwd", "../../../etc/passwd"} {
+		be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+			Extractor: escapingExtractor{target: target},
+		})
+
+		pkg := pkgVer("s3", "v1.0.0")
+		touch(t, pkgdir, pkg.Filename())
+		if err := be.LoadAll(); !errors.Is(err, ErrPathEscape) {
+			t.Errorf("LoadAll with a link to %q = %v, want ErrPathEscape", target, err)
+		}
+		if _, err := os.Lstat(cachePath(cachedir, pkg)); err == nil {
+			t.Errorf("link to %q: the package was extracted", target)
+		}
+	}
+}
+
+func TestCheckTree(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("symbolic links need privileges on windows")
+	}
+
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "dist", "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, filepath.Join(dir, "dist", "bin"), "tool")
+	if err := os.Symlink("dist/bin", filepath.Join(dir, "bin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("../bin/tool", filepath.Join(dir, "dist", "tool")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTree(OSFS{}, dir); err != nil {
+		t.Fatalf("checkTree: %v", err)
+	}
+
+	if err := os.Symlink("bin/../../..", filepath.Join(dir, "up")); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkTree(OSFS{}, dir); !errors.Is(err, ErrPathEscape) {
+		t.Fatalf("checkTree = %v, want ErrPathEscape", err)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"path/filepath"
+	"runtime"
+)
+
+// Name of the link, in the cache directory of each package, to the
+// extracted tree of its active version.
+const currentLink = "current"
+
+// CurrentPath returns the path of the link to the extracted tree of
+// the active version of the named package.  Unlike the tree itself it
+// doesn't change across upgrades, so the host configuration and the
+// service definitions can refer to it.
+func (f *FlatBackend) CurrentPath(name string) string {
+	return filepath.Join(f.cachedir, escapeName(name), currentLink)
+}
+
+// currentPackage returns the installed version of the named package,
+// built for this platform, the current link should point to: the
+// active one or, failing that, the newest.  It returns nil if there
+// is none.
+func (f *FlatBackend) currentPackage(name string) (*Package, error) {
+	version, err := f.ActiveVersion(name)
+	if err != nil {
+		return nil, err
+	}
+
+	var newest *Package
+	for pkg, err := range f.List(name) {
+		if err != nil {
+			return nil, err
+		}
+		if pkg.OperatingSystem != runtime.GOOS ||
+			pkg.Architecture != runtime.GOARCH {
+			continue
+		}
+		if pkg.Version == version {
+			return pkg, nil
+		}
+		if newest == nil || f.versions.Compare(pkg.Version, newest.Version) > 0 {
+			newest = pkg
+		}
+	}
+	return newest, nil
+}
+
+// updateCurrent points the current link of the named package at the
+// tree of the version currentPackage picks, or removes it when no
+// version is left.  A failure doesn't undo the operation that
+// triggered the update: it's reported as a warning.
+func (f *FlatBackend) updateCurrent(name string) {
+	f.currentmu.Lock()
+	defer f.currentmu.Unlock()
+
+	if err := f.linkCurrent(name); err != nil {
+		f.emit(newWarning(&Package{Name: name}, WarnCurrentLink,
+			"couldn't update %s: %v", f.CurrentPath(name), err))
+	}
+}
+
+func (f *FlatBackend) linkCurrent(name string) error {
+	link := f.CurrentPath(name)
+
+	pkg, err := f.currentPackage(name)
+	if err != nil {
+		return err
+	}
+	if pkg == nil {
+		if err := f.fsys.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+
+	// relative, so that the link survives the cache directory
+	// being moved.
+	target, err := filepath.Rel(filepath.Dir(link), f.extractedPath(pkg))
+	if err != nil {
+		return err
+	}
+	if cur, err := f.fsys.Readlink(link); err == nil && cur == target {
+		return nil
+	}
+	return replaceDirLink(f.fsys, target, link)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import "path/filepath"
+
+// replaceDirLink atomically makes link a symbolic link to the
+// directory target, relative to the one link is in: the new link is
+// created aside and renamed over the old one, so that there is no
+// moment where link doesn't exist.
+func replaceDirLink(fsys FS, target, link string) error {
+	tmp := filepath.Join(filepath.Dir(link), stagingPrefix+filepath.Base(link))
+	fsys.Remove(tmp)
+	if err := fsys.Symlink(target, tmp); err != nil {
+		return err
+	}
+	if err := fsys.Rename(tmp, link); err != nil {
+		fsys.Remove(tmp)
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,58 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestFlatBackendCurrentLink(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, nil)
+
+	v1 := pkgVer("s3", "v1.0.0")
+	v2 := pkgVer("s3", "v1.1.0")
+	installExtracted(t, pkgdir, cachedir, v1)
+	installExtracted(t, pkgdir, cachedir, v2)
+
+	current := be.CurrentPath("s3")
+	points := func(want *Package) {
+		t.Helper()
+		got, err := filepath.EvalSymlinks(current)
+		if err != nil {
+			t.Fatalf("EvalSymlinks: %v", err)
+		}
+		wantdir, err := filepath.EvalSymlinks(cachePath(cachedir, want))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != wantdir {
+			t.Errorf("current = %s, want %s", got, wantdir)
+		}
+	}
+
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	points(v2)
+
+	if err := be.SetActive(v1); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+	points(v1)
+
+	if err := be.Unload(v1); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	points(v2)
+
+	if err := be.Unload(v2); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	if _, err := os.Lstat(current); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("current link left behind: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cachedir, "s3")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty directories of s3 left behind: %v", err)
+	}
+}
@@ -0,0 +1,54 @@
+//go:build windows
+
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os/exec"
+	"path/filepath"
+)
+
+// replaceDirLink makes link a link to the directory target, relative
+// to the one link is in.  Directory symbolic links need either the
+// developer mode or an elevated process on Windows, so a junction is
+// created instead when they are not available.  A directory can't be
+// renamed over another, so the old link is removed first.
+func replaceDirLink(fsys FS, target, link string) error {
+	if err := fsys.Remove(link); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	if err := fsys.Symlink(target, link); err == nil {
+		return nil
+	}
+
+	// junctions only take absolute targets.
+	abs, err := filepath.Abs(filepath.Join(filepath.Dir(link), target))
+	if err != nil {
+		return err
+	}
+	out, err := exec.Command("cmd", "/c", "mklink", "/J", link, abs).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("mklink: %w: %s", err, out)
+	}
+	return nil
+}
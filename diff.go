@@ -0,0 +1,247 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+)
+
+// DiffChange tells how an item differs between two versions.
+type DiffChange string
+
+const (
+	DiffAdded   DiffChange = "added"
+	DiffRemoved DiffChange = "removed"
+	DiffChanged DiffChange = "changed"
+)
+
+// FieldChange is a manifest field whose value differs.  List fields
+// are rendered as comma-separated values.
+type FieldChange struct {
+	Field string
+	Old   string
+	New   string
+}
+
+// ConnectorChange is a connector added, removed or changed, as
+// identified by its type.
+type ConnectorChange struct {
+	Type   ConnectorType
+	Change DiffChange
+	Fields []FieldChange // for DiffChanged
+}
+
+// FileChange is a file added, removed, or whose size, mode or,
+// when known, content changed.
+type FileChange struct {
+	Path   string
+	Change DiffChange
+	Old    *FileEntry // nil for DiffAdded
+	New    *FileEntry // nil for DiffRemoved
+}
+
+// Diff is what changes from one version of a package to another.
+type Diff struct {
+	Name       string
+	From       string
+	To         string
+	Fields     []FieldChange
+	Connectors []ConnectorChange
+	Files      []FileChange
+}
+
+// Diff compares the manifests and the files of two versions of a
+// package, so that an upgrade can be reviewed before applying it.
+// The installed versions are read from the backend, the others are
+// fetched from the repository.
+func (p *Manager) Diff(name, v1, v2 string) (*Diff, error) {
+	from, err := p.inspectVersion(name, v1)
+	if err != nil {
+		return nil, err
+	}
+	to, err := p.inspectVersion(name, v2)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Diff{
+		Name:       name,
+		From:       v1,
+		To:         v2,
+		Fields:     diffManifests(from.Manifest, to.Manifest),
+		Connectors: diffConnectors(from.Manifest.Connectors, to.Manifest.Connectors),
+		Files:      diffFiles(from.Files, to.Files),
+	}, nil
+}
+
+// inspectVersion returns the manifest and the files of the given
+// version of a package.
+func (p *Manager) inspectVersion(name, version string) (*Inspection, error) {
+	if pkg, err := p.lookup(name, version); err == nil {
+		fr, ok1 := p.store.(FileReader)
+		fl, ok2 := p.store.(FileLister)
+		if ok1 && ok2 {
+			data, err := fr.ReadFile(pkg, "manifest.yaml")
+			if err != nil {
+				return nil, err
+			}
+			var m Manifest
+			if err := m.Parse(bytes.NewReader(data)); err != nil {
+				return nil, err
+			}
+			files, err := fl.Files(pkg)
+			if err != nil {
+				return nil, err
+			}
+			return &Inspection{Package: *pkg, Manifest: &m, Files: files}, nil
+		}
+	}
+
+	ins, ok := p.store.(Inspector)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	pkg := Package{
+		Name:            name,
+		Version:         version,
+		Architecture:    runtime.GOARCH,
+		OperatingSystem: runtime.GOOS,
+	}
+	rd, _, err := p.openbinary(context.Background(), &pkg, "")
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	return ins.Inspect(&pkg, rd)
+}
+
+// changes appends a FieldChange to fields if the values differ.
+func changes(fields []FieldChange, field, from, to string) []FieldChange {
+	if from != to {
+		fields = append(fields, FieldChange{Field: field, Old: from, New: to})
+	}
+	return fields
+}
+
+func specs(list []Spec) string {
+	var ret []string
+	for _, s := range list {
+		if s.Version != "" {
+			ret = append(ret, s.Name+" "+s.Version)
+		} else {
+			ret = append(ret, s.Name)
+		}
+	}
+	return strings.Join(ret, ", ")
+}
+
+func diffManifests(a, b *Manifest) []FieldChange {
+	var f []FieldChange
+	f = changes(f, "display_name", a.DisplayName, b.DisplayName)
+	f = changes(f, "description", a.Description, b.Description)
+	f = changes(f, "tier", a.Tier, b.Tier)
+	f = changes(f, "contact", a.Contact, b.Contact)
+	f = changes(f, "homepage", a.Homepage, b.Homepage)
+	f = changes(f, "license", a.License, b.License)
+	f = changes(f, "tags", strings.Join(a.Tags, ", "), strings.Join(b.Tags, ", "))
+	f = changes(f, "api_version", a.APIVersion, b.APIVersion)
+	f = changes(f, "dependencies", specs(a.Dependencies), specs(b.Dependencies))
+	f = changes(f, "recommends", specs(a.Recommends), specs(b.Recommends))
+	f = changes(f, "suggests", specs(a.Suggests), specs(b.Suggests))
+	f = changes(f, "provides", strings.Join(a.Provides, ", "), strings.Join(b.Provides, ", "))
+	f = changes(f, "conflicts", strings.Join(a.Conflicts, ", "), strings.Join(b.Conflicts, ", "))
+	f = changes(f, "replaces", strings.Join(a.Replaces, ", "), strings.Join(b.Replaces, ", "))
+	return f
+}
+
+func diffConnector(a, b *ManifestConnector) []FieldChange {
+	var f []FieldChange
+	f = changes(f, "class", string(a.Class), string(b.Class))
+	f = changes(f, "subclass", string(a.SubClass), string(b.SubClass))
+	f = changes(f, "validator", a.Validator, b.Validator)
+	f = changes(f, "protocols", strings.Join(a.Protocols, ", "), strings.Join(b.Protocols, ", "))
+	f = changes(f, "location_flags", strings.Join(a.LocationFlags, ", "), strings.Join(b.LocationFlags, ", "))
+	f = changes(f, "executable", a.Executable, b.Executable)
+	f = changes(f, "args", strings.Join(a.Args, " "), strings.Join(b.Args, " "))
+	f = changes(f, "extra_files", strings.Join(a.ExtraFiles, ", "), strings.Join(b.ExtraFiles, ", "))
+	return f
+}
+
+func diffConnectors(a, b []ManifestConnector) []ConnectorChange {
+	var ret []ConnectorChange
+	for i := range a {
+		j := slices.IndexFunc(b, func(c ManifestConnector) bool {
+			return c.Type == a[i].Type
+		})
+		if j == -1 {
+			ret = append(ret, ConnectorChange{Type: a[i].Type, Change: DiffRemoved})
+			continue
+		}
+		if fields := diffConnector(&a[i], &b[j]); len(fields) != 0 {
+			ret = append(ret, ConnectorChange{
+				Type:   a[i].Type,
+				Change: DiffChanged,
+				Fields: fields,
+			})
+		}
+	}
+	for i := range b {
+		if !slices.ContainsFunc(a, func(c ManifestConnector) bool {
+			return c.Type == b[i].Type
+		}) {
+			ret = append(ret, ConnectorChange{Type: b[i].Type, Change: DiffAdded})
+		}
+	}
+	return ret
+}
+
+func diffFiles(a, b []FileEntry) []FileChange {
+	old := make(map[string]*FileEntry, len(a))
+	for i := range a {
+		old[a[i].Path] = &a[i]
+	}
+
+	var ret []FileChange
+	for i := range b {
+		fe := &b[i]
+		prev, ok := old[fe.Path]
+		switch {
+		case !ok:
+			ret = append(ret, FileChange{Path: fe.Path, Change: DiffAdded, New: fe})
+		case prev.Size != fe.Size || prev.Mode != fe.Mode,
+			prev.Checksum != "" && fe.Checksum != "" && prev.Checksum != fe.Checksum:
+			ret = append(ret, FileChange{Path: fe.Path, Change: DiffChanged, Old: prev, New: fe})
+		}
+		delete(old, fe.Path)
+	}
+	for _, fe := range old {
+		ret = append(ret, FileChange{Path: fe.Path, Change: DiffRemoved, Old: fe})
+	}
+
+	slices.SortFunc(ret, func(x, y FileChange) int {
+		return strings.Compare(x.Path, y.Path)
+	})
+	return ret
+}
@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// diffBackend is a fakeBackend serving manifests and file listings.
+type diffBackend struct {
+	*fakeBackend
+	manifests map[string]string
+	files     map[string][]FileEntry
+}
+
+func (d *diffBackend) ReadFile(pkg *Package, name string) ([]byte, error) {
+	m, ok := d.manifests[pkg.Version]
+	if !ok || name != "manifest.yaml" {
+		return nil, ErrNotInstalled
+	}
+	return []byte(m), nil
+}
+
+func (d *diffBackend) Files(pkg *Package) ([]FileEntry, error) {
+	return d.files[pkg.Version], nil
+}
+
+func TestDiff(t *testing.T) {
+	be := &diffBackend{
+		fakeBackend: newFakeBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v1.1.0")),
+		manifests: map[string]string{
+			"v1.0.0": `name: s3
+license: ISC
+connectors:
+  - type: importer
+    protocols: [s3]
+    executable: s3-importer
+  - type: exporter
+    executable: s3-exporter
+`,
+			"v1.1.0": `name: s3
+license: ISC
+tags: [cloud]
+connectors:
+  - type: importer
+    protocols: [s3, s3s]
+    executable: s3-importer
+  - type: storage
+    executable: s3-storage
+`,
+		},
+		files: map[string][]FileEntry{
+			"v1.0.0": {
+				{Path: "manifest.yaml", Size: 10, Mode: 0644},
+				{Path: "s3-exporter", Size: 100, Mode: 0755},
+				{Path: "s3-importer", Size: 100, Mode: 0755},
+			},
+			"v1.1.0": {
+				{Path: "manifest.yaml", Size: 10, Mode: 0644},
+				{Path: "s3-importer", Size: 100, Mode: 0700},
+				{Path: "s3-storage", Size: 100, Mode: 0755},
+			},
+		},
+	}
+	m, _ := New(be, nil)
+
+	d, err := m.Diff("s3", "v1.0.0", "v1.1.0")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+
+	if !slices.Equal(d.Fields, []FieldChange{{Field: "tags", Old: "", New: "cloud"}}) {
+		t.Errorf("fields = %+v", d.Fields)
+	}
+
+	if len(d.Connectors) != 3 {
+		t.Fatalf("connectors = %+v", d.Connectors)
+	}
+	imp := d.Connectors[0]
+	if imp.Type != "importer" || imp.Change != DiffChanged ||
+		!slices.Equal(imp.Fields, []FieldChange{{Field: "protocols", Old: "s3", New: "s3, s3s"}}) {
+		t.Errorf("importer change = %+v", imp)
+	}
+	if c := d.Connectors[1]; c.Type != "exporter" || c.Change != DiffRemoved {
+		t.Errorf("exporter change = %+v", c)
+	}
+	if c := d.Connectors[2]; c.Type != "storage" || c.Change != DiffAdded {
+		t.Errorf("storage change = %+v", c)
+	}
+
+	var files []string
+	for _, fc := range d.Files {
+		files = append(files, fc.Path+" "+string(fc.Change))
+	}
+	want := []string{"s3-exporter removed", "s3-importer changed", "s3-storage added"}
+	if !slices.Equal(files, want) {
+		t.Errorf("files = %v, want %v", files, want)
+	}
+}
+
+func TestDiffUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(pkgVer("s3", "v1.0.0")), nil)
+	if _, err := m.Diff("s3", "v1.0.0", "v1.1.0"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Diff = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
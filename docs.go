@@ -0,0 +1,105 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+type DocumentationOptions struct {
+	// Return the markdown document instead of rendering it to
+	// HTML.  Its relative links are rewritten all the same.
+	Markdown bool
+
+	// Prefix of the URLs the relative images and links of the
+	// document are rewritten to, followed by the name of the file
+	// in AssetCacheDir, for hosts serving that directory.  The
+	// file:// URLs of the cached files are used otherwise.
+	AssetPrefix string
+}
+
+// Documentation returns the documentation of the integration with
+// the given id, its README, rendered as sanitized HTML.  The document
+// and the assets it refers to are saved in [Options.AssetCacheDir],
+// so that they remain available offline; without it, the relative
+// links point to the repository instead.
+func (p *Manager) Documentation(id string, opts *DocumentationOptions) (string, error) {
+	if opts == nil {
+		opts = &DocumentationOptions{}
+	}
+
+	plug, err := p.integration(id)
+	if err != nil {
+		return "", err
+	}
+	if plug.Documentation == "" {
+		return "", fmt.Errorf("documentation of %s: %w", id, ErrNotFound)
+	}
+
+	base, err := p.indexURL(plug.Documentation)
+	if err != nil {
+		return "", err
+	}
+	src, err := p.readAsset(base)
+	if err != nil {
+		return "", err
+	}
+
+	link := func(ref string) string {
+		u, err := url.Parse(ref)
+		if err != nil {
+			return ref
+		}
+		u = base.ResolveReference(u)
+		if p.assetdir == "" {
+			return u.String()
+		}
+
+		file, err := p.cacheAsset(u, false)
+		if err != nil {
+			// leave it to the reader to fetch it.
+			return u.String()
+		}
+		if opts.AssetPrefix != "" {
+			return opts.AssetPrefix + url.PathEscape(filepath.Base(file))
+		}
+		return (&url.URL{Scheme: "file", Path: filepath.ToSlash(file)}).String()
+	}
+
+	if opts.Markdown {
+		return rewriteMarkdownLinks(string(src), link), nil
+	}
+	return renderMarkdown(string(src), link), nil
+}
+
+// readAsset returns the content of the asset, going through the
+// asset cache when there is one.  The document may have changed, so
+// it's fetched again unless offline.
+func (p *Manager) readAsset(u *url.URL) ([]byte, error) {
+	if p.assetdir == "" {
+		return p.fetchAsset(u)
+	}
+	file, err := p.cacheAsset(u, true)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(file)
+}
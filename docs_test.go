@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const docsIndex = `{
+	"version":"v1",
+	"integrations":[
+		{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0","documentation":"s3/README.md"},
+		{"name":"ftp","edition":"community","api":"v1.1.0","version":"v1.0.0"}
+	]
+}`
+
+func newDocsAPI(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".json"):
+			io.WriteString(w, docsIndex)
+		case r.URL.Path == "/v1/integrations/s3/README.md":
+			io.WriteString(w, "# S3\n\n![shot](assets/shot.png) <b>bold</b>\n")
+		case r.URL.Path == "/v1/integrations/s3/assets/shot.png":
+			io.WriteString(w, "PNG")
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestDocumentation(t *testing.T) {
+	srv := newDocsAPI(t)
+	assets := t.TempDir()
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, AssetCacheDir: assets})
+
+	doc, err := m.Documentation("s3", &DocumentationOptions{AssetPrefix: "/assets/"})
+	if err != nil {
+		t.Fatalf("Documentation: %v", err)
+	}
+	shot := sha256sum("PNG")[len("sha256:"):] + ".png"
+	want := `<h1>S3</h1>` + "\n" + `<p><img src="/assets/` + shot + `" alt="shot"> &lt;b&gt;bold&lt;/b&gt;</p>` + "\n"
+	if doc != want {
+		t.Errorf("Documentation =\n%s\nwant\n%s", doc, want)
+	}
+	if data, err := os.ReadFile(filepath.Join(assets, shot)); err != nil || string(data) != "PNG" {
+		t.Errorf("cached asset = %q, %v", data, err)
+	}
+
+	md, err := m.Documentation("s3", &DocumentationOptions{Markdown: true, AssetPrefix: "/assets/"})
+	if err != nil {
+		t.Fatalf("Documentation: %v", err)
+	}
+	if !strings.Contains(md, "![shot](/assets/"+shot+")") {
+		t.Errorf("markdown = %q", md)
+	}
+
+	// still available offline.
+	srv.Close()
+	if _, err := m.Documentation("s3", nil); err != nil {
+		t.Errorf("Documentation offline: %v", err)
+	}
+}
+
+func TestDocumentationMissing(t *testing.T) {
+	srv := newDocsAPI(t)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	for _, id := range []string{"ftp", "sftp"} {
+		if _, err := m.Documentation(id, nil); !errors.Is(err, ErrNotFound) {
+			t.Errorf("Documentation(%s) = %v, want %v", id, err, ErrNotFound)
+		}
+	}
+}
+
+func TestDocumentationWithoutCache(t *testing.T) {
+	srv := newDocsAPI(t)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	doc, err := m.Documentation("s3", nil)
+	if err != nil {
+		t.Fatalf("Documentation: %v", err)
+	}
+	if !strings.Contains(doc, `src="`+srv.URL+`/v1/integrations/s3/assets/shot.png"`) {
+		t.Errorf("Documentation = %s", doc)
+	}
+}
@@ -0,0 +1,146 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Packages smaller than this are always downloaded in one go.
+const parallelThreshold = 8 << 20
+
+// tempFile is an *os.File removed once closed.
+type tempFile struct {
+	*os.File
+}
+
+func (t *tempFile) Close() error {
+	err := t.File.Close()
+	os.Remove(t.File.Name())
+	return err
+}
+
+// TempDirer is implemented by the backends with a directory of their
+// own for large temporary files, so that they don't end up in a small
+// system temporary directory.
+type TempDirer interface {
+	TempDir() string
+}
+
+// parallelizable returns whether the reply to the initial request
+// allows to download the rest in parallel chunks.
+func (p *Manager) parallelizable(resp *http.Response) bool {
+	return p.parallel > 1 && p.maxrate == 0 &&
+		resp.ContentLength >= p.parallelThreshold &&
+		resp.Header.Get("Accept-Ranges") == "bytes" &&
+		rangeValidator(resp) != ""
+}
+
+// rangeValidator returns what the chunk requests send as If-Range, so
+// that they fail instead of mixing two versions of a file changed in
+// the meantime: a strong ETag or else the modification date.
+func rangeValidator(resp *http.Response) string {
+	if etag := resp.Header.Get("ETag"); etag != "" && !strings.HasPrefix(etag, "W/") {
+		return etag
+	}
+	return resp.Header.Get("Last-Modified")
+}
+
+// downloadParallel fetches the resource targeted by req in chunks
+// using Range requests and reassembles it in a temporary file.
+func (p *Manager) downloadParallel(ctx context.Context, req *http.Request, size int64, validator string) (*tempFile, error) {
+	var dir string
+	if td, ok := p.store.(TempDirer); ok {
+		dir = td.TempDir()
+	}
+	fp, err := os.CreateTemp(dir, stagingPrefix+"download-*")
+	if err != nil {
+		return nil, err
+	}
+	tmp := &tempFile{fp}
+
+	if err := fp.Truncate(size); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	chunk := (size + int64(p.parallel) - 1) / int64(p.parallel)
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for start := int64(0); start < size; start += chunk {
+		end := min(start+chunk, size) - 1
+
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := p.downloadRange(ctx, req, validator, fp, start, end); err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if err := errors.Join(errs...); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+
+	if _, err := fp.Seek(0, io.SeekStart); err != nil {
+		tmp.Close()
+		return nil, err
+	}
+	return tmp, nil
+}
+
+func (p *Manager) downloadRange(ctx context.Context, orig *http.Request, validator string, w io.WriterAt, start, end int64) error {
+	req := orig.Clone(ctx)
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	req.Header.Set("If-Range", validator)
+
+	// a server ignoring the range, or whose file changed, replies
+	// with the whole file and a 200.
+	resp, err := p.do(req, http.StatusPartialContent)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	want := end - start + 1
+	n, err := io.Copy(io.NewOffsetWriter(w, start), io.LimitReader(resp.Body, want))
+	if err != nil {
+		return err
+	}
+	if n != want {
+		return fmt.Errorf("short read for bytes %d-%d: got %d bytes", start, end, n)
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestParallelDownload(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+
+	for _, tt := range []struct {
+		name       string
+		ranges     bool
+		wantRanges int32
+	}{
+		{"with ranges", true, 4},
+		{"without ranges", false, 0},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var ranges atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if !tt.ranges {
+					w.Write(payload)
+					return
+				}
+				if r.Header.Get("Range") != "" {
+					ranges.Add(1)
+				}
+				w.Header().Set("ETag", `"v1"`)
+				http.ServeContent(w, r, "pkg.ptar", time.Time{}, bytes.NewReader(payload))
+			}))
+			defer srv.Close()
+
+			be := newFakeBackend()
+			m, _ := New(be, &Options{InstallURL: srv.URL, ParallelDownloads: 4})
+			m.parallelThreshold = 1024
+
+			if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+				t.Fatalf("Add: %v", err)
+			}
+			if got := ranges.Load(); got != tt.wantRanges {
+				t.Errorf("got %d range requests, want %d", got, tt.wantRanges)
+			}
+
+			got := be.loadData[be.loaded[0].Filename()]
+			if !bytes.Equal(got, payload) {
+				t.Errorf("reassembled %d bytes, want %d", len(got), len(payload))
+			}
+		})
+	}
+}
+
+func TestParallelDownloadRangeFailure(t *testing.T) {
+	payload := strings.Repeat("x", 4096)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			http.Error(w, "boom", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "pkg.ptar", time.Time{}, strings.NewReader(payload))
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL, ParallelDownloads: 2})
+	m.parallelThreshold = 1024
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err == nil {
+		t.Fatal("Add succeeded despite failing range requests")
+	}
+	if len(be.loaded) != 0 {
+		t.Error("package loaded despite failing range requests")
+	}
+}
+
+func TestParallelDownloadValidator(t *testing.T) {
+	payload := bytes.Repeat([]byte("0123456789"), 1000)
+
+	for _, tt := range []struct {
+		name       string
+		etag       func(n int32) string
+		wantRanges bool
+		wantErr    bool
+	}{
+		{"no validator", func(int32) string { return "" }, false, false},
+		{"weak etag", func(int32) string { return `W/"v1"` }, false, false},
+		{"changed upstream", func(n int32) string {
+			if n > 2 {
+				return `"v2"`
+			}
+			return `"v1"`
+		}, true, true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			var hits, ranges atomic.Int32
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				n := hits.Add(1)
+				if r.Header.Get("Range") != "" {
+					ranges.Add(1)
+				}
+				if etag := tt.etag(n); etag != "" {
+					w.Header().Set("ETag", etag)
+				}
+				http.ServeContent(w, r, "pkg.ptar", time.Time{}, bytes.NewReader(payload))
+			}))
+			defer srv.Close()
+
+			be := newFakeBackend()
+			m, _ := New(be, &Options{InstallURL: srv.URL, ParallelDownloads: 4})
+			m.parallelThreshold = 1024
+
+			err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Add err = %v, want error %v", err, tt.wantErr)
+			}
+			if got := ranges.Load() != 0; got != tt.wantRanges {
+				t.Errorf("range requests sent = %v, want %v", got, tt.wantRanges)
+			}
+			if !tt.wantErr && !bytes.Equal(be.loadData[be.loaded[0].Filename()], payload) {
+				t.Error("the package doesn't match the payload")
+			}
+		})
+	}
+}
+
+func TestParallelDownloadCancel(t *testing.T) {
+	payload := bytes.Repeat([]byte("x"), 4096)
+	ctx, cancel := context.WithCancel(context.Background())
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Range") != "" {
+			// the chunks only come once cancelled.
+			cancel()
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		http.ServeContent(w, r, "pkg.ptar", time.Time{}, bytes.NewReader(payload))
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL, ParallelDownloads: 2})
+	m.parallelThreshold = 1024
+
+	_, _, err := m.download(ctx, "s3.ptar")
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("download err = %v, want context.Canceled", err)
+	}
+}
+
+func TestFlatBackendTempDir(t *testing.T) {
+	staging := t.TempDir()
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{StagingDir: staging})
+	if got := be.TempDir(); got != staging {
+		t.Errorf("TempDir = %q, want %q", got, staging)
+	}
+}
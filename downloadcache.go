@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Name of the file, in the download cache directory, telling which
+// cached download holds each package.
+const downloadIndexFile = "downloads.json"
+
+// downloadEntry is a package of the download cache.
+type downloadEntry struct {
+	Digest string    `json:"digest"` // hex sha256
+	Used   time.Time `json:"used"`
+}
+
+func (p *Manager) downloads() (map[string]downloadEntry, error) {
+	data, err := os.ReadFile(filepath.Join(p.dlcachedir, downloadIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]downloadEntry{}, nil
+		}
+		return nil, err
+	}
+
+	index := map[string]downloadEntry{}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return index, nil
+}
+
+func (p *Manager) saveDownloads(index map[string]downloadEntry) error {
+	data, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+	return writeFile(OSFS{}, filepath.Join(p.dlcachedir, downloadIndexFile), data)
+}
+
+func (p *Manager) downloadPath(digest string) string {
+	return filepath.Join(p.dlcachedir, digest+".ptar")
+}
+
+// openCachedDownload opens the cached copy of the package, if any,
+// found by its digest when the checksum is a sha256 one and by its
+// name otherwise.  It returns its size too.
+func (p *Manager) openCachedDownload(pkg *Package, checksum string) (io.ReadCloser, int64, error) {
+	if p.dlcachedir == "" {
+		return nil, 0, nil
+	}
+
+	p.dlmu.Lock()
+	defer p.dlmu.Unlock()
+
+	index, err := p.downloads()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	digest := index[pkg.Filename()].Digest
+	if sum, ok := strings.CutPrefix(checksum, "sha256:"); ok {
+		digest = sum
+	}
+	if digest == "" {
+		return nil, 0, nil
+	}
+
+	fp, err := os.Open(p.downloadPath(digest))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, 0, nil
+		}
+		return nil, 0, err
+	}
+	info, err := fp.Stat()
+	if err != nil {
+		fp.Close()
+		return nil, 0, err
+	}
+
+	index[pkg.Filename()] = downloadEntry{Digest: digest, Used: time.Now()}
+	if err := p.saveDownloads(index); err != nil {
+		fp.Close()
+		return nil, 0, err
+	}
+	return fp, info.Size(), nil
+}
+
+// cachingReader copies a download to a temporary file, moved to the
+// download cache on Close if keepDownload was called after the whole
+// download was read.
+type cachingReader struct {
+	rd   io.Reader
+	body io.Closer
+	tmp  *os.File
+	hash hash.Hash
+
+	p    *Manager
+	pkg  Package
+	eof  bool
+	keep bool
+}
+
+// cacheDownload returns a reader over rd, the possibly verified
+// content of body, keeping a copy in the download cache.  Failing to
+// cache is not an error: the download goes on as usual.
+func (p *Manager) cacheDownload(pkg *Package, rd io.Reader, body io.Closer) io.ReadCloser {
+	c := &cachingReader{rd: rd, body: body, p: p, pkg: *pkg, hash: sha256.New()}
+	if err := os.MkdirAll(p.dlcachedir, 0755); err == nil {
+		c.tmp, _ = os.CreateTemp(p.dlcachedir, stagingPrefix+"*")
+	}
+	return c
+}
+
+func (c *cachingReader) Read(b []byte) (int, error) {
+	n, err := c.rd.Read(b)
+	if c.tmp != nil && n > 0 {
+		if _, werr := c.tmp.Write(b[:n]); werr != nil {
+			c.drop()
+		}
+		c.hash.Write(b[:n])
+	}
+	if err == io.EOF {
+		c.eof = true
+	}
+	return n, err
+}
+
+func (c *cachingReader) drop() {
+	c.tmp.Close()
+	os.Remove(c.tmp.Name())
+	c.tmp = nil
+}
+
+func (c *cachingReader) Close() error {
+	err := c.body.Close()
+	if c.tmp == nil {
+		return err
+	}
+	if !c.keep || !c.eof {
+		c.drop()
+		return err
+	}
+
+	tmp := c.tmp.Name()
+	if cerr := c.tmp.Close(); cerr != nil {
+		os.Remove(tmp)
+		return err
+	}
+	c.p.storeDownload(&c.pkg, tmp, hex.EncodeToString(c.hash.Sum(nil)))
+	return err
+}
+
+// keepDownload marks the download as good to cache, once the package
+// was installed or staged successfully.
+func keepDownload(rd io.ReadCloser) {
+	if c, ok := rd.(*cachingReader); ok {
+		c.keep = true
+	}
+}
+
+// storeDownload moves the downloaded file into the cache and applies
+// the retention policy.
+func (p *Manager) storeDownload(pkg *Package, tmp, digest string) {
+	p.dlmu.Lock()
+	defer p.dlmu.Unlock()
+
+	if err := os.Rename(tmp, p.downloadPath(digest)); err != nil {
+		os.Remove(tmp)
+		return
+	}
+
+	index, err := p.downloads()
+	if err != nil {
+		return
+	}
+	index[pkg.Filename()] = downloadEntry{Digest: digest, Used: time.Now()}
+	p.pruneDownloads(index, false)
+	p.saveDownloads(index)
+}
+
+// pruneDownloads drops from the cache the downloads not used for
+// longer than the maximum age, then the least recently used ones
+// until the cache fits in its maximum size, or all of them.
+func (p *Manager) pruneDownloads(index map[string]downloadEntry, all bool) error {
+	now := time.Now()
+	for name, e := range index {
+		if all || p.dlmaxage > 0 && now.Sub(e.Used) > p.dlmaxage {
+			delete(index, name)
+		}
+	}
+
+	// the same content may be cached for several names.
+	used := map[string]time.Time{}
+	for _, e := range index {
+		if e.Used.After(used[e.Digest]) {
+			used[e.Digest] = e.Used
+		}
+	}
+
+	if p.dlmaxsize > 0 {
+		digests := make([]string, 0, len(used))
+		var total int64
+		for digest := range used {
+			digests = append(digests, digest)
+			if info, err := os.Stat(p.downloadPath(digest)); err == nil {
+				total += info.Size()
+			}
+		}
+		slices.SortFunc(digests, func(a, b string) int {
+			return used[a].Compare(used[b])
+		})
+		for _, digest := range digests {
+			if total <= p.dlmaxsize {
+				break
+			}
+			if info, err := os.Stat(p.downloadPath(digest)); err == nil {
+				total -= info.Size()
+			}
+			delete(used, digest)
+		}
+		for name, e := range index {
+			if _, ok := used[e.Digest]; !ok {
+				delete(index, name)
+			}
+		}
+	}
+
+	entries, err := os.ReadDir(p.dlcachedir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var errs []error
+	for _, e := range entries {
+		digest, ok := strings.CutSuffix(e.Name(), ".ptar")
+		if !ok || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		if _, ok := used[digest]; !ok {
+			if err := os.Remove(filepath.Join(p.dlcachedir, e.Name())); err != nil &&
+				!errors.Is(err, os.ErrNotExist) {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+type CleanOptions struct {
+	// Empty the download cache, instead of only dropping what
+	// its retention policy says to.
+	Downloads bool
+
+	// Empty the asset cache.
+	Assets bool
+}
+
+// Clean trims the caches kept by the manager, leaving the installed
+// packages alone.  By default it only applies the retention policy of
+// the download cache.
+func (p *Manager) Clean(opts *CleanOptions) error {
+	if opts == nil {
+		opts = &CleanOptions{}
+	}
+
+	var errs []error
+	if p.dlcachedir != "" {
+		p.dlmu.Lock()
+		index, err := p.downloads()
+		if err == nil {
+			err = p.pruneDownloads(index, opts.Downloads)
+			if serr := p.saveDownloads(index); serr != nil && !errors.Is(serr, os.ErrNotExist) {
+				err = errors.Join(err, serr)
+			}
+		}
+		p.dlmu.Unlock()
+		errs = append(errs, err)
+	}
+
+	if opts.Assets && p.assetdir != "" {
+		p.assetmu.Lock()
+		err := os.RemoveAll(p.assetdir)
+		p.assetmu.Unlock()
+		errs = append(errs, err)
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,106 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newCountingRepository(t *testing.T, downloads *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".ptar") {
+			*downloads++
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func cachedDownloads(t *testing.T, dir string) []string {
+	t.Helper()
+	matches, err := filepath.Glob(filepath.Join(dir, "*.ptar"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return matches
+}
+
+func TestDownloadCache(t *testing.T) {
+	downloads := 0
+	srv := newCountingRepository(t, &downloads)
+	dir := t.TempDir()
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, &Options{InstallURL: srv.URL, DownloadCacheDir: dir})
+
+	// a failed install isn't cached.
+	bad := &AddOptions{ImplicitFetch: true, Version: "v1.0.0", Checksum: sha256sum("OTHER")}
+	if err := m.Add("s3", bad); err == nil {
+		t.Fatal("Add with a bad checksum succeeded")
+	}
+	if got := cachedDownloads(t, dir); len(got) != 0 {
+		t.Errorf("cached = %v, want nothing", got)
+	}
+
+	for i := range 2 {
+		if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+			t.Fatalf("Add #%d: %v", i, err)
+		}
+		if err := m.Del("s3", nil); err != nil {
+			t.Fatalf("Del: %v", err)
+		}
+	}
+	if downloads != 2 {
+		t.Errorf("%d downloads, want 2", downloads)
+	}
+	want := filepath.Join(dir, sha256sum("PTARDATA")[len("sha256:"):]+".ptar")
+	if got := cachedDownloads(t, dir); len(got) != 1 || got[0] != want {
+		t.Errorf("cached = %v, want [%s]", got, want)
+	}
+
+	// found by digest too, whatever the name.
+	opts := &AddOptions{ImplicitFetch: true, Version: "v1.1.0", Checksum: sha256sum("PTARDATA")}
+	if err := m.Add("s3", opts); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if downloads != 2 {
+		t.Errorf("%d downloads, want 2", downloads)
+	}
+
+	if err := m.Clean(&CleanOptions{Downloads: true}); err != nil {
+		t.Fatalf("Clean: %v", err)
+	}
+	if got := cachedDownloads(t, dir); len(got) != 0 {
+		t.Errorf("cached after Clean = %v", got)
+	}
+	if v := m.installedVersion("s3"); v != "v1.1.0" {
+		t.Errorf("installed version after Clean = %q", v)
+	}
+}
+
+func TestDownloadCacheRetention(t *testing.T) {
+	downloads := 0
+	srv := newCountingRepository(t, &downloads)
+	dir := t.TempDir()
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, &Options{
+		InstallURL:           srv.URL,
+		DownloadCacheDir:     dir,
+		DownloadCacheMaxSize: int64(len("PTARDATA")) - 1,
+	})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := cachedDownloads(t, dir); len(got) != 0 {
+		t.Errorf("cached = %v, want nothing", got)
+	}
+	if _, err := os.Stat(filepath.Join(dir, downloadIndexFile)); err != nil {
+		t.Errorf("download index: %v", err)
+	}
+}
@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+var (
+	ErrNotFound            = errors.New("not found")
+	ErrUnauthorized        = errors.New("unauthorized")
+	ErrPlatformUnsupported = errors.New("platform not supported")
+	ErrRegistryUnavailable = errors.New("registry unavailable")
+)
+
+// FetchError is returned when a request to the repository or the API
+// fails.  It matches one of ErrNotFound, ErrUnauthorized or
+// ErrRegistryUnavailable with [errors.Is] when the failure falls into
+// one of these categories.
+type FetchError struct {
+	URL    string
+	Status string // empty if no response was received
+	Kind   error
+	Hint   string
+	Err    error // underlying error, if any
+}
+
+func newFetchError(url string, resp *http.Response, err error) *FetchError {
+	e := &FetchError{URL: url, Err: err}
+
+	if resp == nil {
+		e.Kind = ErrRegistryUnavailable
+		e.Hint = "check the network connection or try again later"
+		return e
+	}
+
+	e.Status = resp.Status
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		e.Kind = ErrNotFound
+		e.Hint = "check the package name and version"
+	case resp.StatusCode == http.StatusUnauthorized,
+		resp.StatusCode == http.StatusForbidden:
+		e.Kind = ErrUnauthorized
+		e.Hint = "check that you are logged in and allowed to download this package"
+	case resp.StatusCode >= 500:
+		e.Kind = ErrRegistryUnavailable
+		e.Hint = "try again later"
+	}
+	return e
+}
+
+func (e *FetchError) Error() string {
+	var b strings.Builder
+
+	b.WriteString("fetch ")
+	b.WriteString(e.URL)
+	if e.Status != "" {
+		b.WriteString(" failed with ")
+		b.WriteString(e.Status)
+	} else {
+		b.WriteString(" failed")
+	}
+	if e.Err != nil {
+		b.WriteString(": ")
+		b.WriteString(e.Err.Error())
+	}
+	if e.Hint != "" {
+		b.WriteString(" (")
+		b.WriteString(e.Hint)
+		b.WriteString(")")
+	}
+	return b.String()
+}
+
+func (e *FetchError) Unwrap() []error {
+	var errs []error
+	if e.Kind != nil {
+		errs = append(errs, e.Kind)
+	}
+	if e.Err != nil {
+		errs = append(errs, e.Err)
+	}
+	return errs
+}
+
+// PlatformError is returned when a package exists in the repository
+// but no binary was published for the requested platform.  It
+// matches ErrPlatformUnsupported with [errors.Is].
+type PlatformError struct {
+	Name            string
+	Version         string
+	OperatingSystem string
+	Architecture    string
+
+	// "os/arch" pairs for which this version is available.
+	Available []string
+}
+
+func (e *PlatformError) Error() string {
+	msg := fmt.Sprintf("no build of %s %s for %s/%s", e.Name, e.Version,
+		e.OperatingSystem, e.Architecture)
+	if len(e.Available) != 0 {
+		msg += " — available: " + strings.Join(e.Available, ", ")
+	}
+	return msg
+}
+
+func (e *PlatformError) Is(target error) bool {
+	return target == ErrPlatformUnsupported
+}
+
+// PackageError is the failure of an operation on a single package.
+type PackageError struct {
+	Package Package
+	Err     error
+}
+
+func (e *PackageError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Package.Name, e.Package.Version, e.Err)
+}
+
+func (e *PackageError) Unwrap() error {
+	return e.Err
+}
+
+// MultiError is returned by the operations working on several
+// packages at once, like [Manager.Del] with the All option or
+// [FlatBackend.LoadAll].  They go on after a package fails and
+// report all the failures at the end.
+type MultiError struct {
+	Errors []*PackageError
+}
+
+func (e *MultiError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d packages failed:\n%s", len(e.Errors),
+		strings.Join(msgs, "\n"))
+}
+
+func (e *MultiError) Unwrap() []error {
+	errs := make([]error, len(e.Errors))
+	for i, err := range e.Errors {
+		errs[i] = err
+	}
+	return errs
+}
+
+// add records the failure of the given package, if any.
+func (e *MultiError) add(pkg *Package, err error) {
+	if err != nil {
+		e.Errors = append(e.Errors, &PackageError{Package: *pkg, Err: err})
+	}
+}
+
+// err returns nil if no failure was recorded, and the error itself
+// otherwise.
+func (e *MultiError) err() error {
+	if len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
@@ -0,0 +1,133 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestFetchErrorKinds(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{http.StatusNotFound, ErrNotFound},
+		{http.StatusUnauthorized, ErrUnauthorized},
+		{http.StatusForbidden, ErrUnauthorized},
+		{http.StatusBadGateway, ErrRegistryUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "nope", tt.status)
+			}))
+			defer srv.Close()
+
+			m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL})
+			_, err := m.FetchRecipe("s3")
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("FetchRecipe err = %v, want %v", err, tt.want)
+			}
+
+			var ferr *FetchError
+			if !errors.As(err, &ferr) {
+				t.Fatalf("FetchRecipe err = %T, want *FetchError", err)
+			}
+			if !strings.HasSuffix(ferr.URL, "/s3/recipe.yaml") || ferr.Hint == "" {
+				t.Errorf("FetchError = %+v", ferr)
+			}
+		})
+	}
+}
+
+func TestFetchErrorRegistryDown(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	url := srv.URL
+	srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{InstallURL: url})
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrRegistryUnavailable) {
+		t.Errorf("FetchRecipe err = %v, want ErrRegistryUnavailable", err)
+	}
+}
+
+func TestFetchBinaryPlatformUnsupported(t *testing.T) {
+	const index = `{
+		"integrations": [{
+			"name": "s3",
+			"api": "v1.1.0",
+			"version": "v1.0.0",
+			"artifacts": [
+				{"version": "v1.0.0", "os": "plan9", "arch": "sparc64"},
+				{"version": "v1.0.0", "os": "aix", "arch": "ppc64"}
+			]
+		}]
+	}`
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".json") {
+			io.WriteString(w, index)
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL, ApiURL: srv.URL})
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"})
+	if !errors.Is(err, ErrPlatformUnsupported) {
+		t.Fatalf("Add err = %v, want ErrPlatformUnsupported", err)
+	}
+
+	var perr *PlatformError
+	if !errors.As(err, &perr) {
+		t.Fatalf("Add err = %T, want *PlatformError", err)
+	}
+	if perr.OperatingSystem != runtime.GOOS || perr.Architecture != runtime.GOARCH {
+		t.Errorf("platform = %s/%s", perr.OperatingSystem, perr.Architecture)
+	}
+	if len(perr.Available) != 2 || !strings.Contains(err.Error(), "plan9/sparc64, aix/ppc64") {
+		t.Errorf("error = %q", err)
+	}
+}
+
+func TestFetchBinaryNotFoundWithoutIndex(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL})
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"})
+	if !errors.Is(err, ErrNotFound) || errors.Is(err, ErrPlatformUnsupported) {
+		t.Errorf("Add err = %v, want ErrNotFound", err)
+	}
+}
+
+func TestMultiError(t *testing.T) {
+	var merr MultiError
+	merr.add(pkgVer("s3", "v1.0.0"), nil)
+	if err := merr.err(); err != nil {
+		t.Fatalf("err() without failures = %v", err)
+	}
+
+	merr.add(pkgVer("s3", "v1.0.0"), ErrNotFound)
+	merr.add(pkgVer("sftp", "v1.2.0"), ErrUnauthorized)
+
+	err := merr.err()
+	if !errors.Is(err, ErrNotFound) || !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("MultiError doesn't match the wrapped errors: %v", err)
+	}
+
+	var perr *PackageError
+	if !errors.As(err, &perr) || perr.Package.Name != "s3" {
+		t.Errorf("errors.As = %+v, want the s3 failure", perr)
+	}
+
+	msg := err.Error()
+	if !strings.Contains(msg, "s3 v1.0.0") || !strings.Contains(msg, "sftp v1.2.0") {
+		t.Errorf("Error() = %q", msg)
+	}
+}
@@ -0,0 +1,263 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+type EventType string
+
+const (
+	// Periodically sent while a package is being downloaded.
+	EventProgress EventType = "progress"
+
+	// Sent when a phase of an operation is over.
+	EventPhaseDone EventType = "phase-done"
+
+	// Sent for the issues that don't make the operation fail.
+	EventWarning EventType = "warning"
+
+	// Sent for every file written while a package is extracted.
+	EventFileExtracted EventType = "file-extracted"
+
+	// Sent when Sync finds a version with a critical advisory,
+	// telling what was done about it.
+	EventSecurity EventType = "security"
+)
+
+// WarningCode tells what an EventWarning is about.
+type WarningCode string
+
+const (
+	// The manifest has fields this version doesn't know about.
+	WarnUnknownField WarningCode = "unknown-field"
+
+	// The extracted package was missing or incomplete and was
+	// extracted again.
+	WarnReextracted WarningCode = "re-extracted"
+
+	// A package built for another platform was installed.
+	WarnOSArchMismatch WarningCode = "os-arch-mismatch"
+
+	// A package changed on disk couldn't be reloaded.
+	WarnReloadFailed WarningCode = "reload-failed"
+
+	// The integration index couldn't be fetched and an older copy
+	// is used instead.
+	WarnStaleIndex WarningCode = "stale-index"
+
+	// A package no longer matches its install receipt.
+	WarnTampered WarningCode = "tampered"
+
+	// The link to the active version of a package couldn't be
+	// updated.
+	WarnCurrentLink WarningCode = "current-link"
+
+	// A version yanked from the repository was installed anyway.
+	WarnYanked WarningCode = "yanked"
+
+	// A package was installed, but what follows failed: reading its
+	// manifest, pulling its dependencies or its recommendations.
+	WarnPostInstall WarningCode = "post-install"
+)
+
+const (
+	PhaseDownload = "download"
+	PhaseExtract  = "extract"
+	PhaseInstall  = "install"
+)
+
+// How often progress events are sent at most.
+const progressInterval = 100 * time.Millisecond
+
+// Event is sent to [Options.EventHook] to follow the progress of the
+// operations.
+type Event struct {
+	Type    EventType
+	Package Package
+	Phase   string
+
+	// Time spent in the phase so far.
+	Elapsed time.Duration
+
+	// Transfer progress, for EventProgress.  Total is -1 when the
+	// size is not known in advance, in which case ETA is zero.
+	Bytes int64
+	Total int64
+	Rate  float64 // bytes per second
+	ETA   time.Duration
+
+	// The file written, relative to the package root, and its
+	// size, for EventFileExtracted.  Bytes and Total then count
+	// the content of the package extracted so far.
+	Path string
+	Size int64
+
+	// What the issue is, for EventWarning, or what was done, for
+	// EventSecurity.
+	Code    WarningCode
+	Message string
+
+	// The advisory, for EventSecurity.
+	Advisory *Advisory
+
+	// The operation the event is part of, see withOp.
+	op string
+}
+
+// opKey is the context key of the operation being run.
+type opKey struct{}
+
+// withOp returns a context running the operation identified by key,
+// so that its events can be told apart from the ones of the other
+// operations on the same package.
+func withOp(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, opKey{}, key)
+}
+
+// emitContext emits an event of the operation run with ctx.
+func (p *Manager) emitContext(ctx context.Context, ev *Event) {
+	ev.op, _ = ctx.Value(opKey{}).(string)
+	p.emit(ev)
+}
+
+// newWarning returns an EventWarning about the given package.
+func newWarning(pkg *Package, code WarningCode, format string, args ...any) *Event {
+	ev := &Event{
+		Type:    EventWarning,
+		Code:    code,
+		Message: fmt.Sprintf(format, args...),
+	}
+	if pkg != nil {
+		ev.Package = *pkg
+	}
+	return ev
+}
+
+type subscriber struct {
+	fn func(*Event)
+}
+
+func (p *Manager) emit(ev *Event) {
+	if ev.Type == EventProgress {
+		p.setProgress(ev)
+	}
+
+	if p.eventhook != nil {
+		p.eventhook(ev)
+	}
+
+	p.submu.Lock()
+	defer p.submu.Unlock()
+	for sub := range p.subscribers {
+		sub.fn(ev)
+	}
+}
+
+// subscribe makes fn receive the events too, until the returned
+// function is called.
+func (p *Manager) subscribe(fn func(*Event)) func() {
+	sub := &subscriber{fn: fn}
+
+	p.submu.Lock()
+	if p.subscribers == nil {
+		p.subscribers = make(map[*subscriber]struct{})
+	}
+	p.subscribers[sub] = struct{}{}
+	p.submu.Unlock()
+
+	return func() {
+		p.submu.Lock()
+		delete(p.subscribers, sub)
+		p.submu.Unlock()
+	}
+}
+
+// progressReader sends progress events while the underlying reader is
+// consumed, and the phase timing once it's exhausted.
+type progressReader struct {
+	ctx   context.Context
+	rd    io.Reader
+	m     *Manager
+	pkg   Package
+	total int64
+	bytes int64
+	start time.Time
+	last  time.Time
+	end   time.Time // when the reader was exhausted
+}
+
+func newProgressReader(ctx context.Context, m *Manager, pkg *Package, rd io.Reader, total int64) *progressReader {
+	now := time.Now()
+	return &progressReader{
+		ctx:   ctx,
+		rd:    rd,
+		m:     m,
+		pkg:   *pkg,
+		total: total,
+		start: now,
+		last:  now,
+	}
+}
+
+func (pr *progressReader) Read(p []byte) (int, error) {
+	n, err := pr.rd.Read(p)
+	pr.bytes += int64(n)
+
+	now := time.Now()
+	if err == io.EOF || now.Sub(pr.last) >= progressInterval {
+		pr.last = now
+		pr.m.emitContext(pr.ctx, pr.progress(now))
+	}
+
+	if err == io.EOF && pr.end.IsZero() {
+		pr.end = now
+		pr.m.emitContext(pr.ctx, &Event{
+			Type:    EventPhaseDone,
+			Package: pr.pkg,
+			Phase:   PhaseDownload,
+			Elapsed: now.Sub(pr.start),
+		})
+	}
+	return n, err
+}
+
+func (pr *progressReader) progress(now time.Time) *Event {
+	ev := &Event{
+		Type:    EventProgress,
+		Package: pr.pkg,
+		Phase:   PhaseDownload,
+		Elapsed: now.Sub(pr.start),
+		Bytes:   pr.bytes,
+		Total:   pr.total,
+	}
+
+	if secs := ev.Elapsed.Seconds(); secs > 0 {
+		ev.Rate = float64(pr.bytes) / secs
+	}
+	if ev.Total > 0 && ev.Rate > 0 && ev.Bytes < ev.Total {
+		left := float64(ev.Total-ev.Bytes) / ev.Rate
+		ev.ETA = time.Duration(left * float64(time.Second))
+	}
+	return ev
+}
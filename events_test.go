@@ -0,0 +1,99 @@
+package pkg
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestProgressReaderRateAndETA(t *testing.T) {
+	pr := newProgressReader(context.Background(), &Manager{}, pkgVer("s3", "v1.0.0"), strings.NewReader(""), 1000)
+	pr.bytes = 250
+
+	ev := pr.progress(pr.start.Add(time.Second))
+	if ev.Rate != 250 {
+		t.Errorf("Rate = %v, want 250", ev.Rate)
+	}
+	if ev.ETA != 3*time.Second {
+		t.Errorf("ETA = %v, want 3s", ev.ETA)
+	}
+
+	pr.total = -1
+	if ev := pr.progress(pr.start.Add(time.Second)); ev.ETA != 0 {
+		t.Errorf("ETA with unknown total = %v, want 0", ev.ETA)
+	}
+}
+
+func TestFetchBinaryEmitsEvents(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	var events []*Event
+	m, _ := New(newFakeBackend(), &Options{
+		InstallURL: srv.URL,
+		EventHook:  func(ev *Event) { events = append(events, ev) },
+	})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	var progress, phases []*Event
+	for _, ev := range events {
+		switch ev.Type {
+		case EventProgress:
+			progress = append(progress, ev)
+		case EventPhaseDone:
+			phases = append(phases, ev)
+		}
+	}
+
+	if len(progress) == 0 {
+		t.Fatal("no progress events")
+	}
+	last := progress[len(progress)-1]
+	if last.Bytes != 8 || last.Total != 8 || last.Package.Name != "s3" {
+		t.Errorf("last progress = %+v", last)
+	}
+
+	if len(phases) != 2 || phases[0].Phase != PhaseDownload || phases[1].Phase != PhaseInstall {
+		t.Errorf("phases = %+v", phases)
+	}
+}
+
+func TestWarningOSArchMismatch(t *testing.T) {
+	var warnings []*Event
+	m, _ := New(newFakeBackend(), &Options{
+		EventHook: func(ev *Event) {
+			if ev.Type == EventWarning {
+				warnings = append(warnings, ev)
+			}
+		},
+	})
+
+	arch := "amd64"
+	if runtime.GOARCH == arch {
+		arch = "arm64"
+	}
+	ptar := filepath.Join(t.TempDir(), "s3_v1.0.0_"+runtime.GOOS+"_"+arch+".ptar")
+	if err := os.WriteFile(ptar, []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Add(ptar, &AddOptions{AllowOSArchMismatch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if len(warnings) != 1 || warnings[0].Code != WarnOSArchMismatch ||
+		warnings[0].Package.Name != "s3" || warnings[0].Message == "" {
+		t.Errorf("warnings = %+v", warnings)
+	}
+}
@@ -0,0 +1,106 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+	"time"
+)
+
+// ExtractProgress is called with each file written during an
+// extraction, its size, and the size of all the files to extract.
+type ExtractProgress func(path string, size, total int64)
+
+// ProgressExtractor is implemented by the extractors that can report
+// the files as they are written.
+type ProgressExtractor interface {
+	Extractor
+	ExtractWithProgress(ptar, dir string, progress ExtractProgress) error
+}
+
+// extractProgress returns the callback sending the EventFileExtracted
+// events for the package.
+func (f *FlatBackend) extractProgress(pkg *Package) ExtractProgress {
+	start := time.Now()
+	var done int64
+	return func(path string, size, total int64) {
+		done += size
+		f.emit(&Event{
+			Type:    EventFileExtracted,
+			Package: *pkg,
+			Phase:   PhaseExtract,
+			Elapsed: time.Since(start),
+			Bytes:   done,
+			Total:   total,
+			Path:    path,
+			Size:    size,
+		})
+	}
+}
+
+// watchExtraction calls progress for each of the regular files once
+// it's found in dir with its full size.  The exporter writes the
+// files concurrently and without telling, so dir is looked at
+// periodically until the returned function is called, which makes
+// a last pass.
+func watchExtraction(dir string, files []FileEntry, progress ExtractProgress) func() {
+	var pending []FileEntry
+	var total int64
+	for _, fe := range files {
+		if fe.Mode.IsRegular() {
+			pending = append(pending, fe)
+			total += fe.Size
+		}
+	}
+
+	scan := func() {
+		pending = slices.DeleteFunc(pending, func(fe FileEntry) bool {
+			fi, err := os.Stat(filepath.Join(dir, filepath.FromSlash(fe.Path)))
+			if err != nil || fi.Size() != fe.Size {
+				return false
+			}
+			progress(fe.Path, fe.Size, total)
+			return true
+		})
+	}
+
+	done := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Go(func() {
+		ticker := time.NewTicker(progressInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				scan()
+				return
+			case <-ticker.C:
+				scan()
+			}
+		}
+	})
+
+	return func() {
+		close(done)
+		wg.Wait()
+	}
+}
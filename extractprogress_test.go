@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// progressExtractor is a fakeExtractor reporting the manifest it
+// writes.
+type progressExtractor struct {
+	fakeExtractor
+}
+
+func (e *progressExtractor) ExtractWithProgress(ptar, dir string, progress ExtractProgress) error {
+	if err := e.Extract(ptar, dir); err != nil {
+		return err
+	}
+	progress("manifest.yaml", 9, 9)
+	return nil
+}
+
+func TestWatchExtraction(t *testing.T) {
+	dir := t.TempDir()
+	files := []FileEntry{
+		{Path: "bin/s3", Size: 4},
+		{Path: "manifest.yaml", Size: 9},
+		{Path: "missing", Size: 1},
+		{Path: "bin", Mode: os.ModeDir},
+	}
+
+	var got []string
+	var totals []int64
+	stop := watchExtraction(dir, files, func(path string, size, total int64) {
+		got = append(got, path)
+		totals = append(totals, total)
+	})
+
+	if err := os.MkdirAll(filepath.Join(dir, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// a partially written file is not reported.
+	if err := os.WriteFile(filepath.Join(dir, "bin", "s3"), []byte("PTA"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("name: s3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	stop()
+
+	if !slices.Equal(got, []string{"manifest.yaml"}) {
+		t.Errorf("reported = %v, want [manifest.yaml]", got)
+	}
+	if !slices.Equal(totals, []int64{14}) {
+		t.Errorf("totals = %v, want [14]", totals)
+	}
+}
+
+func TestFlatBackendExtractProgress(t *testing.T) {
+	var events []*Event
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{
+		Extractor: &progressExtractor{},
+		EventHook: func(ev *Event) {
+			events = append(events, ev)
+		},
+	})
+
+	pkg := pkgVer("s3", "v1.0.0")
+	if err := be.Load(pkg, strings.NewReader("PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	if len(events) != 1 {
+		t.Fatalf("events = %+v, want one", events)
+	}
+	ev := events[0]
+	if ev.Type != EventFileExtracted || ev.Phase != PhaseExtract || ev.Package.Name != "s3" ||
+		ev.Path != "manifest.yaml" || ev.Size != 9 || ev.Bytes != 9 || ev.Total != 9 {
+		t.Errorf("event = %+v", ev)
+	}
+}
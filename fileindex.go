@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+var ErrNoFileIndex = errors.New("no file index")
+
+// Name of the file, at the root of an extracted package, recording
+// the path, size, mode and digest of every file extracted.
+const fileIndexFile = ".files.json"
+
+// FileVerifier is implemented by the backends that can tell how the
+// files of an installed package changed since it was extracted.
+type FileVerifier interface {
+	VerifyFiles(*Package) ([]FileChange, error)
+}
+
+// VerifyFiles reports the files of the installed package that were
+// added, removed or modified since it was extracted.  The version
+// may be omitted if only one is installed.  It returns no change if
+// the package is intact.
+func (p *Manager) VerifyFiles(name, version string) ([]FileChange, error) {
+	fv, ok := p.store.(FileVerifier)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	pkg, err := p.lookup(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return fv.VerifyFiles(pkg)
+}
+
+// VerifyFiles compares the extracted tree with the file index written
+// when it was extracted.  It fails with ErrNoFileIndex for the trees
+// extracted before the index was kept.
+func (f *FlatBackend) VerifyFiles(pkg *Package) ([]FileChange, error) {
+	extracted := f.extractedPath(pkg)
+	if !f.isExtracted(extracted) {
+		return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+	}
+
+	data, err := readFile(f.fsys, filepath.Join(extracted, fileIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNoFileIndex)
+		}
+		return nil, err
+	}
+
+	var index []FileEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+
+	files, err := scanFiles(f.fsys, extracted, true)
+	if err != nil {
+		return nil, err
+	}
+	return diffFiles(index, files), nil
+}
+
+// writeFileIndex records the files of the tree extracted in dir.
+func writeFileIndex(fsys FS, dir string) error {
+	files, err := scanFiles(fsys, dir, true)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(files)
+	if err != nil {
+		return err
+	}
+	return writeFile(fsys, filepath.Join(dir, fileIndexFile), data)
+}
+
+// scanFiles lists the files of the extracted tree at dir, with their
+// digest if hash is true.  The bookkeeping files are left out.
+func scanFiles(fsys FS, dir string, hash bool) ([]FileEntry, error) {
+	var files []FileEntry
+	err := walkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() || path == filepath.Join(dir, fileIndexFile) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+
+		fe := FileEntry{
+			Path: filepath.ToSlash(rel),
+			Size: info.Size(),
+			Mode: info.Mode(),
+		}
+		if hash && info.Mode().IsRegular() {
+			if fe.Checksum, err = fileChecksum(fsys, path); err != nil {
+				return err
+			}
+		}
+		files = append(files, fe)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
+
+// fileChecksum returns the SHA-256 digest of the file.
+func fileChecksum(fsys FS, path string) (string, error) {
+	fp, err := fsys.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer fp.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, fp); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("sha256:%x", h.Sum(nil)), nil
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestVerifyFiles(t *testing.T) {
+	be, _, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, nil)
+
+	pkg := pkgVer("s3", "v1.0.0")
+	if err := be.Load(pkg, strings.NewReader("PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	changes, err := m.VerifyFiles("s3", "")
+	if err != nil {
+		t.Fatalf("VerifyFiles: %v", err)
+	}
+	if len(changes) != 0 {
+		t.Errorf("changes of an intact package = %+v", changes)
+	}
+
+	files, err := m.Files("s3", "")
+	if err != nil || len(files) != 1 || files[0].Path != "manifest.yaml" {
+		t.Errorf("Files = %+v, %v, want only the manifest", files, err)
+	}
+
+	extracted := cachePath(cachedir, pkg)
+	// same size, different content.
+	if err := os.WriteFile(filepath.Join(extracted, "manifest.yaml"), []byte("name: s4\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "backdoor"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err = m.VerifyFiles("s3", "")
+	if err != nil {
+		t.Fatalf("VerifyFiles: %v", err)
+	}
+	if len(changes) != 2 ||
+		changes[0].Path != "backdoor" || changes[0].Change != DiffAdded ||
+		changes[1].Path != "manifest.yaml" || changes[1].Change != DiffChanged {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	if err := os.Remove(filepath.Join(extracted, "manifest.yaml")); err != nil {
+		t.Fatal(err)
+	}
+	changes, _ = m.VerifyFiles("s3", "")
+	if len(changes) != 2 || changes[1].Path != "manifest.yaml" || changes[1].Change != DiffRemoved {
+		t.Errorf("changes = %+v", changes)
+	}
+
+	if err := os.Remove(filepath.Join(extracted, fileIndexFile)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.VerifyFiles("s3", ""); !errors.Is(err, ErrNoFileIndex) {
+		t.Errorf("VerifyFiles without index err = %v, want ErrNoFileIndex", err)
+	}
+}
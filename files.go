@@ -0,0 +1,81 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrNotInstalled = errors.New("not installed")
+)
+
+// FileLister is implemented by the backends that can tell which files
+// an installed package provides.
+type FileLister interface {
+	Files(*Package) ([]FileEntry, error)
+}
+
+// lookup returns the installed package with the given name and
+// version.  If version is empty, the package must be installed only
+// once.
+func (p *Manager) lookup(name, version string) (*Package, error) {
+	var found *Package
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return nil, err
+		}
+
+		if version != "" && pkg.Version != version {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("%w: multiple versions of %s installed",
+				ErrInvalidOptions, name)
+		}
+		found = pkg
+	}
+
+	if found == nil {
+		return nil, fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+	return found, nil
+}
+
+// Files returns the files provided by an installed package, like
+// dpkg -L.  The version may be omitted if only one is installed.
+func (p *Manager) Files(name, version string) ([]FileEntry, error) {
+	fl, ok := p.store.(FileLister)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	pkg, err := p.lookup(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return fl.Files(pkg)
+}
+
+// Files walks the extracted tree of the package.
+func (f *FlatBackend) Files(pkg *Package) ([]FileEntry, error) {
+	return scanFiles(f.fsys, f.extractedPath(pkg), false)
+}
@@ -0,0 +1,64 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFlatBackendFiles(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, nil)
+
+	pkg := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, pkg.Filename())
+
+	extracted := cachePath(cachedir, pkg)
+	if err := os.MkdirAll(filepath.Join(extracted, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "manifest.yaml"), []byte("name: s3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "bin", "s3-storage"), make([]byte, 16), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	m, _ := New(be, nil)
+	files, err := m.Files("s3", "")
+	if err != nil {
+		t.Fatalf("Files: %v", err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("Files = %+v, want 2 entries", files)
+	}
+	if files[0].Path != "bin/s3-storage" || files[0].Size != 16 {
+		t.Errorf("files[0] = %+v", files[0])
+	}
+	if runtime.GOOS != "windows" && files[0].Mode.Perm()&0100 == 0 {
+		t.Errorf("files[0] mode = %v, want executable", files[0].Mode)
+	}
+	if files[1].Path != "manifest.yaml" {
+		t.Errorf("files[1] = %+v", files[1])
+	}
+}
+
+func TestFilesNotInstalled(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+	m, _ := New(be, nil)
+	if _, err := m.Files("s3", ""); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Files err = %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestLookupAmbiguousVersion(t *testing.T) {
+	m, _ := New(newFakeBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0")), nil)
+	if _, err := m.lookup("s3", ""); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("lookup err = %v, want ErrInvalidOptions", err)
+	}
+	pkg, err := m.lookup("s3", "v2.0.0")
+	if err != nil || pkg.Version != "v2.0.0" {
+		t.Errorf("lookup(s3, v2.0.0) = %+v, %v", pkg, err)
+	}
+}
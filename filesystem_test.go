@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// faultyFS fails the operations it has an error for.
+type faultyFS struct {
+	OSFS
+	renameErr   error
+	mkdirAllErr error
+}
+
+func (f faultyFS) Rename(oldpath, newpath string) error {
+	if f.renameErr != nil {
+		return f.renameErr
+	}
+	return f.OSFS.Rename(oldpath, newpath)
+}
+
+func (f faultyFS) MkdirAll(path string, perm fs.FileMode) error {
+	if f.mkdirAllErr != nil {
+		return f.mkdirAllErr
+	}
+	return f.OSFS.MkdirAll(path, perm)
+}
+
+func TestFlatBackendRenameFailure(t *testing.T) {
+	errRename := errors.New("rename failed")
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{
+		FS: faultyFS{renameErr: errRename},
+	})
+
+	if err := be.Hold("s3"); !errors.Is(err, errRename) {
+		t.Fatalf("Hold = %v, want %v", err, errRename)
+	}
+
+	dirents, err := os.ReadDir(pkgdir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, d := range dirents {
+		if strings.HasPrefix(d.Name(), stagingPrefix) {
+			t.Errorf("staging file %q left behind", d.Name())
+		}
+	}
+
+	held, err := be.Held()
+	if err != nil {
+		t.Fatalf("Held: %v", err)
+	}
+	if len(held) != 0 {
+		t.Errorf("held = %v, want none", held)
+	}
+}
+
+func TestNewFlatBackendSurfacesFSErrors(t *testing.T) {
+	root := t.TempDir()
+	_, err := NewFlatBackend(nil, filepath.Join(root, "pkgs"), filepath.Join(root, "cache"),
+		&FlatBackendOptions{FS: faultyFS{mkdirAllErr: fs.ErrPermission}})
+	if !errors.Is(err, fs.ErrPermission) {
+		t.Fatalf("NewFlatBackend = %v, want %v", err, fs.ErrPermission)
+	}
+}
+
+func TestWalkDir(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"a/x", "a/y", "b/z", "c"} {
+		path := filepath.Join(root, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	err := walkDir(OSFS{}, root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, _ := filepath.Rel(root, path)
+		got = append(got, filepath.ToSlash(rel))
+		if d.IsDir() && d.Name() == "b" {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("walkDir: %v", err)
+	}
+
+	want := []string{".", "a", "a/x", "a/y", "b", "c"}
+	if !slices.Equal(got, want) {
+		t.Errorf("walked %v, want %v", got, want)
+	}
+}
+
+func TestWalkDirMissingRoot(t *testing.T) {
+	root := filepath.Join(t.TempDir(), "missing")
+	err := walkDir(OSFS{}, root, func(path string, d fs.DirEntry, err error) error {
+		return err
+	})
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("walkDir = %v, want %v", err, fs.ErrNotExist)
+	}
+}
@@ -19,13 +19,18 @@
 package pkg
 
 import (
+	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 	"io"
+	"io/fs"
 	"iter"
 	"os"
 	"path/filepath"
+	"runtime"
 	"strings"
+	"sync"
 
 	fsexporter "github.com/PlakarKorp/integrations/fs/exporter"
 	_ "github.com/PlakarKorp/integrations/ptar/storage"
@@ -37,283 +42,893 @@ import (
 	"github.com/PlakarKorp/kloset/snapshot"
 )
 
+// Suffix of the file written next to an extracted package once the
+// extraction has completed.  It's kept out of the tree, so that a
+// package can't ship it.
+const extractedMarker = ".extracted"
+
 // A backend that stores integrations in a single, flat, directory.
 type FlatBackend struct {
-	kcontext *kcontext.KContext
-	pkgdir   string
-	cachedir string
-
-	preloadhook func(*Manifest) error
-	loadhook    func(*Manifest, *Package, string)
-	unloadhook  func(*Manifest, *Package)
+	kcontext   *kcontext.KContext
+	pkgdir     string
+	cachedir   string
+	stagingdir string
+	quota      int64
+	versions   VersionScheme
+	naming     NamingScheme
+	extractor  Extractor
+	fsys       FS
+
+	preloadhook   func(context.Context, *Manifest) error
+	loadhook      func(context.Context, *Manifest, *Package, string) error
+	preunloadhook func(context.Context, *Manifest, *Package) error
+	unloadhook    func(context.Context, *Manifest, *Package)
+	eventhook     func(*Event)
+
+	// held for reading by the operations and for writing while
+	// looking for changes on disk.
+	oplock sync.RWMutex
+
+	loadedmu sync.Mutex
+	loaded   map[string]*loadedPackage
+	loadkeep func(*Package) bool // the selection of the last loadAll
+
+	// the versions installed alongside the live one, which are not
+	// loaded until activated.
+	idle map[string]bool
+
+	tamperpolicy TamperPolicy
+	receiptmu    sync.Mutex
+
+	currentmu sync.Mutex
 }
 
 type FlatBackendOptions struct {
-	PreLoadHook func(*Manifest) error
-	LoadHook    func(*Manifest, *Package, string)
-	UnloadHook  func(*Manifest, *Package)
+	// Maximum number of bytes the package and cache directories
+	// may use together.  Zero means no limit.
+	Quota int64
+
+	// Directory where downloads and extractions are staged before
+	// being moved into place.  Defaults to the package and cache
+	// directories themselves.
+	StagingDir string
+
+	// How the package versions are validated.  Defaults to
+	// [SemverScheme].
+	VersionScheme VersionScheme
+
+	// Where the packages are stored in the package directory.
+	// Defaults to FlatNaming.
+	NamingScheme NamingScheme
+
+	// How the packages are unpacked.  Defaults to exporting the
+	// ptar snapshot.
+	Extractor Extractor
+
+	// Where the files are stored.  Defaults to OSFS.
+	FS FS
+
+	// Number of files the default extractor writes concurrently.
+	// Defaults to the number of CPUs.
+	ExtractConcurrency int
+
+	// When running as root, the default extractor unpacks the
+	// packages and parses their manifest in a helper process
+	// running as this user.  The program must call [RunHelper]
+	// first thing in main.  Ignored when not running as root.
+	ExtractUser string
+
+	// Unpack the packages in a helper process confined to a
+	// scratch directory: in its own namespaces and chroot on
+	// Linux, chroot when running as root on other unix systems,
+	// and with a restricted token on Windows.  As with
+	// ExtractUser, the program must call [RunHelper].  On Linux,
+	// NewFlatBackend starts the helper once to check that the
+	// sandbox is available.
+	SandboxExtraction bool
+
+	// The hooks are passed the backend kcontext, so that they
+	// can be cancelled along with it.
+	PreLoadHook func(context.Context, *Manifest) error
+
+	// Called once a package is in place with the directory it was
+	// extracted to.  If it returns an error, a freshly installed
+	// package is removed again and the error is returned.
+	LoadHook func(context.Context, *Manifest, *Package, string) error
+
+	// Called before removing a package.  The package is kept if
+	// it returns an error, e.g. because it's in use.
+	PreUnloadHook func(context.Context, *Manifest, *Package) error
+	UnloadHook    func(context.Context, *Manifest, *Package)
+
+	// Receives the EventWarning events about the issues the
+	// backend worked around.
+	EventHook func(*Event)
+
+	// What to do when a package no longer matches its install
+	// receipt when loaded again.  Defaults to [TamperIgnore].
+	TamperPolicy TamperPolicy
 }
 
 func NewFlatBackend(kctx *kcontext.KContext, pkgdir, cachedir string, opts *FlatBackendOptions) (*FlatBackend, error) {
-	if err := os.MkdirAll(pkgdir, 0755); err != nil {
+	fsys := opts.FS
+	if fsys == nil {
+		fsys = OSFS{}
+	}
+
+	if err := fsys.MkdirAll(pkgdir, 0755); err != nil {
 		return nil, err
 	}
 
-	if err := os.MkdirAll(cachedir, 0755); err != nil {
+	if err := fsys.MkdirAll(cachedir, 0755); err != nil {
 		return nil, err
 	}
 
-	return &FlatBackend{
+	if opts.StagingDir != "" {
+		if err := fsys.MkdirAll(opts.StagingDir, 0755); err != nil {
+			return nil, err
+		}
+	}
+
+	f := &FlatBackend{
 		kcontext:    kctx,
 		pkgdir:      pkgdir,
 		cachedir:    cachedir,
+		stagingdir:  opts.StagingDir,
+		quota:       opts.Quota,
+		versions:    opts.VersionScheme,
+		naming:      opts.NamingScheme,
+		extractor:   opts.Extractor,
+		fsys:        fsys,
 		preloadhook: opts.PreLoadHook,
 		loadhook:    opts.LoadHook,
-		unloadhook:  opts.UnloadHook,
-	}, nil
+
+		preunloadhook: opts.PreUnloadHook,
+		unloadhook:    opts.UnloadHook,
+		eventhook:     opts.EventHook,
+		tamperpolicy:  opts.TamperPolicy,
+	}
+
+	if f.versions == nil {
+		f.versions = SemverScheme
+	}
+	if f.naming == nil {
+		f.naming = FlatNaming{Versions: f.versions}
+	}
+	if f.extractor == nil {
+		concurrency := opts.ExtractConcurrency
+		if concurrency <= 0 {
+			concurrency = runtime.NumCPU()
+		}
+		f.extractor = &ptarExtractor{kcontext: kctx, concurrency: concurrency}
+		if opts.ExtractUser != "" && privileged() || opts.SandboxExtraction {
+			e, err := newHelperExtractor(opts.ExtractUser, opts.SandboxExtraction, concurrency)
+			if err != nil {
+				return nil, err
+			}
+			f.extractor = e
+		}
+	}
+
+	if err := f.sweepStaging(); err != nil {
+		return nil, err
+	}
+
+	if err := f.migrateCache(); err != nil {
+		return nil, err
+	}
+
+	return f, nil
 }
 
 func (f *FlatBackend) List(name string) iter.Seq2[*Package, error] {
 	return func(yield func(*Package, error) bool) {
-		dir, err := os.Open(f.pkgdir)
-		if err != nil {
-			yield(nil, err)
-			return
-		}
-		defer dir.Close()
-
-		for {
-			dirents, err := dir.ReadDir(16)
+		err := walkDir(f.fsys, f.pkgdir, func(p string, d fs.DirEntry, err error) error {
 			if err != nil {
-				if !errors.Is(err, io.EOF) {
-					yield(nil, err)
-				}
-				return
+				return err
+			}
+			if p == f.pkgdir {
+				return nil
 			}
 
-			for i := range dirents {
-				// skip hidden files
-				if strings.HasPrefix(dirents[i].Name(), ".") {
-					continue
+			// skip hidden files and directories
+			if strings.HasPrefix(d.Name(), ".") {
+				if d.IsDir() {
+					return filepath.SkipDir
 				}
+				return nil
+			}
+			if d.IsDir() {
+				return nil
+			}
 
-				var pkg Package
-				if err := pkg.parseName(dirents[i].Name()); err != nil {
-					if strings.HasPrefix(dirents[i].Name(), "fetch-plugin-") {
-						os.Remove(dirents[i].Name())
-					}
-					continue
-				}
+			rel, err := filepath.Rel(f.pkgdir, p)
+			if err != nil {
+				return err
+			}
 
-				if name != "" && pkg.Name != name {
-					continue
+			pkg, err := f.naming.Parse(filepath.ToSlash(rel))
+			if err != nil {
+				if strings.HasPrefix(d.Name(), "fetch-plugin-") {
+					f.fsys.Remove(p)
 				}
+				return nil
+			}
 
-				if !yield(&pkg, nil) {
-					return
-				}
+			if name != "" && pkg.Name != name {
+				return nil
 			}
+
+			if !yield(pkg, nil) {
+				return filepath.SkipAll
+			}
+			return nil
+		})
+		if err != nil {
+			yield(nil, err)
 		}
 	}
 }
 
-func (f *FlatBackend) extract(destDir, ptar string) error {
-	store, serializedConfig, err := storage.Open(f.kcontext, map[string]string{
+// ptarPath returns where the package is stored.
+func (f *FlatBackend) ptarPath(pkg *Package) string {
+	return filepath.Join(f.pkgdir, filepath.FromSlash(f.naming.Path(pkg)))
+}
+
+// extractedPath returns where the package is extracted, as
+// "<name>/<version>/<os>_<arch>" in the cache directory.
+func (f *FlatBackend) extractedPath(pkg *Package) string {
+	return filepath.Join(f.cachedir, escapeName(pkg.Name), pkg.Version,
+		pkg.OperatingSystem+"_"+pkg.Architecture)
+}
+
+// legacyExtractedPath returns where the package was extracted before
+// the cache directory was organized per package and version: next to
+// the others, named after the ptar.
+func (f *FlatBackend) legacyExtractedPath(pkg *Package) string {
+	rel := strings.TrimSuffix(filepath.FromSlash(f.naming.Path(pkg)), ".ptar")
+	return filepath.Join(f.cachedir, rel)
+}
+
+// migrateCache moves the trees extracted with the legacy layout to
+// the current one.  The leftovers of a previous migration are
+// removed.
+func (f *FlatBackend) migrateCache() error {
+	for pkg, err := range f.List("") {
+		if err != nil {
+			return err
+		}
+
+		legacy := f.legacyExtractedPath(pkg)
+		if _, err := f.fsys.Stat(legacy); err != nil {
+			continue
+		}
+
+		extracted := f.extractedPath(pkg)
+		if _, err := f.fsys.Stat(extracted); err == nil {
+			if err := f.fsys.RemoveAll(legacy); err != nil {
+				return err
+			}
+			continue
+		}
+
+		if err := f.fsys.MkdirAll(filepath.Dir(extracted), 0755); err != nil {
+			return err
+		}
+		if err := f.fsys.Rename(legacy, extracted); err != nil {
+			return err
+		}
+		// the legacy layout kept the marker inside the tree.
+		inner := filepath.Join(extracted, extractedMarker)
+		if _, err := f.fsys.Stat(inner); err == nil {
+			if err := f.fsys.Rename(inner, markerPath(extracted)); err != nil {
+				return err
+			}
+		}
+		f.removeEmptyDirs(filepath.Dir(legacy), f.cachedir)
+	}
+	return nil
+}
+
+// removeEmptyDirs removes dir and its parents up to root, excluded,
+// as long as they are empty.
+func (f *FlatBackend) removeEmptyDirs(dir, root string) {
+	for dir != root && within(root, dir) {
+		if err := f.fsys.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// openSnapshot opens the single snapshot contained in the given ptar
+// file.  It returns the snapshot, the directory the plugin content
+// lives in, and a function to release the underlying store.
+func (f *FlatBackend) openSnapshot(ptar string) (*snapshot.Snapshot, string, func(), error) {
+	return openSnapshot(f.kcontext, ptar)
+}
+
+func openSnapshot(kctx *kcontext.KContext, ptar string) (*snapshot.Snapshot, string, func(), error) {
+	store, serializedConfig, err := storage.Open(kctx, map[string]string{
 		"location": "ptar://" + ptar,
 	})
 	if err != nil {
-		return err
+		return nil, "", nil, err
 	}
-	// Close the store before returning so the underlying handle on the
+	// Close the store when done so the underlying handle on the
 	// .ptar file is released. On Windows an open handle prevents the
 	// caller from linking or renaming the file ("Access is denied").
-	defer store.Close(f.kcontext)
+	release := func() { store.Close(kctx) }
 
-	repo, err := repository.New(f.kcontext, nil, store, serializedConfig)
+	repo, err := repository.New(kctx, nil, store, serializedConfig)
 	if err != nil {
-		return err
+		release()
+		return nil, "", nil, err
 	}
 
 	locopts := locate.NewDefaultLocateOptions()
 	snapids, err := locate.LocateSnapshotIDs(repo, locopts)
 	if err != nil {
-		return fmt.Errorf("failed to list snapshots: %w", err)
+		release()
+		return nil, "", nil, fmt.Errorf("failed to list snapshots: %w", err)
 	}
 	if len(snapids) != 1 {
-		return fmt.Errorf("too many snapshot in ptar plugin: %d",
+		release()
+		return nil, "", nil, fmt.Errorf("too many snapshot in ptar plugin: %d",
 			len(snapids))
 	}
 
 	snapid := snapids[0]
 	snap, err := snapshot.Load(repo, snapid)
 	if err != nil {
-		return err
+		release()
+		return nil, "", nil, err
 	}
 
-	tmpdir, err := os.MkdirTemp(filepath.Dir(destDir), ".extract-*")
+	base := snap.Header.GetSource(0).Importer.Directory
+	return snap, base, release, nil
+}
+
+// Extractor unpacks a stored package.
+type Extractor interface {
+	// Extract writes the plugin content found in the ptar file
+	// to dir, which doesn't exist yet.
+	Extract(ptar, dir string) error
+}
+
+// ptarExtractor is the default Extractor, exporting the ptar snapshot
+// to the filesystem.
+type ptarExtractor struct {
+	kcontext    *kcontext.KContext
+	concurrency int
+}
+
+func (e *ptarExtractor) Extract(ptar, dir string) error {
+	return e.ExtractWithProgress(ptar, dir, nil)
+}
+
+// ExtractWithProgress is Extract, calling progress for every file
+// once it's fully written.
+func (e *ptarExtractor) ExtractWithProgress(ptar, dir string, progress ExtractProgress) error {
+	snap, base, release, err := openSnapshot(e.kcontext, ptar)
 	if err != nil {
 		return err
 	}
-	defer os.RemoveAll(tmpdir)
+	defer release()
+
+	if err := checkSnapshot(snap, base); err != nil {
+		return err
+	}
 
-	content := "fs://" + tmpdir + "/content"
+	if progress != nil {
+		files, err := snapshotFiles(snap, base)
+		if err != nil {
+			return err
+		}
+		stop := watchExtraction(dir, files, progress)
+		defer stop()
+	}
 
-	fsexp, err := fsexporter.NewFSExporter(f.kcontext, &connectors.Options{
-		MaxConcurrency: 1,
+	fsexp, err := fsexporter.NewFSExporter(e.kcontext, &connectors.Options{
+		MaxConcurrency: e.concurrency,
 	}, "fs", map[string]string{
-		"location": content,
+		"location": "fs://" + dir,
 	})
 	if err != nil {
 		return err
 	}
 
-	base := snap.Header.GetSource(0).Importer.Directory
-	err = snap.Export(fsexp, base, &snapshot.ExportOptions{
+	return snap.Export(fsexp, base, &snapshot.ExportOptions{
 		Strip: base,
 	})
+}
+
+func (f *FlatBackend) extract(pkg *Package, destDir, ptar string) error {
+	if err := f.fsys.MkdirAll(filepath.Dir(destDir), 0755); err != nil {
+		return err
+	}
+
+	tmpdir, err := f.fsys.MkdirTemp(f.staging(f.cachedir), stagingPrefix+"extract-*")
 	if err != nil {
 		return err
 	}
+	defer f.fsys.RemoveAll(tmpdir)
+
+	if err := f.unpack(pkg, ptar, tmpdir+"/content"); err != nil {
+		return err
+	}
+	if err := f.pruneForeign(pkg, tmpdir+"/content"); err != nil {
+		return err
+	}
+	if err := writeFileIndex(f.fsys, tmpdir+"/content"); err != nil {
+		return err
+	}
 
-	if err := os.Rename(tmpdir+"/content", destDir); err != nil {
+	if err := move(f.fsys, tmpdir+"/content", destDir); err != nil {
 		return fmt.Errorf("failed to rename: %w", err)
 	}
 
+	// mark the extraction as complete once in place, so that a
+	// tree without the marker is known to be a leftover.
+	fp, err := f.fsys.OpenFile(markerPath(destDir), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	return fp.Close()
+}
+
+// unpack runs the extractor to write the content of the ptar to dir,
+// and checks what it produced.
+func (f *FlatBackend) unpack(pkg *Package, ptar, dir string) error {
+	var err error
+	pe, ok := f.extractor.(ProgressExtractor)
+	if ok && f.eventhook != nil {
+		err = pe.ExtractWithProgress(ptar, dir, f.extractProgress(pkg))
+	} else {
+		err = f.extractor.Extract(ptar, dir)
+	}
+	if err != nil {
+		return err
+	}
+
+	// whatever the extractor, don't trust what it produced.
+	return checkTree(f.fsys, dir)
+}
+
+// isolated returns whether the packages are decoded by a helper
+// process, in which case the backend must not open them itself.
+func (f *FlatBackend) isolated() bool {
+	_, ok := f.extractor.(*helperExtractor)
+	return ok
+}
+
+// pruneForeign removes from the extracted tree the files the manifest
+// scopes to other platforms, so that fat packages don't keep blobs
+// that are useless here.  A missing or broken manifest is reported
+// later, when loading it.
+func (f *FlatBackend) pruneForeign(pkg *Package, dir string) error {
+	m, err := f.readManifest(pkg, filepath.Join(dir, "manifest.yaml"))
+	if err != nil {
+		return nil
+	}
+
+	var needed []string
+	for _, conn := range m.Connectors {
+		needed = append(needed, conn.Executable)
+		needed = append(needed, conn.ExtraFiles...)
+	}
+	keep := map[string]bool{}
+	for _, pattern := range needed {
+		files, err := expandGlob(f.fsys, dir, pattern)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			keep[file] = true
+		}
+	}
+
+	for _, pattern := range m.foreignFiles() {
+		files, err := expandGlob(f.fsys, dir, pattern)
+		if err != nil {
+			continue
+		}
+		for _, file := range files {
+			if keep[file] {
+				continue
+			}
+			if err := f.fsys.RemoveAll(filepath.Join(dir, filepath.FromSlash(file))); err != nil {
+				return err
+			}
+		}
+	}
 	return nil
 }
 
-func (f *FlatBackend) loadmanifest(mpath string) (*Manifest, error) {
-	m, err := NewManifestFromFile(mpath)
+// markerPath returns the marker of the extraction at dir.
+func markerPath(dir string) string {
+	return filepath.Clean(dir) + extractedMarker
+}
+
+// isExtracted returns whether the package was fully extracted at the
+// given location.
+func (f *FlatBackend) isExtracted(dir string) bool {
+	_, err := f.fsys.Stat(markerPath(dir))
+	return err == nil
+}
+
+// removeExtracted removes the extracted tree at dir, starting with
+// its marker so that it's never taken as complete half-removed.
+func (f *FlatBackend) removeExtracted(dir string) error {
+	if err := f.fsys.Remove(markerPath(dir)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return f.fsys.RemoveAll(dir)
+}
+
+func (f *FlatBackend) emit(ev *Event) {
+	if f.eventhook != nil {
+		f.eventhook(ev)
+	}
+}
+
+// readManifest parses the manifest of pkg at the given path.
+func (f *FlatBackend) readManifest(pkg *Package, mpath string) (*Manifest, error) {
+	fp, err := f.fsys.Open(mpath)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	var m Manifest
+	if err := m.Parse(fp); err != nil {
+		return nil, err
+	}
+	m.expandPackage(pkg, filepath.Dir(mpath))
+	return &m, nil
+}
+
+func (f *FlatBackend) loadmanifest(pkg *Package, mpath string) (*Manifest, error) {
+	m, err := f.readManifest(pkg, mpath)
 	if err != nil {
 		return nil, err
 	}
 
 	dir := filepath.Dir(mpath)
-	for _, conn := range m.Connectors {
-		exe := filepath.Join(dir, conn.Executable)
-		if !strings.HasPrefix(exe, dir) {
-			return nil, fmt.Errorf("bad executable path %q", conn.Executable)
+	for i, conn := range m.Connectors {
+		if _, err := containedPath(f.fsys, dir, conn.Executable); err != nil {
+			return nil, fmt.Errorf("bad executable path %q: %w", conn.Executable, err)
 		}
 
 		if _, err := conn.Flags(); err != nil {
 			return nil, err
 		}
+
+		files, err := expandGlobs(f.fsys, dir, conn.ExtraFiles)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if _, err := containedPath(f.fsys, dir, file); err != nil {
+				return nil, fmt.Errorf("bad extra file path %q: %w", file, err)
+			}
+		}
+		m.Connectors[i].ExtraFiles = files
 	}
 
 	return m, nil
 }
 
 func (f *FlatBackend) Load(pkg *Package, rd io.Reader) error {
-	fp, err := os.CreateTemp(f.pkgdir, "."+pkg.Name+"-*")
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	fp, err := f.fsys.CreateTemp(f.staging(f.pkgdir), stagingPrefix+pkg.Name+"-*")
 	if err != nil {
 		return err
 	}
 
-	_, err = io.Copy(fp, rd)
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(fp, h), rd)
 	fp.Close()
 	if err != nil {
-		os.Remove(fp.Name())
+		f.fsys.Remove(fp.Name())
+		return err
+	}
+
+	// the package is not accounted for yet if it's being staged
+	// outside of the package directory.
+	var pending int64
+	if f.stagingdir != "" {
+		pending = size
+	}
+
+	if err := f.checkquota(pending); err != nil {
+		f.fsys.Remove(fp.Name())
 		return err
 	}
 
 	// extract and validate its manifest before enabling it.
 
-	extracted := filepath.Join(f.cachedir, strings.TrimSuffix(pkg.Filename(), ".ptar"))
-	if err := f.extract(extracted, fp.Name()); err != nil {
+	extracted := f.extractedPath(pkg)
+	if err := f.extract(pkg, extracted, fp.Name()); err != nil {
+		f.unload(fp.Name(), extracted)
+		return err
+	}
+
+	if err := f.checkquota(pending); err != nil {
 		f.unload(fp.Name(), extracted)
 		return err
 	}
 
-	m, err := f.loadmanifest(filepath.Join(extracted, "manifest.yaml"))
+	m, err := f.loadmanifest(pkg, filepath.Join(extracted, "manifest.yaml"))
 	if err != nil {
 		f.unload(fp.Name(), extracted)
 		return err
 	}
 
 	if f.preloadhook != nil {
-		if err := f.preloadhook(m); err != nil {
+		if err := f.preloadhook(f.kcontext, m); err != nil {
 			f.unload(fp.Name(), extracted)
 			return err
 		}
 	}
 
-	// Rename rather than hard-link the temp file into place: the temp
-	// file already lives in f.pkgdir, so this is atomic, and os.Rename is
-	// far more portable than os.Link, which fails on Windows on
-	// filesystems or setups that don't support hard links.
-	pkgdir := filepath.Join(f.pkgdir, pkg.Filename())
-	if err := os.Rename(fp.Name(), pkgdir); err != nil {
+	// Rename rather than hard-link the temp file into place: unless
+	// a staging directory on another filesystem is used, the temp
+	// file already lives in f.pkgdir, so this is atomic, and
+	// os.Rename is far more portable than os.Link, which fails on
+	// Windows on filesystems or setups that don't support hard links.
+	pkgdir := f.ptarPath(pkg)
+	if err := f.fsys.MkdirAll(filepath.Dir(pkgdir), 0755); err != nil {
 		f.unload(fp.Name(), extracted)
 		return err
 	}
+	if err := move(f.fsys, fp.Name(), pkgdir); err != nil {
+		f.unload(fp.Name(), extracted)
+		return err
+	}
+	if err := f.setReceipt(pkg, newReceipt(h.Sum(nil))); err != nil {
+		f.unload(pkgdir, extracted)
+		return err
+	}
+
+	// the archived copy, if any, is superseded.
+	defer f.fsys.Remove(f.archivePath(pkg))
+
+	// a version installed alongside another one is only a
+	// candidate: it goes live once activated.
+	candidate, err := f.sideBySide(pkg)
+	if err != nil {
+		f.unload(pkgdir, extracted)
+		f.setReceipt(pkg, nil)
+		return err
+	}
+	if candidate {
+		f.markIdle(pkg)
+		return nil
+	}
 
 	if f.loadhook != nil {
-		f.loadhook(m, pkg, extracted)
+		if err := f.loadhook(f.kcontext, m, pkg, extracted); err != nil {
+			f.unload(pkgdir, extracted)
+			f.setReceipt(pkg, nil)
+			return err
+		}
 	}
 
+	f.markLoaded(pkg)
+	f.updateCurrent(pkg.Name)
 	return nil
 }
 
+// sideBySide returns whether another version of the package is
+// installed.  The version LoadAll picks is then made the active one,
+// if none is, so that the newcomer doesn't take over at the next
+// LoadAll.
+func (f *FlatBackend) sideBySide(pkg *Package) (bool, error) {
+	var cur *Package
+	for other, err := range f.List(pkg.Name) {
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case other.Version == pkg.Version:
+			continue
+		case cur == nil, f.isLoaded(other):
+			cur = other
+		case !f.isLoaded(cur) && f.versions.Compare(other.Version, cur.Version) > 0:
+			cur = other
+		}
+	}
+	if cur == nil {
+		return false, nil
+	}
+
+	actives, err := f.actives()
+	if err != nil {
+		return false, err
+	}
+	if actives[pkg.Name] == "" {
+		if err := f.writeActive(cur); err != nil {
+			return false, err
+		}
+	}
+	return true, nil
+}
+
 func (f *FlatBackend) reload(pkg *Package) error {
+	if err := f.checkReceipt(pkg); err != nil {
+		return err
+	}
+
 	// extract if needed
-	ptar := filepath.Join(f.pkgdir, pkg.Filename())
-	extracted := filepath.Join(f.cachedir, strings.TrimSuffix(pkg.Filename(), ".ptar"))
-	if _, err := os.Stat(extracted); err != nil {
-		if err := f.extract(extracted, ptar); err != nil {
+	ptar := f.ptarPath(pkg)
+	extracted := f.extractedPath(pkg)
+	if !f.isExtracted(extracted) {
+		// start over if a previous extraction was interrupted.
+		if err := f.removeExtracted(extracted); err != nil {
+			return err
+		}
+		if err := f.extract(pkg, extracted, ptar); err != nil {
 			f.unload(ptar, extracted)
 			return err
 		}
+		f.emit(newWarning(pkg, WarnReextracted,
+			"%s was missing or incomplete and was extracted again", extracted))
 	}
 
-	m, err := f.loadmanifest(filepath.Join(extracted, "manifest.yaml"))
+	m, err := f.loadmanifest(pkg, filepath.Join(extracted, "manifest.yaml"))
 	if err != nil {
 		f.unload(ptar, extracted)
 		return err
 	}
 
 	if f.loadhook != nil {
-		f.loadhook(m, pkg, extracted)
+		if err := f.loadhook(f.kcontext, m, pkg, extracted); err != nil {
+			return err
+		}
 	}
 
+	f.markLoaded(pkg)
+	f.updateCurrent(pkg.Name)
 	return nil
 }
 
 func (f *FlatBackend) LoadAll() error {
+	return f.loadAll(func(*Package) bool { return true })
+}
+
+// loadAll loads the packages for which keep returns true.  When
+// several versions of a package are installed, only the active one
+// is loaded or, if none was selected, the newest.  A package failing
+// to load doesn't prevent the others from being loaded: the failures
+// are reported together in a *MultiError.
+func (f *FlatBackend) loadAll(keep func(*Package) bool) error {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	f.loadedmu.Lock()
+	f.loadkeep = keep
+	f.loadedmu.Unlock()
+
+	pkgs, err := f.loadable(keep)
+	if err != nil {
+		return err
+	}
+
+	var merr MultiError
+	for _, pkg := range pkgs {
+		merr.add(pkg, f.reload(pkg))
+	}
+	return merr.err()
+}
+
+// loadable returns, for each package for which keep returns true,
+// the version to load: the active one or, if none was selected, the
+// newest.
+func (f *FlatBackend) loadable(keep func(*Package) bool) ([]*Package, error) {
+	actives, err := f.actives()
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		names    []string
+		selected = map[string]*Package{}
+	)
 	for pkg, err := range f.List("") {
 		if err != nil {
-			return err
+			return nil, err
 		}
-		if err := f.reload(pkg); err != nil {
-			return err
+		if !keep(pkg) {
+			continue
+		}
+
+		cur, ok := selected[pkg.Name]
+		switch {
+		case !ok:
+			names = append(names, pkg.Name)
+		case cur.Version == actives[pkg.Name]:
+			continue
+		case pkg.Version != actives[pkg.Name] &&
+			f.versions.Compare(pkg.Version, cur.Version) <= 0:
+			continue
 		}
+		selected[pkg.Name] = pkg
 	}
-	return nil
+
+	pkgs := make([]*Package, len(names))
+	for i, name := range names {
+		pkgs[i] = selected[name]
+	}
+	return pkgs, nil
 }
 
 func (f *FlatBackend) unload(pkgfile, extracted string) error {
-	err := os.Remove(pkgfile)
+	err := f.fsys.Remove(pkgfile)
 	if extracted != "" {
-		if err := os.RemoveAll(extracted); err != nil {
+		if err := f.removeExtracted(extracted); err != nil {
 			return err
 		}
 	}
 	return err
 }
 
+func (f *FlatBackend) Open(pkg *Package) (io.ReadCloser, error) {
+	fp, err := f.fsys.Open(f.ptarPath(pkg))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return fp, nil
+}
+
 func (f *FlatBackend) Unload(pkg *Package) error {
+	return f.unloadPackage(pkg, false)
+}
+
+// unloadPackage uninstalls the package, moving the stored package to
+// the archive area instead of removing it if asked to.
+func (f *FlatBackend) unloadPackage(pkg *Package, archive bool) error {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
 	var (
-		pkgfile   = filepath.Join(f.pkgdir, pkg.Filename())
-		extf      = strings.TrimSuffix(pkg.Filename(), ".ptar")
-		extracted = filepath.Join(f.cachedir, extf)
+		pkgfile   = f.ptarPath(pkg)
+		extracted = f.extractedPath(pkg)
 	)
 
-	if f.unloadhook != nil {
-		manifest, err := NewManifestFromFile(filepath.Join(extracted, "manifest.yaml"))
-		if err != nil {
+	// the hooks of an idle version never fired.
+	if !f.isIdle(pkg) {
+		if err := f.runUnloadHooks(pkg, extracted); err != nil {
 			return err
 		}
-		f.unloadhook(manifest, pkg)
 	}
 
-	return f.unload(pkgfile, extracted)
+	if archive {
+		if err := f.archive(pkg, pkgfile); err != nil {
+			return err
+		}
+		if err := f.removeExtracted(extracted); err != nil {
+			return err
+		}
+	} else if err := f.unload(pkgfile, extracted); err != nil {
+		return err
+	}
+	f.markUnloaded(pkg)
+	if err := f.setReceipt(pkg, nil); err != nil {
+		return err
+	}
+	f.updateCurrent(pkg.Name)
+
+	// drop the directories the naming scheme may have created,
+	// if they are now empty.
+	if dir := filepath.Dir(pkgfile); dir != f.pkgdir {
+		f.fsys.Remove(dir)
+	}
+	f.removeEmptyDirs(filepath.Dir(extracted), f.cachedir)
+	return nil
 }
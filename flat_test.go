@@ -1,11 +1,16 @@
 package pkg
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"sort"
+	"strings"
 	"testing"
 
 	"github.com/PlakarKorp/kloset/kcontext"
@@ -13,19 +18,24 @@ import (
 
 func newTestFlatBackend(t *testing.T, opts *FlatBackendOptions) (*FlatBackend, string, string) {
 	t.Helper()
-	if opts == nil {
-		opts = &FlatBackendOptions{}
-	}
 	root := t.TempDir()
 	pkgdir := filepath.Join(root, "pkgs")
 	cachedir := filepath.Join(root, "cache")
+	return newTestFlatBackendAt(t, pkgdir, cachedir, opts), pkgdir, cachedir
+}
+
+func newTestFlatBackendAt(t *testing.T, pkgdir, cachedir string, opts *FlatBackendOptions) *FlatBackend {
+	t.Helper()
+	if opts == nil {
+		opts = &FlatBackendOptions{}
+	}
 	kctx := kcontext.NewKContext()
 
 	be, err := NewFlatBackend(kctx, pkgdir, cachedir, opts)
 	if err != nil {
 		t.Fatalf("NewFlatBackend: %v", err)
 	}
-	return be, pkgdir, cachedir
+	return be
 }
 
 func TestNewFlatBackendCreatesDirs(t *testing.T) {
@@ -46,6 +56,12 @@ func touch(t *testing.T, dir, name string) {
 	}
 }
 
+// markExtracted writes the completion marker of the tree at dir.
+func markExtracted(t *testing.T, dir string) {
+	t.Helper()
+	touch(t, filepath.Dir(dir), filepath.Base(dir)+extractedMarker)
+}
+
 func TestFlatBackendList(t *testing.T) {
 	be, pkgdir, _ := newTestFlatBackend(t, nil)
 
@@ -153,7 +169,7 @@ connectors:
 		t.Fatal(err)
 	}
 
-	if _, err := be.loadmanifest(mpath); err == nil {
+	if _, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath); err == nil {
 		t.Fatal("loadmanifest accepted an executable path escaping the manifest dir")
 	}
 }
@@ -180,7 +196,7 @@ connectors:
 		t.Fatal(err)
 	}
 
-	m, err := be.loadmanifest(mpath)
+	m, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath)
 	if err != nil {
 		t.Fatalf("loadmanifest: %v", err)
 	}
@@ -209,7 +225,7 @@ connectors:
 		t.Fatal(err)
 	}
 
-	if _, err := be.loadmanifest(mpath); err == nil {
+	if _, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath); err == nil {
 		t.Fatal("loadmanifest accepted an unknown location flag")
 	}
 }
@@ -229,7 +245,7 @@ func TestFlatBackendUnloadRemovesFiles(t *testing.T) {
 	if err := os.WriteFile(ptarPath, []byte("data"), 0644); err != nil {
 		t.Fatal(err)
 	}
-	extracted := filepath.Join(cachedir, "s3_v1.0.0_"+runtime.GOOS+"_"+runtime.GOARCH)
+	extracted := cachePath(cachedir, pkg)
 	if err := os.MkdirAll(extracted, 0755); err != nil {
 		t.Fatal(err)
 	}
@@ -250,7 +266,7 @@ func TestFlatBackendUnloadHook(t *testing.T) {
 	var called bool
 	var gotPkg *Package
 	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
-		UnloadHook: func(m *Manifest, p *Package) {
+		UnloadHook: func(ctx context.Context, m *Manifest, p *Package) {
 			called = true
 			gotPkg = p
 		},
@@ -265,7 +281,7 @@ func TestFlatBackendUnloadHook(t *testing.T) {
 	if err := os.WriteFile(filepath.Join(pkgdir, pkg.Filename()), []byte("data"), 0644); err != nil {
 		t.Fatal(err)
 	}
-	extracted := filepath.Join(cachedir, "s3_v1.0.0_"+runtime.GOOS+"_"+runtime.GOARCH)
+	extracted := cachePath(cachedir, pkg)
 	if err := os.MkdirAll(extracted, 0755); err != nil {
 		t.Fatal(err)
 	}
@@ -286,7 +302,7 @@ func TestFlatBackendUnloadHook(t *testing.T) {
 
 func TestFlatBackendUnloadHookManifestMissing(t *testing.T) {
 	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{
-		UnloadHook: func(m *Manifest, p *Package) {},
+		UnloadHook: func(ctx context.Context, m *Manifest, p *Package) {},
 	})
 	pkg := &Package{
 		Name:            "s3",
@@ -316,3 +332,379 @@ func TestFlatBackendUnloadIdempotent(t *testing.T) {
 		t.Errorf("unload with missing extracted dir: %v", err)
 	}
 }
+
+func TestFlatBackendReloadUsesCompleteExtraction(t *testing.T) {
+	var loaded []string
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			loaded = append(loaded, m.Name)
+			return nil
+		},
+	})
+
+	pkg := pkgVer("s3", "v1.0.0")
+	// not a real ptar: reload must not try to extract it again.
+	touch(t, pkgdir, pkg.Filename())
+
+	extracted := cachePath(cachedir, pkg)
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	markExtracted(t, extracted)
+	if err := os.WriteFile(filepath.Join(extracted, "manifest.yaml"), []byte("name: s3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if !slices.Equal(loaded, []string{"s3"}) {
+		t.Errorf("loaded = %v", loaded)
+	}
+}
+
+// An extracted tree without the completion marker is the leftover of
+// an interrupted extraction and must not be trusted.
+func TestFlatBackendReloadDiscardsPartialExtraction(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			t.Error("partially extracted package was loaded")
+			return nil
+		},
+	})
+
+	pkg := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, pkg.Filename())
+
+	extracted := cachePath(cachedir, pkg)
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(extracted, "manifest.yaml"), []byte("name: s3\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// the bogus ptar can't be extracted again.
+	if err := be.reload(pkg); err == nil {
+		t.Fatal("reload succeeded with a partial extraction and a bogus ptar")
+	}
+	if _, err := os.Stat(filepath.Join(extracted, "manifest.yaml")); !os.IsNotExist(err) {
+		t.Errorf("partial extraction left behind: %v", err)
+	}
+}
+
+// A marker shipped inside the package is just another file.
+func TestFlatBackendIgnoresInnerMarker(t *testing.T) {
+	be, _, cachedir := newTestFlatBackend(t, nil)
+
+	extracted := cachePath(cachedir, pkgVer("s3", "v1.0.0"))
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, extracted, extractedMarker)
+	if be.isExtracted(extracted) {
+		t.Fatal("marker inside the tree taken as a complete extraction")
+	}
+
+	markExtracted(t, extracted)
+	if !be.isExtracted(extracted) {
+		t.Fatal("marker next to the tree not found")
+	}
+	if err := be.removeExtracted(extracted); err != nil {
+		t.Fatalf("removeExtracted: %v", err)
+	}
+	if _, err := os.Stat(markerPath(extracted)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("marker left behind: %v", err)
+	}
+}
+
+func TestFlatBackendPreUnloadHookVeto(t *testing.T) {
+	errInUse := errors.New("in use")
+	var unloaded bool
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		PreUnloadHook: func(ctx context.Context, m *Manifest, p *Package) error {
+			if m.Name == "s3" {
+				return errInUse
+			}
+			return nil
+		},
+		UnloadHook: func(ctx context.Context, m *Manifest, p *Package) {
+			unloaded = true
+		},
+	})
+
+	s3 := pkgVer("s3", "v1.0.0")
+	installExtracted(t, pkgdir, cachedir, s3)
+	if err := be.Unload(s3); !errors.Is(err, errInUse) {
+		t.Fatalf("Unload err = %v, want the veto", err)
+	}
+	if unloaded {
+		t.Error("UnloadHook called despite the veto")
+	}
+	if _, err := os.Stat(filepath.Join(pkgdir, s3.Filename())); err != nil {
+		t.Errorf("vetoed package was removed: %v", err)
+	}
+
+	sftp := pkgVer("sftp", "v1.0.0")
+	installExtracted(t, pkgdir, cachedir, sftp)
+	if err := be.Unload(sftp); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	if !unloaded {
+		t.Error("UnloadHook not called")
+	}
+}
+
+func TestFlatBackendLoadHookError(t *testing.T) {
+	errRegister := errors.New("cannot register")
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			if ctx == nil {
+				t.Error("LoadHook called without a context")
+			}
+			if m.Name == "s3" {
+				return errRegister
+			}
+			return nil
+		},
+	})
+
+	s3 := pkgVer("s3", "v1.0.0")
+	installExtracted(t, pkgdir, cachedir, s3)
+	if err := be.LoadAll(); !errors.Is(err, errRegister) {
+		t.Fatalf("LoadAll err = %v, want the hook error", err)
+	}
+
+	// activation failed, but the installed package is left alone.
+	if _, err := os.Stat(filepath.Join(pkgdir, s3.Filename())); err != nil {
+		t.Errorf("package removed after a failed activation: %v", err)
+	}
+}
+
+func TestFlatBackendLoadAllKeepsGoing(t *testing.T) {
+	var loaded []string
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			if m.Name == "s3" {
+				return errors.New("cannot register")
+			}
+			loaded = append(loaded, m.Name)
+			return nil
+		},
+	})
+
+	for _, name := range []string{"fs", "s3", "sftp"} {
+		installExtracted(t, pkgdir, cachedir, pkgVer(name, "v1.0.0"))
+	}
+
+	err := be.LoadAll()
+	var merr *MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("LoadAll err = %v, want a *MultiError", err)
+	}
+	if len(merr.Errors) != 1 || merr.Errors[0].Package.Name != "s3" {
+		t.Errorf("MultiError = %v, want only s3", merr)
+	}
+
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, []string{"fs", "sftp"}) {
+		t.Errorf("loaded = %v, want [fs sftp]", loaded)
+	}
+}
+
+func TestFlatBackendOpen(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, nil)
+
+	pkg := pkgVer("s3", "v1.0.0")
+	if _, err := be.Open(pkg); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("Open of a missing package err = %v, want ErrNotInstalled", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(pkgdir, pkg.Filename()), []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rd, err := be.Open(pkg)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer rd.Close()
+
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "PTARDATA" {
+		t.Errorf("Open returned %q", data)
+	}
+}
+
+// fakeExtractor writes a minimal manifest instead of reading the ptar.
+type fakeExtractor struct {
+	extracted []string
+}
+
+func (e *fakeExtractor) Extract(ptar, dir string) error {
+	e.extracted = append(e.extracted, filepath.Base(ptar))
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte("name: s3\n"), 0644)
+}
+
+func TestFlatBackendExtractor(t *testing.T) {
+	ext := &fakeExtractor{}
+	var loaded []string
+	var warnings []WarningCode
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		Extractor: ext,
+		EventHook: func(ev *Event) {
+			warnings = append(warnings, ev.Code)
+		},
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			loaded = append(loaded, m.Name)
+			return nil
+		},
+	})
+
+	pkg := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, pkg.Filename())
+
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if !slices.Equal(ext.extracted, []string{pkg.Filename()}) {
+		t.Errorf("extracted = %v", ext.extracted)
+	}
+	if !slices.Equal(loaded, []string{"s3"}) {
+		t.Errorf("loaded = %v", loaded)
+	}
+	extracted := cachePath(cachedir, pkg)
+	if !be.isExtracted(extracted) {
+		t.Error("extraction not marked as complete")
+	}
+	if !slices.Equal(warnings, []WarningCode{WarnReextracted}) {
+		t.Errorf("warnings = %v, want [%s]", warnings, WarnReextracted)
+	}
+}
+
+func TestFlatBackendExtractConcurrency(t *testing.T) {
+	for _, tt := range []struct {
+		opt  int
+		want int
+	}{
+		{0, runtime.NumCPU()},
+		{-1, runtime.NumCPU()},
+		{4, 4},
+	} {
+		be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{ExtractConcurrency: tt.opt})
+		ext, ok := be.extractor.(*ptarExtractor)
+		if !ok {
+			t.Fatalf("extractor = %T, want *ptarExtractor", be.extractor)
+		}
+		if ext.concurrency != tt.want {
+			t.Errorf("ExtractConcurrency %d: concurrency = %d, want %d", tt.opt, ext.concurrency, tt.want)
+		}
+	}
+}
+
+// cachePath returns where the package is extracted in the cache
+// directory.
+func cachePath(cachedir string, pkg *Package) string {
+	return filepath.Join(cachedir, escapeName(pkg.Name), pkg.Version, pkg.OperatingSystem+"_"+pkg.Architecture)
+}
+
+func TestFlatBackendMigratesCache(t *testing.T) {
+	root := t.TempDir()
+	pkgdir := filepath.Join(root, "pkgs")
+	cachedir := filepath.Join(root, "cache")
+
+	s3 := pkgVer("s3", "v1.0.0")
+	sftp := pkgVer("sftp", "v1.0.0")
+	if err := os.MkdirAll(pkgdir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, pkg := range []*Package{s3, sftp} {
+		touch(t, pkgdir, pkg.Filename())
+		legacy := filepath.Join(cachedir, strings.TrimSuffix(pkg.Filename(), ".ptar"))
+		if err := os.MkdirAll(legacy, 0755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, legacy, extractedMarker)
+	}
+	// sftp was already extracted again in the current layout.
+	if err := os.MkdirAll(cachePath(cachedir, sftp), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	be := newTestFlatBackendAt(t, pkgdir, cachedir, nil)
+	for _, pkg := range []*Package{s3, sftp} {
+		legacy := filepath.Join(cachedir, strings.TrimSuffix(pkg.Filename(), ".ptar"))
+		if _, err := os.Stat(legacy); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("%s: legacy tree left behind: %v", pkg.Name, err)
+		}
+	}
+	if !be.isExtracted(cachePath(cachedir, s3)) {
+		t.Error("s3 extraction not moved to the current layout")
+	}
+	if _, err := os.Stat(filepath.Join(cachePath(cachedir, s3), extractedMarker)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("legacy marker left in the tree: %v", err)
+	}
+
+	if err := be.Unload(s3); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(cachedir, "s3")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("empty directories of s3 left behind: %v", err)
+	}
+}
+
+// fatExtractor extracts a package with libraries for several
+// platforms.
+type fatExtractor struct{}
+
+func (fatExtractor) Extract(ptar, dir string) error {
+	if err := os.MkdirAll(filepath.Join(dir, "lib"), 0755); err != nil {
+		return err
+	}
+	manifest := fmt.Sprintf(`name: s3
+connectors:
+  - type: storage
+    executable: s3-storage
+    platforms:
+      %s/%s:
+        extra_files: [lib/host.so]
+      plan9/mips:
+        extra_files: [lib/foreign.so, ../outside]
+`, runtime.GOOS, runtime.GOARCH)
+	if err := os.WriteFile(filepath.Join(dir, "manifest.yaml"), []byte(manifest), 0644); err != nil {
+		return err
+	}
+	for _, name := range []string{"s3-storage", "lib/host.so", "lib/foreign.so"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFlatBackendPrunesForeignFiles(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Extractor: fatExtractor{}})
+
+	pkg := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, pkg.Filename())
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	extracted := cachePath(cachedir, pkg)
+	for name, want := range map[string]bool{
+		"s3-storage":     true,
+		"lib/host.so":    true,
+		"lib/foreign.so": false,
+	} {
+		_, err := os.Stat(filepath.Join(extracted, name))
+		if got := err == nil; got != want {
+			t.Errorf("%s present = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+// isGlob returns whether the extra file entry is a pattern rather
+// than a file name.
+func isGlob(pattern string) bool {
+	return strings.ContainsAny(pattern, `*?[\`)
+}
+
+// checkGlob checks that the extra file entry is a valid pattern, or
+// file name, that can't match anything outside the package tree.
+// Patterns are slash-separated, "**" matching any number of
+// directories and the other elements as in path.Match.
+func checkGlob(pattern string) error {
+	if !filepath.IsLocal(filepath.FromSlash(pattern)) {
+		return fmt.Errorf("bad extra file %q", pattern)
+	}
+	for elem := range strings.SplitSeq(pattern, "/") {
+		if elem == "**" {
+			continue
+		}
+		if _, err := path.Match(elem, ""); err != nil {
+			return fmt.Errorf("bad extra file %q: %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// matchGlob returns whether the slash-separated name matches the
+// pattern.
+func matchGlob(pattern, name string) bool {
+	pelems := strings.Split(pattern, "/")
+	nelems := strings.Split(name, "/")
+
+	var match func(p, n []string) bool
+	match = func(p, n []string) bool {
+		for len(p) > 0 {
+			if p[0] == "**" {
+				for i := 0; i <= len(n); i++ {
+					if match(p[1:], n[i:]) {
+						return true
+					}
+				}
+				return false
+			}
+			if len(n) == 0 {
+				return false
+			}
+			if ok, _ := path.Match(p[0], n[0]); !ok {
+				return false
+			}
+			p, n = p[1:], n[1:]
+		}
+		return len(n) == 0
+	}
+	return match(pelems, nelems)
+}
+
+// expandGlob returns the files of the tree at dir matching the extra
+// file entry, as slash-separated paths relative to dir.  Entries that
+// are not patterns are returned as is, whether the file exists or not.
+func expandGlob(fsys FS, dir, pattern string) ([]string, error) {
+	if err := checkGlob(pattern); err != nil {
+		return nil, err
+	}
+	if !isGlob(pattern) {
+		return []string{pattern}, nil
+	}
+
+	var ret []string
+	err := walkDir(fsys, dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if rel == fileIndexFile {
+			return nil
+		}
+		if matchGlob(pattern, rel) {
+			ret = append(ret, rel)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	slices.Sort(ret)
+	return ret, nil
+}
+
+// expandGlobs is expandGlob for several entries, without duplicates.
+func expandGlobs(fsys FS, dir string, patterns []string) ([]string, error) {
+	var ret []string
+	for _, pattern := range patterns {
+		files, err := expandGlob(fsys, dir, pattern)
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			if !slices.Contains(ret, file) {
+				ret = append(ret, file)
+			}
+		}
+	}
+	return ret, nil
+}
+
+// checkNamePattern checks that the package name pattern, as in
+// path.Match, is valid.
+func checkNamePattern(pattern string) error {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return fmt.Errorf("%w %q: %w", ErrBadPackageName, pattern, err)
+	}
+	return nil
+}
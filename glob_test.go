@@ -0,0 +1,114 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"lib/*.so", "lib/libs3.so", true},
+		{"lib/*.so", "lib/sub/libs3.so", false},
+		{"lib/*.so", "libs3.so", false},
+		{"assets/**", "assets/icon.png", true},
+		{"assets/**", "assets/img/icon.png", true},
+		{"assets/**", "other/icon.png", false},
+		{"**/*.png", "icon.png", true},
+		{"**/*.png", "assets/img/icon.png", true},
+		{"a/**/b", "a/b", true},
+		{"a/**/b", "a/x/y/b", true},
+		{"a/**/b", "a/x/y/c", false},
+		{"icon.png", "icon.png", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.name); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCheckGlob(t *testing.T) {
+	for _, pattern := range []string{"icon.png", "lib/*.so", "assets/**", "**/[a-z]*"} {
+		if err := checkGlob(pattern); err != nil {
+			t.Errorf("checkGlob(%q) = %v", pattern, err)
+		}
+	}
+	for _, pattern := range []string{"../lib/*.so", "/etc/*", "lib/../../x", "lib/[", ""} {
+		if err := checkGlob(pattern); err == nil {
+			t.Errorf("checkGlob(%q) succeeded", pattern)
+		}
+	}
+}
+
+func TestExpandGlob(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"lib/a.so", "lib/b.so", "lib/sub/c.so", "assets/x/icon.png"} {
+		if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, dir, name)
+	}
+
+	files, err := expandGlobs(OSFS{}, dir, []string{"lib/*.so", "**/*.so", "missing.txt", "**"})
+	if err != nil {
+		t.Fatalf("expandGlobs: %v", err)
+	}
+	want := []string{"lib/a.so", "lib/b.so", "lib/sub/c.so", "missing.txt", "assets/x/icon.png"}
+	if !slices.Equal(files, want) {
+		t.Errorf("expandGlobs = %v, want %v", files, want)
+	}
+
+	if _, err := expandGlob(OSFS{}, dir, "../*"); err == nil {
+		t.Error("expandGlob accepted a pattern escaping the tree")
+	}
+}
+
+func TestLoadManifestExpandsExtraFiles(t *testing.T) {
+	be, _, cachedir := newTestFlatBackend(t, nil)
+
+	mdir := filepath.Join(cachedir, "good")
+	if err := os.MkdirAll(filepath.Join(mdir, "lib"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, mdir, "lib/a.so")
+	touch(t, mdir, "lib/b.so")
+	const manifest = `
+name: good
+connectors:
+  - type: storage
+    executable: s3-storage
+    extra_files: [icon.png, "lib/*.so"]
+`
+	mpath := filepath.Join(mdir, "manifest.yaml")
+	if err := os.WriteFile(mpath, []byte(manifest), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	m, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath)
+	if err != nil {
+		t.Fatalf("loadmanifest: %v", err)
+	}
+	want := []string{"icon.png", "lib/a.so", "lib/b.so"}
+	if got := m.Connectors[0].ExtraFiles; !slices.Equal(got, want) {
+		t.Errorf("ExtraFiles = %v, want %v", got, want)
+	}
+
+	const evil = `
+name: evil
+connectors:
+  - type: storage
+    executable: s3-storage
+    extra_files: ["../*"]
+`
+	if err := os.WriteFile(mpath, []byte(evil), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := be.loadmanifest(pkgVer("s3", "v1.0.0"), mpath); err == nil {
+		t.Error("loadmanifest accepted extra files escaping the manifest dir")
+	}
+}
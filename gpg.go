@@ -0,0 +1,191 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+var ErrUnsigned = errors.New("package not signed")
+
+// SignaturePolicy tells how the detached OpenPGP signatures published
+// next to the local packages and override artifacts are enforced.
+type SignaturePolicy int
+
+const (
+	// SignatureIgnore doesn't look for signatures.
+	SignatureIgnore SignaturePolicy = iota
+
+	// SignatureIfPresent verifies the signature when there is
+	// one, and accepts unsigned packages.
+	SignatureIfPresent
+
+	// SignatureRequired refuses the packages without a valid
+	// signature.
+	SignatureRequired
+)
+
+// Suffixes of the detached signatures, ASCII-armored or not, looked
+// for next to the packages.
+var gpgSuffixes = []string{".asc", ".sig"}
+
+// gpgvProgram verifies the signatures against the keyring.
+const gpgvProgram = "gpgv"
+
+// gpgSignature returns the detached signature of the named package,
+// using read to get the content of the file with the given suffix,
+// or nil if it doesn't exist.  It returns nil too if the policy
+// doesn't call for a verification.
+func (p *Manager) gpgSignature(name string, read func(suffix string) ([]byte, error)) ([]byte, error) {
+	if p.sigpolicy == SignatureIgnore {
+		return nil, nil
+	}
+
+	for _, suffix := range gpgSuffixes {
+		sig, err := read(suffix)
+		if err != nil || sig != nil {
+			return sig, err
+		}
+	}
+
+	if p.sigpolicy == SignatureRequired {
+		return nil, fmt.Errorf("%s: %w", name, ErrUnsigned)
+	}
+	return nil, nil
+}
+
+// verifyGPG wraps rc so that it's checked against the signature, if
+// not nil.
+func (p *Manager) verifyGPG(rc io.ReadCloser, sig []byte) (io.ReadCloser, error) {
+	if sig == nil {
+		return rc, nil
+	}
+
+	rd, err := newGPGReader(rc, p.gpgkeyring, sig)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return rd, nil
+}
+
+// gpgReader feeds what is read through it to gpgv(1) and, once the
+// underlying reader is exhausted, fails with ErrBadSignature instead
+// of io.EOF if the signature doesn't verify, like [verifyingReader].
+type gpgReader struct {
+	rc      io.ReadCloser
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stderr  bytes.Buffer
+	sigfile string
+	done    bool
+	err     error
+}
+
+func newGPGReader(rc io.ReadCloser, keyring string, sig []byte) (*gpgReader, error) {
+	fp, err := os.CreateTemp("", "pkg-sig-")
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fp.Write(sig); err != nil {
+		fp.Close()
+		os.Remove(fp.Name())
+		return nil, err
+	}
+	if err := fp.Close(); err != nil {
+		os.Remove(fp.Name())
+		return nil, err
+	}
+
+	g := &gpgReader{rc: rc, sigfile: fp.Name()}
+	g.cmd = exec.Command(gpgvProgram, "--keyring", keyring, g.sigfile, "-")
+	g.cmd.Stderr = &g.stderr
+	if g.stdin, err = g.cmd.StdinPipe(); err == nil {
+		err = g.cmd.Start()
+	}
+	if err != nil {
+		os.Remove(g.sigfile)
+		return nil, err
+	}
+	return g, nil
+}
+
+func (g *gpgReader) Read(p []byte) (int, error) {
+	if g.done {
+		return 0, g.err
+	}
+
+	n, err := g.rc.Read(p)
+	if n > 0 {
+		if _, werr := g.stdin.Write(p[:n]); werr != nil {
+			// gpgv exited early, most likely because of a
+			// malformed signature.
+			if ferr := g.finish(); ferr != nil {
+				return n, ferr
+			}
+			g.err = fmt.Errorf("%w: %v", ErrBadSignature, werr)
+			return n, g.err
+		}
+	}
+	if err == io.EOF {
+		if ferr := g.finish(); ferr != nil {
+			return n, ferr
+		}
+	}
+	return n, err
+}
+
+// finish waits for gpgv(1) to report whether the signature is valid.
+func (g *gpgReader) finish() error {
+	if g.done {
+		return g.err
+	}
+	g.done = true
+
+	g.stdin.Close()
+	if err := g.cmd.Wait(); err != nil {
+		g.err = fmt.Errorf("%w: %s", ErrBadSignature, strings.TrimSpace(g.stderr.String()))
+	} else {
+		g.err = io.EOF
+	}
+	os.Remove(g.sigfile)
+
+	if g.err == io.EOF {
+		return nil
+	}
+	return g.err
+}
+
+func (g *gpgReader) Close() error {
+	if !g.done {
+		g.done = true
+		g.err = io.ErrClosedPipe
+		g.stdin.Close()
+		g.cmd.Process.Kill()
+		g.cmd.Wait()
+		os.Remove(g.sigfile)
+	}
+	return g.rc.Close()
+}
@@ -0,0 +1,113 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+// gpgSigner is a throwaway gpg(1) home with a signing key, exported
+// to a keyring usable with gpgv(1).
+type gpgSigner struct {
+	home    string
+	keyring string
+}
+
+func newGPGSigner(t *testing.T) *gpgSigner {
+	t.Helper()
+	for _, prog := range []string{"gpg", gpgvProgram} {
+		if _, err := exec.LookPath(prog); err != nil {
+			t.Skipf("%s not available", prog)
+		}
+	}
+
+	// not t.TempDir(): the agent socket path must be short.
+	home, err := os.MkdirTemp("", "gpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(home) })
+	os.Chmod(home, 0700)
+
+	s := &gpgSigner{home: home, keyring: filepath.Join(home, "keyring.gpg")}
+	s.gpg(t, "--passphrase", "", "--quick-gen-key", "test@example.com", "ed25519", "sign", "never")
+	s.gpg(t, "--output", s.keyring, "--export")
+	t.Cleanup(func() {
+		cmd := exec.Command("gpgconf", "--kill", "gpg-agent")
+		cmd.Env = append(os.Environ(), "GNUPGHOME="+home)
+		cmd.Run()
+	})
+	return s
+}
+
+func (s *gpgSigner) gpg(t *testing.T, args ...string) {
+	t.Helper()
+	cmd := exec.Command("gpg", append([]string{"--batch", "--pinentry-mode", "loopback"}, args...)...)
+	cmd.Env = append(os.Environ(), "GNUPGHOME="+s.home)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("gpg %v: %v\n%s", args, err, out)
+	}
+}
+
+func (s *gpgSigner) sign(t *testing.T, file, sig string) {
+	t.Helper()
+	s.gpg(t, "--yes", "--armor", "--output", sig, "--detach-sign", file)
+}
+
+func TestAddVerifiesGPGSignature(t *testing.T) {
+	signer := newGPGSigner(t)
+
+	dir := t.TempDir()
+	ptar := filepath.Join(dir, "s3_v1.0.0_"+runtime.GOOS+"_"+runtime.GOARCH+".ptar")
+	if err := os.WriteFile(ptar, []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	newManager := func(policy SignaturePolicy) (*Manager, *fakeBackend) {
+		be := newFakeBackend()
+		m, err := New(be, &Options{SignaturePolicy: policy, GPGKeyring: signer.keyring})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		return m, be
+	}
+
+	m, _ := newManager(SignatureRequired)
+	if err := m.Add(ptar, nil); !errors.Is(err, ErrUnsigned) {
+		t.Errorf("Add of an unsigned package err = %v, want ErrUnsigned", err)
+	}
+	m, _ = newManager(SignatureIfPresent)
+	if err := m.Add(ptar, nil); err != nil {
+		t.Errorf("Add of an unsigned package when optional: %v", err)
+	}
+
+	signer.sign(t, ptar, ptar+".asc")
+	m, be := newManager(SignatureRequired)
+	if err := m.Add(ptar, nil); err != nil {
+		t.Fatalf("Add of a signed package: %v", err)
+	}
+	if string(be.loadData[filepath.Base(ptar)]) != "PTARDATA" {
+		t.Errorf("loaded data = %q", be.loadData[filepath.Base(ptar)])
+	}
+
+	if err := os.WriteFile(ptar, []byte("TAMPERED"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	m, _ = newManager(SignatureRequired)
+	if err := m.Add(ptar, nil); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("Add of a tampered package err = %v, want ErrBadSignature", err)
+	}
+
+	// the signature is ignored by default.
+	m, _ = New(newFakeBackend(), nil)
+	if err := m.Add(ptar, nil); err != nil {
+		t.Errorf("Add without signature policy: %v", err)
+	}
+
+	if _, err := New(newFakeBackend(), &Options{SignaturePolicy: SignatureRequired}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("New without keyring err = %v, want ErrInvalidOptions", err)
+	}
+}
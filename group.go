@@ -0,0 +1,160 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// IsGroup returns whether the manifest describes a group, a package
+// providing no connector and only pulling other packages, like a
+// "cloud-bundle" installing s3, gcs and azure.
+func (m *Manifest) IsGroup() bool {
+	return len(m.Connectors) == 0 && len(m.Dependencies) != 0
+}
+
+// manifest reads the manifest of an installed package.  It returns
+// nil if the backend can't read it.
+func (p *Manager) manifest(pkg *Package) (*Manifest, error) {
+	data, err := p.manifestData(pkg)
+	if err != nil || data == nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := m.Parse(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
+
+// manifestData returns the raw manifest of an installed package, or
+// nil if the backend can't read it.
+func (p *Manager) manifestData(pkg *Package) ([]byte, error) {
+	fr, ok := p.store.(FileReader)
+	if !ok {
+		return nil, nil
+	}
+	return fr.ReadFile(pkg, "manifest.yaml")
+}
+
+// postadd completes the installation of a package by resolving its
+// relations, pulling its dependencies and offering its
+// recommendations.  The package is installed by then, so the failures
+// are only reported as warnings, except for a conflict, which removes
+// it again.
+func (p *Manager) postadd(ctx context.Context, name, version string) error {
+	pkg, err := p.lookup(name, version)
+	if err != nil {
+		p.emitContext(ctx, newWarning(&Package{Name: name, Version: version},
+			WarnPostInstall, "%v", err))
+		return nil
+	}
+
+	data, err := p.manifestData(pkg)
+	if err != nil {
+		p.emitContext(ctx, newWarning(pkg, WarnPostInstall, "manifest: %v", err))
+		return nil
+	}
+	if data == nil {
+		return nil
+	}
+
+	for _, field := range unknownFields(data) {
+		p.emitContext(ctx, newWarning(pkg, WarnUnknownField, "manifest: %s", field))
+	}
+
+	var m Manifest
+	if err := m.Parse(bytes.NewReader(data)); err != nil {
+		p.emitContext(ctx, newWarning(pkg, WarnPostInstall, "manifest: %v", err))
+		return nil
+	}
+
+	if err := p.resolveRelations(pkg, &m); err != nil {
+		var conflict *ConflictError
+		if errors.As(err, &conflict) {
+			return err
+		}
+		p.emitContext(ctx, newWarning(pkg, WarnPostInstall, "%v", err))
+		return nil
+	}
+	if err := p.addDependencies(name, &m); err != nil {
+		p.emitContext(ctx, newWarning(pkg, WarnPostInstall, "%v", err))
+		return nil
+	}
+	if err := p.recommend(name, &m); err != nil {
+		p.emitContext(ctx, newWarning(pkg, WarnPostInstall, "%v", err))
+	}
+	return nil
+}
+
+// addDependencies installs the dependencies of the given package
+// that are missing, or whose installed version doesn't satisfy the
+// constraint.
+func (p *Manager) addDependencies(name string, m *Manifest) error {
+	for _, dep := range m.Dependencies {
+		// keep what's installed if it's good enough.
+		dep.Pinned = true
+
+		a := p.planSpec(&dep, p.installedVersions(dep.Name))
+		if a.Err == nil && a.Action != SyncKeep {
+			a.Err = p.apply(&a)
+		}
+		if a.Err != nil {
+			return fmt.Errorf("%s: dependency %s: %w", name, dep.Name, a.Err)
+		}
+	}
+	return nil
+}
+
+// delMembers removes the members of deleted groups, unless another
+// installed group still needs them.
+func (p *Manager) delMembers(members []string) error {
+	if len(members) == 0 {
+		return nil
+	}
+
+	var needed []string
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return err
+		}
+		m, err := p.manifest(pkg)
+		if err != nil || m == nil || !m.IsGroup() {
+			continue
+		}
+		for _, dep := range m.Dependencies {
+			needed = append(needed, dep.Name)
+		}
+	}
+
+	for _, name := range members {
+		if slices.Contains(needed, name) {
+			continue
+		}
+		if err := p.Del(name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,161 @@
+package pkg
+
+import (
+	"context"
+	"slices"
+	"testing"
+)
+
+// manifestBackend is a fakeBackend whose packages come with the
+// given manifests, keyed by package name.
+type manifestBackend struct {
+	*fakeBackend
+	manifests map[string]string
+}
+
+func newManifestBackend(manifests map[string]string, pkgs ...*Package) *manifestBackend {
+	return &manifestBackend{fakeBackend: newFakeBackend(pkgs...), manifests: manifests}
+}
+
+func (b *manifestBackend) ReadFile(pkg *Package, name string) ([]byte, error) {
+	if name != "manifest.yaml" {
+		return nil, ErrBadFilePath
+	}
+	m, ok := b.manifests[pkg.Name]
+	if !ok {
+		m = "name: " + pkg.Name + "\n"
+	}
+	return []byte(m), nil
+}
+
+func (b *manifestBackend) installed() []string {
+	var names []string
+	for _, p := range b.pkgs {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+const cloudBundle = `name: cloud-bundle
+dependencies:
+  - name: s3
+  - name: gcs
+    version: v1
+`
+
+func TestManifestIsGroup(t *testing.T) {
+	tests := []struct {
+		m    Manifest
+		want bool
+	}{
+		{Manifest{Dependencies: []Spec{{Name: "s3"}}}, true},
+		{Manifest{}, false},
+		{Manifest{
+			Connectors:   []ManifestConnector{{Executable: "s3"}},
+			Dependencies: []Spec{{Name: "notify"}},
+		}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.m.IsGroup(); got != tt.want {
+			t.Errorf("%+v.IsGroup() = %v, want %v", tt.m, got, tt.want)
+		}
+	}
+}
+
+func TestAddGroup(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{
+		"cloud-bundle": "v1.0.0",
+		"s3":           "v1.2.0",
+		"gcs":          "v1.1.0",
+	})
+
+	be := newManifestBackend(map[string]string{"cloud-bundle": cloudBundle})
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if err := m.Add("cloud-bundle", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"cloud-bundle", "gcs", "s3"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestAddGroupKeepsInstalledMembers(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{
+		"cloud-bundle": "v1.0.0",
+		"s3":           "v1.2.0",
+		"gcs":          "v1.1.0",
+	})
+
+	be := newManifestBackend(map[string]string{"cloud-bundle": cloudBundle},
+		pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if err := m.Add("cloud-bundle", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	for _, p := range be.pkgs {
+		if p.Name == "s3" && p.Version != "v1.0.0" {
+			t.Errorf("installed s3 was changed to %s", p.Version)
+		}
+	}
+}
+
+func TestDelGroup(t *testing.T) {
+	other := `name: other-bundle
+dependencies:
+  - name: gcs
+`
+	be := newManifestBackend(map[string]string{
+		"cloud-bundle": cloudBundle,
+		"other-bundle": other,
+	}, pkgVer("cloud-bundle", "v1.0.0"), pkgVer("other-bundle", "v1.0.0"),
+		pkgVer("s3", "v1.0.0"), pkgVer("gcs", "v1.0.0"), pkgVer("fs", "v1.0.0"))
+	m, _ := New(be, nil)
+
+	if err := m.Del("cloud-bundle", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	// gcs is still needed by other-bundle.
+	if got := be.installed(); !slices.Equal(got, []string{"fs", "gcs", "other-bundle"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestDelGroupKeepMembers(t *testing.T) {
+	be := newManifestBackend(map[string]string{"cloud-bundle": cloudBundle},
+		pkgVer("cloud-bundle", "v1.0.0"), pkgVer("s3", "v1.0.0"), pkgVer("gcs", "v1.0.0"))
+	m, _ := New(be, nil)
+
+	if err := m.Del("cloud-bundle", &DelOptions{KeepMembers: true}); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"gcs", "s3"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestPostaddWarnsOnceInstalled(t *testing.T) {
+	pkg := pkgVer("s3", "v1.0.0")
+	be := &readingBackend{
+		fakeBackend: newFakeBackend(pkg),
+		files: map[string]string{
+			pkg.Filename() + ":manifest.yaml": "name: [s3",
+		},
+	}
+
+	var warnings []WarningCode
+	m, _ := New(be, &Options{EventHook: func(ev *Event) {
+		if ev.Type == EventWarning {
+			warnings = append(warnings, ev.Code)
+		}
+	}})
+
+	if err := m.postadd(context.Background(), "s3", "v1.0.0"); err != nil {
+		t.Fatalf("postadd: %v", err)
+	}
+	if !slices.Equal(warnings, []WarningCode{WarnPostInstall}) {
+		t.Errorf("warnings = %v, want [%s]", warnings, WarnPostInstall)
+	}
+}
@@ -0,0 +1,226 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/PlakarKorp/pkg/pkgpb"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// The gRPC counterpart of [NewHTTPHandler], for the plakar agent and
+// the orchestration systems.  The service is defined in
+// proto/manager.proto.
+
+type grpcServer struct {
+	pkgpb.UnimplementedManagerServer
+	m *Manager
+}
+
+// NewGRPCServer returns the implementation of the plugin management
+// service for the given manager, to register with
+// pkgpb.RegisterManagerServer.  It doesn't authenticate the requests.
+func NewGRPCServer(m *Manager) pkgpb.ManagerServer {
+	return &grpcServer{m: m}
+}
+
+func grpcCode(err error) codes.Code {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNotInstalled):
+		return codes.NotFound
+	case errors.Is(err, ErrAlreadyInstalled):
+		return codes.AlreadyExists
+	case errors.Is(err, ErrConflict):
+		return codes.FailedPrecondition
+	case errors.Is(err, ErrBadPackageName), errors.Is(err, ErrInvalidOptions):
+		return codes.InvalidArgument
+	case errors.Is(err, ErrUnauthorized):
+		return codes.Unavailable
+	case errors.Is(err, errors.ErrUnsupported):
+		return codes.Unimplemented
+	case errors.Is(err, context.Canceled):
+		return codes.Canceled
+	}
+	return codes.Internal
+}
+
+func grpcError(err error) error {
+	return status.Error(grpcCode(err), err.Error())
+}
+
+func packagePB(pkg *Package) *pkgpb.Package {
+	return &pkgpb.Package{
+		Name:            pkg.Name,
+		Version:         pkg.Version,
+		OperatingSystem: pkg.OperatingSystem,
+		Architecture:    pkg.Architecture,
+	}
+}
+
+func progressPB(ev *Event) *pkgpb.Progress {
+	return &pkgpb.Progress{
+		Package: packagePB(&ev.Package),
+		Phase:   ev.Phase,
+		Elapsed: durationpb.New(ev.Elapsed),
+		Bytes:   ev.Bytes,
+		Total:   ev.Total,
+		Rate:    ev.Rate,
+		Eta:     durationpb.New(ev.ETA),
+	}
+}
+
+func (s *grpcServer) Install(req *pkgpb.InstallRequest, stream pkgpb.Manager_InstallServer) error {
+	return s.add(stream, req.GetTarget(), &AddOptions{
+		Version:               req.GetVersion(),
+		Checksum:              req.GetChecksum(),
+		Replace:               req.GetReplace(),
+		AllowMultipleVersions: req.GetAllowMultipleVersions(),
+		AllowOSArchMismatch:   req.GetAllowOsArchMismatch(),
+		ImplicitFetch:         true,
+	})
+}
+
+func (s *grpcServer) Upgrade(req *pkgpb.UpgradeRequest, stream pkgpb.Manager_UpgradeServer) error {
+	name := NormalizeName(req.GetName())
+	if err := validName(name); err != nil {
+		return grpcError(err)
+	}
+	return s.add(stream, name, &AddOptions{
+		Version:       req.GetVersion(),
+		Upgrade:       true,
+		ImplicitFetch: true,
+	})
+}
+
+// add installs the target, streaming the progress of the operation,
+// which is cancelled along with the call.
+func (s *grpcServer) add(stream pkgpb.Manager_InstallServer, target string, opts *AddOptions) error {
+	if target == "" {
+		return grpcError(ErrBadPackageName)
+	}
+
+	// the events may come from the goroutines of a parallel
+	// download.
+	var mu sync.Mutex
+	name := jobName(target)
+	unsubscribe := s.m.subscribe(func(ev *Event) {
+		if ev.Package.Name != name ||
+			ev.Type != EventProgress && ev.Type != EventPhaseDone {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		stream.Send(progressPB(ev))
+	})
+	err := s.m.addContext(stream.Context(), target, opts)
+	unsubscribe()
+
+	last := &pkgpb.Progress{Done: true}
+	if err != nil {
+		last.Error = err.Error()
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return stream.Send(last)
+}
+
+func (s *grpcServer) Remove(ctx context.Context, req *pkgpb.RemoveRequest) (*pkgpb.RemoveResponse, error) {
+	err := s.m.Del(NormalizeName(req.GetName()), &DelOptions{
+		Version:     req.GetVersion(),
+		All:         req.GetAll(),
+		KeepMembers: req.GetKeepMembers(),
+	})
+
+	var merr *MultiError
+	if errors.As(err, &merr) {
+		resp := &pkgpb.RemoveResponse{}
+		for _, e := range merr.Errors {
+			resp.Errors = append(resp.Errors, &pkgpb.PackageError{
+				Package: packagePB(&e.Package),
+				Error:   e.Err.Error(),
+			})
+		}
+		return resp, nil
+	}
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	return &pkgpb.RemoveResponse{}, nil
+}
+
+func (s *grpcServer) List(ctx context.Context, req *pkgpb.ListRequest) (*pkgpb.ListResponse, error) {
+	name := NormalizeName(req.GetName())
+
+	resp := &pkgpb.ListResponse{}
+	for pkg, err := range s.m.List() {
+		if err != nil {
+			return nil, grpcError(err)
+		}
+		if name != "" && pkg.Name != name {
+			continue
+		}
+		resp.Packages = append(resp.Packages, packagePB(pkg))
+	}
+	return resp, nil
+}
+
+func (s *grpcServer) Info(ctx context.Context, req *pkgpb.InfoRequest) (*pkgpb.InfoResponse, error) {
+	pkg, err := s.m.lookup(NormalizeName(req.GetName()), req.GetVersion())
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	manifest, err := s.m.ReadFile(pkg.Name, pkg.Version, "manifest.yaml")
+	if err != nil {
+		return nil, grpcError(err)
+	}
+	files, err := s.m.Files(pkg.Name, pkg.Version)
+	if err != nil {
+		return nil, grpcError(err)
+	}
+
+	resp := &pkgpb.InfoResponse{
+		Package:  packagePB(pkg),
+		Manifest: manifest,
+	}
+	for _, fe := range files {
+		resp.Files = append(resp.Files, &pkgpb.FileEntry{
+			Path: fe.Path,
+			Size: fe.Size,
+			Mode: uint32(fe.Mode),
+		})
+	}
+
+	// the changelog is a nicety: the repository may well be out of
+	// reach.
+	if rel, err := s.m.ReleaseNotes(pkg.Name, pkg.Version); err == nil && rel != nil {
+		resp.Release = &pkgpb.Release{
+			Version: rel.Version,
+			Date:    timestamppb.New(rel.Date),
+			Notes:   rel.Notes,
+		}
+	}
+	return resp, nil
+}
@@ -0,0 +1,174 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"testing"
+
+	"github.com/PlakarKorp/pkg/pkgpb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func newTestGRPCClient(t *testing.T, be Backend) pkgpb.ManagerClient {
+	t.Helper()
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	m, err := New(be, &Options{InstallURL: repo.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lis := bufconn.Listen(1 << 20)
+	srv := grpc.NewServer()
+	pkgpb.RegisterManagerServer(srv, NewGRPCServer(m))
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufconn",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return lis.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return pkgpb.NewManagerClient(conn)
+}
+
+// recvAll reads a progress stream up to its last message.
+func recvAll(t *testing.T, stream grpc.ServerStreamingClient[pkgpb.Progress]) []*pkgpb.Progress {
+	t.Helper()
+	var msgs []*pkgpb.Progress
+	for {
+		msg, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			return msgs
+		}
+		if err != nil {
+			t.Fatalf("Recv: %v", err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestGRPCServerList(t *testing.T) {
+	c := newTestGRPCClient(t, newFakeBackend(pkgVer("fs", "v1.0.0"), pkgVer("s3", "v1.0.0")))
+
+	resp, err := c.List(context.Background(), &pkgpb.ListRequest{Name: "s3"})
+	if err != nil {
+		t.Fatalf("List: %v", err)
+	}
+	if len(resp.Packages) != 1 || resp.Packages[0].Name != "s3" {
+		t.Errorf("List = %v", resp.Packages)
+	}
+}
+
+func TestGRPCServerInstallAndRemove(t *testing.T) {
+	be := newFakeBackend()
+	c := newTestGRPCClient(t, be)
+
+	stream, err := c.Install(context.Background(), &pkgpb.InstallRequest{Target: "s3"})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	msgs := recvAll(t, stream)
+	if len(msgs) < 2 {
+		t.Fatalf("Install sent %d messages, want progress then done", len(msgs))
+	}
+	if last := msgs[len(msgs)-1]; !last.Done || last.Error != "" {
+		t.Errorf("last message = %v", last)
+	}
+	if msgs[0].Done || msgs[0].Package.GetName() != "s3" {
+		t.Errorf("first message = %v", msgs[0])
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Version != "v1.3.0" {
+		t.Fatalf("loaded = %v", be.loaded)
+	}
+
+	stream, err = c.Install(context.Background(), &pkgpb.InstallRequest{Target: "s3"})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	msgs = recvAll(t, stream)
+	if last := msgs[len(msgs)-1]; !last.Done || last.Error == "" {
+		t.Errorf("second install last message = %v, want an error", last)
+	}
+
+	resp, err := c.Remove(context.Background(), &pkgpb.RemoveRequest{Name: "s3"})
+	if err != nil {
+		t.Fatalf("Remove: %v", err)
+	}
+	if len(resp.Errors) != 0 || len(be.unloaded) != 1 {
+		t.Errorf("Remove = %v, unloaded = %v", resp.Errors, be.unloaded)
+	}
+}
+
+func TestGRPCServerUpgrade(t *testing.T) {
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"))
+	c := newTestGRPCClient(t, be)
+
+	stream, err := c.Upgrade(context.Background(), &pkgpb.UpgradeRequest{Name: "s3"})
+	if err != nil {
+		t.Fatalf("Upgrade: %v", err)
+	}
+	msgs := recvAll(t, stream)
+	if last := msgs[len(msgs)-1]; !last.Done || last.Error != "" {
+		t.Errorf("last message = %v", last)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Version != "v1.3.0" {
+		t.Errorf("loaded = %v", be.loaded)
+	}
+
+	stream, err = c.Upgrade(context.Background(), &pkgpb.UpgradeRequest{})
+	if err == nil {
+		_, err = stream.Recv()
+	}
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Upgrade without a name = %v, want InvalidArgument", err)
+	}
+}
+
+func TestGRPCServerInfo(t *testing.T) {
+	s3 := pkgVer("s3", "v1.0.0")
+	be := &listingBackend{
+		readingBackend: &readingBackend{
+			fakeBackend: newFakeBackend(s3),
+			files: map[string]string{
+				s3.Filename() + ":manifest.yaml": "name: s3\n",
+			},
+		},
+		listed: map[string][]string{
+			s3.Filename(): {"manifest.yaml", "s3-storage"},
+		},
+	}
+	c := newTestGRPCClient(t, be)
+
+	resp, err := c.Info(context.Background(), &pkgpb.InfoRequest{Name: "s3"})
+	if err != nil {
+		t.Fatalf("Info: %v", err)
+	}
+	if string(resp.Manifest) != "name: s3\n" || len(resp.Files) != 2 ||
+		resp.Package.GetVersion() != "v1.0.0" {
+		t.Errorf("Info = %v", resp)
+	}
+
+	_, err = c.Info(context.Background(), &pkgpb.InfoRequest{Name: "ftp"})
+	if status.Code(err) != codes.NotFound {
+		t.Errorf("Info of a missing package = %v, want NotFound", err)
+	}
+}
+
+func TestGRPCServerInfoUnsupported(t *testing.T) {
+	c := newTestGRPCClient(t, newFakeBackend(pkgVer("s3", "v1.0.0")))
+
+	_, err := c.Info(context.Background(), &pkgpb.InfoRequest{Name: "s3"})
+	if status.Code(err) != codes.Unimplemented {
+		t.Errorf("Info = %v, want Unimplemented", err)
+	}
+}
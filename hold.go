@@ -0,0 +1,126 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Name of the file, in the package directory, listing the packages
+// on hold.
+const holdsFile = ".holds.json"
+
+// Holder is implemented by the backends that can put packages on
+// hold.  A package on hold is left alone by [Manager.Sync]: it's
+// never installed, upgraded or removed, whatever the desired state
+// says.  It's meant for packages deployed by other means.
+type Holder interface {
+	Hold(name string) error
+	Unhold(name string) error
+	Held() ([]string, error)
+}
+
+// Hold puts the named package on hold.  It doesn't need to be
+// installed.
+func (p *Manager) Hold(name string) error {
+	h, ok := p.store.(Holder)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return h.Hold(name)
+}
+
+// Unhold releases the hold on the named package.
+func (p *Manager) Unhold(name string) error {
+	h, ok := p.store.(Holder)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	return h.Unhold(name)
+}
+
+// Held returns the names of the packages on hold.
+func (p *Manager) Held() ([]string, error) {
+	h, ok := p.store.(Holder)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return h.Held()
+}
+
+// held is like Held but treats backends without holds as holding
+// nothing.
+func (p *Manager) held() ([]string, error) {
+	held, err := p.Held()
+	if errors.Is(err, errors.ErrUnsupported) {
+		return nil, nil
+	}
+	return held, err
+}
+
+func (f *FlatBackend) Held() ([]string, error) {
+	data, err := readFile(f.fsys, filepath.Join(f.pkgdir, holdsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var held []string
+	if err := json.Unmarshal(data, &held); err != nil {
+		return nil, err
+	}
+	return held, nil
+}
+
+func (f *FlatBackend) setHeld(held []string) error {
+	data, err := json.Marshal(held)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.fsys, filepath.Join(f.pkgdir, holdsFile), data)
+}
+
+func (f *FlatBackend) Hold(name string) error {
+	held, err := f.Held()
+	if err != nil {
+		return err
+	}
+	if slices.Contains(held, name) {
+		return nil
+	}
+	held = append(held, name)
+	slices.Sort(held)
+	return f.setHeld(held)
+}
+
+func (f *FlatBackend) Unhold(name string) error {
+	held, err := f.Held()
+	if err != nil {
+		return err
+	}
+	return f.setHeld(slices.DeleteFunc(held, func(n string) bool {
+		return n == name
+	}))
+}
@@ -0,0 +1,88 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// holdBackend is a fakeBackend keeping its holds in memory.
+type holdBackend struct {
+	*fakeBackend
+	held []string
+}
+
+func (b *holdBackend) Hold(name string) error {
+	b.held = append(b.held, name)
+	return nil
+}
+
+func (b *holdBackend) Unhold(name string) error {
+	b.held = slices.DeleteFunc(b.held, func(n string) bool { return n == name })
+	return nil
+}
+
+func (b *holdBackend) Held() ([]string, error) {
+	return b.held, nil
+}
+
+func TestFlatBackendHold(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+
+	for _, name := range []string{"sftp", "s3", "sftp"} {
+		if err := be.Hold(name); err != nil {
+			t.Fatalf("Hold(%s): %v", name, err)
+		}
+	}
+	held, err := be.Held()
+	if err != nil {
+		t.Fatalf("Held: %v", err)
+	}
+	if !slices.Equal(held, []string{"s3", "sftp"}) {
+		t.Errorf("Held = %v", held)
+	}
+
+	if err := be.Unhold("s3"); err != nil {
+		t.Fatalf("Unhold: %v", err)
+	}
+	if held, _ := be.Held(); !slices.Equal(held, []string{"sftp"}) {
+		t.Errorf("Held after Unhold = %v", held)
+	}
+}
+
+func TestSyncSkipsHeld(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v2.0.0", "ftp": "v1.0.0"})
+
+	be := &holdBackend{
+		fakeBackend: newFakeBackend(pkgVer("s3", "v1.0.0"), pkgVer("manual", "v0.1.0")),
+	}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	for _, name := range []string{"s3", "manual", "ftp"} {
+		if err := m.Hold(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	plan, err := m.Sync([]Spec{{Name: "s3"}, {Name: "ftp"}}, nil)
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	for _, a := range plan {
+		if a.Action != SyncHold {
+			t.Errorf("held package got planned: %+v", a)
+		}
+	}
+	if len(plan) != 3 {
+		t.Errorf("plan = %+v", plan)
+	}
+	if len(be.loaded) != 0 || len(be.unloaded) != 0 {
+		t.Error("Sync changed packages on hold")
+	}
+}
+
+func TestHoldUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if err := m.Hold("s3"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Hold err = %v, want ErrUnsupported", err)
+	}
+}
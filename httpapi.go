@@ -0,0 +1,248 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// The plugin management API served by [NewHTTPHandler]:
+//
+//	GET    /integrations          search, as Query; the type, tag,
+//	                              status and edition parameters are
+//	                              the QueryOptions, local=1 sets
+//	                              OnlyLocal
+//	GET    /packages              list the installed packages; the
+//	                              sort, os and arch parameters are
+//	                              the ListOptions, reverse=1 sets
+//	                              Reverse
+//	POST   /packages/NAME         install, with the version, upgrade
+//	                              and replace parameters
+//	DELETE /packages/NAME         remove, all the versions or the one
+//	                              given with the version parameter
+//
+// Installs stream their progress as server-sent events when the
+// request accepts text/event-stream: "progress" events carrying an
+// Event, then either a "done" or an "error" event.  Failures are
+// reported as a JSON object with an "error" field.
+
+type httpHandler struct {
+	m *Manager
+}
+
+// NewHTTPHandler returns a handler exposing the plugin management
+// API for the given manager, e.g. for the web UI to mount.  It
+// doesn't authenticate the requests.
+func NewHTTPHandler(m *Manager) http.Handler {
+	h := &httpHandler{m: m}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /integrations", h.search)
+	mux.HandleFunc("GET /collections", h.collections)
+	mux.HandleFunc("GET /packages", h.list)
+	mux.HandleFunc("POST /packages/{name}", h.install)
+	mux.HandleFunc("DELETE /packages/{name}", h.remove)
+	return mux
+}
+
+func httpStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrNotInstalled):
+		return http.StatusNotFound
+	case errors.Is(err, ErrAlreadyInstalled), errors.Is(err, ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrBadPackageName), errors.Is(err, ErrInvalidOptions):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrUnauthorized):
+		return http.StatusBadGateway
+	}
+	return http.StatusInternalServerError
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, httpStatus(err), map[string]string{"error": err.Error()})
+}
+
+// packageName returns the package name from the request path.  Paths
+// to .ptar files are refused, since they would be opened on the host
+// serving the API.
+func packageName(r *http.Request) (string, error) {
+	name := NormalizeName(r.PathValue("name"))
+	if strings.HasSuffix(name, ".ptar") {
+		return "", fmt.Errorf("%w %q: not a package name", ErrBadPackageName, name)
+	}
+	if err := validName(name); err != nil {
+		return "", err
+	}
+	return name, nil
+}
+
+func (h *httpHandler) search(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	integrations, err := h.m.Query(&QueryOptions{
+		Type:      q.Get("type"),
+		Tag:       q.Get("tag"),
+		Status:    InstallationStatus(q.Get("status")),
+		Edition:   q.Get("edition"),
+		OnlyLocal: q.Get("local") == "1",
+	})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, integrations)
+}
+
+func (h *httpHandler) collections(w http.ResponseWriter, r *http.Request) {
+	collections, err := h.m.Collections()
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	if collections == nil {
+		collections = []Collection{}
+	}
+	writeJSON(w, http.StatusOK, collections)
+}
+
+func (h *httpHandler) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	opts := &ListOptions{
+		SortBy:          ListSort(q.Get("sort")),
+		Reverse:         q.Get("reverse") == "1",
+		OperatingSystem: q.Get("os"),
+		Architecture:    q.Get("arch"),
+	}
+
+	pkgs := []*Package{}
+	for pkg, err := range h.m.ListWith(opts) {
+		if err != nil {
+			writeError(w, err)
+			return
+		}
+		pkgs = append(pkgs, pkg)
+	}
+	writeJSON(w, http.StatusOK, pkgs)
+}
+
+func (h *httpHandler) install(w http.ResponseWriter, r *http.Request) {
+	name, err := packageName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	q := r.URL.Query()
+	opts := &AddOptions{
+		Version:       q.Get("version"),
+		Upgrade:       q.Get("upgrade") == "1",
+		Replace:       q.Get("replace") == "1",
+		ImplicitFetch: true,
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok || !strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		if err := h.m.Add(name, opts); err != nil {
+			writeError(w, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+
+	// the events are emitted from the goroutine running the
+	// operation, with the subscribers locked: they are only queued
+	// there, dropping the ones a slow client can't keep up with, and
+	// written from here.  Only the events of this install are
+	// followed, not the ones of other operations on the package.
+	key := addKey(name, opts)
+	events := make(chan *Event, 64)
+	unsubscribe := h.m.subscribe(func(ev *Event) {
+		if ev.op != key {
+			return
+		}
+		select {
+		case events <- ev:
+		default:
+		}
+	})
+
+	done := make(chan error, 1)
+	go func() {
+		done <- h.m.Add(name, opts)
+	}()
+
+	for done != nil {
+		select {
+		case ev := <-events:
+			writeEvent(w, "progress", ev)
+			flusher.Flush()
+		case err = <-done:
+			done = nil
+		}
+	}
+	unsubscribe()
+	for len(events) > 0 {
+		writeEvent(w, "progress", <-events)
+	}
+
+	if err != nil {
+		writeEvent(w, "error", map[string]string{"error": err.Error()})
+	} else {
+		writeEvent(w, "done", map[string]string{"name": name})
+	}
+	flusher.Flush()
+}
+
+func writeEvent(w http.ResponseWriter, event string, v any) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data)
+}
+
+func (h *httpHandler) remove(w http.ResponseWriter, r *http.Request) {
+	name, err := packageName(r)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	err = h.m.Del(name, &DelOptions{Version: r.URL.Query().Get("version")})
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestHTTPHandler(t *testing.T, be *fakeBackend) *httptest.Server {
+	t.Helper()
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	m, err := New(be, &Options{InstallURL: repo.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(NewHTTPHandler(m))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestHTTPHandlerList(t *testing.T) {
+	srv := newTestHTTPHandler(t, newFakeBackend(pkgVer("fs", "v1.0.0")))
+
+	resp, err := http.Get(srv.URL + "/packages")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var pkgs []Package
+	if err := json.NewDecoder(resp.Body).Decode(&pkgs); err != nil {
+		t.Fatal(err)
+	}
+	if len(pkgs) != 1 || pkgs[0].Name != "fs" {
+		t.Errorf("list = %+v", pkgs)
+	}
+}
+
+func TestHTTPHandlerInstallAndRemove(t *testing.T) {
+	be := newFakeBackend()
+	srv := newTestHTTPHandler(t, be)
+
+	resp, err := http.Post(srv.URL+"/packages/s3", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent {
+		t.Fatalf("install status = %s", resp.Status)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Version != "v1.3.0" {
+		t.Fatalf("loaded = %v", be.loaded)
+	}
+
+	resp, err = http.Post(srv.URL+"/packages/s3", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("second install status = %s, want 409", resp.Status)
+	}
+
+	req, _ := http.NewRequest("DELETE", srv.URL+"/packages/s3", nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNoContent || len(be.unloaded) != 1 {
+		t.Errorf("remove status = %s, unloaded = %v", resp.Status, be.unloaded)
+	}
+}
+
+func TestHTTPHandlerInstallEvents(t *testing.T) {
+	srv := newTestHTTPHandler(t, newFakeBackend())
+
+	req, _ := http.NewRequest("POST", srv.URL+"/packages/s3", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	var events []string
+	sc := bufio.NewScanner(resp.Body)
+	for sc.Scan() {
+		if ev, ok := strings.CutPrefix(sc.Text(), "event: "); ok {
+			events = append(events, ev)
+		}
+	}
+	if len(events) < 2 || events[0] != "progress" || events[len(events)-1] != "done" {
+		t.Errorf("events = %v", events)
+	}
+}
+
+func TestHTTPHandlerRefusesPaths(t *testing.T) {
+	be := newFakeBackend()
+	srv := newTestHTTPHandler(t, be)
+
+	resp, err := http.Post(srv.URL+"/packages/s3_v1.0.0_linux_amd64.ptar", "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest || len(be.loaded) != 0 {
+		t.Errorf("install of a ptar status = %s", resp.Status)
+	}
+}
+
+// emittingBackend emits an event of another operation on the package
+// while it's being installed.
+type emittingBackend struct {
+	*fakeBackend
+	m *Manager
+}
+
+func (b *emittingBackend) Load(p *Package, rd io.Reader) error {
+	b.m.emit(newWarning(p, WarnCurrentLink, "from another operation"))
+	return b.fakeBackend.Load(p, rd)
+}
+
+func TestHTTPHandlerInstallEventsOperation(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := &emittingBackend{fakeBackend: newFakeBackend()}
+	m, err := New(be, &Options{InstallURL: repo.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+	be.m = m
+	srv := httptest.NewServer(NewHTTPHandler(m))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL+"/packages/s3", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(body), "from another operation") {
+		t.Errorf("the events of another operation were streamed:\n%s", body)
+	}
+	if !strings.Contains(string(body), "event: done") {
+		t.Errorf("install not done:\n%s", body)
+	}
+}
@@ -0,0 +1,223 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/PlakarKorp/kloset/snapshot"
+	"github.com/PlakarKorp/kloset/snapshot/vfs"
+)
+
+// FileEntry describes a file contained in a package.
+type FileEntry struct {
+	Path     string // slash-separated, relative to the package root
+	Size     int64
+	Mode     fs.FileMode
+	Checksum string `json:",omitempty"` // "sha256:<hex>", if computed
+}
+
+// Inspection is what can be learned about a package without
+// installing it.
+type Inspection struct {
+	Package  Package
+	Manifest *Manifest
+	Files    []FileEntry
+}
+
+// Inspector is implemented by the backends that can look into a
+// package without loading it.
+type Inspector interface {
+	Inspect(*Package, io.Reader) (*Inspection, error)
+}
+
+// Inspect returns the manifest and the content of a package without
+// installing it.  The target is either the path to a .ptar file or
+// the name of a plugin to fetch from the repository.
+func (p *Manager) Inspect(target string) (*Inspection, error) {
+	ins, ok := p.store.(Inspector)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	base := NormalizeName(filepath.Base(target))
+
+	if strings.HasSuffix(base, ".ptar") {
+		var pkg Package
+		if err := pkg.parseNameWith(base, p.versions); err != nil {
+			return nil, err
+		}
+
+		fp, err := os.Open(target)
+		if err != nil {
+			return nil, err
+		}
+		defer fp.Close()
+
+		return ins.Inspect(&pkg, fp)
+	}
+
+	r, err := p.FetchRecipe(base)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := Package{
+		Name:            r.Name,
+		Version:         r.Semver(),
+		Architecture:    runtime.GOARCH,
+		OperatingSystem: runtime.GOOS,
+	}
+
+	rd, _, err := p.openbinary(context.Background(), &pkg, r.Checksum(runtime.GOOS, runtime.GOARCH))
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	return ins.Inspect(&pkg, rd)
+}
+
+// Inspect reads the manifest and the file listing of the package
+// straight from the snapshot, without extracting it.
+func (f *FlatBackend) Inspect(pkg *Package, rd io.Reader) (*Inspection, error) {
+	fp, err := f.fsys.CreateTemp(f.staging(f.pkgdir), stagingPrefix+pkg.Name+"-*")
+	if err != nil {
+		return nil, err
+	}
+	defer f.fsys.Remove(fp.Name())
+
+	_, err = io.Copy(fp, rd)
+	fp.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	if f.isolated() {
+		return f.inspectExtracted(pkg, fp.Name())
+	}
+
+	snap, base, release, err := f.openSnapshot(fp.Name())
+	if err != nil {
+		return nil, err
+	}
+	defer release()
+
+	data, err := snapshotReadFile(snap, base, "manifest.yaml")
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := m.Parse(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	files, err := snapshotFiles(snap, base)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Inspection{
+		Package:  *pkg,
+		Manifest: &m,
+		Files:    files,
+	}, nil
+}
+
+// inspectExtracted is Inspect for the backends that decode the
+// packages in a helper: the ptar is extracted by the helper, and
+// inspected from there.
+func (f *FlatBackend) inspectExtracted(pkg *Package, ptar string) (*Inspection, error) {
+	tmpdir, err := f.fsys.MkdirTemp(f.staging(f.cachedir), stagingPrefix+"inspect-*")
+	if err != nil {
+		return nil, err
+	}
+	defer f.fsys.RemoveAll(tmpdir)
+
+	dir := filepath.Join(tmpdir, "content")
+	if err := f.unpack(pkg, ptar, dir); err != nil {
+		return nil, err
+	}
+
+	p, err := containedPath(f.fsys, dir, "manifest.yaml")
+	if err != nil {
+		return nil, err
+	}
+	data, err := readFile(f.fsys, p)
+	if err != nil {
+		return nil, err
+	}
+
+	var m Manifest
+	if err := m.Parse(bytes.NewReader(data)); err != nil {
+		return nil, err
+	}
+
+	files, err := scanFiles(f.fsys, dir, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Inspection{
+		Package:  *pkg,
+		Manifest: &m,
+		Files:    files,
+	}, nil
+}
+
+// snapshotFiles lists the regular files found under base in the
+// snapshot.
+func snapshotFiles(snap *snapshot.Snapshot, base string) ([]FileEntry, error) {
+	fsys, err := snap.Filesystem()
+	if err != nil {
+		return nil, err
+	}
+
+	var files []FileEntry
+	err = fsys.WalkDir(base, func(p string, e *vfs.Entry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fi := e.Stat()
+		if fi.IsDir() {
+			return nil
+		}
+
+		files = append(files, FileEntry{
+			Path: strings.TrimPrefix(strings.TrimPrefix(p, base), "/"),
+			Size: fi.Size(),
+			Mode: fi.Mode(),
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return files, nil
+}
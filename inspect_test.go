@@ -0,0 +1,89 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// inspectingBackend is a fakeBackend that also implements Inspector,
+// recording what it was asked to look at.
+type inspectingBackend struct {
+	*fakeBackend
+	inspected []*Package
+	data      []string
+}
+
+func (b *inspectingBackend) Inspect(p *Package, rd io.Reader) (*Inspection, error) {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return nil, err
+	}
+	b.inspected = append(b.inspected, p)
+	b.data = append(b.data, string(data))
+	return &Inspection{Package: *p, Manifest: &Manifest{Name: p.Name}}, nil
+}
+
+func TestInspectUnsupportedBackend(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if _, err := m.Inspect("s3_v1.0.0_linux_amd64.ptar"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Inspect err = %v, want ErrUnsupported", err)
+	}
+}
+
+func TestInspectLocalFile(t *testing.T) {
+	be := &inspectingBackend{fakeBackend: newFakeBackend()}
+	m, _ := New(be, nil)
+
+	// the platform is not checked: one may inspect a package
+	// built for another system.
+	target := filepath.Join(t.TempDir(), "s3_v1.0.0_plan9_sparc64.ptar")
+	if err := os.WriteFile(target, []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	ins, err := m.Inspect(target)
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if ins.Package.Name != "s3" || ins.Package.OperatingSystem != "plan9" {
+		t.Errorf("inspected package = %+v", ins.Package)
+	}
+	if be.data[0] != "PTARDATA" {
+		t.Errorf("inspected data = %q", be.data[0])
+	}
+	if len(be.loaded) != 0 {
+		t.Error("Inspect loaded the package")
+	}
+}
+
+func TestInspectRemote(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "recipe.yaml") {
+			io.WriteString(w, "name: s3\nversion: v1.2.3\n")
+			return
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := &inspectingBackend{fakeBackend: newFakeBackend()}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	ins, err := m.Inspect("s3")
+	if err != nil {
+		t.Fatalf("Inspect: %v", err)
+	}
+	if ins.Package.Version != "v1.2.3" || ins.Package.OperatingSystem != runtime.GOOS {
+		t.Errorf("inspected package = %+v", ins.Package)
+	}
+	if len(be.loaded) != 0 {
+		t.Error("Inspect loaded the package")
+	}
+}
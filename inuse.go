@@ -0,0 +1,97 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInUse = errors.New("package in use")
+)
+
+// InUseError is returned when removing or replacing a package that
+// was acquired and not yet released.
+type InUseError struct {
+	Name  string
+	Users int
+}
+
+func (e *InUseError) Error() string {
+	return fmt.Sprintf("%s: %s is used by %d running operations",
+		ErrInUse, e.Name, e.Users)
+}
+
+func (e *InUseError) Is(target error) bool {
+	return target == ErrInUse
+}
+
+// Acquire marks the named package as in use, for example for the
+// duration of a backup running one of its connectors.  Until every
+// Acquire is matched by a Release, removing or upgrading it fails
+// with an InUseError unless forced.
+func (p *Manager) Acquire(name string) error {
+	installed := false
+	for _, err := range p.store.List(name) {
+		if err != nil {
+			return err
+		}
+		installed = true
+	}
+	if !installed {
+		return fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+
+	p.refmu.Lock()
+	defer p.refmu.Unlock()
+
+	if p.refs == nil {
+		p.refs = make(map[string]int)
+	}
+	p.refs[name]++
+	return nil
+}
+
+// Release drops a reference taken with Acquire.
+func (p *Manager) Release(name string) {
+	p.refmu.Lock()
+	defer p.refmu.Unlock()
+
+	if p.refs[name] <= 1 {
+		delete(p.refs, name)
+	} else {
+		p.refs[name]--
+	}
+}
+
+// checkInUse fails if the named package is acquired, unless force is
+// set.
+func (p *Manager) checkInUse(name string, force bool) error {
+	if force {
+		return nil
+	}
+
+	p.refmu.Lock()
+	defer p.refmu.Unlock()
+
+	if n := p.refs[name]; n > 0 {
+		return &InUseError{Name: name, Users: n}
+	}
+	return nil
+}
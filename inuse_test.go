@@ -0,0 +1,83 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestAcquireBlocksDel(t *testing.T) {
+	be := newFakeBackend(pkgOf(t, "s3"))
+	m, _ := New(be, nil)
+
+	if err := m.Acquire("s3"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := m.Acquire("s3"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+
+	err := m.Del("s3", nil)
+	var inuse *InUseError
+	if !errors.As(err, &inuse) || !errors.Is(err, ErrInUse) {
+		t.Fatalf("Del = %v, want %v", err, ErrInUse)
+	}
+	if inuse.Name != "s3" || inuse.Users != 2 {
+		t.Errorf("InUseError = %+v", inuse)
+	}
+
+	m.Release("s3")
+	if err := m.Del("s3", nil); !errors.Is(err, ErrInUse) {
+		t.Fatalf("Del = %v, want %v", err, ErrInUse)
+	}
+	if len(be.unloaded) != 0 {
+		t.Fatalf("unloaded %d, want 0", len(be.unloaded))
+	}
+
+	m.Release("s3")
+	if err := m.Del("s3", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if len(be.unloaded) != 1 {
+		t.Errorf("unloaded %d, want 1", len(be.unloaded))
+	}
+}
+
+func TestDelForceInUse(t *testing.T) {
+	be := newFakeBackend(pkgOf(t, "s3"))
+	m, _ := New(be, nil)
+
+	if err := m.Acquire("s3"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	if err := m.Del("s3", &DelOptions{Force: true}); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if len(be.unloaded) != 1 {
+		t.Errorf("unloaded %d, want 1", len(be.unloaded))
+	}
+}
+
+func TestUpgradeInUse(t *testing.T) {
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, nil)
+
+	if err := m.Acquire("s3"); err != nil {
+		t.Fatalf("Acquire: %v", err)
+	}
+	err := m.preadd("s3", "v2.0.0", &AddOptions{Upgrade: true})
+	if !errors.Is(err, ErrInUse) {
+		t.Fatalf("preadd = %v, want %v", err, ErrInUse)
+	}
+
+	err = m.preadd("s3", "v2.0.0", &AddOptions{Upgrade: true, Force: true})
+	if err != nil {
+		t.Fatalf("preadd: %v", err)
+	}
+}
+
+func TestAcquireNotInstalled(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if err := m.Acquire("s3"); !errors.Is(err, ErrNotInstalled) {
+		t.Fatalf("Acquire = %v, want %v", err, ErrNotInstalled)
+	}
+}
@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+var (
+	ErrNoSuchJob     = errors.New("no such job")
+	ErrJobInProgress = errors.New("a job is already in progress for this package")
+)
+
+// How long finished jobs can still be queried.
+const jobRetention = time.Hour
+
+type JobID string
+
+type JobState string
+
+const (
+	JobPending   JobState = "pending"
+	JobRunning   JobState = "running"
+	JobDone      JobState = "done"
+	JobFailed    JobState = "failed"
+	JobCancelled JobState = "cancelled"
+)
+
+// Job is the state of an install started with [Manager.AddAsync].
+type Job struct {
+	ID     JobID
+	Target string
+	State  JobState
+
+	// Percentage of the download done, or -1 if the size is not
+	// known.
+	Progress int
+
+	// Why the job failed, with JobFailed, or context.Canceled
+	// with JobCancelled.
+	Err error
+
+	Started  time.Time
+	Finished time.Time // zero while running
+}
+
+type job struct {
+	Job
+	name   string
+	ctx    context.Context
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// finished returns whether the job is over.
+func (j *job) finished() bool {
+	return j.State == JobDone || j.State == JobFailed || j.State == JobCancelled
+}
+
+// jobName returns the name of the package the target refers to, to
+// match the events of the job.
+func jobName(target string) string {
+	base := NormalizeName(filepath.Base(target))
+	if strings.HasSuffix(base, ".ptar") {
+		var pkg Package
+		if err := pkg.parseNameWith(base, LooseScheme); err == nil {
+			return pkg.Name
+		}
+	}
+	return base
+}
+
+func newJobID() JobID {
+	var b [8]byte
+	rand.Read(b[:])
+	return JobID(hex.EncodeToString(b[:]))
+}
+
+// AddAsync starts installing the target in the background, like
+// [Manager.Add], and returns the job to follow it with [Manager.Job]
+// or [Manager.Wait].
+func (p *Manager) AddAsync(target string, opts *AddOptions) (JobID, error) {
+	if target == "" {
+		return "", ErrBadPackageName
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	j := &job{
+		ctx:    ctx,
+		cancel: cancel,
+		Job: Job{
+			ID:       newJobID(),
+			Target:   target,
+			State:    JobPending,
+			Progress: -1,
+			Started:  time.Now(),
+		},
+		name: jobName(target),
+		done: make(chan struct{}),
+	}
+
+	p.jobmu.Lock()
+	defer p.jobmu.Unlock()
+
+	for id, other := range p.jobs {
+		if other.finished() {
+			if time.Since(other.Finished) > jobRetention {
+				delete(p.jobs, id)
+			}
+			continue
+		}
+		if other.name == j.name {
+			cancel()
+			return "", fmt.Errorf("%s: %w", j.name, ErrJobInProgress)
+		}
+	}
+
+	if p.jobs == nil {
+		p.jobs = make(map[JobID]*job)
+	}
+	p.jobs[j.ID] = j
+
+	go p.run(j, opts)
+	return j.ID, nil
+}
+
+// run runs a job once the previous ones are over: the operations on
+// the backend are not meant to run concurrently.
+func (p *Manager) run(j *job, opts *AddOptions) {
+	p.runmu.Lock()
+	defer p.runmu.Unlock()
+	defer j.cancel()
+
+	p.jobmu.Lock()
+	if j.ctx.Err() != nil {
+		// cancelled while pending.
+		j.State, j.Err, j.Finished = JobCancelled, j.ctx.Err(), time.Now()
+		p.jobmu.Unlock()
+		close(j.done)
+		return
+	}
+	j.State = JobRunning
+	p.jobmu.Unlock()
+
+	unsubscribe := p.subscribe(func(ev *Event) {
+		if ev.Type != EventProgress || ev.Package.Name != j.name {
+			return
+		}
+		p.jobmu.Lock()
+		if ev.Total > 0 {
+			j.Progress = int(ev.Bytes * 100 / ev.Total)
+		}
+		p.jobmu.Unlock()
+	})
+	err := p.addContext(j.ctx, j.Target, opts)
+	unsubscribe()
+
+	p.jobmu.Lock()
+	j.Finished = time.Now()
+	if err != nil && j.ctx.Err() != nil {
+		j.State, j.Err = JobCancelled, j.ctx.Err()
+	} else if err != nil {
+		j.State, j.Err = JobFailed, err
+	} else {
+		j.State, j.Progress = JobDone, 100
+	}
+	p.jobmu.Unlock()
+
+	close(j.done)
+}
+
+// Job returns the current state of a job.
+func (p *Manager) Job(id JobID) (*Job, error) {
+	p.jobmu.Lock()
+	defer p.jobmu.Unlock()
+
+	j, ok := p.jobs[id]
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNoSuchJob)
+	}
+	ret := j.Job
+	return &ret, nil
+}
+
+// Wait waits for a job to finish and returns its final state.
+func (p *Manager) Wait(id JobID) (*Job, error) {
+	p.jobmu.Lock()
+	j, ok := p.jobs[id]
+	p.jobmu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("%s: %w", id, ErrNoSuchJob)
+	}
+
+	<-j.done
+	return p.Job(id)
+}
+
+// Cancel aborts a job.  A pending job won't run, and the download of
+// a running one is interrupted: the partial download is discarded
+// and the versions the job replaced are restored.  Cancelling a
+// finished job has no effect.
+func (p *Manager) Cancel(id JobID) error {
+	p.jobmu.Lock()
+	defer p.jobmu.Unlock()
+
+	j, ok := p.jobs[id]
+	if !ok {
+		return fmt.Errorf("%s: %w", id, ErrNoSuchJob)
+	}
+	j.cancel()
+	return nil
+}
+
+// restore reinstalls the versions an operation removed before being
+// cancelled, like [Manager.Recover] does after a crash.
+func (p *Manager) restore(ctx context.Context, intent *Intent) error {
+	if ctx.Err() == nil || len(intent.Remove) == 0 {
+		return nil
+	}
+	return p.rollback(intent, p.installedVersions(intent.Name))
+}
+
+// contextReader fails once its context is cancelled.
+type contextReader struct {
+	ctx context.Context
+	rd  io.Reader
+}
+
+func (r *contextReader) Read(p []byte) (int, error) {
+	if err := r.ctx.Err(); err != nil {
+		return 0, err
+	}
+	return r.rd.Read(p)
+}
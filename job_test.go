@@ -0,0 +1,157 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestAddAsync(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0", "sftp": "v1.0.0"})
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	id, err := m.AddAsync("s3", &AddOptions{ImplicitFetch: true})
+	if err != nil {
+		t.Fatalf("AddAsync: %v", err)
+	}
+
+	job, err := m.Wait(id)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if job.State != JobDone || job.Err != nil || job.Progress != 100 || job.Finished.IsZero() {
+		t.Errorf("job = %+v", job)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Name != "s3" {
+		t.Errorf("loaded = %v", be.loaded)
+	}
+
+	if got, err := m.Job(id); err != nil || got.State != JobDone {
+		t.Errorf("Job = %+v, %v", got, err)
+	}
+}
+
+func TestAddAsyncFailure(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{})
+	m, _ := New(newFakeBackend(), &Options{InstallURL: repo.URL})
+
+	id, err := m.AddAsync("nope", &AddOptions{ImplicitFetch: true})
+	if err != nil {
+		t.Fatalf("AddAsync: %v", err)
+	}
+	job, err := m.Wait(id)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if job.State != JobFailed || !errors.Is(job.Err, ErrNotFound) {
+		t.Errorf("job = %+v, want failed with ErrNotFound", job)
+	}
+}
+
+func TestJobUnknown(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if _, err := m.Job("deadbeef"); !errors.Is(err, ErrNoSuchJob) {
+		t.Errorf("Job err = %v, want ErrNoSuchJob", err)
+	}
+	if _, err := m.Wait("deadbeef"); !errors.Is(err, ErrNoSuchJob) {
+		t.Errorf("Wait err = %v, want ErrNoSuchJob", err)
+	}
+}
+
+func TestJobName(t *testing.T) {
+	tests := map[string]string{
+		"s3":                              "s3",
+		"/tmp/s3_v1.0.0_linux_amd64.ptar": "s3",
+		"capability:object-storage":       "capability:object-storage",
+	}
+	for target, want := range tests {
+		if got := jobName(target); got != want {
+			t.Errorf("jobName(%q) = %q, want %q", target, got, want)
+		}
+	}
+}
+
+func TestCancelRestoresPreviousVersion(t *testing.T) {
+	started := make(chan struct{})
+	unblock := make(chan struct{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case path.Base(r.URL.Path) == "recipe.yaml":
+			io.WriteString(w, "name: s3\nversion: v1.3.0\n")
+		case strings.Contains(r.URL.Path, "v1.3.0"):
+			// never complete the download of the new version.
+			io.WriteString(w, "PTAR")
+			w.(http.Flusher).Flush()
+			close(started)
+			select {
+			case <-r.Context().Done():
+			case <-unblock:
+			}
+		default:
+			io.WriteString(w, "PTARDATA")
+		}
+	}))
+	defer srv.Close()
+	defer close(unblock)
+
+	be := newArchivingBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	id, err := m.AddAsync("s3", &AddOptions{ImplicitFetch: true, Upgrade: true})
+	if err != nil {
+		t.Fatalf("AddAsync: %v", err)
+	}
+
+	<-started
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+
+	job, err := m.Wait(id)
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	if job.State != JobCancelled || !errors.Is(job.Err, context.Canceled) {
+		t.Errorf("job = %+v, want cancelled", job)
+	}
+
+	if got := m.installedVersions("s3"); !slices.Equal(got, []string{"v1.0.0"}) {
+		t.Errorf("installed versions after the cancellation = %v, want [v1.0.0]", got)
+	}
+}
+
+func TestCancelPending(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	// hold the jobs back while cancelling.
+	m.runmu.Lock()
+	id, err := m.AddAsync("s3", &AddOptions{ImplicitFetch: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Cancel(id); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	m.runmu.Unlock()
+
+	job, err := m.Wait(id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.State != JobCancelled || len(be.loaded) != 0 {
+		t.Errorf("job = %+v, loaded = %v", job, be.loaded)
+	}
+
+	if err := m.Cancel("deadbeef"); !errors.Is(err, ErrNoSuchJob) {
+		t.Errorf("Cancel err = %v, want ErrNoSuchJob", err)
+	}
+}
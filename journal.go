@@ -0,0 +1,249 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+)
+
+// Name of the operation journal in the package directory.
+const journalFile = ".journal"
+
+// Intent describes an operation replacing installed versions of a
+// package, recorded before it starts so that it can be recovered if
+// interrupted.
+type Intent struct {
+	Name     string   `json:"name"`
+	Remove   []string `json:"remove"`             // versions being replaced
+	Version  string   `json:"version"`            // version being installed
+	Source   string   `json:"source,omitempty"`   // .ptar file, if not fetched
+	Checksum string   `json:"checksum,omitempty"` // of the fetched package
+}
+
+// Journal is implemented by the backends that can persist the
+// operation in progress.
+type Journal interface {
+	// WriteIntent records the operation about to start.
+	WriteIntent(*Intent) error
+
+	// ReadIntent returns the recorded operation, or nil if there
+	// is none.
+	ReadIntent() (*Intent, error)
+
+	// ClearIntent removes the recorded operation once done.
+	ClearIntent() error
+}
+
+// begin records the intent in the backend journal, if it has one,
+// and returns the function to call when the operation is over.
+func (p *Manager) begin(intent *Intent) (func(), error) {
+	j, ok := p.store.(Journal)
+	if !ok || len(intent.Remove) == 0 {
+		return func() {}, nil
+	}
+
+	if err := j.WriteIntent(intent); err != nil {
+		return nil, err
+	}
+	return func() { j.ClearIntent() }, nil
+}
+
+// Recover completes the operation that was interrupted by a crash,
+// if any, or rolls it back when that's not possible.  It's meant to
+// be called at startup, before the packages are loaded.
+func (p *Manager) Recover() error {
+	j, ok := p.store.(Journal)
+	if !ok {
+		return nil
+	}
+
+	intent, err := j.ReadIntent()
+	if err != nil || intent == nil {
+		return err
+	}
+
+	var installed []string
+	for pkg, err := range p.store.List(intent.Name) {
+		if err != nil {
+			return err
+		}
+		installed = append(installed, pkg.Version)
+	}
+
+	if !slices.Contains(installed, intent.Version) {
+		err := p.complete(intent)
+		if err != nil {
+			if rerr := p.rollback(intent, installed); rerr != nil {
+				return fmt.Errorf("failed to recover %s: %w",
+					intent.Name, errors.Join(err, rerr))
+			}
+			return j.ClearIntent()
+		}
+	}
+
+	// the new version is there, make sure the old ones are gone.
+	for pkg, err := range p.store.List(intent.Name) {
+		if err != nil {
+			return err
+		}
+		if pkg.Version != intent.Version && slices.Contains(intent.Remove, pkg.Version) {
+			if err := p.store.Unload(pkg); err != nil {
+				return err
+			}
+		}
+	}
+	p.discard(intent)
+
+	return j.ClearIntent()
+}
+
+// complete installs the version the interrupted operation was about
+// to install.
+func (p *Manager) complete(intent *Intent) error {
+	if intent.Source == "" {
+		return p.fetchbinary(context.Background(), intent.Name, intent.Version, intent.Checksum)
+	}
+
+	base, compression := p.compression(filepath.Base(intent.Source))
+	var pkg Package
+	if err := pkg.parseNameWith(base, p.versions); err != nil {
+		return err
+	}
+
+	fp, err := p.openPackageFile(intent.Source, compression)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	return p.store.Load(&pkg, fp)
+}
+
+// setAside uninstalls a version the operation replaces, archiving it
+// when the backend can, so that it can be restored from that copy if
+// the operation doesn't go through.
+func (p *Manager) setAside(pkg *Package) error {
+	if a, ok := p.store.(Archiver); ok {
+		return a.Archive(pkg)
+	}
+	return p.store.Unload(pkg)
+}
+
+// discard drops the copies of the versions the operation replaced,
+// once it went through.
+func (p *Manager) discard(intent *Intent) {
+	a, ok := p.store.(Archiver)
+	if !ok {
+		return
+	}
+	for _, version := range intent.Remove {
+		if version != intent.Version {
+			a.DropArchive(removedPackage(intent, version))
+		}
+	}
+}
+
+// removedPackage returns the version of the package the operation
+// replaced.
+func removedPackage(intent *Intent, version string) *Package {
+	return &Package{
+		Name:            intent.Name,
+		Version:         version,
+		Architecture:    runtime.GOARCH,
+		OperatingSystem: runtime.GOOS,
+	}
+}
+
+// rollback reinstalls the versions the interrupted operation had
+// already removed, from the copies set aside or else from the
+// repository, provided the download can be verified.
+func (p *Manager) rollback(intent *Intent, installed []string) error {
+	for _, version := range intent.Remove {
+		if slices.Contains(installed, version) {
+			continue
+		}
+
+		pkg := removedPackage(intent, version)
+		rd, err := p.openArchived(pkg)
+		if err != nil {
+			return err
+		}
+		if rd != nil {
+			err := p.store.Load(pkg, rd)
+			rd.Close()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		checksum, err := p.versionChecksum(intent.Name, version)
+		if err != nil {
+			return err
+		}
+		if checksum == "" {
+			return fmt.Errorf("%s: no copy to restore and no checksum to verify a download: %w",
+				pkg.Filename(), ErrNotFound)
+		}
+		if err := p.fetchbinary(context.Background(), intent.Name, version, checksum); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *FlatBackend) WriteIntent(intent *Intent) error {
+	data, err := json.Marshal(intent)
+	if err != nil {
+		return err
+	}
+
+	return writeFile(f.fsys, filepath.Join(f.pkgdir, journalFile), data)
+}
+
+func (f *FlatBackend) ReadIntent() (*Intent, error) {
+	data, err := readFile(f.fsys, filepath.Join(f.pkgdir, journalFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var intent Intent
+	if err := json.Unmarshal(data, &intent); err != nil {
+		return nil, fmt.Errorf("corrupted journal: %w", err)
+	}
+	return &intent, nil
+}
+
+func (f *FlatBackend) ClearIntent() error {
+	err := f.fsys.Remove(filepath.Join(f.pkgdir, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
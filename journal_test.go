@@ -0,0 +1,312 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// journalBackend is a fakeBackend keeping its journal in memory.
+type journalBackend struct {
+	*fakeBackend
+	intent  *Intent
+	written []Intent
+}
+
+func (j *journalBackend) WriteIntent(intent *Intent) error {
+	cp := *intent
+	j.intent = &cp
+	j.written = append(j.written, cp)
+	return nil
+}
+
+func (j *journalBackend) ReadIntent() (*Intent, error) {
+	return j.intent, nil
+}
+
+func (j *journalBackend) ClearIntent() error {
+	j.intent = nil
+	return nil
+}
+
+// archivingBackend is a journalBackend keeping the archived packages
+// in memory.
+type archivingBackend struct {
+	*journalBackend
+	archived map[string][]byte
+}
+
+func newArchivingBackend(pkgs ...*Package) *archivingBackend {
+	return &archivingBackend{
+		journalBackend: &journalBackend{fakeBackend: newFakeBackend(pkgs...)},
+		archived:       map[string][]byte{},
+	}
+}
+
+func (a *archivingBackend) Load(p *Package, rd io.Reader) error {
+	if err := a.fakeBackend.Load(p, rd); err != nil {
+		return err
+	}
+	delete(a.archived, p.Filename())
+	return nil
+}
+
+func (a *archivingBackend) Archive(p *Package) error {
+	if err := a.fakeBackend.Unload(p); err != nil {
+		return err
+	}
+	a.archived[p.Filename()] = a.loadData[p.Filename()]
+	if a.archived[p.Filename()] == nil {
+		a.archived[p.Filename()] = []byte("ARCHIVED")
+	}
+	return nil
+}
+
+func (a *archivingBackend) OpenArchive(p *Package) (io.ReadCloser, error) {
+	data, ok := a.archived[p.Filename()]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), nil
+}
+
+func (a *archivingBackend) DropArchive(p *Package) error {
+	delete(a.archived, p.Filename())
+	return nil
+}
+
+func installedVersionsOf(be *fakeBackend) []string {
+	var ret []string
+	for _, p := range be.pkgs {
+		ret = append(ret, p.Version)
+	}
+	return ret
+}
+
+func TestAddWritesIntent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := &journalBackend{fakeBackend: newFakeBackend(pkgVer("s3", "v1.0.0"))}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.1.0", Upgrade: true})
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if len(be.written) != 1 {
+		t.Fatalf("got %d intents, want 1", len(be.written))
+	}
+	in := be.written[0]
+	if in.Name != "s3" || in.Version != "v1.1.0" ||
+		len(in.Remove) != 1 || in.Remove[0] != "v1.0.0" {
+		t.Errorf("intent = %+v", in)
+	}
+	if be.intent != nil {
+		t.Errorf("intent not cleared: %+v", be.intent)
+	}
+}
+
+func TestAddFreshInstallSkipsJournal(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := &journalBackend{fakeBackend: newFakeBackend()}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if len(be.written) != 0 {
+		t.Errorf("intents written for a fresh install: %+v", be.written)
+	}
+}
+
+func TestRecoverCompletes(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	// the old version was removed, the new one never made it.
+	be := &journalBackend{fakeBackend: newFakeBackend()}
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	if got := installedVersionsOf(be.fakeBackend); len(got) != 1 || got[0] != "v1.1.0" {
+		t.Errorf("installed = %v, want [v1.1.0]", got)
+	}
+	if be.intent != nil {
+		t.Errorf("intent not cleared: %+v", be.intent)
+	}
+}
+
+func TestRecoverRemovesOldVersion(t *testing.T) {
+	// the new version was installed, but the old one is still there.
+	be := &journalBackend{fakeBackend: newFakeBackend(pkgVer("s3", "v1.1.0"), pkgVer("s3", "v1.0.0"))}
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+
+	m, _ := New(be, nil)
+	if err := m.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	if got := installedVersionsOf(be.fakeBackend); len(got) != 1 || got[0] != "v1.1.0" {
+		t.Errorf("installed = %v, want [v1.1.0]", got)
+	}
+}
+
+func TestRecoverRollsBack(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case path.Base(r.URL.Path) == "recipe.yaml":
+			fmt.Fprintf(w, "name: s3\nversion: v1.0.0\nchecksums:\n  %s/%s: %s\n",
+				runtime.GOOS, runtime.GOARCH, sha256sum("PTARDATA"))
+		case strings.Contains(r.URL.Path, "s3_v1.1.0_"):
+			http.NotFound(w, r)
+		default:
+			io.WriteString(w, "PTARDATA")
+		}
+	}))
+	defer srv.Close()
+
+	be := &journalBackend{fakeBackend: newFakeBackend()}
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	if got := installedVersionsOf(be.fakeBackend); len(got) != 1 || got[0] != "v1.0.0" {
+		t.Errorf("installed = %v, want [v1.0.0]", got)
+	}
+	if be.intent != nil {
+		t.Errorf("intent not cleared: %+v", be.intent)
+	}
+}
+
+func TestRecoverRollsBackFromArchive(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	// the old version was set aside, the new one never made it.
+	be := newArchivingBackend()
+	be.archived[pkgVer("s3", "v1.0.0").Filename()] = []byte("OLDDATA")
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Recover(); err != nil {
+		t.Fatalf("Recover: %v", err)
+	}
+
+	if got := installedVersionsOf(be.fakeBackend); len(got) != 1 || got[0] != "v1.0.0" {
+		t.Errorf("installed = %v, want [v1.0.0]", got)
+	}
+	if data := string(be.loadData[pkgVer("s3", "v1.0.0").Filename()]); data != "OLDDATA" {
+		t.Errorf("restored %q, want the archived copy", data)
+	}
+	if len(be.archived) != 0 {
+		t.Errorf("archived = %v, want the copy consumed", be.archived)
+	}
+}
+
+func TestRecoverRollBackUnverified(t *testing.T) {
+	// the repository has the old version, but no checksum for it.
+	repo := newTestRepository(t, map[string]string{"s3": "v1.1.0"})
+
+	be := &journalBackend{fakeBackend: newFakeBackend()}
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+	be.loadErr = errors.New("no space left")
+
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+	if err := m.Recover(); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Recover err = %v, want ErrNotFound", err)
+	}
+	if be.intent == nil {
+		t.Error("intent cleared after a failed recovery")
+	}
+}
+
+func TestAddDiscardsReplaced(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.1.0"})
+	be := newArchivingBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Upgrade: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := installedVersionsOf(be.fakeBackend); len(got) != 1 || got[0] != "v1.1.0" {
+		t.Errorf("installed = %v, want [v1.1.0]", got)
+	}
+	if len(be.archived) != 0 {
+		t.Errorf("archived = %v, want the replaced version dropped", be.archived)
+	}
+}
+
+func TestRecoverFailureKeepsIntent(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.NotFound(w, r)
+	}))
+	defer srv.Close()
+
+	be := &journalBackend{fakeBackend: newFakeBackend()}
+	be.intent = &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Recover(); err == nil {
+		t.Fatal("Recover succeeded without a way to complete or roll back")
+	}
+	if be.intent == nil {
+		t.Error("intent cleared after a failed recovery")
+	}
+}
+
+func TestFlatBackendJournal(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+
+	if in, err := be.ReadIntent(); err != nil || in != nil {
+		t.Fatalf("ReadIntent on an empty journal = %+v, %v", in, err)
+	}
+
+	want := &Intent{Name: "s3", Remove: []string{"v1.0.0"}, Version: "v1.1.0"}
+	if err := be.WriteIntent(want); err != nil {
+		t.Fatalf("WriteIntent: %v", err)
+	}
+
+	got, err := be.ReadIntent()
+	if err != nil {
+		t.Fatalf("ReadIntent: %v", err)
+	}
+	if got == nil || got.Name != want.Name || got.Version != want.Version ||
+		len(got.Remove) != 1 || got.Remove[0] != "v1.0.0" {
+		t.Errorf("ReadIntent = %+v, want %+v", got, want)
+	}
+
+	if err := be.ClearIntent(); err != nil {
+		t.Fatalf("ClearIntent: %v", err)
+	}
+	if err := be.ClearIntent(); err != nil {
+		t.Errorf("ClearIntent twice: %v", err)
+	}
+	if in, _ := be.ReadIntent(); in != nil {
+		t.Errorf("ReadIntent after ClearIntent = %+v", in)
+	}
+}
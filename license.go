@@ -0,0 +1,142 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+)
+
+// The names the license text is looked for under, at the root of the
+// packages, when the backend can't list their files.
+var licenseFiles = []string{
+	"LICENSE", "LICENSE.md", "LICENSE.txt",
+	"LICENCE", "LICENCE.md", "LICENCE.txt",
+	"COPYING", "COPYING.md", "COPYING.txt",
+}
+
+// LicenseEntry is the license of an installed package, as found in a
+// LicenseReport.
+type LicenseEntry struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+
+	// The license declared by the manifest, e.g. an SPDX
+	// identifier, if any.
+	License string `json:"license,omitempty"`
+
+	// The file the license text was found in, if any.
+	File string `json:"file,omitempty"`
+
+	// Why the package couldn't be inspected.
+	Error string `json:"error,omitempty"`
+}
+
+// isLicenseFile returns whether the slash-separated path names a
+// license text at the root of the package.
+func isLicenseFile(name string) bool {
+	if strings.Contains(name, "/") {
+		return false
+	}
+	base := strings.ToUpper(strings.TrimSuffix(name, path.Ext(name)))
+	return base == "LICENSE" || base == "LICENCE" || base == "COPYING"
+}
+
+// licenseFile returns the name and the content of the license text
+// of the package, or ErrNotFound if it has none.
+func (p *Manager) licenseFile(pkg *Package) (string, []byte, error) {
+	fr, ok := p.store.(FileReader)
+	if !ok {
+		return "", nil, errors.ErrUnsupported
+	}
+
+	candidates := licenseFiles
+	if fl, ok := p.store.(FileLister); ok {
+		files, err := fl.Files(pkg)
+		if err != nil {
+			return "", nil, err
+		}
+		candidates = nil
+		for _, file := range files {
+			if isLicenseFile(file.Path) {
+				candidates = append(candidates, file.Path)
+			}
+		}
+		slices.Sort(candidates)
+	}
+
+	for _, name := range candidates {
+		if data, err := fr.ReadFile(pkg, name); err == nil {
+			return name, data, nil
+		}
+	}
+	return "", nil, fmt.Errorf("license of %s: %w", pkg.Name, ErrNotFound)
+}
+
+// LicenseText returns the license text shipped with the active
+// version of the named package.
+func (p *Manager) LicenseText(name string) (string, error) {
+	pkg, err := p.Active(name)
+	if err != nil {
+		return "", err
+	}
+
+	_, data, err := p.licenseFile(pkg)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// LicenseReport summarizes the license of every installed package,
+// by name and version, for compliance reviews.  A package that can't
+// be inspected is reported with the error rather than failing the
+// whole report.
+func (p *Manager) LicenseReport() ([]LicenseEntry, error) {
+	var ret []LicenseEntry
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+
+		entry := LicenseEntry{Name: pkg.Name, Version: pkg.Version}
+		m, err := p.manifest(pkg)
+		if err == nil && m != nil {
+			entry.License = m.License
+		}
+		if err == nil {
+			entry.File, _, err = p.licenseFile(pkg)
+			if errors.Is(err, ErrNotFound) || errors.Is(err, errors.ErrUnsupported) {
+				err = nil
+			}
+		}
+		if err != nil {
+			entry.Error = err.Error()
+		}
+		ret = append(ret, entry)
+	}
+
+	slices.SortFunc(ret, func(a, b LicenseEntry) int {
+		return cmp.Or(cmp.Compare(a.Name, b.Name), p.versions.Compare(a.Version, b.Version))
+	})
+	return ret, nil
+}
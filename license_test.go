@@ -0,0 +1,92 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// listingBackend is a readingBackend that can list the files too.
+type listingBackend struct {
+	*readingBackend
+	listed map[string][]string
+}
+
+func (b *listingBackend) Files(p *Package) ([]FileEntry, error) {
+	var ret []FileEntry
+	for _, name := range b.listed[p.Filename()] {
+		ret = append(ret, FileEntry{Path: name})
+	}
+	return ret, nil
+}
+
+func TestLicenseText(t *testing.T) {
+	s3, ftp := pkgVer("s3", "v1.0.0"), pkgVer("ftp", "v1.0.0")
+	be := &readingBackend{
+		fakeBackend: newFakeBackend(s3, ftp),
+		files: map[string]string{
+			s3.Filename() + ":LICENSE.md": "ISC license",
+		},
+	}
+	m, _ := New(be, nil)
+
+	text, err := m.LicenseText("s3")
+	if err != nil || text != "ISC license" {
+		t.Errorf("LicenseText = %q, %v", text, err)
+	}
+	if _, err := m.LicenseText("ftp"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("LicenseText(ftp) = %v, want %v", err, ErrNotFound)
+	}
+	if _, err := m.LicenseText("sftp"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("LicenseText(sftp) = %v, want %v", err, ErrNotInstalled)
+	}
+
+	m, _ = New(newFakeBackend(s3), nil)
+	if _, err := m.LicenseText("s3"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("LicenseText = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestLicenseTextListed(t *testing.T) {
+	s3 := pkgVer("s3", "v1.0.0")
+	be := &listingBackend{
+		readingBackend: &readingBackend{
+			fakeBackend: newFakeBackend(s3),
+			files:       map[string]string{s3.Filename() + ":Copying.rst": "GPL"},
+		},
+		listed: map[string][]string{s3.Filename(): {"manifest.yaml", "docs/LICENSE", "Copying.rst"}},
+	}
+	m, _ := New(be, nil)
+
+	if text, err := m.LicenseText("s3"); err != nil || text != "GPL" {
+		t.Errorf("LicenseText = %q, %v", text, err)
+	}
+}
+
+func TestLicenseReport(t *testing.T) {
+	s3, s3v2, ftp := pkgVer("s3", "v1.0.0"), pkgVer("s3", "v2.0.0"), pkgVer("ftp", "v1.0.0")
+	be := &readingBackend{
+		fakeBackend: newFakeBackend(s3v2, ftp, s3),
+		files: map[string]string{
+			s3.Filename() + ":manifest.yaml":   "name: s3\nlicense: ISC\n",
+			s3.Filename() + ":LICENSE":         "ISC license",
+			s3v2.Filename() + ":manifest.yaml": "name: s3\nlicense: MIT\n",
+			s3v2.Filename() + ":COPYING":       "MIT license",
+			ftp.Filename() + ":manifest.yaml":  "name: ftp\n",
+		},
+	}
+	m, _ := New(be, nil)
+
+	report, err := m.LicenseReport()
+	if err != nil {
+		t.Fatalf("LicenseReport: %v", err)
+	}
+	want := []LicenseEntry{
+		{Name: "ftp", Version: "v1.0.0"},
+		{Name: "s3", Version: "v1.0.0", License: "ISC", File: "LICENSE"},
+		{Name: "s3", Version: "v2.0.0", License: "MIT", File: "COPYING"},
+	}
+	if !slices.Equal(report, want) {
+		t.Errorf("LicenseReport = %+v, want %+v", report, want)
+	}
+}
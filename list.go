@@ -0,0 +1,218 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"iter"
+	"os"
+	"slices"
+	"strings"
+	"time"
+)
+
+// ListSort is the order of the packages returned by ListWith.  Ties are
+// broken by name and version.
+type ListSort string
+
+const (
+	ListByName        ListSort = "name"
+	ListByVersion     ListSort = "version"
+	ListBySize        ListSort = "size"
+	ListByInstallTime ListSort = "installed"
+)
+
+type ListOptions struct {
+	// How to order the packages.  The backend order is kept if
+	// empty.
+	SortBy ListSort
+
+	// Return the packages in the reverse order.
+	Reverse bool
+
+	// Only return the packages for this operating system and
+	// architecture, if given.
+	OperatingSystem string
+	Architecture    string
+}
+
+// PackageStat is what a backend knows about an installed package.
+type PackageStat struct {
+	Size      int64
+	Installed time.Time
+}
+
+// PackageStater is implemented by the backends that can tell the size
+// and install time of the installed packages.  The stats are returned
+// in the order of the given packages.
+type PackageStater interface {
+	PackageStats([]*Package) ([]*PackageStat, error)
+}
+
+// List lists all the installed packages.
+func (p *Manager) List() iter.Seq2[*Package, error] {
+	return p.store.List("")
+}
+
+// ListWith lists the installed packages matching the options, in the
+// requested order.
+func (p *Manager) ListWith(opts *ListOptions) iter.Seq2[*Package, error] {
+	if opts == nil {
+		opts = &ListOptions{}
+	}
+
+	keep := func(pkg *Package) bool {
+		return (opts.OperatingSystem == "" || pkg.OperatingSystem == opts.OperatingSystem) &&
+			(opts.Architecture == "" || pkg.Architecture == opts.Architecture)
+	}
+
+	if opts.SortBy == "" && !opts.Reverse {
+		return func(yield func(*Package, error) bool) {
+			for pkg, err := range p.store.List("") {
+				if err == nil && !keep(pkg) {
+					continue
+				}
+				if !yield(pkg, err) {
+					return
+				}
+			}
+		}
+	}
+
+	return func(yield func(*Package, error) bool) {
+		pkgs, err := p.sortedList(opts, keep)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		for _, pkg := range pkgs {
+			if !yield(pkg, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (p *Manager) sortedList(opts *ListOptions, keep func(*Package) bool) ([]*Package, error) {
+	var stater PackageStater
+	switch opts.SortBy {
+	case "", ListByName, ListByVersion:
+	case ListBySize, ListByInstallTime:
+		var ok bool
+		if stater, ok = p.store.(PackageStater); !ok {
+			return nil, errors.ErrUnsupported
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown sort order %q", ErrInvalidOptions, opts.SortBy)
+	}
+
+	var pkgs []*Package
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+		if keep(pkg) {
+			pkgs = append(pkgs, pkg)
+		}
+	}
+
+	stats := make(map[*Package]*PackageStat)
+	if stater != nil {
+		sts, err := stater.PackageStats(pkgs)
+		if err != nil {
+			return nil, err
+		}
+		for i, pkg := range pkgs {
+			stats[pkg] = sts[i]
+		}
+	}
+
+	byName := func(a, b *Package) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return p.versions.Compare(a.Version, b.Version)
+	}
+
+	var compare func(a, b *Package) int
+	switch opts.SortBy {
+	case "":
+		// the backend order, reversed.
+	case ListByName:
+		compare = byName
+	case ListByVersion:
+		compare = func(a, b *Package) int {
+			if c := p.versions.Compare(a.Version, b.Version); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name, b.Name)
+		}
+	case ListBySize:
+		compare = func(a, b *Package) int {
+			if c := cmp.Compare(stats[a].Size, stats[b].Size); c != 0 {
+				return c
+			}
+			return byName(a, b)
+		}
+	case ListByInstallTime:
+		compare = func(a, b *Package) int {
+			if c := stats[a].Installed.Compare(stats[b].Installed); c != 0 {
+				return c
+			}
+			return byName(a, b)
+		}
+	}
+
+	if compare != nil {
+		slices.SortStableFunc(pkgs, compare)
+	}
+	if opts.Reverse {
+		slices.Reverse(pkgs)
+	}
+	return pkgs, nil
+}
+
+// PackageStats returns the size of the stored packages and when they
+// were installed, according to their receipt or, failing that, to the
+// time they were last modified.
+func (f *FlatBackend) PackageStats(pkgs []*Package) ([]*PackageStat, error) {
+	receipts, err := f.Receipts()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make([]*PackageStat, 0, len(pkgs))
+	for _, pkg := range pkgs {
+		info, err := f.fsys.Stat(f.ptarPath(pkg))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+			}
+			return nil, err
+		}
+
+		st := &PackageStat{Size: info.Size(), Installed: info.ModTime()}
+		if r, ok := receipts[pkg.Filename()]; ok && !r.Installed.IsZero() {
+			st.Installed = r.Installed
+		}
+		stats = append(stats, st)
+	}
+	return stats, nil
+}
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func listNames(t *testing.T, m *Manager, opts *ListOptions) []string {
+	t.Helper()
+	var names []string
+	for pkg, err := range m.ListWith(opts) {
+		if err != nil {
+			t.Fatalf("ListWith(%+v): %v", opts, err)
+		}
+		names = append(names, pkg.Name+"@"+pkg.Version)
+	}
+	return names
+}
+
+func TestListSortAndFilter(t *testing.T) {
+	pkg := func(name, version, goos string) *Package {
+		return &Package{Name: name, Version: version, OperatingSystem: goos, Architecture: "amd64"}
+	}
+	be := newFakeBackend(
+		pkg("s3", "v1.2.0", "linux"),
+		pkg("ftp", "v1.0.0", "windows"),
+		pkg("s3", "v1.10.0", "linux"),
+		pkg("ftp", "v1.0.0", "linux"),
+	)
+	m, _ := New(be, nil)
+
+	tests := []struct {
+		opts ListOptions
+		want []string
+	}{
+		{ListOptions{}, []string{"s3@v1.2.0", "ftp@v1.0.0", "s3@v1.10.0", "ftp@v1.0.0"}},
+		{ListOptions{Reverse: true}, []string{"ftp@v1.0.0", "s3@v1.10.0", "ftp@v1.0.0", "s3@v1.2.0"}},
+		{ListOptions{SortBy: ListByName}, []string{"ftp@v1.0.0", "ftp@v1.0.0", "s3@v1.2.0", "s3@v1.10.0"}},
+		{ListOptions{SortBy: ListByVersion, Reverse: true}, []string{"s3@v1.10.0", "s3@v1.2.0", "ftp@v1.0.0", "ftp@v1.0.0"}},
+		{ListOptions{SortBy: ListByName, OperatingSystem: "windows"}, []string{"ftp@v1.0.0"}},
+		{ListOptions{OperatingSystem: "linux", Architecture: "amd64"}, []string{"s3@v1.2.0", "s3@v1.10.0", "ftp@v1.0.0"}},
+		{ListOptions{Architecture: "arm64"}, nil},
+	}
+	for _, tt := range tests {
+		if got := listNames(t, m, &tt.opts); !slices.Equal(got, tt.want) {
+			t.Errorf("ListWith(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+
+	for _, opts := range []ListOptions{{SortBy: "color"}, {SortBy: ListBySize}} {
+		for _, err := range m.ListWith(&opts) {
+			if !errors.Is(err, ErrInvalidOptions) && !errors.Is(err, errors.ErrUnsupported) {
+				t.Errorf("ListWith(%+v) = %v", opts, err)
+			}
+		}
+	}
+}
+
+func TestFlatBackendListBySize(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, nil)
+
+	if err := be.Load(pkgVer("s3", "v1.0.0"), strings.NewReader("PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := be.Load(pkgVer("ftp", "v1.0.0"), strings.NewReader("LONGER PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	tests := []struct {
+		opts ListOptions
+		want []string
+	}{
+		{ListOptions{SortBy: ListBySize}, []string{"s3@v1.0.0", "ftp@v1.0.0"}},
+		{ListOptions{SortBy: ListBySize, Reverse: true}, []string{"ftp@v1.0.0", "s3@v1.0.0"}},
+		{ListOptions{SortBy: ListByInstallTime}, []string{"s3@v1.0.0", "ftp@v1.0.0"}},
+	}
+	for _, tt := range tests {
+		if got := listNames(t, m, &tt.opts); !slices.Equal(got, tt.want) {
+			t.Errorf("ListWith(%+v) = %v, want %v", tt.opts, got, tt.want)
+		}
+	}
+}
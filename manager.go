@@ -19,20 +19,23 @@
 package pkg
 
 import (
+	"bytes"
+	"cmp"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"iter"
+	"io"
 	"net/http"
 	"net/url"
-	"os"
+	"os/user"
 	"path"
 	"path/filepath"
 	"runtime"
 	"slices"
 	"strings"
-
-	"golang.org/x/mod/semver"
+	"sync"
+	"time"
 )
 
 const PLUGIN_API_VERSION = "v1.1.0"
@@ -54,6 +57,72 @@ type Manager struct {
 	reqhook         RequestHook
 	binaryNeedsAuth bool
 	useragent       string
+	telemetry       TelemetryReporter
+	apiversions     []string
+	apiversion      string
+	apimu           sync.Mutex
+	trustedkeys     []*PublicKey
+	pinnedclient    *http.Client
+	eventhook       func(*Event)
+	maxrate         int64
+	parallel        int
+	minstrength     int
+	user            string
+	recommendhook   func(*Recommendation) bool
+	providerpolicy  ProviderPolicy
+	versions        VersionScheme
+	webhooks        []Webhook
+	processes       *ProcessRegistry
+	stoptimeout     time.Duration
+
+	parallelThreshold int64
+
+	submu       sync.Mutex
+	subscribers map[*subscriber]struct{}
+
+	statemu sync.Mutex
+	states  map[string]*IntegrationInstallation
+
+	jobmu sync.Mutex
+	jobs  map[JobID]*job
+	runmu sync.Mutex
+
+	flightmu sync.Mutex
+	flights  map[string]*flight
+
+	refmu sync.Mutex
+	refs  map[string]int
+
+	indexmu      sync.Mutex
+	index        *IntegrationIndex
+	indexfetched time.Time
+	indexttl     time.Duration
+	indexcache   string
+
+	mdpolicy MetadataPolicy
+	mdstate  string
+	mdmu     sync.Mutex
+	mdseen   map[string]int64
+
+	overrides string
+	vendordir string
+
+	sigpolicy  SignaturePolicy
+	gpgkeyring string
+
+	compressions []Compression
+
+	assetdir string
+	assetmu  sync.Mutex
+
+	dlcachedir string
+	dlmaxage   time.Duration
+	dlmaxsize  int64
+	dlmu       sync.Mutex
+
+	stagepolicy    string
+	installpolicy  func(*InstallRequest) error
+	securitypolicy SecurityPolicy
 }
 
 type Options struct {
@@ -66,6 +135,146 @@ type Options struct {
 	// InstallURL.  "(os/architecture)" will be appended
 	// implicitly.
 	UserAgent string
+
+	// Reporter for anonymous install statistics.  Telemetry is
+	// disabled when nil.
+	Telemetry TelemetryReporter
+
+	// Plugin API versions supported by the host.  When more than
+	// one is given, the newest one also served by the repository
+	// is used.  Defaults to [PLUGIN_API_VERSION].
+	APIVersions []string
+
+	// Keys trusted to sign the recipes.  When set, recipes must
+	// come with a valid signify(1) signature by one of them.
+	TrustedKeys []*PublicKey
+
+	// SPKI hashes, in the "sha256/<base64>" form, of which one
+	// must appear in the certificate chain of the InstallURL and
+	// ApiURL hosts.
+	PinnedCertificates []string
+
+	// Called to report the progress of the operations.
+	EventHook func(*Event)
+
+	// Limit the download of packages to this many bytes per
+	// second.  Zero means no limit.
+	MaxBytesPerSecond int64
+
+	// Number of concurrent requests used to download large
+	// packages, when the repository supports Range requests.  It
+	// is ignored when MaxBytesPerSecond is set.
+	ParallelDownloads int
+
+	// Minimum security level, in bits, of the algorithm used by
+	// the checksums packages are verified against.  For example,
+	// 256 refuses sha256 and accepts only sha512.  When set, the
+	// packages without a checksum are refused.
+	MinChecksumStrength int
+
+	// Name of the user recorded in the audit log.  Defaults to
+	// the user running the process.
+	User string
+
+	// Endpoints receiving a signed JSON payload for every install
+	// and removal.
+	Webhooks []Webhook
+
+	// Called after an install for each package the installed one
+	// recommends or suggests that is missing.  The package is
+	// installed if it returns true.
+	RecommendHook func(*Recommendation) bool
+
+	// Chooses the package to install for a capability.  Defaults
+	// to [PreferOfficial].
+	ProviderPolicy ProviderPolicy
+
+	// How the package versions are validated and compared.
+	// Defaults to [SemverScheme].  The backend must be set up
+	// with the same scheme.
+	VersionScheme VersionScheme
+
+	// Registry of the connector processes spawned from the
+	// packages, as maintained by the code executing them.
+	Processes *ProcessRegistry
+
+	// How long the connectors of a package being removed or
+	// upgraded are given to exit after SIGTERM, before they are
+	// killed.  Defaults to 10 seconds.
+	StopTimeout time.Duration
+
+	// How long the integration index fetched from ApiURL is
+	// reused.  Defaults to 5 minutes, and a negative value
+	// disables the cache.
+	IndexTTL time.Duration
+
+	// File the integration index is saved to, so that it can
+	// still be queried and searched when ApiURL is unreachable.
+	IndexCacheFile string
+
+	// How the signed timestamp and targets metadata of the
+	// repository is enforced.  Anything but [MetadataIgnore]
+	// requires TrustedKeys.
+	MetadataPolicy MetadataPolicy
+
+	// File the versions of the metadata seen so far are saved
+	// to, so that older metadata is refused across restarts too.
+	MetadataStateFile string
+
+	// Directory of "<name>.yaml" recipes taking precedence over
+	// the ones of the repository.  Their artifacts entries may
+	// point the package to builds made or hosted locally.
+	RecipeOverridesDir string
+
+	// Directory of packages that [Manager.Bootstrap] installs
+	// when missing from the backend.
+	VendorDir string
+
+	// How the detached OpenPGP signatures, "<file>.asc" or
+	// "<file>.sig", of the local packages and of the override
+	// artifacts are enforced.  Anything but [SignatureIgnore]
+	// requires GPGKeyring.
+	SignaturePolicy SignaturePolicy
+
+	// Keyring, as exported by gpg(1), of the keys the signatures
+	// are verified against with gpgv(1).
+	GPGKeyring string
+
+	// Compressed variants of the packages to download instead of
+	// the plain ones, by order of preference, when the repository
+	// publishes them.  Local files with their suffix are accepted
+	// too.
+	Compressions []Compression
+
+	// Directory the documentation and the images of the
+	// integrations are cached in, so that they are available
+	// offline and not fetched again on every page load.
+	AssetCacheDir string
+
+	// Directory the downloaded packages are kept in, named after
+	// their digest, so that installing them again, e.g. when
+	// rolling back, doesn't download them.  Disabled if empty.
+	DownloadCacheDir string
+
+	// Retention policy of the download cache: the packages not
+	// used for longer than the maximum age are dropped, then the
+	// least recently used ones past the maximum size.  Zero means
+	// no limit.
+	DownloadCacheMaxAge  time.Duration
+	DownloadCacheMaxSize int64
+
+	// Least stable release stage installed from the repository
+	// without AddOptions.AllowStage.  Defaults to StageStable.
+	StagePolicy string
+
+	// Called before installing any package, from the repository
+	// or from a local file; an error refuses the installation.
+	// See [Policy] for a rule-based one.
+	InstallPolicy func(*InstallRequest) error
+
+	// What Sync does with the versions affected by a critical
+	// advisory of the integration index.
+	SecurityPolicy SecurityPolicy
 }
 
 // WithBearer adds an Authorization header with the Bearer token
@@ -96,6 +305,74 @@ func New(store Backend, opts *Options) (*Manager, error) {
 		useragent:       opts.UserAgent,
 		binaryNeedsAuth: opts.BinaryNeedsAuth,
 		reqhook:         opts.RequestHook,
+		telemetry:       opts.Telemetry,
+		trustedkeys:     opts.TrustedKeys,
+		eventhook:       opts.EventHook,
+		maxrate:         opts.MaxBytesPerSecond,
+		parallel:        opts.ParallelDownloads,
+		minstrength:     opts.MinChecksumStrength,
+		user:            opts.User,
+		webhooks:        opts.Webhooks,
+		recommendhook:   opts.RecommendHook,
+		providerpolicy:  opts.ProviderPolicy,
+		versions:        opts.VersionScheme,
+		processes:       opts.Processes,
+		stoptimeout:     opts.StopTimeout,
+		indexttl:        opts.IndexTTL,
+		indexcache:      opts.IndexCacheFile,
+		mdpolicy:        opts.MetadataPolicy,
+		mdstate:         opts.MetadataStateFile,
+		overrides:       opts.RecipeOverridesDir,
+		vendordir:       opts.VendorDir,
+		sigpolicy:       opts.SignaturePolicy,
+		compressions:    opts.Compressions,
+		assetdir:        opts.AssetCacheDir,
+		dlcachedir:      opts.DownloadCacheDir,
+		dlmaxage:        opts.DownloadCacheMaxAge,
+		dlmaxsize:       opts.DownloadCacheMaxSize,
+		stagepolicy:     opts.StagePolicy,
+		installpolicy:   opts.InstallPolicy,
+		securitypolicy:  opts.SecurityPolicy,
+
+		parallelThreshold: parallelThreshold,
+	}
+
+	if m.stagepolicy == "" {
+		m.stagepolicy = StageStable
+	}
+	if err := checkStageName(m.stagepolicy); err != nil {
+		return nil, err
+	}
+
+	if m.mdpolicy != MetadataIgnore && len(m.trustedkeys) == 0 {
+		return nil, fmt.Errorf("%w: metadata policy set without trusted keys", ErrInvalidOptions)
+	}
+
+	if m.sigpolicy != SignatureIgnore {
+		if opts.GPGKeyring == "" {
+			return nil, fmt.Errorf("%w: signature policy set without a keyring", ErrInvalidOptions)
+		}
+		// gpgv looks for relative keyrings in its home directory.
+		keyring, err := filepath.Abs(opts.GPGKeyring)
+		if err != nil {
+			return nil, err
+		}
+		m.gpgkeyring = keyring
+	}
+
+	apiversions, err := parseAPIVersions(opts.APIVersions)
+	if err != nil {
+		return nil, err
+	}
+	m.apiversions = apiversions
+
+	if len(opts.PinnedCertificates) != 0 {
+		pins, err := parsePins(opts.PinnedCertificates)
+		if err != nil {
+			return nil, err
+		}
+		base := http.DefaultTransport.(*http.Transport)
+		m.pinnedclient = &http.Client{Transport: pinTransport(base, pins)}
 	}
 
 	if opts.InstallURL != "" {
@@ -114,6 +391,28 @@ func New(store Backend, opts *Options) (*Manager, error) {
 		m.api = u
 	}
 
+	if m.user == "" {
+		if u, err := user.Current(); err == nil {
+			m.user = u.Username
+		}
+	}
+
+	if m.versions == nil {
+		m.versions = SemverScheme
+	}
+
+	if m.indexttl == 0 {
+		m.indexttl = defaultIndexTTL
+	}
+
+	if m.stoptimeout == 0 {
+		m.stoptimeout = defaultStopTimeout
+	}
+
+	if m.providerpolicy == nil {
+		m.providerpolicy = PreferOfficial
+	}
+
 	if m.useragent == "" {
 		m.useragent = "pkg/v0.0.1"
 	}
@@ -121,16 +420,14 @@ func New(store Backend, opts *Options) (*Manager, error) {
 	return m, nil
 }
 
-// List lists all the installed packages.
-func (p *Manager) List() iter.Seq2[*Package, error] {
-	return p.store.List("")
-}
-
 type AddOptions struct {
 	// The version to install, if given.  Otherwise, the latest
 	// version available will be used.
 	Version string
 
+	// The expected checksum of the package fetched for Version.
+	Checksum string
+
 	// If exists a older version of the plugin, remove it prior
 	// to install this version.
 	Upgrade bool
@@ -153,6 +450,17 @@ type AddOptions struct {
 	// Install the package even if the OS and Architecture don't
 	// match.
 	AllowOSArchMismatch bool
+
+	// Replace the installed version even if it is in use.
+	Force bool
+
+	// Least stable release stage installed from the repository,
+	// e.g. StageBeta, when it's below Options.StagePolicy.
+	AllowStage string
+
+	// Install the version even if it was yanked from the
+	// repository.
+	AllowYanked bool
 }
 
 func (p *Manager) preadd(name, version string, opts *AddOptions) error {
@@ -175,7 +483,7 @@ func (p *Manager) preadd(name, version string, opts *AddOptions) error {
 		// Replace removes whatever other version is present,
 		// regardless of how it compares to the requested one.
 		if !opts.Replace {
-			cmp := semver.Compare(version, pkg.Version)
+			cmp := p.versions.Compare(version, pkg.Version)
 			if cmp == 0 {
 				return ErrAlreadyInstalled
 			}
@@ -187,7 +495,20 @@ func (p *Manager) preadd(name, version string, opts *AddOptions) error {
 			}
 		}
 
-		if err := p.store.Unload(pkg); err != nil {
+		if err := p.checkInUse(pkg.Name, opts.Force); err != nil {
+			return err
+		}
+		if err := p.stopConnectors(pkg); err != nil {
+			return err
+		}
+		// a copy of the version reinstalled would be picked
+		// instead of downloading it again.
+		if pkg.Version == version {
+			err = p.store.Unload(pkg)
+		} else {
+			err = p.setAside(pkg)
+		}
+		if err != nil {
 			return err
 		}
 	}
@@ -195,74 +516,333 @@ func (p *Manager) preadd(name, version string, opts *AddOptions) error {
 	return nil
 }
 
+// installedVersion returns the installed version of the named
+// package, or the empty string if it's not installed.
+func (p *Manager) installedVersion(name string) string {
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return ""
+		}
+		return pkg.Version
+	}
+	return ""
+}
+
+// replaced returns the installed versions of the named package that
+// the operation will remove.
+func (p *Manager) replaced(name string, opts *AddOptions) []string {
+	if opts.AllowMultipleVersions {
+		return nil
+	}
+	return p.installedVersions(name)
+}
+
+// installedVersions returns all the installed versions of the named
+// package.
+func (p *Manager) installedVersions(name string) []string {
+	var ret []string
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			break
+		}
+		ret = append(ret, pkg.Version)
+	}
+	return ret
+}
+
 // Add installs a package.  By default, it will fail if another
-// version of the same plugin is already present.
+// version of the same plugin is already present.  With ImplicitFetch,
+// the target may be a pattern, as in path.Match, installing every
+// integration of the index it matches that is not installed yet.
 func (p *Manager) Add(target string, opts *AddOptions) error {
-	if opts == nil {
-		opts = &AddOptions{}
+	if opts != nil && opts.ImplicitFetch && isGlob(target) {
+		return p.addPattern(target, opts)
 	}
+	return p.addContext(context.Background(), target, opts)
+}
 
-	if opts.Upgrade && opts.Downgrade {
-		return ErrInvalidOptions
+// addPattern installs the integrations matching the pattern, going on
+// after a failure.
+func (p *Manager) addPattern(pattern string, opts *AddOptions) error {
+	if err := checkNamePattern(pattern); err != nil {
+		return err
+	}
+	if opts.Version != "" || opts.Checksum != "" {
+		return fmt.Errorf("%w: version given for a pattern", ErrInvalidOptions)
+	}
+	if p.api == nil {
+		return fmt.Errorf("%w: no API URL to expand %q", ErrInvalidOptions, pattern)
 	}
 
-	if opts.Replace && (opts.Upgrade || opts.Downgrade) {
-		return ErrInvalidOptions
+	plugs, err := p.integrations()
+	if err != nil {
+		return err
 	}
 
-	if opts.AllowMultipleVersions && (opts.Upgrade || opts.Downgrade || opts.Replace) {
-		return ErrInvalidOptions
+	var names []string
+	for name := range plugs {
+		if ok, _ := path.Match(pattern, name); ok {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Errorf("%w: no integration matches %q", ErrNotFound, pattern)
+	}
+	slices.Sort(names)
+
+	var merr MultiError
+	for _, name := range names {
+		err := p.addContext(context.Background(), name, opts)
+		if errors.Is(err, ErrAlreadyInstalled) {
+			continue
+		}
+		merr.add(&Package{Name: name}, err)
+	}
+	return merr.err()
+}
+
+// addContext is Add, aborting the download if the context is
+// cancelled.  Identical concurrent requests are coalesced.
+func (p *Manager) addContext(ctx context.Context, target string, opts *AddOptions) error {
+	return p.coalesce(ctx, addKey(target, opts), func(ctx context.Context) error {
+		return p.addOnce(ctx, target, opts)
+	})
+}
+
+func (p *Manager) addOnce(ctx context.Context, target string, opts *AddOptions) error {
+	var ev TelemetryEvent
+	var rec AuditRecord
+	err := p.add(ctx, target, opts, &ev, &rec)
+	p.report(&ev, err)
+	p.audit(&rec, err)
+	p.setDone(rec.Name, err)
+	if err != nil {
+		return err
+	}
+	return p.postadd(ctx, rec.Name, rec.To)
+}
+
+func (p *Manager) add(ctx context.Context, target string, opts *AddOptions, ev *TelemetryEvent, rec *AuditRecord) error {
+	if opts == nil {
+		opts = &AddOptions{}
+	}
+	if err := opts.check(); err != nil {
+		return err
+	}
+
+	if capability, ok := strings.CutPrefix(target, capabilityPrefix); ok {
+		name, err := p.resolveCapability(capability)
+		if err != nil {
+			return err
+		}
+		target = name
 	}
 
-	base := filepath.Base(target)
+	base, compression := p.compression(NormalizeName(filepath.Base(target)))
 
 	if opts.ImplicitFetch && !strings.HasSuffix(base, ".ptar") {
-		var name, version string
+		var name, version, checksum string
 
 		if opts.Version != "" {
-			name, version = base, opts.Version
+			name, version, checksum = base, opts.Version, opts.Checksum
 		} else {
 			r, err := p.FetchRecipe(base)
 			if err != nil {
 				return err
 			}
 			name, version = r.Name, r.Semver()
+			checksum = r.Checksum(runtime.GOOS, runtime.GOARCH)
+		}
+
+		if err := p.checkStage(name, version, opts); err != nil {
+			return err
+		}
+		if err := p.checkYanked(name, version, opts); err != nil {
+			return err
+		}
+
+		ev.Operation = TelemetryInstall
+		rec.Operation = AuditAdd
+		if rec.From = p.installedVersion(name); rec.From != "" {
+			ev.Operation = TelemetryUpgrade
+			rec.Operation = AuditUpgrade
+		}
+		ev.Name, ev.Version = name, version
+		ev.OperatingSystem, ev.Architecture = runtime.GOOS, runtime.GOARCH
+		rec.Name, rec.To = name, version
+		if err := p.checkPolicy(name, version, false); err != nil {
+			return err
+		}
+		p.setState(name, StatusInstalling, version)
+
+		intent := &Intent{
+			Name:     name,
+			Remove:   p.replaced(name, opts),
+			Version:  version,
+			Checksum: checksum,
 		}
+		end, err := p.begin(intent)
+		if err != nil {
+			return err
+		}
+		defer end()
 
 		if err := p.preadd(name, version, opts); err != nil {
 			return err
 		}
 
-		return p.fetchbinary(name, version)
+		if err := p.fetchbinary(ctx, name, version, checksum); err != nil {
+			if rerr := p.restore(ctx, intent); rerr != nil {
+				return errors.Join(err, rerr)
+			}
+			return err
+		}
+		p.discard(intent)
+		return nil
 	}
 
 	var pkg Package
-	if err := pkg.parseName(base); err != nil {
+	if err := pkg.parseNameWith(base, p.versions); err != nil {
+		return err
+	}
+
+	source, err := filepath.Abs(target)
+	if err != nil {
 		return err
 	}
 
-	if !opts.AllowOSArchMismatch {
-		if pkg.OperatingSystem != runtime.GOOS || pkg.Architecture != runtime.GOARCH {
+	open := func() (io.ReadCloser, error) {
+		return p.openPackageFile(target, compression)
+	}
+	return p.load(ctx, &pkg, open, source, opts, ev, rec)
+}
+
+// AddFromReader installs the package read from rd, for the callers
+// that already hold its content.  Unlike [Manager.Add], identical
+// concurrent calls are not coalesced, and an interrupted replacement
+// is recovered by fetching the version from the repository.
+func (p *Manager) AddFromReader(pkg *Package, rd io.Reader, opts *AddOptions) error {
+	var ev TelemetryEvent
+	var rec AuditRecord
+	err := p.addReader(pkg, rd, opts, &ev, &rec)
+	p.report(&ev, err)
+	p.audit(&rec, err)
+	p.setDone(rec.Name, err)
+	if err != nil {
+		return err
+	}
+	return p.postadd(context.Background(), rec.Name, rec.To)
+}
+
+func (p *Manager) addReader(pkg *Package, rd io.Reader, opts *AddOptions, ev *TelemetryEvent, rec *AuditRecord) error {
+	if opts == nil {
+		opts = &AddOptions{}
+	}
+	if err := opts.check(); err != nil {
+		return err
+	}
+
+	// make sure the package is one that could have been named.
+	var checked Package
+	if err := checked.parseNameWith(pkg.Filename(), p.versions); err != nil {
+		return err
+	}
+
+	open := func() (io.ReadCloser, error) {
+		return io.NopCloser(rd), nil
+	}
+	return p.load(context.Background(), &checked, open, "", opts, ev, rec)
+}
+
+// check fails with ErrInvalidOptions if the options contradict each
+// other.
+func (opts *AddOptions) check() error {
+	if opts.Upgrade && opts.Downgrade {
+		return ErrInvalidOptions
+	}
+
+	if opts.Replace && (opts.Upgrade || opts.Downgrade) {
+		return ErrInvalidOptions
+	}
+
+	if opts.AllowMultipleVersions && (opts.Upgrade || opts.Downgrade || opts.Replace) {
+		return ErrInvalidOptions
+	}
+
+	if opts.AllowStage != "" {
+		return checkStageName(opts.AllowStage)
+	}
+	return nil
+}
+
+// load installs the package whose content is returned by open.  The
+// source is the path of the package file recorded in the journal, if
+// there is one.
+func (p *Manager) load(ctx context.Context, pkg *Package, open func() (io.ReadCloser, error), source string, opts *AddOptions, ev *TelemetryEvent, rec *AuditRecord) error {
+	ev.Operation = TelemetryInstall
+	rec.Operation = AuditAdd
+	if rec.From = p.installedVersion(pkg.Name); rec.From != "" {
+		ev.Operation = TelemetryUpgrade
+		rec.Operation = AuditUpgrade
+	}
+	ev.Name, ev.Version = pkg.Name, pkg.Version
+	ev.OperatingSystem, ev.Architecture = pkg.OperatingSystem, pkg.Architecture
+	rec.Name, rec.To = pkg.Name, pkg.Version
+	if err := p.checkPolicy(pkg.Name, pkg.Version, true); err != nil {
+		return err
+	}
+	p.setState(pkg.Name, StatusInstalling, pkg.Version)
+
+	if pkg.OperatingSystem != runtime.GOOS || pkg.Architecture != runtime.GOARCH {
+		if !opts.AllowOSArchMismatch {
 			return ErrBadOSArch
 		}
+		p.emitContext(ctx, newWarning(pkg, WarnOSArchMismatch, "installing a package for %s/%s on %s/%s",
+			pkg.OperatingSystem, pkg.Architecture, runtime.GOOS, runtime.GOARCH))
 	}
 
+	intent := &Intent{
+		Name:    pkg.Name,
+		Remove:  p.replaced(pkg.Name, opts),
+		Version: pkg.Version,
+		Source:  source,
+	}
+	end, err := p.begin(intent)
+	if err != nil {
+		return err
+	}
+	defer end()
+
 	if err := p.preadd(pkg.Name, pkg.Version, opts); err != nil {
 		return err
 	}
 
-	fp, err := os.Open(target)
+	fp, err := open()
 	if err != nil {
 		return err
 	}
 	defer fp.Close()
 
-	return p.store.Load(&pkg, fp)
+	if err := p.store.Load(pkg, &contextReader{ctx: ctx, rd: fp}); err != nil {
+		if rerr := p.restore(ctx, intent); rerr != nil {
+			return errors.Join(err, rerr)
+		}
+		return err
+	}
+	p.discard(intent)
+	return nil
 }
 
-func (p *Manager) fetch(url *url.URL, endpoint string, reqauth bool) (*http.Response, error) {
+func (p *Manager) newRequest(url *url.URL, endpoint string, reqauth bool) (*http.Request, error) {
+	if url == nil {
+		return nil, fmt.Errorf("%w: no repository configured", ErrInvalidOptions)
+	}
+
 	u := *url
 	u.Path = path.Join(u.Path, endpoint)
+	// some stores decode '+' as a space, which would break the
+	// versions with build metadata.
+	u.RawPath = strings.ReplaceAll(u.EscapedPath(), "+", "%2B")
 
 	req, err := http.NewRequest("GET", u.String(), nil)
 	if err != nil {
@@ -280,35 +860,124 @@ func (p *Manager) fetch(url *url.URL, endpoint string, reqauth bool) (*http.Resp
 		return nil, ErrAuthorizationRequired
 	}
 
-	resp, err := http.DefaultClient.Do(req)
+	return req, nil
+}
+
+// do sends the request and fails unless the reply has the expected
+// status code.
+func (p *Manager) do(req *http.Request, status int) (*http.Response, error) {
+	resp, err := p.client(req.URL.Host).Do(req)
 	if err != nil {
-		return nil, err
+		return nil, newFetchError(req.URL.String(), nil, err)
 	}
 
-	if resp.StatusCode != 200 {
+	if resp.StatusCode != status {
 		resp.Body.Close()
-		return nil, fmt.Errorf("fetch failed with %s", resp.Status)
+		return nil, newFetchError(req.URL.String(), resp, nil)
 	}
 	return resp, nil
 }
 
-func (p *Manager) FetchRecipe(name string) (*Recipe, error) {
-	s := path.Join(PLUGIN_API_VERSION, name, "recipe.yaml")
-	resp, err := p.fetch(p.repository, s, false)
+func (p *Manager) fetch(url *url.URL, endpoint string, reqauth bool) (*http.Response, error) {
+	req, err := p.newRequest(url, endpoint, reqauth)
+	if err != nil {
+		return nil, err
+	}
+	return p.do(req, http.StatusOK)
+}
+
+func (p *Manager) fetchBytes(url *url.URL, endpoint string, reqauth bool) ([]byte, error) {
+	resp, err := p.fetch(url, endpoint, reqauth)
 	if err != nil {
 		return nil, err
 	}
 	defer resp.Body.Close()
 
+	return io.ReadAll(resp.Body)
+}
+
+func (p *Manager) fetchIndex() (*IntegrationIndex, error) {
+	endp := "v1/integrations/integrations-" + PLUGIN_BUNDLE_VERSION + ".json"
+	data, err := p.fetchBytes(p.api, endp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	md, err := p.targetsMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkTarget(md, endp, data); err != nil {
+		return nil, err
+	}
+
+	var index IntegrationIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
+
+func (p *Manager) FetchRecipe(name string) (*Recipe, error) {
+	if r, err := p.localRecipe(name); err != nil || r != nil {
+		return r, err
+	}
+
+	apiversion, err := p.APIVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	s := path.Join(apiversion, name, "recipe.yaml")
+	data, err := p.fetchBytes(p.repository, s, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(p.trustedkeys) != 0 {
+		sig, err := p.fetchBytes(p.repository, s+".sig", false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch the recipe signature: %w", err)
+		}
+		if err := verifySignature(p.trustedkeys, data, sig); err != nil {
+			return nil, fmt.Errorf("recipe for %s: %w", name, err)
+		}
+	}
+
+	md, err := p.targetsMetadata()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.checkTarget(md, s, data); err != nil {
+		return nil, fmt.Errorf("recipe for %s: %w", name, err)
+	}
+
 	var recipe Recipe
-	if err := recipe.Parse(resp.Body); err != nil {
+	if err := recipe.Parse(bytes.NewReader(data)); err != nil {
 		return nil, err
 	}
 
 	return &recipe, nil
 }
 
-func (p *Manager) fetchbinary(name, version string) error {
+// versionChecksum returns the checksum of the given version of the
+// package for the current platform, or the empty string if it's not
+// known: the recipe only has the ones of the latest version.
+func (p *Manager) versionChecksum(name, version string) (string, error) {
+	r, err := p.FetchRecipe(name)
+	if err != nil {
+		return "", err
+	}
+	if r.Semver() != version {
+		return "", nil
+	}
+	return r.Checksum(runtime.GOOS, runtime.GOARCH), nil
+}
+
+// fetchbinary downloads and loads the package for the current
+// platform.  If checksum is not empty, the package is rejected unless
+// its content matches.
+func (p *Manager) fetchbinary(ctx context.Context, name, version, checksum string) error {
 	pkg := Package{
 		Name:            name,
 		Version:         version,
@@ -316,14 +985,188 @@ func (p *Manager) fetchbinary(name, version string) error {
 		OperatingSystem: runtime.GOOS,
 	}
 
-	s := path.Join(PLUGIN_API_VERSION, name, pkg.Filename())
-	resp, err := p.fetch(p.repository, s, p.binaryNeedsAuth)
+	rd, size, err := p.openbinary(ctx, &pkg, checksum)
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
+	defer rd.Close()
+
+	// unblock the pending reads when cancelled.
+	stop := context.AfterFunc(ctx, func() { rd.Close() })
+	defer stop()
+
+	pr := newProgressReader(ctx, p, &pkg, &contextReader{ctx: ctx, rd: rd}, size)
+	if err := p.store.Load(&pkg, pr); err != nil {
+		return err
+	}
+	keepDownload(rd)
+
+	// the backend consumes the download as it goes, so the install
+	// phase is only what's left after the whole package was read.
+	end := pr.end
+	if end.IsZero() {
+		end = pr.start
+	}
+	p.emitContext(ctx, &Event{
+		Type:    EventPhaseDone,
+		Package: pkg,
+		Phase:   PhaseInstall,
+		Elapsed: time.Since(end),
+	})
+	return nil
+}
+
+// openbinary starts the download of the given package, from the
+// local overrides, the archive, the download cache or the repository.
+// It returns the size of the package too, or -1 if not known.
+func (p *Manager) openbinary(ctx context.Context, pkg *Package, checksum string) (io.ReadCloser, int64, error) {
+	// no checksum is weaker than any algorithm.
+	if checksum == "" && p.minstrength > 0 {
+		return nil, 0, fmt.Errorf("%w: %s has no checksum, %d bits required",
+			ErrWeakChecksum, pkg.Filename(), p.minstrength)
+	}
+
+	body, size, err := p.openOverride(pkg)
+	if err != nil {
+		return nil, 0, err
+	}
+	local := false
+	if body == nil {
+		size = -1
+		if body, err = p.openArchived(pkg); err != nil {
+			return nil, 0, err
+		}
+		local = body != nil
+	}
+	if body == nil {
+		if body, size, err = p.openCachedDownload(pkg, checksum); err != nil {
+			return nil, 0, err
+		}
+		local = body != nil
+	}
+	if body == nil {
+		body, size, err = p.openrepository(ctx, pkg)
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+
+	var rd io.Reader = body
+	if p.maxrate > 0 && !local {
+		rd = newThrottledReader(rd, p.maxrate)
+	}
+
+	if checksum != "" {
+		rd, err = newVerifyingReader(rd, checksum, p.minstrength)
+		if err != nil {
+			body.Close()
+			return nil, 0, err
+		}
+	}
+
+	if p.dlcachedir != "" && !local {
+		return p.cacheDownload(pkg, rd, body), size, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{rd, body}, size, nil
+}
+
+// openrepository starts the download of the given package from the
+// repository, in parallel when possible.
+func (p *Manager) openrepository(ctx context.Context, pkg *Package) (io.ReadCloser, int64, error) {
+	apiversion, err := p.APIVersion()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	s := path.Join(apiversion, pkg.Name, pkg.Filename())
+	for i := range p.compressions {
+		c := &p.compressions[i]
+		body, _, err := p.download(ctx, s+c.Suffix)
+		if errors.Is(err, ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		// the decompressed size is not known.
+		rc, err := decompress(body, c)
+		return rc, -1, err
+	}
+
+	body, size, err := p.download(ctx, s)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			if perr := p.platformError(pkg, apiversion); perr != nil {
+				return nil, 0, perr
+			}
+		}
+		return nil, 0, err
+	}
+	return body, size, nil
+}
+
+// download fetches the given endpoint of the repository, in parallel
+// when possible.
+func (p *Manager) download(ctx context.Context, endpoint string) (io.ReadCloser, int64, error) {
+	req, err := p.newRequest(p.repository, endpoint, p.binaryNeedsAuth)
+	if err != nil {
+		return nil, 0, err
+	}
+	resp, err := p.do(req.WithContext(ctx), http.StatusOK)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var body io.ReadCloser = resp.Body
+	if p.parallelizable(resp) {
+		// drop the initial request and fetch the rest in chunks.
+		resp.Body.Close()
+		body, err = p.downloadParallel(ctx, resp.Request, resp.ContentLength,
+			rangeValidator(resp))
+		if err != nil {
+			return nil, 0, err
+		}
+	}
+	return body, resp.ContentLength, nil
+}
+
+// platformError checks the index to tell apart a missing package
+// from one that was not built for the current platform.  It returns
+// nil if it can't decide.
+func (p *Manager) platformError(pkg *Package, apiversion string) error {
+	if p.api == nil {
+		return nil
+	}
+
+	index, err := p.cachedIndex()
+	if err != nil {
+		return nil
+	}
+
+	for i := range index.Integrations {
+		plug := &index.Integrations[i]
+		if plug.Name != pkg.Name || plug.API != apiversion {
+			continue
+		}
 
-	return p.store.Load(&pkg, resp.Body)
+		platforms := plug.Platforms(pkg.Version)
+		if len(platforms) == 0 ||
+			plug.Supports(pkg.Version, pkg.OperatingSystem, pkg.Architecture) {
+			return nil
+		}
+		return &PlatformError{
+			Name:            pkg.Name,
+			Version:         pkg.Version,
+			OperatingSystem: pkg.OperatingSystem,
+			Architecture:    pkg.Architecture,
+			Available:       platforms,
+		}
+	}
+
+	return nil
 }
 
 type DelOptions struct {
@@ -334,9 +1177,26 @@ type DelOptions struct {
 	// If version is not the empty string, delete only the given
 	// version.  It's incompatible with All.
 	Version string
+
+	// When deleting a group, keep the packages it pulled.
+	KeepMembers bool
+
+	// Delete the packages even if they are in use.
+	Force bool
+
+	// Keep the stored packages in the archive area of the backend,
+	// so installing them again doesn't download them.
+	KeepArchive bool
+	// Once no version of a package is left, remove everything kept
+	// about it too: its receipts, hold, scope, archived and staged
+	// copies, and cached assets.  The audit log is kept.  It's
+	// incompatible with KeepArchive.
+	Purge bool
 }
 
-// Del uninstalls all matching packages.
+// Del uninstalls all matching packages.  The target may be a pattern,
+// as in path.Match, matched against the names of the installed
+// packages.
 func (p *Manager) Del(target string, opts *DelOptions) error {
 	if opts == nil {
 		opts = &DelOptions{}
@@ -350,32 +1210,128 @@ func (p *Manager) Del(target string, opts *DelOptions) error {
 		return ErrInvalidOptions
 	}
 
-	for pkg, err := range p.store.List(target) {
+	archiver, ok := p.store.(Archiver)
+	if opts.KeepArchive && !ok {
+		return errors.ErrUnsupported
+	}
+
+	purger, ok := p.store.(Purger)
+	if opts.Purge && opts.KeepArchive {
+		return ErrInvalidOptions
+	}
+	if opts.Purge && !ok {
+		return errors.ErrUnsupported
+	}
+
+	name, pattern := target, ""
+	if isGlob(target) {
+		if err := checkNamePattern(target); err != nil {
+			return err
+		}
+		name, pattern = "", target
+	}
+
+	var (
+		members []string
+		removed []string
+		merr    MultiError
+	)
+	for pkg, err := range p.store.List(name) {
 		if err != nil {
 			return err
 		}
 
+		if pattern != "" {
+			if ok, _ := path.Match(pattern, pkg.Name); !ok {
+				continue
+			}
+		}
 		if opts.Version != "" && pkg.Version != opts.Version {
 			continue
 		}
 
-		if err := p.store.Unload(pkg); err != nil {
-			return err
+		var m *Manifest
+		if !opts.KeepMembers {
+			m, _ = p.manifest(pkg)
+		}
+
+		p.setState(pkg.Name, StatusRemoving, pkg.Version)
+		err := p.checkInUse(pkg.Name, opts.Force)
+		if err == nil {
+			err = p.stopConnectors(pkg)
+		}
+		if err == nil {
+			if opts.KeepArchive {
+				err = archiver.Archive(pkg)
+			} else {
+				err = p.store.Unload(pkg)
+			}
+		}
+		p.setDone(pkg.Name, err)
+		p.audit(&AuditRecord{
+			Operation: AuditDel,
+			Name:      pkg.Name,
+			From:      pkg.Version,
+		}, err)
+		if err != nil {
+			// keep going with the other packages.
+			merr.add(pkg, err)
+			continue
+		}
+		if !slices.Contains(removed, pkg.Name) {
+			removed = append(removed, pkg.Name)
+		}
+
+		if m != nil && m.IsGroup() {
+			for _, dep := range m.Dependencies {
+				members = append(members, dep.Name)
+			}
 		}
 	}
 
-	return nil
+	if opts.Purge {
+		// the residue of a package already removed goes too.
+		if name != "" && opts.Version == "" && !slices.Contains(removed, name) {
+			removed = append(removed, name)
+		}
+		for _, name := range removed {
+			merr.add(&Package{Name: name}, p.purge(purger, name))
+		}
+	}
+
+	if err := p.delMembers(members); err != nil {
+		return errors.Join(merr.err(), err)
+	}
+	return merr.err()
 }
 
 type QueryOptions struct {
 	Type    string
 	Tag     string
-	Status  string
+	Status  InstallationStatus
 	Edition string
 
 	OnlyLocal bool
+
+	// Only return the integrations from the official publisher.
+	OnlyOfficial bool
+
+	// How to order the integrations.  Defaults to SortByName.
+	SortBy QuerySort
 }
 
+// QuerySort is the order of the integrations returned by Query.  The
+// popularity orders put first the most popular integrations, and last
+// the ones the index has no figures for, ordered by name.
+type QuerySort string
+
+const (
+	SortByName      QuerySort = "name"
+	SortByDownloads QuerySort = "downloads"
+	SortByStars     QuerySort = "stars"
+	SortByRecent    QuerySort = "recent"
+)
+
 func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 	if opts == nil {
 		opts = &QueryOptions{}
@@ -386,6 +1342,15 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 		edition = "community"
 	}
 
+	// don't negotiate the API version when staying offline.
+	apiversion := p.apiversions[0]
+	if !opts.OnlyLocal {
+		apiversion, err = p.APIVersion()
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	packages := make(map[string]*Integration)
 	for p, err := range p.List() {
 		if err != nil {
@@ -400,24 +1365,16 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 			Name:        p.Name,
 			DisplayName: p.Name,
 			Tags:        []string{},
-			API:         PLUGIN_API_VERSION,
+			API:         apiversion,
 			Installation: IntegrationInstallation{
-				Status:  "installed",
+				Status:  StatusInstalled,
 				Version: p.Version,
 			},
 		}
 	}
 
 	if !opts.OnlyLocal {
-		endp := "v1/integrations/integrations-" + PLUGIN_BUNDLE_VERSION + ".json"
-		res, err := p.fetch(p.api, endp, false)
-		if err != nil {
-			return nil, err
-		}
-		defer res.Body.Close()
-
-		var index IntegrationIndex
-		err = json.NewDecoder(res.Body).Decode(&index)
+		index, err := p.cachedIndex()
 		if err != nil {
 			return nil, err
 		}
@@ -425,7 +1382,7 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 		for i := range index.Integrations {
 			plug := &index.Integrations[i]
 
-			if plug.API != PLUGIN_API_VERSION {
+			if plug.API != apiversion {
 				continue
 			}
 			if plug.Edition != edition {
@@ -435,22 +1392,11 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 			// Set compatibility fields for the former model
 			plug.Id = plug.Name
 			plug.LatestVersion = plug.Version
-			pr := semver.Prerelease(plug.Version)
-			switch {
-			case pr == "":
-				plug.Stage = "stable"
-			case strings.HasPrefix(pr, "-devel."):
-				plug.Stage = "devel"
-			case strings.HasPrefix(pr, "-beta."):
-				plug.Stage = "beta"
-			case strings.HasPrefix(pr, "-rc."):
-				plug.Stage = "testing"
-			default:
-				plug.Stage = pr
-			}
+			plug.Stage = versionStage(plug.Version)
 			plug.Types.Destination = plug.HasConnectorType("exporter")
 			plug.Types.Source = plug.HasConnectorType("importer")
 			plug.Types.Storage = plug.HasConnectorType("storage")
+			p.verifyPublisher(&plug.Publisher)
 
 			if p, ok := packages[plug.Id]; ok {
 				p.Id = plug.Id
@@ -466,16 +1412,24 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 				p.Documentation = plug.Documentation
 				p.Icon = plug.Icon
 				p.Featured = plug.Featured
+				p.Artifacts = plug.Artifacts
+				p.Publisher = plug.Publisher
+				p.Provides = plug.Provides
+				p.Stats = plug.Stats
 
-				p.Installation.Available = true
+				p.Installation.Available = plug.Supports(plug.Version,
+					runtime.GOOS, runtime.GOARCH)
 			} else {
-				plug.Installation.Status = "not-installed"
-				plug.Installation.Available = true
+				plug.Installation.Status = StatusNotInstalled
+				plug.Installation.Available = plug.Supports(plug.Version,
+					runtime.GOOS, runtime.GOARCH)
 				packages[plug.Id] = plug
 			}
 		}
 	}
 
+	p.mergeStates(packages, apiversion)
+
 	for _, plug := range packages {
 		if opts.Type == "storage" && !plug.Types.Storage {
 			continue
@@ -495,11 +1449,40 @@ func (p *Manager) Query(opts *QueryOptions) (ret []*Integration, err error) {
 			continue
 		}
 
+		if opts.OnlyOfficial && !plug.Publisher.Official() {
+			continue
+		}
+
 		ret = append(ret, plug)
 	}
 
 	slices.SortFunc(ret, func(a, b *Integration) int {
+		if c := comparePopularity(opts.SortBy, a.Stats, b.Stats); c != 0 {
+			return c
+		}
 		return strings.Compare(a.Name, b.Name)
 	})
 	return ret, nil
 }
+
+// comparePopularity orders the stats from the most to the least
+// popular according to the given order, the missing ones last.
+func comparePopularity(order QuerySort, a, b *IntegrationStats) int {
+	var zero IntegrationStats
+	if a == nil {
+		a = &zero
+	}
+	if b == nil {
+		b = &zero
+	}
+
+	switch order {
+	case SortByDownloads:
+		return cmp.Compare(b.Downloads, a.Downloads)
+	case SortByStars:
+		return cmp.Compare(b.Stars, a.Stars)
+	case SortByRecent:
+		return b.LastRelease.Compare(a.LastRelease)
+	}
+	return 0
+}
@@ -1,12 +1,14 @@
 package pkg
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"io"
 	"iter"
 	"net/http"
 	"net/http/httptest"
+	"path"
 	"runtime"
 	"slices"
 	"strings"
@@ -25,6 +27,8 @@ type fakeBackend struct {
 	listErr   error
 	loadErr   error
 	unloadErr error
+
+	corrupted []string // names of the packages failing Verify
 }
 
 func newFakeBackend(pkgs ...*Package) *fakeBackend {
@@ -79,6 +83,22 @@ func (f *fakeBackend) Unload(p *Package) error {
 	return nil
 }
 
+func (f *fakeBackend) Open(p *Package) (io.ReadCloser, error) {
+	for _, q := range f.pkgs {
+		if q.Filename() == p.Filename() {
+			return io.NopCloser(bytes.NewReader(f.loadData[p.Filename()])), nil
+		}
+	}
+	return nil, ErrNotInstalled
+}
+
+func (f *fakeBackend) Verify(p *Package) error {
+	if slices.Contains(f.corrupted, p.Name) {
+		return ErrCorrupted
+	}
+	return nil
+}
+
 func pkgOf(t *testing.T, name string) *Package {
 	t.Helper()
 	return &Package{
@@ -333,6 +353,60 @@ func TestDelByName(t *testing.T) {
 	}
 }
 
+func TestDelPattern(t *testing.T) {
+	be := newFakeBackend(pkgOf(t, "s3"), pkgOf(t, "s3-glacier"), pkgOf(t, "ftp"))
+	m, _ := New(be, nil)
+	if err := m.Del("s3*", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	var names []string
+	for _, pkg := range be.unloaded {
+		names = append(names, pkg.Name)
+	}
+	if !slices.Equal(names, []string{"s3", "s3-glacier"}) {
+		t.Errorf("unloaded = %v, want [s3 s3-glacier]", names)
+	}
+
+	if err := m.Del("s3[", nil); !errors.Is(err, ErrBadPackageName) {
+		t.Errorf("Del of a bad pattern = %v, want %v", err, ErrBadPackageName)
+	}
+}
+
+func TestAddPattern(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case strings.HasSuffix(r.URL.Path, ".json"):
+			io.WriteString(w, `{"version":"v1","integrations":[
+				{"name":"s3","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0"},
+				{"name":"s3-glacier","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0"},
+				{"name":"ftp","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0"}]}`)
+		case path.Base(r.URL.Path) == "recipe.yaml":
+			fmt.Fprintf(w, "name: %s\nversion: v1.0.0\n", path.Base(path.Dir(r.URL.Path)))
+		default:
+			io.WriteString(w, "PTARDATA")
+		}
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, &Options{ApiURL: srv.URL, InstallURL: srv.URL})
+
+	// s3 is already there.
+	if err := m.Add("s3*", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Name != "s3-glacier" {
+		t.Errorf("loaded = %v, want [s3-glacier]", be.loaded)
+	}
+
+	if err := m.Add("imap*", &AddOptions{ImplicitFetch: true}); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Add of an unmatched pattern = %v, want %v", err, ErrNotFound)
+	}
+	if err := m.Add("s3*", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Add of a pattern with a version = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
 func TestDelAll(t *testing.T) {
 	be := newFakeBackend(pkgOf(t, "s3"), pkgOf(t, "ftp"))
 	m, _ := New(be, nil)
@@ -741,3 +815,148 @@ func TestQueryAPIError(t *testing.T) {
 		t.Fatal("expected error when API returns 500")
 	}
 }
+
+func TestFetchEscapesBuildMetadata(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.URL.RawPath, "v1.0.7%2Bbuild.1") {
+			t.Errorf("request path = %q, want an escaped '+'", r.URL.RawPath)
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.7+build.1"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Version != "v1.0.7+build.1" {
+		t.Errorf("loaded = %+v", be.loaded)
+	}
+}
+
+// failingBackend fails to unload a single package.
+type failingBackend struct {
+	*fakeBackend
+	fail string
+}
+
+func (f *failingBackend) Unload(p *Package) error {
+	if p.Name == f.fail {
+		return errors.New("busy")
+	}
+	return f.fakeBackend.Unload(p)
+}
+
+func TestDelAllKeepsGoing(t *testing.T) {
+	be := &failingBackend{
+		fakeBackend: newFakeBackend(pkgVer("fs", "v1.0.0"), pkgVer("s3", "v1.0.0"), pkgVer("sftp", "v1.0.0")),
+		fail:        "s3",
+	}
+	m, err := New(be, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = m.Del("", &DelOptions{All: true})
+	var merr *MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("Del err = %v, want a *MultiError", err)
+	}
+	if len(merr.Errors) != 1 || merr.Errors[0].Package.Name != "s3" {
+		t.Errorf("MultiError = %v, want only s3", merr)
+	}
+	if len(be.unloaded) != 2 {
+		t.Errorf("unloaded %d packages, want 2", len(be.unloaded))
+	}
+}
+
+func TestQuerySortByPopularity(t *testing.T) {
+	const index = `{
+		"version":"v1",
+		"integrations":[
+			{"name":"ftp","edition":"community","api":"v1.1.0","version":"v1.0.0"},
+			{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "stats":{"downloads":500,"stars":3,"last_release":"2026-01-01T00:00:00Z"}},
+			{"name":"sftp","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "stats":{"downloads":100,"stars":12}},
+			{"name":"imap","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "stats":{"last_release":"2026-06-01T00:00:00Z"}}
+		]
+	}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, index)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(pkgOf(t, "s3")), &Options{ApiURL: srv.URL})
+
+	for _, tt := range []struct {
+		sort QuerySort
+		want []string
+	}{
+		{"", []string{"ftp", "imap", "s3", "sftp"}},
+		{SortByDownloads, []string{"s3", "sftp", "ftp", "imap"}},
+		{SortByStars, []string{"sftp", "s3", "ftp", "imap"}},
+		{SortByRecent, []string{"imap", "s3", "ftp", "sftp"}},
+	} {
+		got, err := m.Query(&QueryOptions{SortBy: tt.sort})
+		if err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+		var names []string
+		for _, g := range got {
+			names = append(names, g.Name)
+		}
+		if !slices.Equal(names, tt.want) {
+			t.Errorf("sort by %q = %v, want %v", tt.sort, names, tt.want)
+		}
+	}
+
+	got, err := m.Query(nil)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	for _, g := range got {
+		if g.Name == "s3" && (g.Stats == nil || g.Stats.Downloads != 500) {
+			t.Errorf("installed s3 stats = %+v", g.Stats)
+		}
+		if g.Name == "ftp" && g.Stats != nil {
+			t.Errorf("ftp stats = %+v, want none", g.Stats)
+		}
+	}
+}
+
+func TestAddFromReader(t *testing.T) {
+	be := newFakeBackend()
+	m, _ := New(be, nil)
+
+	pkg := &Package{
+		Name:            "s3",
+		Version:         "v1.0.0",
+		OperatingSystem: runtime.GOOS,
+		Architecture:    runtime.GOARCH,
+	}
+	if err := m.AddFromReader(pkg, strings.NewReader("PTARDATA"), nil); err != nil {
+		t.Fatalf("AddFromReader: %v", err)
+	}
+	if len(be.loaded) != 1 || string(be.loadData[pkg.Filename()]) != "PTARDATA" {
+		t.Errorf("loaded = %v, data = %q", be.loaded, be.loadData[pkg.Filename()])
+	}
+
+	if err := m.AddFromReader(pkg, strings.NewReader("PTARDATA"), nil); !errors.Is(err, ErrAlreadyInstalled) {
+		t.Errorf("second AddFromReader err = %v, want ErrAlreadyInstalled", err)
+	}
+
+	bad := *pkg
+	bad.Version = "latest"
+	if err := m.AddFromReader(&bad, strings.NewReader("PTARDATA"), nil); err == nil {
+		t.Error("AddFromReader accepted an invalid version")
+	}
+
+	other := *pkg
+	other.Architecture = "sparc64"
+	if err := m.AddFromReader(&other, strings.NewReader("PTARDATA"), nil); !errors.Is(err, ErrBadOSArch) {
+		t.Errorf("AddFromReader for another platform err = %v, want ErrBadOSArch", err)
+	}
+}
@@ -19,16 +19,36 @@
 package pkg
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
 	"io"
+	"maps"
 	"os"
+	"path"
 	"runtime"
+	"slices"
 	"strings"
 
 	"github.com/PlakarKorp/kloset/location"
 	"go.yaml.in/yaml/v3"
 )
 
+var (
+	ErrManifestSchema = errors.New("unsupported manifest schema")
+	ErrUnknownField   = errors.New("unknown manifest field")
+)
+
+// ManifestSchema is the version of the manifest format this package
+// writes and understands.  Manifests without a schema_version are
+// version 1; Parse upgrades the older ones in memory.
+const ManifestSchema = 2
+
+// manifestMigrations[v] upgrades a manifest from schema v to v+1.
+var manifestMigrations = map[int]func(*Manifest){
+	1: migrateManifestV1,
+}
+
 type ManifestConnector struct {
 	Type          ConnectorType    `yaml:"type"`
 	Class         ResourceClass    `yaml:"class"`
@@ -39,9 +59,27 @@ type ManifestConnector struct {
 	Executable    string           `yaml:"executable"`
 	Args          []string         `yaml:"args"`
 	ExtraFiles    []string         `yaml:"extra_files"`
+
+	// Overrides for some platforms, keyed by "os" or "os/arch".
+	// They are applied by Parse for the host platform, the
+	// os/arch entry after the os one.
+	Platforms map[string]PlatformOverride `yaml:"platforms"`
+}
+
+// PlatformOverride is what a connector changes on a given platform.
+// The executable and arguments, when set, replace the default ones
+// while the extra files are added to them.
+type PlatformOverride struct {
+	Executable string   `yaml:"executable"`
+	Args       []string `yaml:"args"`
+	ExtraFiles []string `yaml:"extra_files"`
 }
 
 type Manifest struct {
+	// The version of the manifest format, not to be confused with
+	// the plugin API version.
+	SchemaVersion int `yaml:"schema_version"`
+
 	Name        string   `yaml:"name"`
 	DisplayName string   `yaml:"display_name"`
 	Description string   `yaml:"description"`
@@ -53,6 +91,22 @@ type Manifest struct {
 	APIVersion  string   `yaml:"api_version"`
 
 	Connectors []ManifestConnector `yaml:"connectors"`
+
+	// Packages installed along with this one.
+	Dependencies []Spec `yaml:"dependencies"`
+
+	// Packages that work well with this one, which are offered
+	// but not installed automatically.  Recommends are the ones
+	// most users want, Suggests are more situational.
+	Recommends []Spec `yaml:"recommends"`
+	Suggests   []Spec `yaml:"suggests"`
+
+	// Relations with the other packages: the names this one can
+	// stand for, the ones it can't be installed with, and the
+	// ones it supersedes and removes when installed.
+	Provides  []string `yaml:"provides"`
+	Conflicts []string `yaml:"conflicts"`
+	Replaces  []string `yaml:"replaces"`
 }
 
 func NewManifestFromFile(path string) (*Manifest, error) {
@@ -78,15 +132,221 @@ func (m *Manifest) Parse(rd io.Reader) error {
 		return fmt.Errorf("failed to decode the manifest: %w", err)
 	}
 
-	// Windows really wants executables to end with .exe
-	if os.Getenv("GOOS") == "windows" || runtime.GOOS == "windows" {
-		for i := range m.Connectors {
-			if !strings.HasSuffix(m.Connectors[i].Executable, ".exe") {
-				m.Connectors[i].Executable += ".exe"
+	if err := m.migrate(); err != nil {
+		return err
+	}
+
+	goos, goarch := hostPlatform()
+	for i := range m.Connectors {
+		if err := m.Connectors[i].resolve(goos, goarch); err != nil {
+			return err
+		}
+	}
+	m.expand(map[string]string{
+		"OS":   goos,
+		"ARCH": goarch,
+	})
+
+	return nil
+}
+
+// expandVar replaces the ${NAME} references in s with the value of
+// the variable, leaving the unknown ones alone.
+func expandVar(s string, vars map[string]string) string {
+	var sb strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start == -1 {
+			break
+		}
+		end := strings.IndexByte(s[start:], '}')
+		if end == -1 {
+			break
+		}
+		end += start
+
+		sb.WriteString(s[:start])
+		if v, ok := vars[s[start+2:end]]; ok {
+			sb.WriteString(v)
+		} else {
+			sb.WriteString(s[start : end+1])
+		}
+		s = s[end+1:]
+	}
+	sb.WriteString(s)
+	return sb.String()
+}
+
+// expand replaces the variables in the executable, arguments and
+// extra files of the connectors.  Parse expands ${OS} and ${ARCH}
+// while ${VERSION} and ${PKGDIR} are expanded once the package is
+// known, by expandPackage.
+func (m *Manifest) expand(vars map[string]string) {
+	// the executable and extra files are relative to the package
+	// directory, so ${PKGDIR} in there is the directory itself.
+	pathvars := vars
+	if _, ok := vars["PKGDIR"]; ok {
+		pathvars = maps.Clone(vars)
+		pathvars["PKGDIR"] = "."
+	}
+	expandPath := func(s string) string {
+		if e := expandVar(s, pathvars); e != s {
+			return path.Clean(e)
+		}
+		return s
+	}
+
+	for i := range m.Connectors {
+		conn := &m.Connectors[i]
+		conn.Executable = expandPath(conn.Executable)
+		for j := range conn.Args {
+			conn.Args[j] = expandVar(conn.Args[j], vars)
+		}
+		for j := range conn.ExtraFiles {
+			conn.ExtraFiles[j] = expandPath(conn.ExtraFiles[j])
+		}
+	}
+}
+
+// expandPackage expands the variables that depend on the installed
+// package: its version and the directory it was extracted to.
+func (m *Manifest) expandPackage(pkg *Package, pkgdir string) {
+	m.expand(map[string]string{
+		"VERSION": pkg.Version,
+		"PKGDIR":  pkgdir,
+	})
+}
+
+// ParseStrict is Parse, failing on the fields this version doesn't
+// know about instead of ignoring them, to catch the typos when
+// writing a manifest.  The packages already published are loaded
+// with Parse, the unknown fields being reported as warnings.
+func (m *Manifest) ParseStrict(rd io.Reader) error {
+	data, err := io.ReadAll(rd)
+	if err != nil {
+		return err
+	}
+	if fields := unknownFields(data); len(fields) != 0 {
+		return fmt.Errorf("%w: %s", ErrUnknownField, strings.Join(fields, "; "))
+	}
+	return m.Parse(bytes.NewReader(data))
+}
+
+// migrate upgrades the manifest to the current schema.
+func (m *Manifest) migrate() error {
+	if m.SchemaVersion == 0 {
+		m.SchemaVersion = 1
+	}
+	if m.SchemaVersion < 0 || m.SchemaVersion > ManifestSchema {
+		return fmt.Errorf("%w: version %d", ErrManifestSchema, m.SchemaVersion)
+	}
+	for ; m.SchemaVersion < ManifestSchema; m.SchemaVersion++ {
+		manifestMigrations[m.SchemaVersion](m)
+	}
+	return nil
+}
+
+// migrateManifestV1 makes explicit the .exe suffix schema 1 implied
+// for the executables on Windows: from schema 2 on, the executable
+// names are used as is.
+func migrateManifestV1(m *Manifest) {
+	for i := range m.Connectors {
+		conn := &m.Connectors[i]
+		if conn.Executable == "" || strings.HasSuffix(conn.Executable, ".exe") {
+			continue
+		}
+
+		o := conn.Platforms["windows"]
+		if o.Executable != "" {
+			continue
+		}
+		o.Executable = conn.Executable + ".exe"
+		if conn.Platforms == nil {
+			conn.Platforms = map[string]PlatformOverride{}
+		}
+		conn.Platforms["windows"] = o
+	}
+}
+
+// hostPlatform returns the platform the manifests are resolved for.
+// The GOOS and GOARCH environment variables take precedence, as when
+// cross-compiling.
+func hostPlatform() (goos, goarch string) {
+	goos, goarch = os.Getenv("GOOS"), os.Getenv("GOARCH")
+	if goos == "" {
+		goos = runtime.GOOS
+	}
+	if goarch == "" {
+		goarch = runtime.GOARCH
+	}
+	return goos, goarch
+}
+
+// resolve applies the overrides for the given platform.
+func (conn *ManifestConnector) resolve(goos, goarch string) error {
+	for key := range conn.Platforms {
+		if o, a, ok := strings.Cut(key, "/"); o == "" ||
+			ok && (a == "" || strings.Contains(a, "/")) {
+			return fmt.Errorf("bad platform %q for the %s connector", key, conn.Type)
+		}
+	}
+
+	for _, key := range []string{goos, goos + "/" + goarch} {
+		o, ok := conn.Platforms[key]
+		if !ok {
+			continue
+		}
+		if o.Executable != "" {
+			conn.Executable = o.Executable
+		}
+		if o.Args != nil {
+			conn.Args = o.Args
+		}
+		conn.ExtraFiles = append(conn.ExtraFiles, o.ExtraFiles...)
+	}
+
+	return nil
+}
+
+// foreignFiles returns the files the platform overrides list only
+// for other platforms than the one the manifest was resolved for,
+// which are of no use here.
+func (m *Manifest) foreignFiles() []string {
+	needed := map[string]bool{}
+	for _, conn := range m.Connectors {
+		needed[conn.Executable] = true
+		for _, file := range conn.ExtraFiles {
+			needed[file] = true
+		}
+	}
+
+	var ret []string
+	for _, conn := range m.Connectors {
+		for _, o := range conn.Platforms {
+			for _, file := range append([]string{o.Executable}, o.ExtraFiles...) {
+				if file != "" && !needed[file] {
+					needed[file] = true
+					ret = append(ret, file)
+				}
 			}
 		}
 	}
+	slices.Sort(ret)
+	return ret
+}
+
+// unknownFields returns a description of the fields of the manifest
+// that are not known to this version, which are otherwise silently
+// ignored.
+func unknownFields(data []byte) []string {
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
 
+	var m Manifest
+	var te *yaml.TypeError
+	if err := dec.Decode(&m); errors.As(err, &te) {
+		return te.Errors
+	}
 	return nil
 }
 
@@ -1,8 +1,10 @@
 package pkg
 
 import (
+	"errors"
 	"os"
 	"path/filepath"
+	"slices"
 	"strings"
 	"testing"
 
@@ -173,3 +175,214 @@ func TestManifestConnectorFlagsUnknown(t *testing.T) {
 		t.Errorf("error = %v, want it to mention the bad flag", err)
 	}
 }
+
+func TestUnknownFields(t *testing.T) {
+	if got := unknownFields([]byte(sampleManifest)); len(got) != 0 {
+		t.Errorf("unknownFields(sample) = %v", got)
+	}
+
+	got := unknownFields([]byte("name: s3\nflavour: vanilla\n"))
+	if len(got) != 1 || !strings.Contains(got[0], "flavour") {
+		t.Errorf("unknownFields = %v, want the flavour field", got)
+	}
+}
+
+func TestManifestPlatforms(t *testing.T) {
+	const manifest = `
+connectors:
+  - type: storage
+    executable: s3-storage
+    args: [--verbose]
+    extra_files: [icon.png]
+    platforms:
+      windows:
+        executable: s3.exe
+      linux:
+        args: [--syslog]
+        extra_files: [s3.service]
+      linux/amd64:
+        extra_files: [libs3.so]
+`
+	tests := []struct {
+		goos, goarch string
+		exe          string
+		args         []string
+		extra        []string
+	}{
+		{"linux", "amd64", "s3-storage", []string{"--syslog"}, []string{"icon.png", "s3.service", "libs3.so"}},
+		{"linux", "arm64", "s3-storage", []string{"--syslog"}, []string{"icon.png", "s3.service"}},
+		{"windows", "amd64", "s3.exe", []string{"--verbose"}, []string{"icon.png"}},
+		{"openbsd", "amd64", "s3-storage", []string{"--verbose"}, []string{"icon.png"}},
+	}
+	for _, tt := range tests {
+		t.Setenv("GOOS", tt.goos)
+		t.Setenv("GOARCH", tt.goarch)
+
+		var m Manifest
+		if err := m.Parse(strings.NewReader(manifest)); err != nil {
+			t.Fatalf("%s/%s: Parse: %v", tt.goos, tt.goarch, err)
+		}
+		c := m.Connectors[0]
+		if c.Executable != tt.exe {
+			t.Errorf("%s/%s: Executable = %q, want %q", tt.goos, tt.goarch, c.Executable, tt.exe)
+		}
+		if !slices.Equal(c.Args, tt.args) {
+			t.Errorf("%s/%s: Args = %v, want %v", tt.goos, tt.goarch, c.Args, tt.args)
+		}
+		if !slices.Equal(c.ExtraFiles, tt.extra) {
+			t.Errorf("%s/%s: ExtraFiles = %v, want %v", tt.goos, tt.goarch, c.ExtraFiles, tt.extra)
+		}
+	}
+}
+
+func TestManifestBadPlatform(t *testing.T) {
+	for _, key := range []string{"/amd64", "linux/", "linux/amd64/v2"} {
+		manifest := "connectors:\n  - type: storage\n    platforms:\n      " + key + ":\n        executable: x\n"
+		var m Manifest
+		if err := m.Parse(strings.NewReader(manifest)); err == nil {
+			t.Errorf("%s: Parse succeeded", key)
+		}
+	}
+}
+
+func TestManifestForeignFiles(t *testing.T) {
+	const manifest = `
+connectors:
+  - type: storage
+    executable: s3-storage
+    extra_files: [icon.png]
+    platforms:
+      windows:
+        executable: s3.exe
+        extra_files: [s3.dll]
+      linux/amd64:
+        extra_files: [libs3-amd64.so, icon.png]
+      linux/arm64:
+        extra_files: [libs3-arm64.so]
+`
+	t.Setenv("GOOS", "linux")
+	t.Setenv("GOARCH", "amd64")
+
+	var m Manifest
+	if err := m.Parse(strings.NewReader(manifest)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	want := []string{"libs3-arm64.so", "s3.dll", "s3.exe"}
+	if got := m.foreignFiles(); !slices.Equal(got, want) {
+		t.Errorf("foreignFiles = %v, want %v", got, want)
+	}
+}
+
+func TestExpandVar(t *testing.T) {
+	vars := map[string]string{"OS": "linux", "ARCH": "amd64"}
+	tests := []struct{ in, want string }{
+		{"s3-${OS}-${ARCH}", "s3-linux-amd64"},
+		{"${OS}", "linux"},
+		{"${HOME}/x", "${HOME}/x"},
+		{"$OS", "$OS"},
+		{"${OS", "${OS"},
+		{"plain", "plain"},
+	}
+	for _, tt := range tests {
+		if got := expandVar(tt.in, vars); got != tt.want {
+			t.Errorf("expandVar(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestManifestExpand(t *testing.T) {
+	const manifest = `
+connectors:
+  - type: storage
+    executable: bin/s3-${OS}-${ARCH}
+    args: ["--plugin=${PKGDIR}/conf", "--version=${VERSION}"]
+    extra_files: ["${PKGDIR}/lib/${OS}/*.so"]
+`
+	t.Setenv("GOOS", "linux")
+	t.Setenv("GOARCH", "arm64")
+
+	var m Manifest
+	if err := m.Parse(strings.NewReader(manifest)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	c := &m.Connectors[0]
+	if c.Executable != "bin/s3-linux-arm64" {
+		t.Errorf("Executable = %q", c.Executable)
+	}
+	if c.Args[1] != "--version=${VERSION}" {
+		t.Errorf("Args expanded before the package is known: %v", c.Args)
+	}
+
+	m.expandPackage(pkgVer("s3", "v1.2.0"), "/cache/s3")
+	want := []string{"--plugin=/cache/s3/conf", "--version=v1.2.0"}
+	if !slices.Equal(c.Args, want) {
+		t.Errorf("Args = %v, want %v", c.Args, want)
+	}
+	if !slices.Equal(c.ExtraFiles, []string{"lib/linux/*.so"}) {
+		t.Errorf("ExtraFiles = %v", c.ExtraFiles)
+	}
+}
+
+func TestManifestSchemaMigration(t *testing.T) {
+	t.Setenv("GOOS", "windows")
+
+	var m Manifest
+	if err := m.Parse(strings.NewReader(sampleManifest)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if m.SchemaVersion != ManifestSchema {
+		t.Errorf("SchemaVersion = %d, want %d", m.SchemaVersion, ManifestSchema)
+	}
+	if o := m.Connectors[0].Platforms["windows"]; o.Executable != "s3-storage.exe" {
+		t.Errorf("windows executable = %q, want s3-storage.exe", o.Executable)
+	}
+
+	// from schema 2 on, the executable is used as is.
+	const v2 = `
+schema_version: 2
+connectors:
+  - type: storage
+    executable: tool
+`
+	var m2 Manifest
+	if err := m2.Parse(strings.NewReader(v2)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := m2.Connectors[0].Executable; got != "tool" {
+		t.Errorf("Executable = %q, want tool", got)
+	}
+}
+
+func TestManifestSchemaTooNew(t *testing.T) {
+	var m Manifest
+	err := m.Parse(strings.NewReader("schema_version: 99\nname: s3\n"))
+	if !errors.Is(err, ErrManifestSchema) {
+		t.Errorf("Parse = %v, want %v", err, ErrManifestSchema)
+	}
+}
+
+func TestManifestParseStrict(t *testing.T) {
+	var m Manifest
+	if err := m.ParseStrict(strings.NewReader(sampleManifest)); err != nil {
+		t.Fatalf("ParseStrict: %v", err)
+	}
+	if m.Name != "s3" {
+		t.Errorf("Name = %q, want s3", m.Name)
+	}
+
+	const typo = `
+name: s3
+connectors:
+  - type: storage
+    protocls: [s3]
+`
+	err := m.ParseStrict(strings.NewReader(typo))
+	if !errors.Is(err, ErrUnknownField) || !strings.Contains(err.Error(), "protocls") {
+		t.Errorf("ParseStrict = %v, want %v about protocls", err, ErrUnknownField)
+	}
+
+	// the lenient mode ignores it.
+	if err := m.Parse(strings.NewReader(typo)); err != nil {
+		t.Errorf("Parse: %v", err)
+	}
+}
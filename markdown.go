@@ -0,0 +1,355 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"html"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// renderMarkdown converts the markdown document to HTML.  Only the
+// common subset of the syntax found in the READMEs is understood:
+// headings, paragraphs, lists, block quotes, code, rules, emphasis,
+// links and images.  The raw HTML of the document is escaped rather
+// than passed through and only the links to safe schemes are kept,
+// so that the result can be embedded in a page as is.  The targets
+// of the relative links and images are passed through link.
+func renderMarkdown(src string, link func(string) string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	var sb strings.Builder
+	r := &mdRenderer{link: link}
+	r.blocks(&sb, strings.Split(src, "\n"))
+	return sb.String()
+}
+
+var (
+	mdHeading    = regexp.MustCompile(`^ {0,3}(#{1,6})(?:[ \t]+(.*?))?(?:[ \t]+#+)?[ \t]*$`)
+	mdRule       = regexp.MustCompile(`^ {0,3}(?:(?:-[ \t]*){3,}|(?:\*[ \t]*){3,}|(?:_[ \t]*){3,})$`)
+	mdFence      = regexp.MustCompile("^ {0,3}(`{3,}|~{3,})[ \t]*([^`]*)$")
+	mdBullet     = regexp.MustCompile(`^ {0,3}([-*+])[ \t]+(.*)$`)
+	mdOrdered    = regexp.MustCompile(`^ {0,3}(\d{1,9})[.)][ \t]+(.*)$`)
+	mdQuote      = regexp.MustCompile(`^ {0,3}> ?(.*)$`)
+	mdFenceLang  = regexp.MustCompile(`^[A-Za-z0-9_+-]+`)
+	mdSafeScheme = map[string]bool{"": true, "http": true, "https": true, "mailto": true}
+)
+
+type mdRenderer struct {
+	link func(string) string
+}
+
+// startsBlock returns whether the line interrupts a paragraph.
+func startsBlock(line string) bool {
+	return mdHeading.MatchString(line) || mdRule.MatchString(line) ||
+		mdFence.MatchString(line) || mdBullet.MatchString(line) ||
+		mdOrdered.MatchString(line) || mdQuote.MatchString(line)
+}
+
+func (r *mdRenderer) blocks(sb *strings.Builder, lines []string) {
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		switch {
+		case strings.TrimSpace(line) == "":
+			i++
+
+		case mdFence.MatchString(line):
+			m := mdFence.FindStringSubmatch(line)
+			fence := m[1]
+			i++
+			var code []string
+			for ; i < len(lines); i++ {
+				if t := strings.TrimSpace(lines[i]); strings.HasPrefix(t, fence) &&
+					strings.Trim(t, fence[:1]) == "" {
+					i++
+					break
+				}
+				code = append(code, lines[i])
+			}
+			sb.WriteString("<pre><code")
+			if lang := mdFenceLang.FindString(m[2]); lang != "" {
+				sb.WriteString(` class="language-` + lang + `"`)
+			}
+			sb.WriteString(">")
+			for _, l := range code {
+				sb.WriteString(html.EscapeString(l) + "\n")
+			}
+			sb.WriteString("</code></pre>\n")
+
+		case mdHeading.MatchString(line):
+			m := mdHeading.FindStringSubmatch(line)
+			tag := "h" + string(rune('0'+len(m[1])))
+			sb.WriteString("<" + tag + ">" + r.inline(m[2]) + "</" + tag + ">\n")
+			i++
+
+		case mdRule.MatchString(line):
+			sb.WriteString("<hr>\n")
+			i++
+
+		case mdQuote.MatchString(line):
+			var quoted []string
+			for ; i < len(lines) && mdQuote.MatchString(lines[i]); i++ {
+				quoted = append(quoted, mdQuote.FindStringSubmatch(lines[i])[1])
+			}
+			sb.WriteString("<blockquote>\n")
+			r.blocks(sb, quoted)
+			sb.WriteString("</blockquote>\n")
+
+		case mdBullet.MatchString(line), mdOrdered.MatchString(line):
+			i = r.list(sb, lines, i)
+
+		default:
+			var para []string
+			for ; i < len(lines); i++ {
+				if strings.TrimSpace(lines[i]) == "" ||
+					(len(para) != 0 && startsBlock(lines[i])) {
+					break
+				}
+				para = append(para, strings.TrimSpace(lines[i]))
+			}
+			sb.WriteString("<p>" + r.inline(strings.Join(para, "\n")) + "</p>\n")
+		}
+	}
+}
+
+// list renders the list starting at lines[i] and returns the index
+// of the line following it.  The nested lists are flattened.
+func (r *mdRenderer) list(sb *strings.Builder, lines []string, i int) int {
+	ordered := !mdBullet.MatchString(lines[i])
+	item := mdBullet
+	tag := "ul"
+	if ordered {
+		item, tag = mdOrdered, "ol"
+	}
+
+	sb.WriteString("<" + tag + ">\n")
+	for i < len(lines) {
+		m := item.FindStringSubmatch(lines[i])
+		if m == nil {
+			break
+		}
+		text := []string{m[2]}
+		for i++; i < len(lines); i++ {
+			l := lines[i]
+			if strings.TrimSpace(l) == "" || mdBullet.MatchString(l) ||
+				mdOrdered.MatchString(l) || !strings.HasPrefix(l, " ") && startsBlock(l) {
+				break
+			}
+			text = append(text, strings.TrimSpace(l))
+		}
+		sb.WriteString("<li>" + r.inline(strings.Join(text, "\n")) + "</li>\n")
+
+		// a blank line between the items doesn't end the list.
+		if i+1 < len(lines) && strings.TrimSpace(lines[i]) == "" &&
+			item.MatchString(lines[i+1]) {
+			i++
+		}
+	}
+	sb.WriteString("</" + tag + ">\n")
+	return i
+}
+
+// inline renders the spans of a block.
+func (r *mdRenderer) inline(s string) string {
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == '\\' && i+1 < len(s) && strings.IndexByte("\\`*_{}[]()#+-.!<>|~\"'", s[i+1]) != -1:
+			sb.WriteString(html.EscapeString(s[i+1 : i+2]))
+			i += 2
+			continue
+
+		case c == '`':
+			n := len(s[i:]) - len(strings.TrimLeft(s[i:], "`"))
+			ticks := s[i : i+n]
+			if end := strings.Index(s[i+n:], ticks); end != -1 {
+				code := strings.TrimSpace(s[i+n : i+n+end])
+				sb.WriteString("<code>" + html.EscapeString(code) + "</code>")
+				i += n + end + n
+				continue
+			}
+			sb.WriteString(ticks)
+			i += n
+			continue
+
+		case c == '!' && strings.HasPrefix(s[i+1:], "["):
+			if text, dest, n, ok := parseMdLink(s[i+1:]); ok {
+				if u, ok := r.target(dest); ok {
+					sb.WriteString(`<img src="` + html.EscapeString(u) +
+						`" alt="` + html.EscapeString(text) + `">`)
+				} else {
+					sb.WriteString(html.EscapeString(text))
+				}
+				i += 1 + n
+				continue
+			}
+
+		case c == '[':
+			if text, dest, n, ok := parseMdLink(s[i:]); ok {
+				if u, ok := r.target(dest); ok {
+					sb.WriteString(`<a href="` + html.EscapeString(u) + `">` +
+						r.inline(text) + "</a>")
+				} else {
+					sb.WriteString(r.inline(text))
+				}
+				i += n
+				continue
+			}
+
+		case c == '<':
+			if end := strings.IndexByte(s[i:], '>'); end != -1 {
+				dest := s[i+1 : i+end]
+				if u, err := url.Parse(dest); err == nil && u.IsAbs() &&
+					!strings.ContainsAny(dest, " \t\n") {
+					if t, ok := r.target(dest); ok {
+						sb.WriteString(`<a href="` + html.EscapeString(t) + `">` +
+							html.EscapeString(dest) + "</a>")
+						i += end + 1
+						continue
+					}
+				}
+			}
+
+		case c == '*' || c == '_' && (i == 0 || !isWordByte(s[i-1])):
+			delim := s[i : i+1]
+			if strings.HasPrefix(s[i:], delim+delim) {
+				delim += delim
+			}
+			rest := s[i+len(delim):]
+			if end := strings.Index(rest, delim); end > 0 &&
+				rest[0] != ' ' && rest[end-1] != ' ' {
+				tag := "em"
+				if len(delim) == 2 {
+					tag = "strong"
+				}
+				sb.WriteString("<" + tag + ">" + r.inline(rest[:end]) + "</" + tag + ">")
+				i += len(delim) + end + len(delim)
+				continue
+			}
+			sb.WriteString(delim)
+			i += len(delim)
+			continue
+		}
+
+		sb.WriteString(html.EscapeString(s[i : i+1]))
+		i++
+	}
+	return sb.String()
+}
+
+func isWordByte(c byte) bool {
+	return c == '_' || '0' <= c && c <= '9' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+// target returns the URL to use for the destination of a link or an
+// image, or false if its scheme is not safe.
+func (r *mdRenderer) target(dest string) (string, bool) {
+	u, err := url.Parse(dest)
+	if err != nil || !mdSafeScheme[strings.ToLower(u.Scheme)] {
+		return "", false
+	}
+	if !u.IsAbs() && r.link != nil && !strings.HasPrefix(dest, "#") {
+		dest = r.link(dest)
+	}
+	return dest, true
+}
+
+// parseMdLink parses the "[text](destination "title")" at the start
+// of s.  It returns the text, the destination and the length of the
+// link.
+func parseMdLink(s string) (text, dest string, n int, ok bool) {
+	depth := 0
+	closing := -1
+	for i := 0; i < len(s) && closing == -1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				closing = i
+			}
+		}
+	}
+	if closing == -1 || !strings.HasPrefix(s[closing+1:], "(") {
+		return "", "", 0, false
+	}
+	text = s[1:closing]
+
+	start := closing + 2
+	depth = 1
+	end := -1
+	for i := start; i < len(s) && end == -1; i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth == 0 {
+				end = i
+			}
+		}
+	}
+	if end == -1 {
+		return "", "", 0, false
+	}
+
+	inner := strings.TrimSpace(s[start:end])
+	if strings.HasPrefix(inner, "<") {
+		if gt := strings.IndexByte(inner, '>'); gt != -1 {
+			dest = inner[1:gt]
+		}
+	} else {
+		dest, _, _ = strings.Cut(inner, " ")
+	}
+	return text, dest, end + 1, true
+}
+
+// rewriteMarkdownLinks passes the destinations of the relative links
+// and images of the markdown document through link, leaving the rest
+// of the document untouched.
+func rewriteMarkdownLinks(src string, link func(string) string) string {
+	var sb strings.Builder
+	for {
+		i := strings.Index(src, "](")
+		if i == -1 {
+			break
+		}
+		sb.WriteString(src[:i+2])
+		src = src[i+2:]
+
+		end := strings.IndexAny(src, ") \n")
+		if end == -1 {
+			break
+		}
+		dest := src[:end]
+		if u, err := url.Parse(dest); err == nil && !u.IsAbs() &&
+			dest != "" && !strings.HasPrefix(dest, "#") {
+			dest = link(dest)
+		}
+		sb.WriteString(dest)
+		src = src[end:]
+	}
+	sb.WriteString(src)
+	return sb.String()
+}
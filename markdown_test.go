@@ -0,0 +1,46 @@
+package pkg
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct{ in, want string }{
+		{"# Title", "<h1>Title</h1>\n"},
+		{"### Sub ###", "<h3>Sub</h3>\n"},
+		{"some *em* and **strong** text", "<p>some <em>em</em> and <strong>strong</strong> text</p>\n"},
+		{"snake_case_name", "<p>snake_case_name</p>\n"},
+		{"use `a < b`", "<p>use <code>a &lt; b</code></p>\n"},
+		{"<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>\n"},
+		{"[site](https://plakar.io)", `<p><a href="https://plakar.io">site</a></p>` + "\n"},
+		{"[bad](javascript:alert(1))", "<p>bad</p>\n"},
+		{"![shot](img/shot.png)", `<p><img src="local:img/shot.png" alt="shot"></p>` + "\n"},
+		{"[top](#install)", `<p><a href="#install">top</a></p>` + "\n"},
+		{"<https://plakar.io>", `<p><a href="https://plakar.io">https://plakar.io</a></p>` + "\n"},
+		{"- one\n- two\n\n- three", "<ul>\n<li>one</li>\n<li>two</li>\n<li>three</li>\n</ul>\n"},
+		{"1. one\n2. two", "<ol>\n<li>one</li>\n<li>two</li>\n</ol>\n"},
+		{"> quoted\n> text", "<blockquote>\n<p>quoted\ntext</p>\n</blockquote>\n"},
+		{"```sh\n$ plakar <x>\n```", `<pre><code class="language-sh">$ plakar &lt;x&gt;` + "\n</code></pre>\n"},
+		{"para\n---", "<p>para</p>\n<hr>\n"},
+		{`\*not em\*`, "<p>*not em*</p>\n"},
+	}
+	link := func(ref string) string { return "local:" + ref }
+	for _, tt := range tests {
+		if got := renderMarkdown(tt.in, link); got != tt.want {
+			t.Errorf("renderMarkdown(%q) =\n%q\nwant\n%q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRewriteMarkdownLinks(t *testing.T) {
+	src := "See ![shot](img/shot.png), [docs](https://plakar.io) and [top](#top)."
+	got := rewriteMarkdownLinks(src, func(ref string) string { return "/assets/" + ref })
+	want := "See ![shot](/assets/img/shot.png), [docs](https://plakar.io) and [top](#top)."
+	if got != want {
+		t.Errorf("rewriteMarkdownLinks = %q, want %q", got, want)
+	}
+	if !strings.Contains(rewriteMarkdownLinks("no links", nil), "no links") {
+		t.Error("document without links changed")
+	}
+}
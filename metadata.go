@@ -0,0 +1,222 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var (
+	ErrBadMetadata      = errors.New("invalid repository metadata")
+	ErrMetadataExpired  = errors.New("repository metadata expired")
+	ErrMetadataRollback = errors.New("repository metadata older than already seen")
+	ErrNotInMetadata    = errors.New("not listed in the repository metadata")
+)
+
+// MetadataPolicy tells how the signed repository metadata is
+// enforced.
+type MetadataPolicy int
+
+const (
+	// MetadataIgnore doesn't fetch the metadata at all.
+	MetadataIgnore MetadataPolicy = iota
+
+	// MetadataIfPresent enforces the metadata when the
+	// repository serves it, and trusts the recipes and index as
+	// they are otherwise.
+	MetadataIfPresent
+
+	// MetadataRequired refuses the recipes and the index unless
+	// they are covered by valid and fresh metadata.
+	MetadataRequired
+)
+
+// The metadata roles, each served as "<role>.json" at the root of the
+// repository along with its signify(1) signature in "<role>.json.sig".
+// The timestamp role is short-lived and pins the checksum of the
+// targets one, which lists the checksums of the recipes and of the
+// integration index.  Both are signed by one of the
+// [Options.TrustedKeys], which act as the root of trust.
+const (
+	RoleTimestamp = "timestamp"
+	RoleTargets   = "targets"
+)
+
+// Metadata is a signed and expiring statement by the repository about
+// the content it serves.  Version increases with every update, so
+// that an older copy can't be replayed (rollback attack), and
+// Expires bounds how long a copy can be held back (freeze attack).
+type Metadata struct {
+	Role    string    `json:"role"`
+	Version int64     `json:"version"`
+	Expires time.Time `json:"expires"`
+
+	// Checksums, in the "algorithm:hexdigest" form, keyed by the
+	// path of the file relative to the host serving it:
+	// "targets.json" for the timestamp role, the recipe and index
+	// endpoints for the targets role.
+	Targets map[string]string `json:"targets"`
+}
+
+// targetsMetadata fetches and verifies the timestamp and targets
+// metadata as configured by the policy.  It returns nil when the
+// metadata is not to be enforced.
+func (p *Manager) targetsMetadata() (*Metadata, error) {
+	if p.mdpolicy == MetadataIgnore {
+		return nil, nil
+	}
+
+	ts, err := p.fetchMetadata(RoleTimestamp, nil)
+	if err != nil {
+		if p.mdpolicy == MetadataIfPresent && errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return p.fetchMetadata(RoleTargets, ts)
+}
+
+// fetchMetadata fetches the metadata for the given role and checks
+// its signature, freshness and version.  If parent is not nil, the
+// metadata must also match the checksum it lists.
+func (p *Manager) fetchMetadata(role string, parent *Metadata) (*Metadata, error) {
+	endp := role + ".json"
+	data, err := p.fetchBytes(p.repository, endp, false)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := p.fetchBytes(p.repository, endp+".sig", false)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch the %s metadata signature: %w", role, err)
+	}
+	if err := verifySignature(p.trustedkeys, data, sig); err != nil {
+		return nil, fmt.Errorf("%s metadata: %w", role, err)
+	}
+
+	if parent != nil {
+		if err := p.checkTarget(parent, endp, data); err != nil {
+			return nil, err
+		}
+	}
+
+	var md Metadata
+	if err := json.Unmarshal(data, &md); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadMetadata, err)
+	}
+	if md.Role != role {
+		return nil, fmt.Errorf("%w: got role %q instead of %q", ErrBadMetadata, md.Role, role)
+	}
+	if !time.Now().Before(md.Expires) {
+		return nil, fmt.Errorf("%w: %s metadata expired at %s", ErrMetadataExpired,
+			role, md.Expires.Format(time.RFC3339))
+	}
+
+	if err := p.seeMetadata(role, md.Version); err != nil {
+		return nil, err
+	}
+	return &md, nil
+}
+
+// seeMetadata records the version of the metadata for the role,
+// failing if an higher one was already seen.
+func (p *Manager) seeMetadata(role string, version int64) error {
+	p.mdmu.Lock()
+	defer p.mdmu.Unlock()
+
+	if p.mdseen == nil {
+		p.mdseen = p.loadMetadataState()
+	}
+
+	seen := p.mdseen[role]
+	if version < seen {
+		return fmt.Errorf("%w: %s metadata version %d, %d already seen",
+			ErrMetadataRollback, role, version, seen)
+	}
+	if version > seen {
+		p.mdseen[role] = version
+		p.saveMetadataState()
+	}
+	return nil
+}
+
+// checkTarget verifies data against the checksum md lists for the
+// target.
+func (p *Manager) checkTarget(md *Metadata, target string, data []byte) error {
+	if md == nil {
+		return nil
+	}
+
+	checksum, ok := md.Targets[target]
+	if !ok {
+		return fmt.Errorf("%s: %w", target, ErrNotInMetadata)
+	}
+
+	rd, err := newVerifyingReader(bytes.NewReader(data), checksum, p.minstrength)
+	if err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+	if _, err := io.Copy(io.Discard, rd); err != nil {
+		return fmt.Errorf("%s: %w", target, err)
+	}
+	return nil
+}
+
+// loadMetadataState reads the metadata versions seen so far from the
+// state file, if one is configured.
+func (p *Manager) loadMetadataState() map[string]int64 {
+	seen := map[string]int64{}
+	if p.mdstate == "" {
+		return seen
+	}
+
+	data, err := os.ReadFile(p.mdstate)
+	if err != nil {
+		return seen
+	}
+	json.Unmarshal(data, &seen)
+	return seen
+}
+
+// saveMetadataState writes the metadata versions seen so far to the
+// state file, if one is configured.  Failing to do so only weakens
+// the rollback protection across restarts.
+func (p *Manager) saveMetadataState() {
+	if p.mdstate == "" {
+		return
+	}
+
+	data, err := json.Marshal(p.mdseen)
+	if err != nil {
+		return
+	}
+
+	if err := os.MkdirAll(filepath.Dir(p.mdstate), 0755); err != nil {
+		return
+	}
+	writeFile(OSFS{}, p.mdstate, data)
+}
@@ -0,0 +1,131 @@
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const metadataRecipe = "name: s3\nversion: v1.0.0\n"
+
+// metadataRepository serves a signed recipe for s3 along with the
+// last published timestamp and targets metadata, unless disabled.
+type metadataRepository struct {
+	signer   *testSigner
+	files    map[string]string
+	recipe   string
+	disabled bool
+}
+
+func (r *metadataRepository) publish(t *testing.T, version int64, expires time.Time, targets map[string]string) {
+	t.Helper()
+	encode := func(md Metadata) string {
+		data, err := json.Marshal(md)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+
+	tg := encode(Metadata{Role: RoleTargets, Version: version, Expires: expires, Targets: targets})
+	ts := encode(Metadata{Role: RoleTimestamp, Version: version, Expires: expires,
+		Targets: map[string]string{"targets.json": sha256sum(tg)}})
+
+	r.files = map[string]string{
+		"targets.json":       tg,
+		"targets.json.sig":   r.signer.sign([]byte(tg)),
+		"timestamp.json":     ts,
+		"timestamp.json.sig": r.signer.sign([]byte(ts)),
+	}
+}
+
+func (r *metadataRepository) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	name := strings.TrimPrefix(req.URL.Path, "/")
+	switch {
+	case strings.HasSuffix(name, "recipe.yaml"):
+		w.Write([]byte(r.recipe))
+	case strings.HasSuffix(name, "recipe.yaml.sig"):
+		w.Write([]byte(r.signer.sign([]byte(r.recipe))))
+	case !r.disabled && r.files[name] != "":
+		w.Write([]byte(r.files[name]))
+	default:
+		http.NotFound(w, req)
+	}
+}
+
+func TestMetadata(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	repo := &metadataRepository{signer: signer, recipe: metadataRecipe}
+	srv := httptest.NewServer(repo)
+	defer srv.Close()
+
+	target := PLUGIN_API_VERSION + "/s3/recipe.yaml"
+	future := time.Now().Add(time.Hour)
+	state := filepath.Join(t.TempDir(), "metadata.json")
+
+	newManager := func(policy MetadataPolicy) *Manager {
+		m, err := New(newFakeBackend(), &Options{
+			InstallURL:        srv.URL,
+			TrustedKeys:       []*PublicKey{signer.key(t)},
+			MetadataPolicy:    policy,
+			MetadataStateFile: state,
+		})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		return m
+	}
+
+	repo.publish(t, 2, future, map[string]string{target: sha256sum(metadataRecipe)})
+	m := newManager(MetadataRequired)
+	if _, err := m.FetchRecipe("s3"); err != nil {
+		t.Fatalf("FetchRecipe with fresh metadata: %v", err)
+	}
+
+	repo.publish(t, 2, future, map[string]string{target: sha256sum("name: evil\n")})
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrChecksumMismatch) {
+		t.Errorf("recipe not matching the metadata err = %v, want ErrChecksumMismatch", err)
+	}
+
+	repo.publish(t, 2, future, map[string]string{})
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrNotInMetadata) {
+		t.Errorf("unlisted recipe err = %v, want ErrNotInMetadata", err)
+	}
+
+	repo.publish(t, 3, time.Now().Add(-time.Minute), map[string]string{target: sha256sum(metadataRecipe)})
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrMetadataExpired) {
+		t.Errorf("expired metadata err = %v, want ErrMetadataExpired", err)
+	}
+
+	// the version seen is persisted, so a new manager refuses the
+	// rollback too.
+	repo.publish(t, 1, future, map[string]string{target: sha256sum(metadataRecipe)})
+	for _, m := range []*Manager{m, newManager(MetadataRequired)} {
+		if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrMetadataRollback) {
+			t.Errorf("older metadata err = %v, want ErrMetadataRollback", err)
+		}
+	}
+
+	repo.publish(t, 4, future, map[string]string{target: sha256sum(metadataRecipe)})
+	repo.files["targets.json.sig"] = newTestSigner(t, 1).sign([]byte(repo.files["targets.json"]))
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("badly signed metadata err = %v, want ErrBadSignature", err)
+	}
+
+	repo.disabled = true
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing metadata err = %v, want ErrNotFound", err)
+	}
+	if _, err := newManager(MetadataIfPresent).FetchRecipe("s3"); err != nil {
+		t.Errorf("FetchRecipe without metadata when optional: %v", err)
+	}
+
+	if _, err := New(newFakeBackend(), &Options{MetadataPolicy: MetadataRequired}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("New without trusted keys err = %v, want ErrInvalidOptions", err)
+	}
+}
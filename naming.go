@@ -0,0 +1,86 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"path"
+)
+
+// NamingScheme defines where a backend stores each package.
+type NamingScheme interface {
+	// Path returns the slash-separated path of the package,
+	// relative to the root of the store.
+	Path(*Package) string
+
+	// Parse returns the package stored at the given path, or an
+	// error if it's not where a package would be stored.
+	Parse(path string) (*Package, error)
+}
+
+// FlatNaming stores the packages as "name_version_os_arch.ptar"
+// files, all in the same directory.  It's the default.
+type FlatNaming struct {
+	Versions VersionScheme // defaults to SemverScheme
+}
+
+func (n FlatNaming) Path(pkg *Package) string {
+	return pkg.Filename()
+}
+
+func (n FlatNaming) Parse(p string) (*Package, error) {
+	if path.Dir(p) != "." {
+		return nil, fmt.Errorf("%w %q: not at the root", ErrBadPackageName, p)
+	}
+	return parseFilename(p, n.Versions)
+}
+
+// NestedNaming stores the packages in a directory per name, as
+// "name/name_version_os_arch.ptar".
+type NestedNaming struct {
+	Versions VersionScheme // defaults to SemverScheme
+}
+
+func (n NestedNaming) Path(pkg *Package) string {
+	return path.Join(escapeName(pkg.Name), pkg.Filename())
+}
+
+func (n NestedNaming) Parse(p string) (*Package, error) {
+	pkg, err := parseFilename(path.Base(p), n.Versions)
+	if err != nil {
+		return nil, err
+	}
+	if path.Dir(p) != escapeName(pkg.Name) {
+		return nil, fmt.Errorf("%w %q: not in the directory of %s",
+			ErrBadPackageName, p, pkg.Name)
+	}
+	return pkg, nil
+}
+
+func parseFilename(name string, versions VersionScheme) (*Package, error) {
+	if versions == nil {
+		versions = SemverScheme
+	}
+
+	var pkg Package
+	if err := pkg.parseNameWith(name, versions); err != nil {
+		return nil, err
+	}
+	return &pkg, nil
+}
@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestNamingSchemes(t *testing.T) {
+	pkg := &Package{Name: "my_s3", Version: "v1.0.0", OperatingSystem: "linux", Architecture: "amd64"}
+
+	tests := []struct {
+		scheme NamingScheme
+		path   string
+	}{
+		{FlatNaming{}, "my%5Fs3_v1.0.0_linux_amd64.ptar"},
+		{NestedNaming{}, "my%5Fs3/my%5Fs3_v1.0.0_linux_amd64.ptar"},
+	}
+	for _, tt := range tests {
+		if got := tt.scheme.Path(pkg); got != tt.path {
+			t.Errorf("%T.Path = %q, want %q", tt.scheme, got, tt.path)
+		}
+		got, err := tt.scheme.Parse(tt.path)
+		if err != nil {
+			t.Fatalf("%T.Parse(%q): %v", tt.scheme, tt.path, err)
+		}
+		if *got != *pkg {
+			t.Errorf("%T.Parse(%q) = %+v, want %+v", tt.scheme, tt.path, got, pkg)
+		}
+	}
+
+	if _, err := (FlatNaming{}).Parse("s3/s3_v1.0.0_linux_amd64.ptar"); err == nil {
+		t.Error("FlatNaming parsed a nested path")
+	}
+	if _, err := (NestedNaming{}).Parse("sftp/s3_v1.0.0_linux_amd64.ptar"); err == nil {
+		t.Error("NestedNaming parsed a package in the wrong directory")
+	}
+	if _, err := (NestedNaming{Versions: LooseScheme}).Parse("s3/s3_1.0.0.1_linux_amd64.ptar"); err != nil {
+		t.Errorf("NestedNaming with LooseScheme: %v", err)
+	}
+}
+
+func TestFlatBackendNestedNaming(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{NamingScheme: NestedNaming{}})
+
+	for _, p := range []*Package{pkgVer("s3", "v1.0.0"), pkgVer("sftp", "v2.0.0")} {
+		dir := filepath.Join(pkgdir, p.Name)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, dir, p.Filename())
+	}
+	// not where NestedNaming puts packages.
+	touch(t, pkgdir, pkgVer("fs", "v1.0.0").Filename())
+
+	var got []string
+	for pkg, err := range be.List("") {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		got = append(got, pkg.Name)
+	}
+	if len(got) != 2 || got[0] != "s3" || got[1] != "sftp" {
+		t.Errorf("List = %v", got)
+	}
+
+	if err := be.Unload(pkgVer("s3", "v1.0.0")); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(pkgdir, "s3")); !os.IsNotExist(err) {
+		t.Errorf("empty package directory left behind: %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// localRecipe reads the recipe for the named package from the
+// overrides directory.  It returns nil if there is none.
+func (p *Manager) localRecipe(name string) (*Recipe, error) {
+	if p.overrides == "" {
+		return nil, nil
+	}
+
+	r, err := NewRecipeFromFile(filepath.Join(p.overrides, name+".yaml"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	return r, err
+}
+
+// openOverride opens the artifact the local recipe gives for the
+// package, if any.  It returns a nil reader when the package is not
+// overridden for this version and platform, and must be fetched from
+// the repository as usual.
+func (p *Manager) openOverride(pkg *Package) (io.ReadCloser, int64, error) {
+	r, err := p.localRecipe(pkg.Name)
+	if err != nil || r == nil || r.Semver() != pkg.Version {
+		return nil, 0, err
+	}
+
+	artifact := r.Artifact(pkg.OperatingSystem, pkg.Architecture)
+	if artifact == "" {
+		return nil, 0, nil
+	}
+
+	if strings.HasPrefix(artifact, "http://") || strings.HasPrefix(artifact, "https://") {
+		req, err := http.NewRequest(http.MethodGet, artifact, nil)
+		if err != nil {
+			return nil, 0, err
+		}
+		checksum, err := p.fetchSidecar(artifact)
+		if err != nil {
+			return nil, 0, err
+		}
+		sig, err := p.gpgSignature(artifact, func(suffix string) ([]byte, error) {
+			return p.fetchOptional(artifact + suffix)
+		})
+		if err != nil {
+			return nil, 0, err
+		}
+		resp, err := p.do(req, http.StatusOK)
+		if err != nil {
+			return nil, 0, err
+		}
+		rc, err := p.verifySidecar(resp.Body, checksum)
+		if err != nil {
+			return nil, 0, err
+		}
+		rc, err = p.verifyGPG(rc, sig)
+		return rc, resp.ContentLength, err
+	}
+
+	if !filepath.IsAbs(artifact) {
+		artifact = filepath.Join(p.overrides, artifact)
+	}
+	size := int64(-1)
+	if st, err := os.Stat(artifact); err == nil {
+		size = st.Size()
+	}
+	rc, err := p.openLocal(artifact)
+	return rc, size, err
+}
@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestRecipeOverrides(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "recipe.yaml") {
+			io.WriteString(w, "name: "+path.Base(path.Dir(r.URL.Path))+"\nversion: v1.0.0\n")
+			return
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "s3.ptar"), []byte("LOCALDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	recipe := fmt.Sprintf("name: s3\nversion: v1.1.0\nartifacts:\n  %s: s3.ptar\nchecksums:\n  %s: %s\n",
+		platform, platform, sha256sum("LOCALDATA"))
+	if err := os.WriteFile(filepath.Join(dir, "s3.yaml"), []byte(recipe), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL, RecipeOverridesDir: dir})
+
+	r, err := m.FetchRecipe("s3")
+	if err != nil {
+		t.Fatalf("FetchRecipe: %v", err)
+	}
+	if r.Version != "v1.1.0" {
+		t.Errorf("recipe version = %q, want the local v1.1.0", r.Version)
+	}
+	if r, err := m.FetchRecipe("fs"); err != nil || r.Version != "v1.0.0" {
+		t.Errorf("FetchRecipe(fs) = %+v, %v, want the remote one", r, err)
+	}
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	want := "s3_v1.1.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".ptar"
+	if string(be.loadData[want]) != "LOCALDATA" {
+		t.Errorf("loaded data = %q, want the local artifact", be.loadData[want])
+	}
+
+	// other versions come from the repository.
+	be = newFakeBackend()
+	m, _ = New(be, &Options{InstallURL: srv.URL, RecipeOverridesDir: dir})
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add v1.0.0: %v", err)
+	}
+	want = "s3_v1.0.0_" + runtime.GOOS + "_" + runtime.GOARCH + ".ptar"
+	if string(be.loadData[want]) != "PTARDATA" {
+		t.Errorf("loaded data = %q, want PTARDATA", be.loadData[want])
+	}
+}
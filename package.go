@@ -22,8 +22,11 @@ import (
 	"errors"
 	"fmt"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"golang.org/x/mod/semver"
+	"golang.org/x/text/unicode/norm"
 )
 
 var (
@@ -38,6 +41,12 @@ type Package struct {
 }
 
 func (pkg *Package) parseName(name string) error {
+	return pkg.parseNameWith(name, SemverScheme)
+}
+
+// parseNameWith is like parseName but validates the version with
+// the given scheme.
+func (pkg *Package) parseNameWith(name string, scheme VersionScheme) error {
 	baseName, has := strings.CutSuffix(name, ".ptar")
 	if !has {
 		return fmt.Errorf("%w %q: does not end with .ptar",
@@ -49,34 +58,77 @@ func (pkg *Package) parseName(name string) error {
 		return fmt.Errorf("%w %q: is malformed", ErrBadPackageName, name)
 	}
 
-	pkg.Name = atoms[0]
+	pkg.Name = unescapeName(atoms[0])
 	pkg.Version = atoms[1]
 	pkg.OperatingSystem = atoms[2]
 	pkg.Architecture = atoms[3]
 
-	return pkg.Validate()
+	return pkg.ValidateWith(scheme)
 }
 
-func isNameChar(c byte) bool {
-	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' || c == '-'
+// Names may contain any letter or digit, and some punctuation.  As
+// '_' separates the fields of the filenames, it's escaped there.
+func isNameChar(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.'
 }
 
-func isOsArchChar(c byte) bool {
-	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9'
+const escapedUnderscore = "%5F"
+
+func escapeName(name string) string {
+	return strings.ReplaceAll(name, "_", escapedUnderscore)
 }
 
-func (pkg *Package) Validate() error {
-	if pkg.Name == "" {
+// unescapeName undoes escapeName and normalizes the name, since
+// some filesystems store the names decomposed.
+func unescapeName(name string) string {
+	return norm.NFC.String(strings.ReplaceAll(name, escapedUnderscore, "_"))
+}
+
+// NormalizeName returns the canonical form of a package name: the
+// same name written with combined or decomposed characters must
+// refer to the same package.
+func NormalizeName(name string) string {
+	return norm.NFC.String(name)
+}
+
+// validName checks a package or profile name.
+func validName(name string) error {
+	if name == "" {
 		return ErrBadPackageName
 	}
-	for i := 0; i < len(pkg.Name); i++ {
-		if !isNameChar(pkg.Name[i]) {
-			return fmt.Errorf("%w %q: contains invalid char '%c",
-				ErrBadPackageName, pkg.Name, pkg.Name[i])
+	if !utf8.ValidString(name) || !norm.NFC.IsNormalString(name) {
+		return fmt.Errorf("%w %q: not in normal form", ErrBadPackageName, name)
+	}
+	// hidden files are not listed.
+	if name[0] == '.' {
+		return fmt.Errorf("%w %q: starts with a dot", ErrBadPackageName, name)
+	}
+	for _, r := range name {
+		if !isNameChar(r) {
+			return fmt.Errorf("%w %q: contains invalid char '%c'",
+				ErrBadPackageName, name, r)
 		}
 	}
+	return nil
+}
+
+func isOsArchChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9'
+}
+
+// Validate checks the package, expecting a semantic version.
+func (pkg *Package) Validate() error {
+	return pkg.ValidateWith(SemverScheme)
+}
+
+// ValidateWith checks the package, validating the version with the
+// given scheme.
+func (pkg *Package) ValidateWith(scheme VersionScheme) error {
+	if err := validName(pkg.Name); err != nil {
+		return err
+	}
 
-	if !semver.IsValid(pkg.Version) {
+	if !scheme.Valid(pkg.Version) {
 		return fmt.Errorf("%w: invalid version %q", ErrBadPackageName, pkg.Version)
 	}
 
@@ -98,5 +150,19 @@ func (pkg *Package) Validate() error {
 }
 
 func (p *Package) Filename() string {
-	return fmt.Sprintf("%s_%s_%s_%s.ptar", p.Name, p.Version, p.OperatingSystem, p.Architecture)
+	return fmt.Sprintf("%s_%s_%s_%s.ptar", escapeName(p.Name), p.Version, p.OperatingSystem, p.Architecture)
+}
+
+// Prerelease returns the prerelease part of a semantic version, like
+// "-rc.1", or the empty string.
+func (p *Package) Prerelease() string {
+	return semver.Prerelease(p.Version)
+}
+
+// Build returns the build metadata of a semantic version, like
+// "+build.2024", or the empty string.  It's ignored when comparing
+// versions, but two builds of the same version are still different
+// packages.
+func (p *Package) Build() string {
+	return semver.Build(p.Version)
 }
@@ -78,8 +78,21 @@ func TestPackageValidate(t *testing.T) {
 			wantErr: true,
 		},
 		{
-			name:    "underscore in name is invalid",
-			pkg:     Package{Name: "s3_extra", Version: "v1.2.3", OperatingSystem: "linux", Architecture: "amd64"},
+			name: "underscore and dot in name",
+			pkg:  Package{Name: "s3_extra.v2", Version: "v1.2.3", OperatingSystem: "linux", Architecture: "amd64"},
+		},
+		{
+			name: "non-ASCII name",
+			pkg:  Package{Name: "stockage-européen", Version: "v1.2.3", OperatingSystem: "linux", Architecture: "amd64"},
+		},
+		{
+			name:    "leading dot in name",
+			pkg:     Package{Name: ".s3", Version: "v1.2.3", OperatingSystem: "linux", Architecture: "amd64"},
+			wantErr: true,
+		},
+		{
+			name:    "decomposed name",
+			pkg:     Package{Name: "europe\u0301en", Version: "v1.2.3", OperatingSystem: "linux", Architecture: "amd64"},
 			wantErr: true,
 		},
 		{
@@ -118,3 +131,45 @@ func TestPackageFilenameRoundTrip(t *testing.T) {
 		t.Errorf("round-trip mismatch: got %+v, want %+v", got, orig)
 	}
 }
+
+func TestPackageBuildMetadataRoundTrip(t *testing.T) {
+	orig := Package{Name: "s3", Version: "v1.0.7-rc.1+build.2024", OperatingSystem: "linux", Architecture: "amd64"}
+
+	var got Package
+	if err := got.parseName(orig.Filename()); err != nil {
+		t.Fatalf("parseName(%q): %v", orig.Filename(), err)
+	}
+	if got != orig {
+		t.Errorf("round-trip mismatch: got %+v, want %+v", got, orig)
+	}
+	if got.Prerelease() != "-rc.1" || got.Build() != "+build.2024" {
+		t.Errorf("Prerelease/Build = %q/%q", got.Prerelease(), got.Build())
+	}
+}
+
+func TestPackageNameEscaping(t *testing.T) {
+	orig := Package{Name: "my_plugin", Version: "v1.0.0", OperatingSystem: "linux", Architecture: "amd64"}
+	if got, want := orig.Filename(), "my%5Fplugin_v1.0.0_linux_amd64.ptar"; got != want {
+		t.Errorf("Filename() = %q, want %q", got, want)
+	}
+
+	var got Package
+	if err := got.parseName(orig.Filename()); err != nil {
+		t.Fatalf("parseName(%q): %v", orig.Filename(), err)
+	}
+	if got != orig {
+		t.Errorf("round-trip mismatch: got %+v, want %+v", got, orig)
+	}
+}
+
+// Some filesystems hand back decomposed names; they must parse into
+// the same package.
+func TestPackageParseNameNormalizes(t *testing.T) {
+	var p Package
+	if err := p.parseName("europe\u0301en_v1.0.0_linux_amd64.ptar"); err != nil {
+		t.Fatalf("parseName: %v", err)
+	}
+	if p.Name != "europ\u00e9en" || p.Name != NormalizeName("europe\u0301en") {
+		t.Errorf("Name = %q", p.Name)
+	}
+}
@@ -0,0 +1,90 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+var (
+	ErrCertificateNotPinned = errors.New("certificate doesn't match any pinned key")
+)
+
+// parsePins decodes the given SPKI pins, i.e. the base64 encoded
+// SHA-256 of a certificate' SubjectPublicKeyInfo, optionally prefixed
+// by "sha256/".
+func parsePins(pins []string) ([][]byte, error) {
+	var ret [][]byte
+	for _, pin := range pins {
+		b, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(pin, "sha256/"))
+		if err != nil || len(b) != sha256.Size {
+			return nil, fmt.Errorf("%w: bad certificate pin %q", ErrInvalidOptions, pin)
+		}
+		ret = append(ret, b)
+	}
+	return ret, nil
+}
+
+// pinTransport returns a copy of the given transport that refuses TLS
+// connections unless one of the certificates in the verified chain
+// has a pinned public key.
+func pinTransport(t *http.Transport, pins [][]byte) *http.Transport {
+	t = t.Clone()
+	if t.TLSClientConfig == nil {
+		t.TLSClientConfig = &tls.Config{}
+	}
+
+	t.TLSClientConfig.VerifyConnection = func(cs tls.ConnectionState) error {
+		for _, chain := range cs.VerifiedChains {
+			for _, cert := range chain {
+				sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+				for _, pin := range pins {
+					if bytes.Equal(sum[:], pin) {
+						return nil
+					}
+				}
+			}
+		}
+		return ErrCertificateNotPinned
+	}
+	return t
+}
+
+// client returns the HTTP client to use for the given host.  The
+// pins apply to the repository and to the API, which provides the
+// checksums and the advisories.
+func (p *Manager) client(host string) *http.Client {
+	if p.pinnedclient == nil {
+		return http.DefaultClient
+	}
+	for _, u := range []*url.URL{p.repository, p.api} {
+		if u != nil && host == u.Host {
+			return p.pinnedclient
+		}
+	}
+	return http.DefaultClient
+}
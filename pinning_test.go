@@ -0,0 +1,151 @@
+package pkg
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"errors"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func spkiPin(t *testing.T, srv *httptest.Server) string {
+	t.Helper()
+	sum := sha256.Sum256(srv.Certificate().RawSubjectPublicKeyInfo)
+	return "sha256/" + base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func TestParsePins(t *testing.T) {
+	sum := sha256.Sum256([]byte("key"))
+	pin := base64.StdEncoding.EncodeToString(sum[:])
+
+	pins, err := parsePins([]string{pin, "sha256/" + pin})
+	if err != nil {
+		t.Fatalf("parsePins: %v", err)
+	}
+	if len(pins) != 2 {
+		t.Errorf("got %d pins, want 2", len(pins))
+	}
+
+	for _, bad := range []string{"sha256/!!", "c2hvcnQ="} {
+		if _, err := parsePins([]string{bad}); !errors.Is(err, ErrInvalidOptions) {
+			t.Errorf("parsePins(%q) err = %v, want ErrInvalidOptions", bad, err)
+		}
+	}
+
+	if _, err := New(newFakeBackend(), &Options{PinnedCertificates: []string{"bad"}}); err == nil {
+		t.Error("New accepted an invalid pin")
+	}
+}
+
+// newTLSServerWithKey starts a TLS test server with a certificate of
+// its own: all the httptest servers share the same built-in one.
+func newTLSServerWithKey(t *testing.T, h http.Handler) *httptest.Server {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "pkg test"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IPAddresses:           []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback},
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	srv := httptest.NewUnstartedServer(h)
+	srv.TLS = &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	}
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestPinnedCertificates(t *testing.T) {
+	recipe := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "name: s3\nversion: v1.2.3\n")
+	})
+	srv := httptest.NewTLSServer(recipe)
+	defer srv.Close()
+	other := newTLSServerWithKey(t, recipe)
+
+	if spkiPin(t, srv) == spkiPin(t, other) {
+		t.Fatal("both servers have the same key")
+	}
+
+	// trust both servers in the transport New builds upon.
+	roots := x509.NewCertPool()
+	roots.AddCert(srv.Certificate())
+	roots.AddCert(other.Certificate())
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = &tls.Config{RootCAs: roots}
+	saved := http.DefaultTransport
+	http.DefaultTransport = transport
+	t.Cleanup(func() { http.DefaultTransport = saved })
+
+	for _, tt := range []struct {
+		name    string
+		url     string
+		pin     string
+		wantErr bool
+	}{
+		{"matching pin", srv.URL, spkiPin(t, srv), false},
+		{"other pin", srv.URL, spkiPin(t, other), true},
+		{"own certificate", other.URL, spkiPin(t, other), false},
+		{"own certificate, other pin", other.URL, spkiPin(t, srv), true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := New(newFakeBackend(), &Options{
+				InstallURL:         tt.url,
+				PinnedCertificates: []string{tt.pin},
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			_, err = m.FetchRecipe("s3")
+			if tt.wantErr {
+				if !errors.Is(err, ErrCertificateNotPinned) {
+					t.Errorf("FetchRecipe err = %v, want ErrCertificateNotPinned", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Errorf("FetchRecipe: %v", err)
+			}
+		})
+	}
+
+	t.Run("api", func(t *testing.T) {
+		m, err := New(newFakeBackend(), &Options{
+			ApiURL:             srv.URL,
+			PinnedCertificates: []string{spkiPin(t, other)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := m.fetchIndex(); !errors.Is(err, ErrCertificateNotPinned) {
+			t.Errorf("fetchIndex err = %v, want ErrCertificateNotPinned", err)
+		}
+	})
+}
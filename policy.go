@@ -0,0 +1,205 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrPolicyViolation = errors.New("installation refused by policy")
+)
+
+// InstallRequest describes a package about to be installed, for the
+// [Options.InstallPolicy] to decide on.  What comes from the
+// integration index is left empty when the package isn't found there.
+type InstallRequest struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Stage   string `json:"stage"`
+
+	Publisher Publisher `json:"publisher"`
+	License   string    `json:"license,omitempty"`
+
+	// When the version was released, if known.
+	Released time.Time `json:"released,omitzero"`
+
+	// Whether the package is verified against a signature before
+	// being installed.
+	Signed bool `json:"signed"`
+
+	// Whether the package comes from a local file rather than
+	// from the repository.
+	Local bool `json:"local"`
+}
+
+// PolicyViolation is the error returned when the install policy
+// refuses a package.  It's recorded as is in the audit log.
+type PolicyViolation struct {
+	Request *InstallRequest `json:"request"`
+	Rule    string          `json:"rule,omitempty"`
+	Reason  string          `json:"reason"`
+}
+
+func (e *PolicyViolation) Error() string {
+	msg := fmt.Sprintf("%s %s: %s: %s", e.Request.Name, e.Request.Version,
+		ErrPolicyViolation, e.Reason)
+	if e.Rule != "" {
+		msg += " (rule " + e.Rule + ")"
+	}
+	return msg
+}
+
+func (e *PolicyViolation) Unwrap() error {
+	return ErrPolicyViolation
+}
+
+type PolicyAction int
+
+const (
+	PolicyAllow PolicyAction = iota
+	PolicyDeny
+)
+
+// PolicyRule matches the install requests meeting all of its
+// criteria; the empty ones match everything.
+type PolicyRule struct {
+	// Name identifying the rule in the violations.
+	Name   string
+	Action PolicyAction
+
+	// path.Match patterns of the package names.
+	Names []string
+
+	// Organizations of the publishers.  Only the verified ones
+	// are considered.
+	Publishers []string
+
+	// Licenses, as declared by the index.
+	Licenses []string
+
+	// Release stages, e.g. StageBeta.
+	Stages []string
+
+	// Match the versions released less than this long ago, or
+	// whose release date is not known.
+	YoungerThan time.Duration
+
+	// Match the packages that are not verified against a
+	// signature.
+	Unsigned bool
+}
+
+func (r *PolicyRule) matches(req *InstallRequest, now time.Time) bool {
+	if len(r.Names) != 0 && !slices.ContainsFunc(r.Names, func(pattern string) bool {
+		ok, _ := path.Match(pattern, req.Name)
+		return ok
+	}) {
+		return false
+	}
+	if len(r.Publishers) != 0 && (!req.Publisher.Verified ||
+		!slices.Contains(r.Publishers, req.Publisher.Organization)) {
+		return false
+	}
+	if len(r.Licenses) != 0 && !slices.ContainsFunc(r.Licenses, func(l string) bool {
+		return strings.EqualFold(l, req.License)
+	}) {
+		return false
+	}
+	if len(r.Stages) != 0 && !slices.Contains(r.Stages, req.Stage) {
+		return false
+	}
+	if r.YoungerThan != 0 && !req.Released.IsZero() &&
+		now.Sub(req.Released) >= r.YoungerThan {
+		return false
+	}
+	if r.Unsigned && req.Signed {
+		return false
+	}
+	return true
+}
+
+// Policy is a list of rules, the first one matching a request
+// deciding whether it's allowed.  The requests no rule matches get
+// the Default action.  Its Check method is meant to be passed as
+// [Options.InstallPolicy].
+type Policy struct {
+	Rules   []PolicyRule
+	Default PolicyAction
+}
+
+func (pol *Policy) Check(req *InstallRequest) error {
+	now := time.Now()
+	for i := range pol.Rules {
+		r := &pol.Rules[i]
+		if !r.matches(req, now) {
+			continue
+		}
+		if r.Action == PolicyAllow {
+			return nil
+		}
+		return &PolicyViolation{Request: req, Rule: r.Name, Reason: "denied by rule"}
+	}
+
+	if pol.Default == PolicyAllow {
+		return nil
+	}
+	return &PolicyViolation{Request: req, Reason: "not allowed by any rule"}
+}
+
+// checkPolicy evaluates the install policy, if any, for the given
+// version.
+func (p *Manager) checkPolicy(name, version string, local bool) error {
+	if p.installpolicy == nil {
+		return nil
+	}
+
+	req := &InstallRequest{
+		Name:    name,
+		Version: version,
+		Stage:   versionStage(version),
+		Local:   local,
+	}
+	if local {
+		req.Signed = p.sigpolicy == SignatureRequired
+	} else {
+		req.Signed = len(p.trustedkeys) != 0
+	}
+
+	// the index is only a source of information here: the policy
+	// decides what to do with the requests it can't describe.
+	if plug, err := p.integration(name); err == nil {
+		req.Publisher = plug.Publisher
+		p.verifyPublisher(&req.Publisher)
+		req.License = plug.License
+		if plug.Version == version && plug.Stats != nil {
+			req.Released = plug.Stats.LastRelease
+		}
+	}
+
+	err := p.installpolicy(req)
+	if err != nil && !errors.Is(err, ErrPolicyViolation) {
+		err = &PolicyViolation{Request: req, Reason: err.Error()}
+	}
+	return err
+}
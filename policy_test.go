@@ -0,0 +1,109 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestPolicyCheck(t *testing.T) {
+	pol := &Policy{
+		Rules: []PolicyRule{
+			{Name: "no-gpl", Action: PolicyDeny, Licenses: []string{"GPL-3.0"}},
+			{Name: "quarantine", Action: PolicyDeny, YoungerThan: 7 * 24 * time.Hour},
+			{Name: "official", Action: PolicyAllow, Publishers: []string{OfficialPublisher}},
+			{Name: "testing", Action: PolicyAllow, Names: []string{"s3*"}, Stages: []string{StageStable}},
+		},
+		Default: PolicyDeny,
+	}
+
+	official := Publisher{Organization: OfficialPublisher, Verified: true}
+	old := time.Now().Add(-30 * 24 * time.Hour)
+	tests := []struct {
+		req  InstallRequest
+		ok   bool
+		rule string
+	}{
+		{InstallRequest{Name: "ftp", Publisher: official, Released: old}, true, ""},
+		{InstallRequest{Name: "ftp", Publisher: official, Released: old, License: "gpl-3.0"}, false, "no-gpl"},
+		{InstallRequest{Name: "ftp", Publisher: official, Released: time.Now()}, false, "quarantine"},
+		// an unknown release date is treated as a recent one.
+		{InstallRequest{Name: "ftp", Publisher: official}, false, "quarantine"},
+		// unverified publisher: falls to the default.
+		{InstallRequest{Name: "ftp", Publisher: Publisher{Organization: OfficialPublisher}, Released: old}, false, ""},
+		{InstallRequest{Name: "s3-ext", Stage: StageStable, Released: old}, true, ""},
+		{InstallRequest{Name: "s3-ext", Stage: StageBeta, Released: old}, false, ""},
+	}
+
+	for _, tt := range tests {
+		err := pol.Check(&tt.req)
+		if tt.ok {
+			if err != nil {
+				t.Errorf("%+v: Check = %v", tt.req, err)
+			}
+			continue
+		}
+		var v *PolicyViolation
+		if !errors.As(err, &v) || !errors.Is(err, ErrPolicyViolation) {
+			t.Errorf("%+v: Check = %v, want a violation", tt.req, err)
+			continue
+		}
+		if v.Rule != tt.rule {
+			t.Errorf("%+v: violated rule %q, want %q", tt.req, v.Rule, tt.rule)
+		}
+	}
+}
+
+func TestPolicyUnsigned(t *testing.T) {
+	pol := &Policy{Rules: []PolicyRule{{Action: PolicyDeny, Unsigned: true}}}
+	if err := pol.Check(&InstallRequest{Name: "s3", Signed: true}); err != nil {
+		t.Errorf("Check(signed) = %v", err)
+	}
+	if err := pol.Check(&InstallRequest{Name: "s3"}); !errors.Is(err, ErrPolicyViolation) {
+		t.Errorf("Check(unsigned) = %v, want %v", err, ErrPolicyViolation)
+	}
+}
+
+func TestAddInstallPolicy(t *testing.T) {
+	released := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"version":"v1","integrations":[
+			{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "license":"ISC","stats":{"last_release":"2026-01-02T00:00:00Z"}}]}`)
+	}))
+	defer srv.Close()
+
+	var got *InstallRequest
+	be := &auditBackend{fakeBackend: newFakeBackend()}
+	m, _ := New(be, &Options{
+		ApiURL:     srv.URL,
+		InstallURL: srv.URL,
+		InstallPolicy: func(req *InstallRequest) error {
+			got = req
+			return errors.New("not today")
+		},
+	})
+
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"})
+	if !errors.Is(err, ErrPolicyViolation) {
+		t.Fatalf("Add = %v, want %v", err, ErrPolicyViolation)
+	}
+	if len(be.loaded) != 0 {
+		t.Errorf("loaded = %v, want nothing", be.loaded)
+	}
+	if got == nil || got.License != "ISC" || !got.Released.Equal(released) ||
+		got.Stage != StageStable || got.Local || got.Signed {
+		t.Errorf("request = %+v", got)
+	}
+
+	if len(be.records) != 1 {
+		t.Fatalf("got %d audit records, want 1", len(be.records))
+	}
+	rec := be.records[0]
+	if !rec.Failed || rec.Violation == nil || rec.Violation.Reason != "not today" {
+		t.Errorf("audit record = %+v", rec)
+	}
+}
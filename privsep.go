@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/PlakarKorp/kloset/kcontext"
+)
+
+const (
+	// helperEnv is set in the environment of the extraction
+	// helper so that [RunHelper] knows it has to take over the
+	// process.
+	helperEnv = "PLAKAR_PKG_EXTRACT_HELPER"
+
+	// helperSandboxEnv asks the helper to confine itself to its
+	// working directory before extracting.
+	helperSandboxEnv = "PLAKAR_PKG_EXTRACT_SANDBOX"
+
+	// helperUserEnv holds the uid:gid the helper switches to once
+	// confined.
+	helperUserEnv = "PLAKAR_PKG_EXTRACT_USER"
+
+	// helperProbe, as the value of helperEnv, has the helper return
+	// once confined, to check that it can be.
+	helperProbe = "probe"
+)
+
+// RunHelper turns the current process into the extraction helper
+// when it was started as one, and returns otherwise.
+//
+// Programs setting FlatBackendOptions.ExtractUser or SandboxExtraction
+// must call it at the very beginning of main: the backend re-executes
+// the current binary to extract and parse the packages.
+func RunHelper() {
+	if os.Getenv(helperEnv) == "" {
+		return
+	}
+
+	err := enterSandbox()
+	if err == nil && os.Getenv(helperEnv) != helperProbe {
+		err = runHelper(os.Args[1:])
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+// runHelper extracts the ptar into dir and parses the manifest it
+// contains, so that neither is done with the parent's privileges.
+func runHelper(args []string) error {
+	if len(args) != 3 {
+		return fmt.Errorf("usage: helper ptar dir concurrency")
+	}
+
+	concurrency, err := strconv.Atoi(args[2])
+	if err != nil || concurrency <= 0 {
+		return fmt.Errorf("invalid concurrency: %q", args[2])
+	}
+
+	e := &ptarExtractor{
+		kcontext:    kcontext.NewKContext(),
+		concurrency: concurrency,
+	}
+	if err := e.Extract(args[0], args[1]); err != nil {
+		return err
+	}
+
+	var m Manifest
+	err = m.ParseFile(filepath.Join(args[1], "manifest.yaml"))
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// helperExtractor runs the extraction in a helper process, with the
+// credentials of an unprivileged user and/or confined to a scratch
+// directory.
+type helperExtractor struct {
+	concurrency int
+	sandbox     bool
+
+	// set when the helper has to switch to uid:gid.
+	drop     bool
+	uid, gid uint32
+}
+
+func newHelperExtractor(user string, sandbox bool, concurrency int) (*helperExtractor, error) {
+	e := &helperExtractor{concurrency: concurrency, sandbox: sandbox}
+	if user != "" && privileged() {
+		if err := e.setUser(user); err != nil {
+			return nil, err
+		}
+	}
+	if sandbox && !sandboxSupported() {
+		return nil, fmt.Errorf("%w: sandboxed extraction", errors.ErrUnsupported)
+	}
+	return e, nil
+}
+
+func (e *helperExtractor) Extract(ptar, dir string) error {
+	// the staging directories are private to us, so the helper
+	// gets its own to work in.
+	work, err := os.MkdirTemp("", stagingPrefix+"helper-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(work)
+
+	input := filepath.Join(work, "package.ptar")
+	if err := copyFile(OSFS{}, ptar, input, 0400); err != nil {
+		return err
+	}
+	if err := e.prepare(work, input); err != nil {
+		return err
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+
+	// the paths are relative to the working directory, which is
+	// all the helper sees once sandboxed.
+	cmd := exec.Command(exe, "package.ptar", "content", strconv.Itoa(e.concurrency))
+	cmd.Dir = work
+	cmd.Env = []string{helperEnv + "=1"}
+	if e.sandbox {
+		// work is the root of the sandbox.
+		cmd.Env = append(cmd.Env, helperSandboxEnv+"=1", "HOME=/", "TMPDIR=/")
+	} else {
+		cmd.Env = append(cmd.Env, "HOME="+work, "TMPDIR="+work)
+	}
+
+	release, err := e.configure(cmd)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("extraction helper: %s", msg)
+		}
+		return fmt.Errorf("extraction helper: %w", err)
+	}
+
+	content := filepath.Join(work, "content")
+	if e.drop {
+		if err := reclaim(content); err != nil {
+			return err
+		}
+	}
+	return move(OSFS{}, content, dir)
+}
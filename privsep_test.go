@@ -0,0 +1,194 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/PlakarKorp/kloset/kcontext"
+)
+
+// The extraction helper re-executes the test binary.
+func TestMain(m *testing.M) {
+	RunHelper()
+	os.Exit(m.Run())
+}
+
+func TestExtractUserIgnoredUnprivileged(t *testing.T) {
+	if privileged() {
+		t.Skip("running as root")
+	}
+
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{ExtractUser: "nobody"})
+	if _, ok := be.extractor.(*ptarExtractor); !ok {
+		t.Errorf("extractor = %T, want *ptarExtractor", be.extractor)
+	}
+}
+
+func TestExtractUserUnknown(t *testing.T) {
+	if !privileged() {
+		t.Skip("not running as root")
+	}
+
+	root := t.TempDir()
+	_, err := NewFlatBackend(kcontext.NewKContext(), filepath.Join(root, "pkgs"),
+		filepath.Join(root, "cache"), &FlatBackendOptions{ExtractUser: "no-such-user-here"})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("NewFlatBackend = %v, want ErrInvalidOptions", err)
+	}
+}
+
+func TestExtractUserCustomExtractor(t *testing.T) {
+	ext := &fakeExtractor{}
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{
+		ExtractUser: "nobody",
+		Extractor:   ext,
+	})
+	if be.extractor != Extractor(ext) {
+		t.Errorf("extractor = %T, want the custom one", be.extractor)
+	}
+}
+
+func TestRunHelperArgs(t *testing.T) {
+	for _, args := range [][]string{
+		nil,
+		{"a.ptar", "dir"},
+		{"a.ptar", "dir", "zero"},
+		{"a.ptar", "dir", "0"},
+	} {
+		if err := runHelper(args); err == nil {
+			t.Errorf("runHelper(%q) succeeded", args)
+		}
+	}
+}
+
+func TestRunHelperNotHelper(t *testing.T) {
+	t.Setenv(helperEnv, "")
+	RunHelper() // must return
+}
+
+func TestSandboxExtraction(t *testing.T) {
+	if !sandboxSupported() {
+		t.Skip("sandboxed extraction unsupported")
+	}
+
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{SandboxExtraction: true})
+	e, ok := be.extractor.(*helperExtractor)
+	if !ok {
+		t.Fatalf("extractor = %T, want *helperExtractor", be.extractor)
+	}
+	if !e.sandbox || e.drop && !privileged() {
+		t.Errorf("extractor = %+v", e)
+	}
+
+	cmd := exec.Command("true")
+	release, err := e.configure(cmd)
+	if err != nil {
+		t.Fatalf("configure: %v", err)
+	}
+	defer release()
+	if cmd.SysProcAttr == nil {
+		t.Error("configure left the process attributes unset")
+	}
+}
+
+func TestEnterSandboxNotAsked(t *testing.T) {
+	t.Setenv(helperSandboxEnv, "")
+	if err := enterSandbox(); err != nil {
+		t.Errorf("enterSandbox: %v", err)
+	}
+}
+
+// helperBackend returns a backend extracting through the helper,
+// started from the test binary.
+func helperBackend(t *testing.T, user string, sandbox bool) *FlatBackend {
+	t.Helper()
+	be, _, _ := newTestFlatBackend(t, nil)
+	e, err := newHelperExtractor(user, sandbox, 1)
+	if err != nil {
+		t.Fatalf("newHelperExtractor: %v", err)
+	}
+	be.extractor = e
+	return be
+}
+
+// othersCanExec returns whether an unprivileged user can run the
+// test binary, which go test usually keeps in a private directory.
+func othersCanExec() bool {
+	exe, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	for p := exe; ; p = filepath.Dir(p) {
+		fi, err := os.Stat(p)
+		if err != nil || fi.Mode().Perm()&0001 == 0 {
+			return false
+		}
+		if filepath.Dir(p) == p {
+			return true
+		}
+	}
+}
+
+// The helper is started and reports the failure to decode a bogus
+// ptar, without it ever being opened by the test process.
+func TestHelperEndToEnd(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		user    string
+		sandbox bool
+		skip    bool
+	}{
+		{name: "plain"},
+		{name: "user", user: "nobody", skip: !privileged() || !othersCanExec()},
+		{name: "sandbox", sandbox: true, skip: !sandboxSupported()},
+		{name: "sandbox-user", user: "nobody", sandbox: true,
+			skip: !privileged() || !sandboxSupported()},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.skip {
+				t.Skip("unsupported here")
+			}
+			be := helperBackend(t, tc.user, tc.sandbox)
+			pkg := pkgVer("s3", "v1.0.0")
+
+			ptar := filepath.Join(t.TempDir(), pkg.Filename())
+			if err := os.WriteFile(ptar, []byte("not a ptar"), 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			dir := filepath.Join(t.TempDir(), "content")
+			err := be.extractor.Extract(ptar, dir)
+			if err == nil {
+				t.Fatal("Extract succeeded on a bogus ptar")
+			}
+			if msg := err.Error(); msg != "extraction helper: invalid magic" {
+				t.Errorf("Extract = %v, want the helper failing on the ptar", err)
+			}
+			if _, err := os.Stat(dir); !errors.Is(err, os.ErrNotExist) {
+				t.Errorf("failed extraction left %s: %v", dir, err)
+			}
+
+			_, err = be.Inspect(pkg, bytes.NewReader([]byte("not a ptar")))
+			if err == nil || !strings.HasPrefix(err.Error(), "extraction helper: ") {
+				t.Errorf("Inspect = %v, want it to go through the helper", err)
+			}
+		})
+	}
+}
+
+// Verify decodes the stored package through the helper too.
+func TestHelperVerify(t *testing.T) {
+	be := helperBackend(t, "", false)
+	pkg := pkgVer("s3", "v1.0.0")
+	installExtracted(t, be.pkgdir, be.cachedir, pkg)
+
+	err := be.Verify(pkg)
+	if !errors.Is(err, ErrCorrupted) || !strings.Contains(err.Error(), "extraction helper: ") {
+		t.Errorf("Verify = %v, want the helper failing on the ptar", err)
+	}
+}
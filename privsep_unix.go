@@ -0,0 +1,188 @@
+//go:build unix
+
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+)
+
+func privileged() bool {
+	return os.Geteuid() == 0
+}
+
+func (e *helperExtractor) setUser(name string) error {
+	u, err := user.Lookup(name)
+	if err != nil {
+		return fmt.Errorf("%w: extract user: %w", ErrInvalidOptions, err)
+	}
+	uid, err := strconv.ParseUint(u.Uid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: extract user: invalid uid %q", ErrInvalidOptions, u.Uid)
+	}
+	gid, err := strconv.ParseUint(u.Gid, 10, 32)
+	if err != nil {
+		return fmt.Errorf("%w: extract user: invalid gid %q", ErrInvalidOptions, u.Gid)
+	}
+	if uid == 0 {
+		return fmt.Errorf("%w: extract user %q is privileged", ErrInvalidOptions, name)
+	}
+	e.drop = true
+	e.uid, e.gid = uint32(uid), uint32(gid)
+	return nil
+}
+
+// prepare hands the working directory over to the helper user.
+func (e *helperExtractor) prepare(work, input string) error {
+	if !e.drop {
+		return nil
+	}
+	for _, p := range []string{work, input} {
+		if err := os.Chown(p, int(e.uid), int(e.gid)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *helperExtractor) configure(cmd *exec.Cmd) (func(), error) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	if !e.sandbox {
+		if e.drop {
+			cmd.SysProcAttr.Credential = &syscall.Credential{
+				Uid:    e.uid,
+				Gid:    e.gid,
+				Groups: []uint32{},
+			}
+		}
+		return func() {}, nil
+	}
+
+	// confining the helper needs privileges, so it drops them
+	// itself afterwards.
+	if e.drop {
+		cmd.Env = append(cmd.Env, fmt.Sprintf("%s=%d:%d", helperUserEnv, e.uid, e.gid))
+	}
+	sandboxAttrs(cmd.SysProcAttr)
+	return func() {}, nil
+}
+
+// enterSandbox confines the helper to its working directory and
+// switches to the helper user, if asked to.
+func enterSandbox() error {
+	if os.Getenv(helperSandboxEnv) == "" {
+		return nil
+	}
+
+	if err := privatizeMounts(); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+	if err := syscall.Chroot(wd); err != nil {
+		return fmt.Errorf("sandbox: chroot: %w", err)
+	}
+	if err := os.Chdir("/"); err != nil {
+		return fmt.Errorf("sandbox: %w", err)
+	}
+
+	ids := os.Getenv(helperUserEnv)
+	if ids == "" {
+		return nil
+	}
+	u, g, ok := strings.Cut(ids, ":")
+	uid, uerr := strconv.Atoi(u)
+	gid, gerr := strconv.Atoi(g)
+	if !ok || uerr != nil || gerr != nil || uid == 0 {
+		return fmt.Errorf("sandbox: invalid user %q", ids)
+	}
+	if err := syscall.Setgroups([]int{}); err != nil {
+		return fmt.Errorf("sandbox: setgroups: %w", err)
+	}
+	if err := syscall.Setgid(gid); err != nil {
+		return fmt.Errorf("sandbox: setgid: %w", err)
+	}
+	if err := syscall.Setuid(uid); err != nil {
+		return fmt.Errorf("sandbox: setuid: %w", err)
+	}
+	return nil
+}
+
+// reclaim gives the tree written by the helper back to root, so that
+// the unprivileged user can't alter the installed plugins later on.
+func reclaim(dir string) error {
+	return filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		fi, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		// a hard link could make us take over a file that lives
+		// outside of the tree.
+		if st, ok := fi.Sys().(*syscall.Stat_t); ok && !fi.IsDir() && st.Nlink > 1 {
+			return fmt.Errorf("extraction helper: %s: unexpected hard link", path)
+		}
+
+		if err := os.Lchown(path, 0, 0); err != nil {
+			return err
+		}
+
+		mode := fi.Mode()
+		if mode.IsRegular() && mode&(fs.ModeSetuid|fs.ModeSetgid) != 0 {
+			return clearSetid(path, fi)
+		}
+		return nil
+	})
+}
+
+// clearSetid strips the setuid and setgid bits of the regular file
+// at path, through a descriptor so that the change can't be diverted
+// by a symlink swapped in since it was inspected.
+func clearSetid(path string, fi fs.FileInfo) error {
+	fp, err := os.OpenFile(path, os.O_RDONLY|syscall.O_NOFOLLOW|syscall.O_NONBLOCK, 0)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	cur, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	if !os.SameFile(fi, cur) || !cur.Mode().IsRegular() {
+		return fmt.Errorf("extraction helper: %s: changed during reclaim", path)
+	}
+	return fp.Chmod(cur.Mode().Perm())
+}
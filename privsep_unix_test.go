@@ -0,0 +1,68 @@
+//go:build unix
+
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReclaim(t *testing.T) {
+	if !privileged() {
+		t.Skip("not running as root")
+	}
+
+	outside := filepath.Join(t.TempDir(), "outside")
+	if err := os.WriteFile(outside, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(outside, 0755|os.ModeSetuid); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	plugin := filepath.Join(dir, "plugin")
+	if err := os.WriteFile(plugin, nil, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chmod(plugin, 0755|os.ModeSetuid|os.ModeSetgid); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink(outside, filepath.Join(dir, "link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Lchown(dir, 65534, 65534); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := reclaim(dir); err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+
+	fi, err := os.Stat(plugin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode() != 0755 {
+		t.Errorf("plugin mode = %v, want 0755", fi.Mode())
+	}
+	fi, err = os.Stat(outside)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fi.Mode()&os.ModeSetuid == 0 {
+		t.Error("reclaim changed a file outside of the tree")
+	}
+}
+
+func TestReclaimHardLink(t *testing.T) {
+	dir := t.TempDir()
+	touch(t, dir, "a")
+	if err := os.Link(filepath.Join(dir, "a"), filepath.Join(dir, "b")); err != nil {
+		t.Fatal(err)
+	}
+	if err := reclaim(dir); err == nil {
+		t.Error("reclaim accepted a hard link")
+	}
+}
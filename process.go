@@ -0,0 +1,234 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// defaultStopTimeout is how long connectors are given to exit after
+// being asked to, and again after being killed.
+const defaultStopTimeout = 10 * time.Second
+
+var (
+	ErrProcessesRunning = errors.New("connector processes still running")
+)
+
+// Process is a connector process running out of a package.
+type Process struct {
+	Package   string    `json:"package"`
+	Version   string    `json:"version,omitempty"`
+	Connector string    `json:"connector"`
+	PID       int       `json:"pid"`
+	Started   time.Time `json:"started"`
+}
+
+// ProcessRegistry keeps track of the connector processes spawned
+// from the installed packages.  The code executing the connectors
+// registers them with Track, and the manager relies on it to know
+// what is running out of a package.
+type ProcessRegistry struct {
+	mu    sync.Mutex
+	procs map[*process]struct{}
+}
+
+type process struct {
+	Process
+	proc *os.Process
+	done chan struct{}
+}
+
+func NewProcessRegistry() *ProcessRegistry {
+	return &ProcessRegistry{
+		procs: make(map[*process]struct{}),
+	}
+}
+
+// Track registers a started connector of the named package.  The
+// returned function must be called once the process has exited; it
+// can safely be called more than once.
+func (r *ProcessRegistry) Track(pkg, connector string, proc *os.Process) func() {
+	return r.TrackVersion(pkg, "", connector, proc)
+}
+
+// TrackVersion is like Track for a connector of a given version of
+// the package, when several may run side by side.
+func (r *ProcessRegistry) TrackVersion(pkg, version, connector string, proc *os.Process) func() {
+	p := &process{
+		Process: Process{
+			Package:   pkg,
+			Version:   version,
+			Connector: connector,
+			PID:       proc.Pid,
+			Started:   time.Now(),
+		},
+		proc: proc,
+		done: make(chan struct{}),
+	}
+
+	r.mu.Lock()
+	r.procs[p] = struct{}{}
+	r.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			r.mu.Lock()
+			delete(r.procs, p)
+			r.mu.Unlock()
+			close(p.done)
+		})
+	}
+}
+
+// Running returns the processes running out of the named package,
+// sorted by PID.
+func (r *ProcessRegistry) Running(pkg string) []Process {
+	var ret []Process
+	for _, p := range r.running(pkg, "") {
+		ret = append(ret, p.Process)
+	}
+	return ret
+}
+
+// running returns the processes of the package.  When version is
+// set, those tracked for another version are left out.
+func (r *ProcessRegistry) running(pkg, version string) []*process {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var ret []*process
+	for p := range r.procs {
+		if p.Package != pkg {
+			continue
+		}
+		if version == "" || p.Version == "" || p.Version == version {
+			ret = append(ret, p)
+		}
+	}
+	slices.SortFunc(ret, func(a, b *process) int {
+		return cmp.Compare(a.PID, b.PID)
+	})
+	return ret
+}
+
+// Stop terminates the processes running out of the named package.
+// They are sent SIGTERM and, if they are still there after the
+// timeout, SIGKILL.  It returns once they were all released, or fails
+// with ErrProcessesRunning if some are not after another timeout.
+func (r *ProcessRegistry) Stop(pkg string, timeout time.Duration) error {
+	return r.StopVersion(pkg, "", timeout)
+}
+
+// StopVersion is like Stop but spares the processes tracked for
+// another version than the given one.
+func (r *ProcessRegistry) StopVersion(pkg, version string, timeout time.Duration) error {
+	procs := r.running(pkg, version)
+	if len(procs) == 0 {
+		return nil
+	}
+
+	for _, p := range procs {
+		if err := p.proc.Signal(syscall.SIGTERM); err != nil {
+			// not every platform can deliver SIGTERM.
+			p.proc.Kill()
+		}
+	}
+	if procs = waitProcesses(procs, timeout); len(procs) == 0 {
+		return nil
+	}
+
+	for _, p := range procs {
+		p.proc.Kill()
+	}
+	if procs = waitProcesses(procs, timeout); len(procs) == 0 {
+		return nil
+	}
+
+	var pids []int
+	for _, p := range procs {
+		pids = append(pids, p.PID)
+	}
+	return fmt.Errorf("%w: %s: pids %v", ErrProcessesRunning, pkg, pids)
+}
+
+// waitProcesses waits up to timeout for the processes to be released
+// and returns the ones that are not.
+func waitProcesses(procs []*process, timeout time.Duration) []*process {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for i, p := range procs {
+		select {
+		case <-p.done:
+		case <-timer.C:
+			return slices.DeleteFunc(procs[i:], func(p *process) bool {
+				select {
+				case <-p.done:
+					return true
+				default:
+					return false
+				}
+			})
+		}
+	}
+	return nil
+}
+
+// stopConnectors stops the connectors of the package before it is
+// removed from under them.
+func (p *Manager) stopConnectors(pkg *Package) error {
+	if p.processes == nil {
+		return nil
+	}
+	return p.processes.StopVersion(pkg.Name, pkg.Version, p.stoptimeout)
+}
+
+// All returns the running processes grouped by package name.
+func (r *ProcessRegistry) All() map[string][]Process {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	ret := make(map[string][]Process)
+	for p := range r.procs {
+		ret[p.Package] = append(ret[p.Package], p.Process)
+	}
+	for _, procs := range ret {
+		slices.SortFunc(procs, func(a, b Process) int {
+			return cmp.Compare(a.PID, b.PID)
+		})
+	}
+	return ret
+}
+
+// Processes returns the connector processes currently running,
+// grouped by package name.  It returns nil when the manager was
+// created without a process registry.
+func (p *Manager) Processes() map[string][]Process {
+	if p.processes == nil {
+		return nil
+	}
+	return p.processes.All()
+}
@@ -0,0 +1,154 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// startTracked runs the shell command as a connector of pkg.
+func startTracked(t *testing.T, reg *ProcessRegistry, pkg, script string) *exec.Cmd {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("needs a POSIX shell")
+	}
+
+	cmd := exec.Command("sh", "-c", script)
+	if err := cmd.Start(); err != nil {
+		t.Fatal(err)
+	}
+	release := reg.Track(pkg, pkg+"-importer", cmd.Process)
+	go func() {
+		cmd.Wait()
+		release()
+	}()
+	t.Cleanup(func() { cmd.Process.Kill() })
+	return cmd
+}
+
+func TestProcessRegistry(t *testing.T) {
+	reg := NewProcessRegistry()
+	m, err := New(newFakeBackend(), &Options{Processes: reg})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got := m.Processes(); len(got) != 0 {
+		t.Fatalf("Processes = %v, want none", got)
+	}
+
+	self := &os.Process{Pid: os.Getpid()}
+	other := &os.Process{Pid: os.Getpid() + 1}
+	release := reg.Track("s3", "s3-importer", other)
+	reg.Track("s3", "s3-exporter", self)
+	reg.Track("ftp", "ftp-importer", self)
+
+	procs := m.Processes()
+	if len(procs) != 2 || len(procs["s3"]) != 2 || len(procs["ftp"]) != 1 {
+		t.Fatalf("Processes = %v", procs)
+	}
+	if s3 := procs["s3"]; s3[0].PID > s3[1].PID {
+		t.Errorf("processes not sorted by pid: %v", s3)
+	}
+
+	release()
+	release()
+	running := reg.Running("s3")
+	if len(running) != 1 || running[0].Connector != "s3-exporter" {
+		t.Errorf("Running(s3) = %v", running)
+	}
+}
+
+func TestProcessesWithoutRegistry(t *testing.T) {
+	m, err := New(newFakeBackend(), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := m.Processes(); got != nil {
+		t.Errorf("Processes = %v, want nil", got)
+	}
+}
+
+func TestProcessRegistryStop(t *testing.T) {
+	reg := NewProcessRegistry()
+	startTracked(t, reg, "s3", "sleep 60")
+	startTracked(t, reg, "ftp", "sleep 60")
+
+	if err := reg.Stop("s3", 5*time.Second); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if got := reg.Running("s3"); len(got) != 0 {
+		t.Errorf("Running(s3) = %v, want none", got)
+	}
+	if got := reg.Running("ftp"); len(got) != 1 {
+		t.Errorf("Running(ftp) = %v, want one", got)
+	}
+}
+
+func TestProcessRegistryStopKills(t *testing.T) {
+	reg := NewProcessRegistry()
+	startTracked(t, reg, "s3", "trap '' TERM; while :; do sleep 1; done")
+	// give the shell time to install the trap.
+	time.Sleep(100 * time.Millisecond)
+
+	start := time.Now()
+	if err := reg.Stop("s3", 200*time.Millisecond); err != nil {
+		t.Fatalf("Stop: %v", err)
+	}
+	if time.Since(start) < 200*time.Millisecond {
+		t.Error("process killed before the timeout")
+	}
+	if got := reg.Running("s3"); len(got) != 0 {
+		t.Errorf("Running(s3) = %v, want none", got)
+	}
+}
+
+func TestProcessRegistryStopUnreleased(t *testing.T) {
+	reg := NewProcessRegistry()
+	reg.Track("s3", "s3-importer", &os.Process{Pid: -1})
+
+	err := reg.Stop("s3", 10*time.Millisecond)
+	if !errors.Is(err, ErrProcessesRunning) {
+		t.Fatalf("Stop = %v, want %v", err, ErrProcessesRunning)
+	}
+}
+
+func TestDelStopsConnectors(t *testing.T) {
+	reg := NewProcessRegistry()
+	be := newFakeBackend(pkgOf(t, "s3"))
+	m, err := New(be, &Options{Processes: reg, StopTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	startTracked(t, reg, "s3", "sleep 60")
+
+	if err := m.Del("s3", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if got := m.Processes(); len(got) != 0 {
+		t.Errorf("Processes = %v, want none", got)
+	}
+	if len(be.unloaded) != 1 {
+		t.Errorf("unloaded %d, want 1", len(be.unloaded))
+	}
+}
+
+func TestDelKeepsPackageWithStuckConnectors(t *testing.T) {
+	reg := NewProcessRegistry()
+	be := newFakeBackend(pkgOf(t, "s3"))
+	m, err := New(be, &Options{Processes: reg, StopTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reg.Track("s3", "s3-importer", &os.Process{Pid: -1})
+
+	if err := m.Del("s3", nil); !errors.Is(err, ErrProcessesRunning) {
+		t.Fatalf("Del = %v, want %v", err, ErrProcessesRunning)
+	}
+	if len(be.unloaded) != 0 {
+		t.Errorf("unloaded %d, want 0", len(be.unloaded))
+	}
+}
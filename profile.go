@@ -0,0 +1,258 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"go.yaml.in/yaml/v3"
+)
+
+var (
+	ErrNoSuchProfile  = errors.New("no such profile")
+	ErrBadProfileName = errors.New("invalid profile name")
+)
+
+// Directory, under the package directory, where the profiles are
+// kept.
+const profilesDir = ".profiles"
+
+// Profile is a named set of packages, like "laptop" or "ci", that
+// can be applied at once and shared between machines.
+type Profile struct {
+	Name     string `yaml:"name"`
+	Packages []Spec `yaml:"packages"`
+}
+
+func NewProfileFromFile(path string) (*Profile, error) {
+	var pr Profile
+	if err := pr.ParseFile(path); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+func (pr *Profile) ParseFile(path string) error {
+	fp, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	return pr.Parse(fp)
+}
+
+func (pr *Profile) Parse(rd io.Reader) error {
+	if err := yaml.NewDecoder(rd).Decode(pr); err != nil {
+		return fmt.Errorf("failed to decode the profile: %w", err)
+	}
+	return pr.Validate()
+}
+
+// Write encodes the profile in the format understood by Parse.
+func (pr *Profile) Write(w io.Writer) error {
+	enc := yaml.NewEncoder(w)
+	if err := enc.Encode(pr); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+func (pr *Profile) Validate() error {
+	if err := validName(pr.Name); err != nil {
+		return fmt.Errorf("%w: %w", ErrBadProfileName, err)
+	}
+	return nil
+}
+
+// ProfileStore is implemented by the backends that can keep
+// profiles.
+type ProfileStore interface {
+	SaveProfile(*Profile) error
+	LoadProfile(name string) (*Profile, error)
+	DeleteProfile(name string) error
+	Profiles() iter.Seq2[string, error]
+}
+
+func (p *Manager) profiles() (ProfileStore, error) {
+	ps, ok := p.store.(ProfileStore)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return ps, nil
+}
+
+// SaveProfile stores the profile, replacing the one with the same
+// name if any.
+func (p *Manager) SaveProfile(pr *Profile) error {
+	ps, err := p.profiles()
+	if err != nil {
+		return err
+	}
+	if err := pr.Validate(); err != nil {
+		return err
+	}
+	return ps.SaveProfile(pr)
+}
+
+// LoadProfile returns the named profile.
+func (p *Manager) LoadProfile(name string) (*Profile, error) {
+	ps, err := p.profiles()
+	if err != nil {
+		return nil, err
+	}
+	return ps.LoadProfile(name)
+}
+
+// DeleteProfile removes the named profile.  The packages installed
+// are not affected.
+func (p *Manager) DeleteProfile(name string) error {
+	ps, err := p.profiles()
+	if err != nil {
+		return err
+	}
+	return ps.DeleteProfile(name)
+}
+
+// Profiles returns the names of the stored profiles.
+func (p *Manager) Profiles() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		ps, err := p.profiles()
+		if err != nil {
+			yield("", err)
+			return
+		}
+		for name, err := range ps.Profiles() {
+			if !yield(name, err) {
+				return
+			}
+		}
+	}
+}
+
+// ApplyProfile syncs the installed packages with the named profile.
+// See [Manager.Sync].
+func (p *Manager) ApplyProfile(name string, opts *SyncOptions) ([]SyncAction, error) {
+	pr, err := p.LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return p.Sync(pr.Packages, opts)
+}
+
+func (f *FlatBackend) profilePath(name string) (string, error) {
+	pr := Profile{Name: name}
+	if err := pr.Validate(); err != nil {
+		return "", err
+	}
+	return filepath.Join(f.pkgdir, profilesDir, name+".yaml"), nil
+}
+
+func (f *FlatBackend) SaveProfile(pr *Profile) error {
+	path, err := f.profilePath(pr.Name)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := f.fsys.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fp, err := f.fsys.CreateTemp(dir, stagingPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	if err := pr.Write(fp); err != nil {
+		fp.Close()
+		f.fsys.Remove(fp.Name())
+		return err
+	}
+	fp.Close()
+
+	if err := f.fsys.Rename(fp.Name(), path); err != nil {
+		f.fsys.Remove(fp.Name())
+		return err
+	}
+	return nil
+}
+
+func (f *FlatBackend) LoadProfile(name string) (*Profile, error) {
+	path, err := f.profilePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := f.fsys.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("%w: %s", ErrNoSuchProfile, name)
+		}
+		return nil, err
+	}
+	defer fp.Close()
+
+	var pr Profile
+	if err := pr.Parse(fp); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+func (f *FlatBackend) DeleteProfile(name string) error {
+	path, err := f.profilePath(name)
+	if err != nil {
+		return err
+	}
+
+	err = f.fsys.Remove(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%w: %s", ErrNoSuchProfile, name)
+	}
+	return err
+}
+
+func (f *FlatBackend) Profiles() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		dirents, err := f.fsys.ReadDir(filepath.Join(f.pkgdir, profilesDir))
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				yield("", err)
+			}
+			return
+		}
+
+		for _, d := range dirents {
+			name, ok := strings.CutSuffix(d.Name(), ".yaml")
+			if !ok || strings.HasPrefix(name, ".") {
+				continue
+			}
+			if !yield(name, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,168 @@
+package pkg
+
+import (
+	"bytes"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestProfileParse(t *testing.T) {
+	const doc = `name: server
+packages:
+  - name: s3
+    version: v1
+    pinned: true
+  - name: sftp
+`
+	var pr Profile
+	if err := pr.Parse(strings.NewReader(doc)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if pr.Name != "server" || len(pr.Packages) != 2 {
+		t.Fatalf("profile = %+v", pr)
+	}
+	if s := pr.Packages[0]; s.Name != "s3" || s.Version != "v1" || !s.Pinned {
+		t.Errorf("first spec = %+v", s)
+	}
+
+	var buf bytes.Buffer
+	if err := pr.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	var back Profile
+	if err := back.Parse(&buf); err != nil {
+		t.Fatalf("Parse after Write: %v", err)
+	}
+	if back.Name != pr.Name || len(back.Packages) != 2 || back.Packages[0] != pr.Packages[0] {
+		t.Errorf("round trip = %+v, want %+v", back, pr)
+	}
+}
+
+func TestProfileBadName(t *testing.T) {
+	for _, name := range []string{"", "../etc", "a b", ".hidden"} {
+		pr := Profile{Name: name}
+		if err := pr.Validate(); !errors.Is(err, ErrBadProfileName) {
+			t.Errorf("Validate(%q) err = %v, want ErrBadProfileName", name, err)
+		}
+	}
+}
+
+func TestFlatBackendProfiles(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+
+	for _, name := range []string{"laptop", "ci"} {
+		pr := &Profile{Name: name, Packages: []Spec{{Name: "s3"}}}
+		if err := be.SaveProfile(pr); err != nil {
+			t.Fatalf("SaveProfile(%s): %v", name, err)
+		}
+	}
+
+	var names []string
+	for name, err := range be.Profiles() {
+		if err != nil {
+			t.Fatalf("Profiles: %v", err)
+		}
+		names = append(names, name)
+	}
+	if len(names) != 2 {
+		t.Errorf("Profiles = %v", names)
+	}
+
+	pr, err := be.LoadProfile("ci")
+	if err != nil {
+		t.Fatalf("LoadProfile: %v", err)
+	}
+	if pr.Name != "ci" || len(pr.Packages) != 1 || pr.Packages[0].Name != "s3" {
+		t.Errorf("LoadProfile = %+v", pr)
+	}
+
+	if err := be.DeleteProfile("ci"); err != nil {
+		t.Fatalf("DeleteProfile: %v", err)
+	}
+	if _, err := be.LoadProfile("ci"); !errors.Is(err, ErrNoSuchProfile) {
+		t.Errorf("LoadProfile after delete err = %v, want ErrNoSuchProfile", err)
+	}
+	if err := be.DeleteProfile("ci"); !errors.Is(err, ErrNoSuchProfile) {
+		t.Errorf("DeleteProfile twice err = %v, want ErrNoSuchProfile", err)
+	}
+
+	// profiles must not show up as packages.
+	for pkg := range be.List("") {
+		t.Errorf("List returned %+v", pkg)
+	}
+}
+
+func TestProfilesUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if _, err := m.ApplyProfile("ci", nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ApplyProfile err = %v, want ErrUnsupported", err)
+	}
+}
+
+// profileBackend is a fakeBackend keeping its profiles in memory.
+type profileBackend struct {
+	*fakeBackend
+	profiles map[string]*Profile
+}
+
+func (b *profileBackend) SaveProfile(pr *Profile) error {
+	b.profiles[pr.Name] = pr
+	return nil
+}
+
+func (b *profileBackend) LoadProfile(name string) (*Profile, error) {
+	pr, ok := b.profiles[name]
+	if !ok {
+		return nil, ErrNoSuchProfile
+	}
+	return pr, nil
+}
+
+func (b *profileBackend) DeleteProfile(name string) error {
+	delete(b.profiles, name)
+	return nil
+}
+
+func (b *profileBackend) Profiles() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		for name := range b.profiles {
+			if !yield(name, nil) {
+				return
+			}
+		}
+	}
+}
+
+func TestApplyProfile(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.0.0", "sftp": "v2.0.0"})
+
+	be := &profileBackend{
+		fakeBackend: newFakeBackend(pkgVer("ftp", "v1.0.0")),
+		profiles:    map[string]*Profile{},
+	}
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	err := m.SaveProfile(&Profile{
+		Name:     "server",
+		Packages: []Spec{{Name: "s3"}, {Name: "sftp"}},
+	})
+	if err != nil {
+		t.Fatalf("SaveProfile: %v", err)
+	}
+
+	if _, err := m.ApplyProfile("server", nil); err != nil {
+		t.Fatalf("ApplyProfile: %v", err)
+	}
+
+	var names []string
+	for _, p := range be.pkgs {
+		names = append(names, p.Name)
+	}
+	slices.Sort(names)
+	if !slices.Equal(names, []string{"s3", "sftp"}) {
+		t.Errorf("installed = %v, want [s3 sftp]", names)
+	}
+}
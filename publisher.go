@@ -0,0 +1,62 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import "encoding/hex"
+
+// OfficialPublisher is the organization publishing the official
+// connectors.
+const OfficialPublisher = "PlakarKorp"
+
+// Publisher identifies who publishes an integration.
+type Publisher struct {
+	Organization string `json:"organization"`
+	Identity     string `json:"identity"` // hex number of the signing key
+
+	// Set by the Manager when Identity is one of the trusted
+	// keys; the value found in the index is never trusted.
+	Verified bool `json:"verified"`
+}
+
+// Official returns whether the integration is a verified one from
+// the PlakarKorp organization.
+func (pub *Publisher) Official() bool {
+	return pub.Verified && pub.Organization == OfficialPublisher
+}
+
+// Identity returns the key number in the form used by
+// [Publisher.Identity].
+func (k *PublicKey) Identity() string {
+	return hex.EncodeToString(k.KeyNum[:])
+}
+
+// verifyPublisher marks the publisher as verified if it claims the
+// identity of one of the keys trusted to sign the recipes.
+func (p *Manager) verifyPublisher(pub *Publisher) {
+	pub.Verified = false
+	if pub.Identity == "" {
+		return
+	}
+	for _, k := range p.trustedkeys {
+		if k.Identity() == pub.Identity {
+			pub.Verified = true
+			return
+		}
+	}
+}
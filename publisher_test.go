@@ -0,0 +1,70 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublisherOfficial(t *testing.T) {
+	tests := []struct {
+		pub  Publisher
+		want bool
+	}{
+		{Publisher{Organization: "PlakarKorp", Verified: true}, true},
+		{Publisher{Organization: "PlakarKorp"}, false},
+		{Publisher{Organization: "someone", Verified: true}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.pub.Official(); got != tt.want {
+			t.Errorf("%+v.Official() = %v, want %v", tt.pub, got, tt.want)
+		}
+	}
+}
+
+func TestQueryPublisher(t *testing.T) {
+	signer := newTestSigner(t, 7)
+	key := signer.key(t)
+
+	index := `{
+		"version":"v1",
+		"integrations":[
+			{"name":"official","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "publisher":{"organization":"PlakarKorp","identity":"` + key.Identity() + `"}},
+			{"name":"impostor","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "publisher":{"organization":"PlakarKorp","identity":"0000000000000000","verified":true}},
+			{"name":"community","edition":"community","api":"v1.1.0","version":"v1.0.0",
+			 "publisher":{"organization":"someone"}}
+		]
+	}`
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, index)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{
+		ApiURL:      srv.URL,
+		TrustedKeys: []*PublicKey{key},
+	})
+
+	got, err := m.Query(nil)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	verified := map[string]bool{}
+	for _, in := range got {
+		verified[in.Name] = in.Publisher.Verified
+	}
+	if !verified["official"] || verified["impostor"] || verified["community"] {
+		t.Errorf("verified publishers = %v", verified)
+	}
+
+	got, err = m.Query(&QueryOptions{OnlyOfficial: true})
+	if err != nil {
+		t.Fatalf("Query official: %v", err)
+	}
+	if len(got) != 1 || got[0].Name != "official" {
+		t.Errorf("Query OnlyOfficial returned %+v", got)
+	}
+}
@@ -0,0 +1,236 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Purger is implemented by the backends keeping, besides the installed
+// packages, state about them that outlives their removal.
+type Purger interface {
+	// Purge removes everything kept about the named package, of
+	// which no version is installed anymore.
+	Purge(name string) error
+}
+
+// purge removes the residue of the named package, once no version of
+// it is left: what the backend keeps about it and its cached assets.
+func (p *Manager) purge(purger Purger, name string) error {
+	if p.installedVersion(name) != "" {
+		return nil
+	}
+	if err := purger.Purge(name); err != nil {
+		return err
+	}
+	return p.purgeAssets(name)
+}
+
+// purgeAssets removes the cached assets of the named integration: those
+// published under its directory of the index, and its icon and
+// featured image.  The copies shared with other assets are kept.
+func (p *Manager) purgeAssets(name string) error {
+	if p.assetdir == "" || p.api == nil {
+		return nil
+	}
+
+	var urls []*url.URL
+	if u, err := p.indexURL(escapeName(name) + "/"); err == nil {
+		urls = append(urls, u)
+	}
+	if plug, err := p.integration(name); err == nil {
+		for _, ref := range []string{plug.Icon, plug.Featured} {
+			if ref == "" {
+				continue
+			}
+			if u, err := p.indexURL(ref); err == nil {
+				urls = append(urls, u)
+			}
+		}
+	}
+
+	p.assetmu.Lock()
+	defer p.assetmu.Unlock()
+
+	assets, err := p.assets()
+	if err != nil {
+		return err
+	}
+
+	removed := map[string]bool{}
+	for key, file := range assets {
+		for _, u := range urls {
+			s := u.String()
+			if key == s || strings.HasSuffix(s, "/") && strings.HasPrefix(key, s) {
+				delete(assets, key)
+				removed[file] = true
+				break
+			}
+		}
+	}
+	if len(removed) == 0 {
+		return nil
+	}
+
+	for _, file := range assets {
+		delete(removed, file)
+	}
+	for file := range removed {
+		err := os.Remove(filepath.Join(p.assetdir, file))
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+
+	index, err := json.Marshal(assets)
+	if err != nil {
+		return err
+	}
+	return writeFile(OSFS{}, filepath.Join(p.assetdir, assetIndexFile), index)
+}
+
+// Purge drops the archived and staged copies of the package, its
+// receipts, hold, scope and active version, and its directory in the
+// cache.
+func (f *FlatBackend) Purge(name string) error {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	for _, dir := range []string{archiveDir, stagedDir} {
+		if err := f.removeCopies(filepath.Join(f.pkgdir, dir), name); err != nil {
+			return err
+		}
+	}
+
+	if err := f.dropReceipts(name); err != nil {
+		return err
+	}
+	if err := f.Unhold(name); err != nil {
+		return err
+	}
+	if err := f.SetScope(name, nil); err != nil {
+		return err
+	}
+
+	actives, err := f.actives()
+	if err != nil {
+		return err
+	}
+	if _, ok := actives[name]; ok {
+		delete(actives, name)
+		data, err := json.Marshal(actives)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(f.fsys, filepath.Join(f.pkgdir, activeFile), data); err != nil {
+			return err
+		}
+	}
+
+	return f.removeExtractions(filepath.Join(f.cachedir, escapeName(name)))
+}
+
+// removeExtractions removes the extracted trees of a package, found
+// under dir as version/os_arch, markers first.
+func (f *FlatBackend) removeExtractions(dir string) error {
+	versions, err := f.fsys.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	for _, v := range versions {
+		if !v.IsDir() {
+			continue
+		}
+		platforms, err := f.fsys.ReadDir(filepath.Join(dir, v.Name()))
+		if err != nil {
+			return err
+		}
+		for _, d := range platforms {
+			if d.IsDir() {
+				continue
+			}
+			if strings.HasSuffix(d.Name(), extractedMarker) {
+				err := f.fsys.Remove(filepath.Join(dir, v.Name(), d.Name()))
+				if err != nil && !errors.Is(err, os.ErrNotExist) {
+					return err
+				}
+			}
+		}
+	}
+	return f.fsys.RemoveAll(dir)
+}
+
+// removeCopies removes the packages of the given name found in dir.
+func (f *FlatBackend) removeCopies(dir, name string) error {
+	dirents, err := f.fsys.ReadDir(dir)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	for _, d := range dirents {
+		var pkg Package
+		if err := pkg.parseNameWith(d.Name(), f.versions); err != nil || pkg.Name != name {
+			continue
+		}
+		if err := f.fsys.Remove(filepath.Join(dir, d.Name())); err != nil &&
+			!errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// dropReceipts removes the receipts of every version of the package.
+func (f *FlatBackend) dropReceipts(name string) error {
+	f.receiptmu.Lock()
+	defer f.receiptmu.Unlock()
+
+	receipts, err := f.Receipts()
+	if err != nil {
+		return err
+	}
+
+	changed := false
+	for filename := range receipts {
+		var pkg Package
+		if err := pkg.parseNameWith(filename, f.versions); err == nil && pkg.Name == name {
+			delete(receipts, filename)
+			changed = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	data, err := json.Marshal(receipts)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.fsys, filepath.Join(f.pkgdir, receiptsFile), data)
+}
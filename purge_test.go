@@ -0,0 +1,111 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDelPurge(t *testing.T) {
+	srv := newAssetsAPI(t, map[string]int{})
+	assetdir := t.TempDir()
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, &Options{ApiURL: srv.URL, AssetCacheDir: assetdir})
+
+	if err := be.Load(pkgVer("s3", "v1.0.0"), strings.NewReader("PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if err := be.Hold("s3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.SetScope("s3", []string{"repo-a"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, dir := range []string{archiveDir, stagedDir} {
+		if err := os.MkdirAll(filepath.Join(pkgdir, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+		touch(t, filepath.Join(pkgdir, dir), pkgVer("s3", "v0.9.0").Filename())
+		touch(t, filepath.Join(pkgdir, dir), pkgVer("ftp", "v1.0.0").Filename())
+	}
+
+	icon, err := m.Icon("s3")
+	if err != nil {
+		t.Fatalf("Icon: %v", err)
+	}
+	featured, err := m.Featured("s3")
+	if err != nil {
+		t.Fatalf("Featured: %v", err)
+	}
+	// the same image as the s3 one.
+	if _, err := m.Icon("gcs"); err != nil {
+		t.Fatalf("Icon(gcs): %v", err)
+	}
+
+	if err := m.Del("s3", &DelOptions{Purge: true}); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	if receipts, _ := be.Receipts(); len(receipts) != 0 {
+		t.Errorf("receipts = %v", receipts)
+	}
+	if held, _ := be.Held(); len(held) != 0 {
+		t.Errorf("held = %v", held)
+	}
+	if scope, _ := be.Scope("s3"); scope != nil {
+		t.Errorf("scope = %v", scope)
+	}
+	for _, dir := range []string{archiveDir, stagedDir} {
+		if _, err := os.Stat(filepath.Join(pkgdir, dir, pkgVer("s3", "v0.9.0").Filename())); !errors.Is(err, os.ErrNotExist) {
+			t.Errorf("s3 left in %s: %v", dir, err)
+		}
+		if _, err := os.Stat(filepath.Join(pkgdir, dir, pkgVer("ftp", "v1.0.0").Filename())); err != nil {
+			t.Errorf("ftp removed from %s: %v", dir, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(cachedir, "s3")); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("cache directory left behind: %v", err)
+	}
+
+	if _, err := os.Stat(featured); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("featured image left behind: %v", err)
+	}
+	if _, err := os.Stat(icon); err != nil {
+		t.Errorf("icon shared with gcs removed: %v", err)
+	}
+	assets, _ := m.assets()
+	for u := range assets {
+		if strings.Contains(u, "/s3/") {
+			t.Errorf("asset %s still indexed", u)
+		}
+	}
+}
+
+func TestDelPurgeRemoved(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+	m, _ := New(be, nil)
+	if err := be.Hold("s3"); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Del("s3", &DelOptions{Purge: true}); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if held, _ := be.Held(); len(held) != 0 {
+		t.Errorf("held = %v", held)
+	}
+}
+
+func TestDelPurgeOptions(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+	m, _ := New(be, nil)
+	if err := m.Del("s3", &DelOptions{Purge: true, KeepArchive: true}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Del = %v, want %v", err, ErrInvalidOptions)
+	}
+
+	m, _ = New(newFakeBackend(), nil)
+	if err := m.Del("s3", &DelOptions{Purge: true}); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Del = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
@@ -0,0 +1,98 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+)
+
+var (
+	ErrQuotaExceeded = errors.New("storage quota exceeded")
+)
+
+// QuotaError is returned when installing a package would make the
+// store grow past its configured quota.
+type QuotaError struct {
+	Quota int64
+	Usage int64
+}
+
+func (e *QuotaError) Error() string {
+	return fmt.Sprintf("%s: %d bytes used out of %d, remove unused packages to free some space",
+		ErrQuotaExceeded, e.Usage, e.Quota)
+}
+
+func (e *QuotaError) Is(target error) bool {
+	return target == ErrQuotaExceeded
+}
+
+// diskUsage returns the total size of the regular files found under
+// the given directories.  Directories that don't exist are ignored.
+func diskUsage(fsys FS, dirs ...string) (int64, error) {
+	var total int64
+	for _, dir := range dirs {
+		err := walkDir(fsys, dir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			info, err := d.Info()
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					return nil
+				}
+				return err
+			}
+			total += info.Size()
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+	}
+	return total, nil
+}
+
+// checkquota fails with a *QuotaError if the package and cache
+// directories, plus the given pending bytes, use more than the
+// configured quota.  A zero quota means no limit.
+func (f *FlatBackend) checkquota(pending int64) error {
+	if f.quota <= 0 {
+		return nil
+	}
+
+	usage, err := diskUsage(f.fsys, f.pkgdir, f.cachedir)
+	if err != nil {
+		return err
+	}
+
+	usage += pending
+	if usage > f.quota {
+		return &QuotaError{Quota: f.quota, Usage: usage}
+	}
+	return nil
+}
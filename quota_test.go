@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDiskUsage(t *testing.T) {
+	root := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "one"), make([]byte, 10), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(root, "a", "b", "two"), make([]byte, 32), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := diskUsage(OSFS{}, root, filepath.Join(root, "does-not-exist"))
+	if err != nil {
+		t.Fatalf("diskUsage: %v", err)
+	}
+	if got != 42 {
+		t.Errorf("diskUsage = %d, want 42", got)
+	}
+}
+
+func TestCheckQuotaUnlimited(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, nil)
+	if err := os.WriteFile(filepath.Join(pkgdir, "big"), make([]byte, 1024), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.checkquota(0); err != nil {
+		t.Errorf("checkquota without a quota: %v", err)
+	}
+}
+
+func TestCheckQuotaExceeded(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Quota: 100})
+	if err := os.WriteFile(filepath.Join(pkgdir, "a"), make([]byte, 60), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.checkquota(0); err != nil {
+		t.Fatalf("checkquota under the limit: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(cachedir, "b"), make([]byte, 60), 0644); err != nil {
+		t.Fatal(err)
+	}
+	err := be.checkquota(0)
+	if !errors.Is(err, ErrQuotaExceeded) {
+		t.Fatalf("checkquota err = %v, want ErrQuotaExceeded", err)
+	}
+
+	var qerr *QuotaError
+	if !errors.As(err, &qerr) {
+		t.Fatalf("checkquota err = %T, want *QuotaError", err)
+	}
+	if qerr.Quota != 100 || qerr.Usage != 120 {
+		t.Errorf("QuotaError = %+v", qerr)
+	}
+}
+
+func TestCheckQuotaPending(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, &FlatBackendOptions{Quota: 100})
+	if err := be.checkquota(100); err != nil {
+		t.Errorf("checkquota(100): %v", err)
+	}
+	if err := be.checkquota(101); !errors.Is(err, ErrQuotaExceeded) {
+		t.Errorf("checkquota(101) err = %v, want ErrQuotaExceeded", err)
+	}
+}
@@ -0,0 +1,99 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/PlakarKorp/kloset/snapshot"
+)
+
+var (
+	ErrBadFilePath = errors.New("invalid file path")
+)
+
+// FileReader is implemented by the backends that can read a single
+// file out of a stored package.
+type FileReader interface {
+	ReadFile(pkg *Package, name string) ([]byte, error)
+}
+
+// packagePath turns name into a slash-separated path relative to the
+// package root, refusing anything that would escape it.
+func packagePath(name string) (string, error) {
+	clean := path.Clean(filepath.ToSlash(name))
+	if clean == "." || clean == ".." || path.IsAbs(clean) ||
+		strings.HasPrefix(clean, "../") {
+		return "", fmt.Errorf("%w %q", ErrBadFilePath, name)
+	}
+	return clean, nil
+}
+
+// ReadFile returns the content of the named file, e.g. "LICENSE",
+// from an installed package.  The version may be omitted if only one
+// is installed.
+func (p *Manager) ReadFile(name, version, file string) ([]byte, error) {
+	fr, ok := p.store.(FileReader)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+
+	pkg, err := p.lookup(name, version)
+	if err != nil {
+		return nil, err
+	}
+
+	return fr.ReadFile(pkg, file)
+}
+
+// ReadFile reads the named file from the extracted tree, which the
+// backend validated when loading the package, rather than decoding
+// the stored ptar again.
+func (f *FlatBackend) ReadFile(pkg *Package, name string) ([]byte, error) {
+	extracted := f.extractedPath(pkg)
+	if !f.isExtracted(extracted) {
+		return nil, fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+	}
+
+	p, err := containedPath(f.fsys, extracted, name)
+	if err != nil {
+		return nil, err
+	}
+	return readFile(f.fsys, p)
+}
+
+func snapshotReadFile(snap *snapshot.Snapshot, base, name string) ([]byte, error) {
+	rel, err := packagePath(name)
+	if err != nil {
+		return nil, err
+	}
+
+	rd, err := snap.NewReader(path.Join(base, rel))
+	if err != nil {
+		return nil, err
+	}
+	defer rd.Close()
+
+	return io.ReadAll(rd)
+}
@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+type readingBackend struct {
+	*fakeBackend
+	files map[string]string
+}
+
+func (b *readingBackend) ReadFile(p *Package, name string) ([]byte, error) {
+	data, ok := b.files[p.Filename()+":"+name]
+	if !ok {
+		return nil, errors.New("no such file")
+	}
+	return []byte(data), nil
+}
+
+func TestPackagePath(t *testing.T) {
+	tests := []struct {
+		in, want string
+		wantErr  bool
+	}{
+		{in: "manifest.yaml", want: "manifest.yaml"},
+		{in: "./bin/../LICENSE", want: "LICENSE"},
+		{in: "docs/README.md", want: "docs/README.md"},
+		{in: "", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "..", wantErr: true},
+		{in: "../etc/passwd", wantErr: true},
+		{in: "bin/../../etc/passwd", wantErr: true},
+		{in: "/etc/passwd", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := packagePath(tt.in)
+		if tt.wantErr {
+			if !errors.Is(err, ErrBadFilePath) {
+				t.Errorf("packagePath(%q) err = %v, want ErrBadFilePath", tt.in, err)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("packagePath(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestManagerReadFile(t *testing.T) {
+	pkg := pkgVer("s3", "v1.0.0")
+	be := &readingBackend{
+		fakeBackend: newFakeBackend(pkg),
+		files:       map[string]string{pkg.Filename() + ":LICENSE": "ISC"},
+	}
+	m, _ := New(be, nil)
+
+	data, err := m.ReadFile("s3", "", "LICENSE")
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if string(data) != "ISC" {
+		t.Errorf("ReadFile = %q", data)
+	}
+
+	if _, err := m.ReadFile("ftp", "", "LICENSE"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("ReadFile(ftp) err = %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestManagerReadFileUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(pkgVer("s3", "v1.0.0")), nil)
+	if _, err := m.ReadFile("s3", "", "LICENSE"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("ReadFile err = %v, want ErrUnsupported", err)
+	}
+}
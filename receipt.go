@@ -0,0 +1,150 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+var ErrTampered = errors.New("package modified since install")
+
+// Name of the file, in the package directory, keeping the install
+// receipts.
+const receiptsFile = ".receipts.json"
+
+// Receipt records the digest of a package as it was installed.
+type Receipt struct {
+	Checksum  string    `json:"checksum"`
+	Installed time.Time `json:"installed"`
+}
+
+// TamperPolicy tells what the FlatBackend does when a package stored
+// on disk no longer matches its install receipt when it's loaded
+// again, e.g. by LoadAll at startup.
+type TamperPolicy int
+
+const (
+	// TamperIgnore doesn't check the packages.
+	TamperIgnore TamperPolicy = iota
+
+	// TamperWarn emits a WarnTampered warning and loads the
+	// package anyway.
+	TamperWarn
+
+	// TamperRefuse doesn't load the package and fails with
+	// ErrTampered.
+	TamperRefuse
+)
+
+// Receipts returns the install receipts, keyed by package file name.
+// The packages installed before the receipts were kept have none.
+func (f *FlatBackend) Receipts() (map[string]Receipt, error) {
+	data, err := readFile(f.fsys, filepath.Join(f.pkgdir, receiptsFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return map[string]Receipt{}, nil
+		}
+		return nil, err
+	}
+
+	receipts := map[string]Receipt{}
+	if err := json.Unmarshal(data, &receipts); err != nil {
+		return nil, err
+	}
+	return receipts, nil
+}
+
+// setReceipt records the receipt of the package, or drops it if nil.
+func (f *FlatBackend) setReceipt(pkg *Package, receipt *Receipt) error {
+	f.receiptmu.Lock()
+	defer f.receiptmu.Unlock()
+
+	receipts, err := f.Receipts()
+	if err != nil {
+		return err
+	}
+
+	if receipt != nil {
+		receipts[pkg.Filename()] = *receipt
+	} else if _, ok := receipts[pkg.Filename()]; ok {
+		delete(receipts, pkg.Filename())
+	} else {
+		return nil
+	}
+
+	data, err := json.Marshal(receipts)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.fsys, filepath.Join(f.pkgdir, receiptsFile), data)
+}
+
+// checkReceipt compares the stored package with its receipt, as the
+// tamper policy says.
+func (f *FlatBackend) checkReceipt(pkg *Package) error {
+	if f.tamperpolicy == TamperIgnore {
+		return nil
+	}
+
+	receipts, err := f.Receipts()
+	if err != nil {
+		return err
+	}
+	receipt, ok := receipts[pkg.Filename()]
+	if !ok {
+		return nil
+	}
+
+	fp, err := f.fsys.Open(f.ptarPath(pkg))
+	if err != nil {
+		return err
+	}
+	defer fp.Close()
+
+	rd, err := newVerifyingReader(fp, receipt.Checksum, 0)
+	if err != nil {
+		return err
+	}
+	if _, err = io.Copy(io.Discard, rd); !errors.Is(err, ErrChecksumMismatch) {
+		return err
+	}
+
+	if f.tamperpolicy == TamperWarn {
+		f.emit(newWarning(pkg, WarnTampered,
+			"%s changed since it was installed on %s", pkg.Filename(),
+			receipt.Installed.Format(time.RFC3339)))
+		return nil
+	}
+	return fmt.Errorf("%s: %w", pkg.Filename(), ErrTampered)
+}
+
+// newReceipt returns the receipt for a package with the given
+// SHA-256 digest.
+func newReceipt(sum []byte) *Receipt {
+	return &Receipt{
+		Checksum:  fmt.Sprintf("sha256:%x", sum),
+		Installed: time.Now(),
+	}
+}
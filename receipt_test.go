@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestReceipts(t *testing.T) {
+	for _, tt := range []struct {
+		policy   TamperPolicy
+		wantErr  error
+		warnings []WarningCode
+		loaded   []string
+	}{
+		{TamperIgnore, nil, nil, []string{"s3", "s3"}},
+		{TamperWarn, nil, []WarningCode{WarnTampered}, []string{"s3", "s3"}},
+		{TamperRefuse, ErrTampered, nil, []string{"s3"}},
+	} {
+		var loaded []string
+		var warnings []WarningCode
+		be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{
+			Extractor:    &fakeExtractor{},
+			TamperPolicy: tt.policy,
+			EventHook: func(ev *Event) {
+				warnings = append(warnings, ev.Code)
+			},
+			LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+				loaded = append(loaded, m.Name)
+				return nil
+			},
+		})
+
+		pkg := pkgVer("s3", "v1.0.0")
+		if err := be.Load(pkg, strings.NewReader("PTARDATA")); err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		receipts, err := be.Receipts()
+		if err != nil {
+			t.Fatalf("Receipts: %v", err)
+		}
+		if got := receipts[pkg.Filename()].Checksum; got != sha256sum("PTARDATA") {
+			t.Errorf("receipt checksum = %q", got)
+		}
+
+		ptar := filepath.Join(pkgdir, pkg.Filename())
+		if err := os.WriteFile(ptar, []byte("EVILDATA"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		err = be.LoadAll()
+		if tt.wantErr == nil && err != nil {
+			t.Errorf("policy %d: LoadAll: %v", tt.policy, err)
+		}
+		if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+			t.Errorf("policy %d: LoadAll err = %v, want %v", tt.policy, err, tt.wantErr)
+		}
+		if !slices.Equal(warnings, tt.warnings) {
+			t.Errorf("policy %d: warnings = %v, want %v", tt.policy, warnings, tt.warnings)
+		}
+		if !slices.Equal(loaded, tt.loaded) {
+			t.Errorf("policy %d: loaded = %v, want %v", tt.policy, loaded, tt.loaded)
+		}
+
+		if err := be.Unload(pkg); err != nil {
+			t.Fatalf("Unload: %v", err)
+		}
+		if receipts, _ := be.Receipts(); len(receipts) != 0 {
+			t.Errorf("receipts left after Unload: %v", receipts)
+		}
+	}
+}
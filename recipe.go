@@ -32,7 +32,16 @@ type Recipe struct {
 	Name       string `yaml:"name"`
 	Version    string `yaml:"version"`
 	Repository string `yaml:"repository"`
-	// Checksum   string `yaml:"checksum"`
+
+	// Digests of the published artifacts, keyed by "os/arch", in
+	// the "sha256:<hex>" form.
+	Checksums map[string]string `yaml:"checksums"`
+
+	// Locations of the artifacts, keyed by "os/arch", used
+	// instead of the repository ones.  Only honoured in the
+	// local overrides directory; they may be URLs or paths,
+	// relative to that directory.
+	Artifacts map[string]string `yaml:"artifacts"`
 }
 
 func NewRecipeFromFile(path string) (*Recipe, error) {
@@ -75,6 +84,18 @@ func (recipe *Recipe) Subdir() string {
 	return "."
 }
 
+// Checksum returns the expected digest of the artifact for the given
+// platform, or the empty string if the recipe doesn't pin it.
+func (recipe *Recipe) Checksum(goos, goarch string) string {
+	return recipe.Checksums[goos+"/"+goarch]
+}
+
+// Artifact returns the location of the artifact for the given
+// platform, or the empty string if the recipe doesn't set it.
+func (recipe *Recipe) Artifact(goos, goarch string) string {
+	return recipe.Artifacts[goos+"/"+goarch]
+}
+
 // xxx unused
 func (recipe *Recipe) PkgName() string {
 	GOOS := runtime.GOOS
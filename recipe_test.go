@@ -160,3 +160,23 @@ func TestRecipePkgNameDefaultsToRuntime(t *testing.T) {
 		t.Errorf("PkgName() = %q, want %q", got, want)
 	}
 }
+
+func TestRecipeChecksum(t *testing.T) {
+	const doc = `
+name: s3
+version: v1.2.3
+checksums:
+  linux/amd64: sha256:aaaa
+  darwin/arm64: sha256:bbbb
+`
+	var r Recipe
+	if err := r.Parse(strings.NewReader(doc)); err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	if got := r.Checksum("darwin", "arm64"); got != "sha256:bbbb" {
+		t.Errorf("Checksum(darwin, arm64) = %q", got)
+	}
+	if got := r.Checksum("plan9", "sparc64"); got != "" {
+		t.Errorf("Checksum(plan9, sparc64) = %q, want empty", got)
+	}
+}
@@ -0,0 +1,73 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import "fmt"
+
+// Recommendation is a companion package offered after an install
+// through [Options.RecommendHook].
+type Recommendation struct {
+	Package   string // the package just installed
+	Spec      Spec   // the package it recommends
+	Suggested bool   // whether it's only a suggestion
+}
+
+// recommend offers the recommendations and suggestions of the given
+// package that are not installed yet.
+func (p *Manager) recommend(name string, m *Manifest) error {
+	if p.recommendhook == nil {
+		return nil
+	}
+
+	offer := func(spec Spec, suggested bool) error {
+		if p.installedVersion(spec.Name) != "" {
+			return nil
+		}
+
+		ok := p.recommendhook(&Recommendation{
+			Package:   name,
+			Spec:      spec,
+			Suggested: suggested,
+		})
+		if !ok {
+			return nil
+		}
+
+		a := p.planSpec(&spec, nil)
+		if a.Err == nil {
+			a.Err = p.apply(&a)
+		}
+		if a.Err != nil {
+			return fmt.Errorf("%s: recommended %s: %w", name, spec.Name, a.Err)
+		}
+		return nil
+	}
+
+	for _, spec := range m.Recommends {
+		if err := offer(spec, false); err != nil {
+			return err
+		}
+	}
+	for _, spec := range m.Suggests {
+		if err := offer(spec, true); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"slices"
+	"testing"
+)
+
+const s3WithCompanions = `name: s3
+recommends:
+  - name: notify
+suggests:
+  - name: metrics
+  - name: fs
+`
+
+func TestRecommendHook(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{
+		"s3":      "v1.0.0",
+		"notify":  "v1.0.0",
+		"metrics": "v1.0.0",
+	})
+
+	var offered []Recommendation
+	be := newManifestBackend(map[string]string{"s3": s3WithCompanions},
+		pkgVer("fs", "v1.0.0"))
+	m, _ := New(be, &Options{
+		InstallURL: srv.URL,
+		RecommendHook: func(r *Recommendation) bool {
+			offered = append(offered, *r)
+			return !r.Suggested
+		},
+	})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	// fs is already installed and isn't offered.
+	want := []Recommendation{
+		{Package: "s3", Spec: Spec{Name: "notify"}},
+		{Package: "s3", Spec: Spec{Name: "metrics"}, Suggested: true},
+	}
+	if !slices.Equal(offered, want) {
+		t.Errorf("offered = %+v, want %+v", offered, want)
+	}
+
+	// only the accepted recommendation got installed.
+	if got := be.installed(); !slices.Equal(got, []string{"fs", "notify", "s3"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestRecommendWithoutHook(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.0.0"})
+
+	be := newManifestBackend(map[string]string{"s3": s3WithCompanions})
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"s3"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
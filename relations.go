@@ -0,0 +1,104 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+var (
+	ErrConflict = errors.New("conflicting packages")
+)
+
+// ConflictError is returned when a package can't be installed
+// because it conflicts with an installed one.
+type ConflictError struct {
+	Name string
+	With string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s: %s conflicts with %s", ErrConflict, e.Name, e.With)
+}
+
+func (e *ConflictError) Is(target error) bool {
+	return target == ErrConflict
+}
+
+// provided returns the names the package can be referred to.
+func provided(name string, m *Manifest) []string {
+	return append([]string{name}, m.Provides...)
+}
+
+// conflicts returns whether the manifest am declares a conflict with
+// any name provided by the package b.
+func conflicts(am *Manifest, b string, bm *Manifest) bool {
+	for _, n := range provided(b, bm) {
+		if slices.Contains(am.Conflicts, n) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolveRelations removes the installed packages that the new one
+// replaces.  If it conflicts with one it doesn't replace, the new
+// package is removed instead and a *ConflictError returned.  This
+// can only happen once the package is stored, since the manifest is
+// not known before.
+func (p *Manager) resolveRelations(pkg *Package, m *Manifest) error {
+	var others []*Package
+	for other, err := range p.store.List("") {
+		if err != nil {
+			return err
+		}
+		if other.Name != pkg.Name {
+			others = append(others, other)
+		}
+	}
+
+	var replaced []*Package
+	for _, other := range others {
+		om, err := p.manifest(other)
+		if err != nil {
+			return err
+		}
+
+		if slices.Contains(m.Replaces, other.Name) {
+			replaced = append(replaced, other)
+			continue
+		}
+
+		if conflicts(m, other.Name, om) || conflicts(om, pkg.Name, m) {
+			if err := p.Del(pkg.Name, &DelOptions{Version: pkg.Version}); err != nil {
+				return err
+			}
+			return &ConflictError{Name: pkg.Name, With: other.Name}
+		}
+	}
+
+	for _, other := range replaced {
+		if err := p.Del(other.Name, &DelOptions{Version: other.Version}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,66 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+const s3Pro = `name: s3-pro
+provides: [s3-compatible]
+conflicts: [s3]
+replaces: [s3]
+`
+
+func TestAddReplaces(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3-pro": "v1.0.0"})
+
+	be := newManifestBackend(map[string]string{"s3-pro": s3Pro},
+		pkgVer("s3", "v1.0.0"), pkgVer("fs", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if err := m.Add("s3-pro", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"fs", "s3-pro"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestAddConflicts(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"minio": "v1.0.0"})
+
+	// minio conflicts with what s3-pro provides.
+	be := newManifestBackend(map[string]string{
+		"s3-pro": s3Pro,
+		"minio":  "name: minio\nconflicts: [s3-compatible]\n",
+	}, pkgVer("s3-pro", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	err := m.Add("minio", &AddOptions{ImplicitFetch: true})
+	var cerr *ConflictError
+	if !errors.As(err, &cerr) || !errors.Is(err, ErrConflict) {
+		t.Fatalf("Add err = %v, want a *ConflictError", err)
+	}
+	if cerr.Name != "minio" || cerr.With != "s3-pro" {
+		t.Errorf("ConflictError = %+v", cerr)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"s3-pro"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestAddConflictDeclaredByInstalled(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.0.0"})
+
+	be := newManifestBackend(map[string]string{"s3-pro": s3Pro},
+		pkgVer("s3-pro", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Add err = %v, want ErrConflict", err)
+	}
+	if got := be.installed(); !slices.Equal(got, []string{"s3-pro"}) {
+		t.Errorf("installed = %v", got)
+	}
+}
@@ -0,0 +1,294 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"time"
+)
+
+// Reloader is implemented by the backends that can reload an
+// installed package on the live host, firing the unload hooks for
+// what was loaded and the load hooks for what is on disk now.  A
+// package that isn't loaded is left alone.
+type Reloader interface {
+	Reload(*Package) error
+}
+
+// Reload reloads the loaded versions of the named package, so that a
+// package replaced on disk is picked up without restarting the host.
+// The versions installed but not loaded are left alone.
+func (p *Manager) Reload(name string) error {
+	r, ok := p.store.(Reloader)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	var (
+		merr  MultiError
+		found bool
+	)
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return err
+		}
+		found = true
+		merr.add(pkg, r.Reload(pkg))
+	}
+	if !found {
+		return fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+	return merr.err()
+}
+
+// loadedPackage remembers what the stored file of a loaded package
+// looked like, to notice when it's replaced.
+type loadedPackage struct {
+	pkg     *Package
+	size    int64
+	modtime time.Time
+}
+
+// markLoaded records that the package was loaded from its current
+// file.
+func (f *FlatBackend) markLoaded(pkg *Package) {
+	fi, err := f.fsys.Stat(f.ptarPath(pkg))
+	if err != nil {
+		return
+	}
+
+	f.loadedmu.Lock()
+	defer f.loadedmu.Unlock()
+	if f.loaded == nil {
+		f.loaded = make(map[string]*loadedPackage)
+	}
+	f.loaded[f.ptarPath(pkg)] = &loadedPackage{
+		pkg:     pkg,
+		size:    fi.Size(),
+		modtime: fi.ModTime(),
+	}
+	delete(f.idle, f.ptarPath(pkg))
+}
+
+// markIdle records that the package is installed but not loaded.
+func (f *FlatBackend) markIdle(pkg *Package) {
+	f.loadedmu.Lock()
+	defer f.loadedmu.Unlock()
+	if f.idle == nil {
+		f.idle = make(map[string]bool)
+	}
+	f.idle[f.ptarPath(pkg)] = true
+}
+
+func (f *FlatBackend) isIdle(pkg *Package) bool {
+	f.loadedmu.Lock()
+	defer f.loadedmu.Unlock()
+	return f.idle[f.ptarPath(pkg)]
+}
+
+// isLoaded returns whether the package was loaded by this backend.
+func (f *FlatBackend) isLoaded(pkg *Package) bool {
+	f.loadedmu.Lock()
+	defer f.loadedmu.Unlock()
+	_, ok := f.loaded[f.ptarPath(pkg)]
+	return ok
+}
+
+func (f *FlatBackend) markUnloaded(pkg *Package) {
+	f.loadedmu.Lock()
+	defer f.loadedmu.Unlock()
+	delete(f.loaded, f.ptarPath(pkg))
+	delete(f.idle, f.ptarPath(pkg))
+}
+
+// Reload fires the unload hooks for the package as it's currently
+// extracted, extracts its stored file again and fires the load hooks.
+// The package is left alone if the PreUnloadHook refuses it, or if
+// it isn't loaded.
+func (f *FlatBackend) Reload(pkg *Package) error {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	if _, err := f.fsys.Stat(f.ptarPath(pkg)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+		}
+		return err
+	}
+	if !f.isLoaded(pkg) {
+		return nil
+	}
+	return f.reloadLive(pkg)
+}
+
+func (f *FlatBackend) reloadLive(pkg *Package) error {
+	if _, err := f.fsys.Stat(f.ptarPath(pkg)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+		}
+		return err
+	}
+
+	extracted := f.extractedPath(pkg)
+	if err := f.runUnloadHooks(pkg, extracted); err != nil &&
+		!errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+
+	return f.loadFresh(pkg)
+}
+
+// loadFresh extracts the stored file of the package again, dropping
+// whatever was extracted before, and fires the load hooks.
+func (f *FlatBackend) loadFresh(pkg *Package) error {
+	extracted := f.extractedPath(pkg)
+	if err := f.removeExtracted(extracted); err != nil {
+		return err
+	}
+	if err := f.extract(pkg, extracted, f.ptarPath(pkg)); err != nil {
+		return err
+	}
+	return f.reload(pkg)
+}
+
+// runUnloadHooks calls the unload hooks with the manifest found in
+// the extracted tree.
+func (f *FlatBackend) runUnloadHooks(pkg *Package, extracted string) error {
+	if f.preunloadhook == nil && f.unloadhook == nil {
+		return nil
+	}
+
+	manifest, err := f.readManifest(pkg, filepath.Join(extracted, "manifest.yaml"))
+	if err != nil {
+		return err
+	}
+
+	if f.preunloadhook != nil {
+		if err := f.preunloadhook(f.kcontext, manifest, pkg); err != nil {
+			return err
+		}
+	}
+
+	if f.unloadhook != nil {
+		f.unloadhook(f.kcontext, manifest, pkg)
+	}
+	return nil
+}
+
+// Watch checks the package directory every interval until the
+// context is done, and applies the changes made behind the
+// backend's back: replaced packages are reloaded, new ones are
+// loaded and the unload hooks are fired for the removed ones.  Only
+// the packages loaded by this backend are watched for replacements
+// and removals, and the new ones are picked as LoadAll or LoadAllFor
+// last did: the packages of the names not loaded yet, in their
+// active version, and in the scope it was given.  Failures are
+// reported as EventWarning events.
+func (f *FlatBackend) Watch(ctx context.Context, interval time.Duration) error {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+			if err := f.rescan(); err != nil {
+				f.emit(newWarning(nil, WarnReloadFailed,
+					"failed to scan %s: %v", f.pkgdir, err))
+			}
+		}
+	}
+}
+
+// rescan reloads what changed on disk since the packages were
+// loaded.
+func (f *FlatBackend) rescan() error {
+	f.oplock.Lock()
+	defer f.oplock.Unlock()
+
+	f.loadedmu.Lock()
+	gone := make(map[string]*loadedPackage, len(f.loaded))
+	for path, lp := range f.loaded {
+		gone[path] = lp
+	}
+	keep := f.loadkeep
+	f.loadedmu.Unlock()
+	if keep == nil {
+		keep = func(*Package) bool { return true }
+	}
+
+	// the names with a version still loaded.
+	live := map[string]bool{}
+	for pkg, err := range f.List("") {
+		if err != nil {
+			return err
+		}
+
+		path := f.ptarPath(pkg)
+		lp, known := gone[path]
+		if !known {
+			continue
+		}
+		delete(gone, path)
+		live[pkg.Name] = true
+
+		fi, err := f.fsys.Stat(path)
+		if err != nil {
+			continue
+		}
+		if fi.Size() == lp.size && fi.ModTime().Equal(lp.modtime) {
+			continue
+		}
+		if err := f.reloadLive(pkg); err != nil {
+			f.emit(newWarning(pkg, WarnReloadFailed,
+				"failed to reload %s: %v", pkg.Filename(), err))
+		}
+	}
+
+	pkgs, err := f.loadable(keep)
+	if err != nil {
+		return err
+	}
+	for _, pkg := range pkgs {
+		if live[pkg.Name] {
+			continue
+		}
+		if err := f.loadFresh(pkg); err != nil {
+			f.emit(newWarning(pkg, WarnReloadFailed,
+				"failed to load %s: %v", pkg.Filename(), err))
+		}
+	}
+
+	for _, lp := range gone {
+		extracted := f.extractedPath(lp.pkg)
+		if err := f.runUnloadHooks(lp.pkg, extracted); err != nil &&
+			!errors.Is(err, fs.ErrNotExist) {
+			f.emit(newWarning(lp.pkg, WarnReloadFailed,
+				"failed to unload %s: %v", lp.pkg.Filename(), err))
+			continue
+		}
+		f.removeExtracted(extracted)
+		f.markUnloaded(lp.pkg)
+	}
+	return nil
+}
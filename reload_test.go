@@ -0,0 +1,160 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// newReloadBackend returns a backend recording the hooks it fires.
+func newReloadBackend(t *testing.T, hooks *[]string) (*FlatBackend, *fakeExtractor, string) {
+	t.Helper()
+	ext := &fakeExtractor{}
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{
+		Extractor: ext,
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			*hooks = append(*hooks, "load "+p.Version)
+			return nil
+		},
+		UnloadHook: func(ctx context.Context, m *Manifest, p *Package) {
+			*hooks = append(*hooks, "unload "+p.Version)
+		},
+	})
+	return be, ext, pkgdir
+}
+
+func TestFlatBackendReload(t *testing.T) {
+	var hooks []string
+	be, ext, pkgdir := newReloadBackend(t, &hooks)
+
+	pkg := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, pkg.Filename())
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	m, _ := New(be, nil)
+	if err := m.Reload("s3"); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+
+	want := []string{"load v1.0.0", "unload v1.0.0", "load v1.0.0"}
+	if !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %v, want %v", hooks, want)
+	}
+	if len(ext.extracted) != 2 {
+		t.Errorf("extracted %d times, want 2", len(ext.extracted))
+	}
+
+	if err := m.Reload("ftp"); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Reload(ftp) = %v, want %v", err, ErrNotInstalled)
+	}
+}
+
+func TestReloadUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(pkgOf(t, "s3")), nil)
+	if err := m.Reload("s3"); !errors.Is(err, errors.ErrUnsupported) {
+		t.Fatalf("Reload = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
+
+func TestFlatBackendRescan(t *testing.T) {
+	var hooks []string
+	be, _, pkgdir := newReloadBackend(t, &hooks)
+
+	old := pkgVer("s3", "v1.0.0")
+	touch(t, pkgdir, old.Filename())
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+
+	// nothing changed.
+	if err := be.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if len(hooks) != 1 {
+		t.Fatalf("hooks = %v, want only the initial load", hooks)
+	}
+
+	// the file is replaced in place.
+	path := filepath.Join(pkgdir, old.Filename())
+	if err := os.WriteFile(path, []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	want := []string{"load v1.0.0", "unload v1.0.0", "load v1.0.0"}
+	if !slices.Equal(hooks, want) {
+		t.Fatalf("hooks = %v, want %v", hooks, want)
+	}
+
+	// an upgrade is dropped in the directory.
+	hooks = nil
+	if err := os.Remove(path); err != nil {
+		t.Fatal(err)
+	}
+	touch(t, pkgdir, pkgVer("s3", "v1.1.0").Filename())
+	if err := be.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	want = []string{"load v1.1.0", "unload v1.0.0"}
+	if !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %v, want %v", hooks, want)
+	}
+	if _, err := os.Stat(be.extractedPath(old)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("old extraction left behind: %v", err)
+	}
+}
+
+// rescan only loads what LoadAll would have: the active version, in
+// the scope it was given.
+func TestFlatBackendRescanSelection(t *testing.T) {
+	var hooks []string
+	be, _, pkgdir := newReloadBackend(t, &hooks)
+
+	for _, pkg := range []*Package{pkgVer("s3", "v1.0.0"), pkgVer("s3", "v1.1.0")} {
+		touch(t, pkgdir, pkg.Filename())
+	}
+	if err := be.SetScope("sftp", []string{"https://other.example/"}); err != nil {
+		t.Fatal(err)
+	}
+	// as if it was loaded once.
+	active := pkgVer("s3", "v1.0.0")
+	installExtracted(t, pkgdir, be.cachedir, active)
+	if err := be.SetActive(active); err != nil {
+		t.Fatalf("SetActive: %v", err)
+	}
+
+	if err := be.LoadAllFor("https://plakar.example/"); err != nil {
+		t.Fatalf("LoadAllFor: %v", err)
+	}
+	if want := []string{"load v1.0.0"}; !slices.Equal(hooks, want) {
+		t.Fatalf("hooks = %v, want %v", hooks, want)
+	}
+
+	// a package scoped to another repository shows up.
+	hooks = nil
+	touch(t, pkgdir, pkgVer("sftp", "v1.0.0").Filename())
+	if err := be.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if err := be.rescan(); err != nil {
+		t.Fatalf("rescan: %v", err)
+	}
+	if len(hooks) != 0 {
+		t.Errorf("hooks = %v, want none", hooks)
+	}
+
+	// only the loaded version is reloaded.
+	m, _ := New(be, nil)
+	if err := m.Reload("s3"); err != nil {
+		t.Fatalf("Reload: %v", err)
+	}
+	if want := []string{"unload v1.0.0", "load v1.0.0"}; !slices.Equal(hooks, want) {
+		t.Errorf("hooks = %v, want %v", hooks, want)
+	}
+}
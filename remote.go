@@ -0,0 +1,318 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// The remote backend API is made of the following endpoints, all
+// requiring an "Authorization: Bearer <token>" header:
+//
+//	GET    /packages?name=NAME        list, as a JSON array of Package
+//	PUT    /packages/FILENAME         load the ptar in the body
+//	GET    /packages/FILENAME         return the stored ptar
+//	DELETE /packages/FILENAME         unload
+//	POST   /packages/FILENAME/verify  verify
+//
+// Failures are reported with the status code and the error message
+// as a plain text body.
+
+// remoteNames is how the package filenames are parsed by the agent.
+// It's lax on purpose, the agent backend validates the versions.
+var remoteNames = EpochScheme(LooseScheme)
+
+// RemoteBackend is a Backend driving the backend of a remote agent
+// through the API served by [NewBackendHandler].
+type RemoteBackend struct {
+	url     string
+	client  *http.Client
+	reqhook RequestHook
+}
+
+type RemoteBackendOptions struct {
+	// The HTTP client to use.  Defaults to http.DefaultClient.
+	Client *http.Client
+
+	// Called on every request, e.g. to authenticate it with
+	// [WithBearer].
+	RequestHook RequestHook
+}
+
+// NewRemoteBackend returns a backend talking to the agent API at
+// the given URL.
+func NewRemoteBackend(baseurl string, opts *RemoteBackendOptions) (*RemoteBackend, error) {
+	if opts == nil {
+		opts = &RemoteBackendOptions{}
+	}
+
+	if _, err := url.Parse(baseurl); err != nil {
+		return nil, err
+	}
+
+	r := &RemoteBackend{
+		url:     strings.TrimSuffix(baseurl, "/"),
+		client:  opts.Client,
+		reqhook: opts.RequestHook,
+	}
+	if r.client == nil {
+		r.client = http.DefaultClient
+	}
+	return r, nil
+}
+
+func (r *RemoteBackend) do(method, path string, body io.Reader) (*http.Response, error) {
+	req, err := http.NewRequest(method, r.url+path, body)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.reqhook != nil {
+		if err := r.reqhook(req); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.StatusCode/100 != 2 {
+		defer resp.Body.Close()
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, remoteError(resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return resp, nil
+}
+
+// remoteError turns an error response back into an error matching
+// the sentinel the agent returned.
+func remoteError(status int, msg string) error {
+	var kind error
+	switch status {
+	case http.StatusNotFound:
+		kind = ErrNotInstalled
+	case http.StatusConflict:
+		kind = ErrCorrupted
+	case http.StatusUnauthorized, http.StatusForbidden:
+		kind = ErrUnauthorized
+	case http.StatusBadRequest:
+		kind = ErrBadPackageName
+	default:
+		return fmt.Errorf("remote backend: %s", msg)
+	}
+	return fmt.Errorf("remote backend: %w: %s", kind, msg)
+}
+
+func packageURLPath(pkg *Package) string {
+	return "/packages/" + url.PathEscape(pkg.Filename())
+}
+
+func (r *RemoteBackend) List(name string) iter.Seq2[*Package, error] {
+	return func(yield func(*Package, error) bool) {
+		resp, err := r.do("GET", "/packages?name="+url.QueryEscape(name), nil)
+		if err != nil {
+			yield(nil, err)
+			return
+		}
+		defer resp.Body.Close()
+
+		var pkgs []*Package
+		if err := json.NewDecoder(resp.Body).Decode(&pkgs); err != nil {
+			yield(nil, err)
+			return
+		}
+
+		for _, pkg := range pkgs {
+			if !yield(pkg, nil) {
+				return
+			}
+		}
+	}
+}
+
+func (r *RemoteBackend) Load(pkg *Package, rd io.Reader) error {
+	resp, err := r.do("PUT", packageURLPath(pkg), rd)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (r *RemoteBackend) Unload(pkg *Package) error {
+	resp, err := r.do("DELETE", packageURLPath(pkg), nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+func (r *RemoteBackend) Open(pkg *Package) (io.ReadCloser, error) {
+	resp, err := r.do("GET", packageURLPath(pkg), nil)
+	if err != nil {
+		return nil, err
+	}
+	return resp.Body, nil
+}
+
+func (r *RemoteBackend) Verify(pkg *Package) error {
+	resp, err := r.do("POST", packageURLPath(pkg)+"/verify", nil)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// backendHandler serves a Backend to RemoteBackend clients.
+type backendHandler struct {
+	store Backend
+	token string
+}
+
+// NewBackendHandler returns the handler to run on an agent to let a
+// RemoteBackend drive its backend.  Requests must carry the given
+// bearer token.
+func NewBackendHandler(store Backend, token string) http.Handler {
+	h := &backendHandler{store: store, token: token}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /packages", h.list)
+	mux.HandleFunc("PUT /packages/{filename}", h.load)
+	mux.HandleFunc("GET /packages/{filename}", h.open)
+	mux.HandleFunc("DELETE /packages/{filename}", h.unload)
+	mux.HandleFunc("POST /packages/{filename}/verify", h.verify)
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !h.authorized(r) {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+func (h *backendHandler) authorized(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || h.token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(token), []byte(h.token)) == 1
+}
+
+// handlerError reports err with the status code RemoteBackend maps
+// back to the right sentinel.
+func handlerError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch {
+	case errors.Is(err, ErrNotInstalled):
+		status = http.StatusNotFound
+	case errors.Is(err, ErrCorrupted):
+		status = http.StatusConflict
+	case errors.Is(err, ErrBadPackageName):
+		status = http.StatusBadRequest
+	}
+	http.Error(w, err.Error(), status)
+}
+
+func (h *backendHandler) pkg(w http.ResponseWriter, r *http.Request) (*Package, bool) {
+	var pkg Package
+	if err := pkg.parseNameWith(r.PathValue("filename"), remoteNames); err != nil {
+		handlerError(w, err)
+		return nil, false
+	}
+	return &pkg, true
+}
+
+func (h *backendHandler) list(w http.ResponseWriter, r *http.Request) {
+	pkgs := []*Package{}
+	for pkg, err := range h.store.List(r.URL.Query().Get("name")) {
+		if err != nil {
+			handlerError(w, err)
+			return
+		}
+		pkgs = append(pkgs, pkg)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(pkgs)
+}
+
+func (h *backendHandler) load(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.pkg(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.Load(pkg, r.Body); err != nil {
+		handlerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *backendHandler) open(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.pkg(w, r)
+	if !ok {
+		return
+	}
+	rd, err := h.store.Open(pkg)
+	if err != nil {
+		handlerError(w, err)
+		return
+	}
+	defer rd.Close()
+
+	w.Header().Set("Content-Type", "application/octet-stream")
+	io.Copy(w, rd)
+}
+
+func (h *backendHandler) unload(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.pkg(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.Unload(pkg); err != nil {
+		handlerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *backendHandler) verify(w http.ResponseWriter, r *http.Request) {
+	pkg, ok := h.pkg(w, r)
+	if !ok {
+		return
+	}
+	if err := h.store.Verify(pkg); err != nil {
+		handlerError(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,95 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newTestRemote(t *testing.T, be Backend, token string) *RemoteBackend {
+	t.Helper()
+	srv := httptest.NewServer(NewBackendHandler(be, "s3cr3t"))
+	t.Cleanup(srv.Close)
+
+	r, err := NewRemoteBackend(srv.URL, &RemoteBackendOptions{
+		RequestHook: WithBearer(func() (string, error) { return token, nil }),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return r
+}
+
+func TestRemoteBackend(t *testing.T) {
+	be := newFakeBackend(pkgVer("fs", "v1.0.0"))
+	r := newTestRemote(t, be, "s3cr3t")
+
+	s3 := pkgVer("s3", "v1.2.0")
+	if err := r.Load(s3, strings.NewReader("PTARDATA")); err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+
+	var names []string
+	for pkg, err := range r.List("") {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		names = append(names, pkg.Name+"@"+pkg.Version)
+	}
+	if strings.Join(names, " ") != "fs@v1.0.0 s3@v1.2.0" {
+		t.Errorf("List = %v", names)
+	}
+
+	rd, err := r.Open(s3)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	data, _ := io.ReadAll(rd)
+	rd.Close()
+	if string(data) != "PTARDATA" {
+		t.Errorf("Open returned %q", data)
+	}
+
+	if err := r.Verify(s3); err != nil {
+		t.Errorf("Verify: %v", err)
+	}
+	be.corrupted = []string{"s3"}
+	if err := r.Verify(s3); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Verify err = %v, want ErrCorrupted", err)
+	}
+
+	if err := r.Unload(s3); err != nil {
+		t.Fatalf("Unload: %v", err)
+	}
+	if _, err := r.Open(s3); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Open after Unload err = %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestRemoteBackendUnauthorized(t *testing.T) {
+	r := newTestRemote(t, newFakeBackend(), "wrong")
+	for _, err := range r.List("") {
+		if !errors.Is(err, ErrUnauthorized) {
+			t.Errorf("List err = %v, want ErrUnauthorized", err)
+		}
+	}
+	if err := r.Load(pkgVer("s3", "v1.0.0"), strings.NewReader("")); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("Load err = %v, want ErrUnauthorized", err)
+	}
+}
+
+func TestRemoteBackendManager(t *testing.T) {
+	be := newFakeBackend(pkgVer("fs", "v1.0.0"), pkgVer("s3", "v1.0.0"))
+	m, err := New(newTestRemote(t, be, "s3cr3t"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Del("s3", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+	if len(be.unloaded) != 1 || be.unloaded[0].Name != "s3" {
+		t.Errorf("unloaded = %v", be.unloaded)
+	}
+}
@@ -0,0 +1,80 @@
+//go:build linux
+
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+)
+
+// sandboxSupported starts the helper in the sandbox and has it return
+// at once: namespaces, the unprivileged user ones in particular, are
+// often restricted.
+func sandboxSupported() bool {
+	exe, err := os.Executable()
+	if err != nil {
+		return false
+	}
+	work, err := os.MkdirTemp("", stagingPrefix+"probe-")
+	if err != nil {
+		return false
+	}
+	defer os.RemoveAll(work)
+
+	cmd := exec.Command(exe)
+	cmd.Dir = work
+	cmd.Env = []string{
+		helperEnv + "=" + helperProbe,
+		helperSandboxEnv + "=1",
+		"HOME=/",
+		"TMPDIR=/",
+	}
+	cmd.SysProcAttr = &syscall.SysProcAttr{}
+	sandboxAttrs(cmd.SysProcAttr)
+	return cmd.Run() == nil
+}
+
+// sandboxAttrs starts the helper in its own namespaces, without
+// network access.  Unprivileged, it gets a user namespace in which
+// it is root, so that it can chroot.
+func sandboxAttrs(attr *syscall.SysProcAttr) {
+	attr.Cloneflags = syscall.CLONE_NEWNS | syscall.CLONE_NEWNET |
+		syscall.CLONE_NEWIPC | syscall.CLONE_NEWUTS | syscall.CLONE_NEWPID
+	if privileged() {
+		return
+	}
+
+	attr.Cloneflags |= syscall.CLONE_NEWUSER
+	attr.UidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: os.Getuid(), Size: 1},
+	}
+	attr.GidMappings = []syscall.SysProcIDMap{
+		{ContainerID: 0, HostID: os.Getgid(), Size: 1},
+	}
+	attr.GidMappingsEnableSetgroups = false
+}
+
+// privatizeMounts keeps whatever happens to the helper mount
+// namespace from propagating back to the host.
+func privatizeMounts() error {
+	return syscall.Mount("", "/", "", syscall.MS_REC|syscall.MS_PRIVATE, "")
+}
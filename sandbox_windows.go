@@ -0,0 +1,72 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"os"
+	"os/exec"
+	"syscall"
+	"unsafe"
+)
+
+const disableMaxPrivilege = 0x1
+
+var procCreateRestrictedToken = syscall.NewLazyDLL("advapi32.dll").NewProc("CreateRestrictedToken")
+
+func sandboxSupported() bool {
+	return procCreateRestrictedToken.Find() == nil
+}
+
+// configure runs the sandboxed helper with a restricted copy of our
+// token, stripped of all its privileges.
+func (e *helperExtractor) configure(cmd *exec.Cmd) (func(), error) {
+	// windows needs these to initialize the process.
+	cmd.Env = append(cmd.Env, "SYSTEMROOT="+os.Getenv("SYSTEMROOT"))
+	if !e.sandbox {
+		return func() {}, nil
+	}
+
+	proc, err := syscall.GetCurrentProcess()
+	if err != nil {
+		return nil, err
+	}
+
+	var token syscall.Token
+	err = syscall.OpenProcessToken(proc,
+		syscall.TOKEN_DUPLICATE|syscall.TOKEN_QUERY|syscall.TOKEN_ASSIGN_PRIMARY, &token)
+	if err != nil {
+		return nil, err
+	}
+	defer token.Close()
+
+	var restricted syscall.Token
+	r, _, err := procCreateRestrictedToken.Call(uintptr(token), disableMaxPrivilege,
+		0, 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&restricted)))
+	if r == 0 {
+		return nil, err
+	}
+
+	cmd.SysProcAttr = &syscall.SysProcAttr{Token: restricted}
+	return func() { restricted.Close() }, nil
+}
+
+// The restricted token is all the confinement there is on windows.
+func enterSandbox() error {
+	return nil
+}
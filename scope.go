@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+)
+
+// Name of the file, in the package directory, mapping the packages
+// to the repositories they are scoped to.
+const scopesFile = ".scopes.json"
+
+// Scoper is implemented by the backends that can restrict packages
+// to some kloset repositories.  A package without a scope is global
+// and is loaded for every repository.
+type Scoper interface {
+	SetScope(name string, repositories []string) error
+	Scope(name string) ([]string, error)
+}
+
+// SetScope restricts the named package to the given repositories,
+// or makes it global again if none is given.
+func (p *Manager) SetScope(name string, repositories []string) error {
+	s, ok := p.store.(Scoper)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+	if p.installedVersion(name) == "" {
+		return fmt.Errorf("%s: %w", name, ErrNotInstalled)
+	}
+	return s.SetScope(name, repositories)
+}
+
+// Scope returns the repositories the named package is restricted
+// to, or nil if it's global.
+func (p *Manager) Scope(name string) ([]string, error) {
+	s, ok := p.store.(Scoper)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return s.Scope(name)
+}
+
+func (f *FlatBackend) scopes() (map[string][]string, error) {
+	scopes := make(map[string][]string)
+
+	data, err := readFile(f.fsys, filepath.Join(f.pkgdir, scopesFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return scopes, nil
+		}
+		return nil, err
+	}
+
+	if err := json.Unmarshal(data, &scopes); err != nil {
+		return nil, err
+	}
+	return scopes, nil
+}
+
+func (f *FlatBackend) SetScope(name string, repositories []string) error {
+	scopes, err := f.scopes()
+	if err != nil {
+		return err
+	}
+
+	if len(repositories) == 0 {
+		delete(scopes, name)
+	} else {
+		repositories = slices.Clone(repositories)
+		slices.Sort(repositories)
+		scopes[name] = slices.Compact(repositories)
+	}
+
+	data, err := json.Marshal(scopes)
+	if err != nil {
+		return err
+	}
+	return writeFile(f.fsys, filepath.Join(f.pkgdir, scopesFile), data)
+}
+
+func (f *FlatBackend) Scope(name string) ([]string, error) {
+	scopes, err := f.scopes()
+	if err != nil {
+		return nil, err
+	}
+	return scopes[name], nil
+}
+
+// LoadAllFor is like LoadAll but skips the packages scoped to other
+// repositories than the given one.
+func (f *FlatBackend) LoadAllFor(repository string) error {
+	scopes, err := f.scopes()
+	if err != nil {
+		return err
+	}
+
+	return f.loadAll(func(pkg *Package) bool {
+		s, ok := scopes[pkg.Name]
+		return !ok || slices.Contains(s, repository)
+	})
+}
@@ -0,0 +1,98 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+// installExtracted lays out an installed and fully extracted package
+// with a minimal manifest, without going through a real ptar.
+func installExtracted(t *testing.T, pkgdir, cachedir string, pkg *Package) string {
+	t.Helper()
+	touch(t, pkgdir, pkg.Filename())
+
+	extracted := cachePath(cachedir, pkg)
+	if err := os.MkdirAll(extracted, 0755); err != nil {
+		t.Fatal(err)
+	}
+	markExtracted(t, extracted)
+	manifest := []byte("name: " + pkg.Name + "\n")
+	if err := os.WriteFile(filepath.Join(extracted, "manifest.yaml"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+	return extracted
+}
+
+func TestFlatBackendScope(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+
+	if s, err := be.Scope("s3"); err != nil || s != nil {
+		t.Fatalf("Scope of an unscoped package = %v, %v", s, err)
+	}
+
+	if err := be.SetScope("s3", []string{"repo-b", "repo-a", "repo-b"}); err != nil {
+		t.Fatalf("SetScope: %v", err)
+	}
+	s, err := be.Scope("s3")
+	if err != nil {
+		t.Fatalf("Scope: %v", err)
+	}
+	if !slices.Equal(s, []string{"repo-a", "repo-b"}) {
+		t.Errorf("Scope = %v", s)
+	}
+
+	if err := be.SetScope("s3", nil); err != nil {
+		t.Fatalf("SetScope(nil): %v", err)
+	}
+	if s, _ := be.Scope("s3"); s != nil {
+		t.Errorf("Scope after reset = %v", s)
+	}
+}
+
+func TestFlatBackendLoadAllFor(t *testing.T) {
+	var loaded []string
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			loaded = append(loaded, m.Name)
+			return nil
+		},
+	})
+
+	for _, name := range []string{"fs", "s3", "sftp"} {
+		installExtracted(t, pkgdir, cachedir, pkgVer(name, "v1.0.0"))
+	}
+	if err := be.SetScope("s3", []string{"repo-a"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.SetScope("sftp", []string{"repo-b"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := be.LoadAllFor("repo-a"); err != nil {
+		t.Fatalf("LoadAllFor: %v", err)
+	}
+	slices.Sort(loaded)
+	if !slices.Equal(loaded, []string{"fs", "s3"}) {
+		t.Errorf("loaded for repo-a = %v, want [fs s3]", loaded)
+	}
+
+	loaded = nil
+	if err := be.LoadAll(); err != nil {
+		t.Fatalf("LoadAll: %v", err)
+	}
+	if len(loaded) != 3 {
+		t.Errorf("LoadAll loaded %v", loaded)
+	}
+}
+
+func TestSetScopeNotInstalled(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+	m, _ := New(be, nil)
+	if err := m.SetScope("s3", []string{"repo-a"}); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("SetScope err = %v, want ErrNotInstalled", err)
+	}
+}
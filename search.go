@@ -0,0 +1,156 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// Weights of the matches found by Search: a hit on the name counts
+// more than one on the tags, which counts more than one in the
+// description.
+const (
+	scoreNameExact   = 100
+	scoreNamePrefix  = 60
+	scoreNameSubstr  = 40
+	scoreNameFuzzy   = 30
+	scoreTagExact    = 25
+	scoreTagSubstr   = 15
+	scoreTagFuzzy    = 10
+	scoreDescSubstr  = 5
+	scoreDescFuzzy   = 2
+	minFuzzyTokenLen = 3
+)
+
+// Search returns the integrations matching the query, the best
+// matches first.  The query words are looked for in the names, the
+// tags, the capabilities and the descriptions, tolerating typos.  It
+// works on the cached index, so it keeps working offline when
+// Options.IndexCacheFile is set.  The options filter the integrations
+// as for Query.
+func (p *Manager) Search(query string, opts *QueryOptions) ([]*Integration, error) {
+	integrations, err := p.Query(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	words := searchWords(query)
+	if len(words) == 0 {
+		return integrations, nil
+	}
+
+	scores := make(map[*Integration]int)
+	var ret []*Integration
+	for _, integration := range integrations {
+		if score := searchScore(integration, words); score > 0 {
+			scores[integration] = score
+			ret = append(ret, integration)
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b *Integration) int {
+		return cmp.Compare(scores[b], scores[a])
+	})
+	return ret, nil
+}
+
+// searchWords splits s into lowercase words.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+func searchScore(integration *Integration, words []string) int {
+	names := searchWords(integration.Name + " " + integration.DisplayName)
+	var tags []string
+	for _, tag := range integration.Tags {
+		tags = append(tags, searchWords(tag)...)
+	}
+	for _, capability := range integration.Provides {
+		tags = append(tags, searchWords(capability)...)
+	}
+	desc := searchWords(integration.Description)
+
+	total := 0
+	for _, w := range words {
+		total += max(
+			matchScore(w, names, scoreNameExact, scoreNamePrefix, scoreNameSubstr, scoreNameFuzzy),
+			matchScore(w, tags, scoreTagExact, scoreTagSubstr, scoreTagSubstr, scoreTagFuzzy),
+			matchScore(w, desc, scoreDescSubstr, scoreDescSubstr, scoreDescSubstr, scoreDescFuzzy),
+		)
+	}
+	return total
+}
+
+// matchScore returns the score of the best match of the word among
+// the candidates.
+func matchScore(word string, candidates []string, exact, prefix, substr, fuzzy int) int {
+	best := 0
+	for _, c := range candidates {
+		switch {
+		case c == word:
+			return exact
+		case strings.HasPrefix(c, word):
+			best = max(best, prefix)
+		case strings.Contains(c, word):
+			best = max(best, substr)
+		case len(word) >= minFuzzyTokenLen && editDistance(word, c) <= maxTypos(word):
+			best = max(best, fuzzy)
+		}
+	}
+	return best
+}
+
+// maxTypos is how many edits are tolerated for a word.
+func maxTypos(word string) int {
+	if len(word) > 6 {
+		return 2
+	}
+	return 1
+}
+
+// editDistance is the number of insertions, deletions, substitutions
+// and transpositions of adjacent letters needed to turn a into b.
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1)
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
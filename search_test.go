@@ -0,0 +1,124 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+const searchIndex = `{
+	"version":"v1",
+	"integrations":[
+		{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "description":"Amazon S3 and compatible object stores","tags":["cloud"]},
+		{"name":"minio","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "description":"MinIO object storage","tags":["s3-compatible"]},
+		{"name":"garage","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "description":"Garage distributed storage","provides":["s3"]},
+		{"name":"ftp","edition":"community","api":"v1.1.0","version":"v1.0.0",
+		 "description":"File transfer protocol","tags":["legacy"]}
+	]
+}`
+
+func searchNames(integrations []*Integration) []string {
+	var names []string
+	for _, i := range integrations {
+		names = append(names, i.Name)
+	}
+	return names
+}
+
+func TestSearch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, searchIndex)
+	}))
+	defer srv.Close()
+
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	for _, tt := range []struct {
+		query string
+		want  []string
+	}{
+		{"s3-compat", []string{"s3", "minio", "garage"}},
+		{"minoi", []string{"minio"}},
+		{"garag", []string{"garage"}},
+		{"transfer", []string{"ftp"}},
+		{"nothing-like-it", nil},
+		{"", []string{"ftp", "garage", "minio", "s3"}},
+	} {
+		got, err := m.Search(tt.query, nil)
+		if err != nil {
+			t.Fatalf("Search(%q): %v", tt.query, err)
+		}
+		if names := searchNames(got); !slices.Equal(names, tt.want) {
+			t.Errorf("Search(%q) = %v, want %v", tt.query, names, tt.want)
+		}
+	}
+}
+
+func TestSearchOffline(t *testing.T) {
+	online := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !online {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		io.WriteString(w, searchIndex)
+	}))
+	defer srv.Close()
+
+	cache := filepath.Join(t.TempDir(), "cache", "index.json")
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, IndexCacheFile: cache})
+	if _, err := m.Search("s3", nil); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+
+	// a new manager with the registry down uses the saved index.
+	online = false
+	var warnings []WarningCode
+	m, _ = New(newFakeBackend(), &Options{
+		ApiURL:         srv.URL,
+		IndexCacheFile: cache,
+		EventHook: func(ev *Event) {
+			warnings = append(warnings, ev.Code)
+		},
+	})
+	got, err := m.Search("minio", nil)
+	if err != nil {
+		t.Fatalf("Search offline: %v", err)
+	}
+	if names := searchNames(got); !slices.Equal(names, []string{"minio"}) {
+		t.Errorf("Search offline = %v", names)
+	}
+	if !slices.Contains(warnings, WarnStaleIndex) {
+		t.Errorf("warnings = %v, want %s", warnings, WarnStaleIndex)
+	}
+
+	// without a cache, the failure is reported.
+	m, _ = New(newFakeBackend(), &Options{ApiURL: srv.URL})
+	if _, err := m.Search("minio", nil); !errors.Is(err, ErrRegistryUnavailable) {
+		t.Errorf("Search = %v, want %v", err, ErrRegistryUnavailable)
+	}
+}
+
+func TestEditDistance(t *testing.T) {
+	for _, tt := range []struct {
+		a, b string
+		want int
+	}{
+		{"minio", "minio", 0},
+		{"minoi", "minio", 1},
+		{"garag", "garage", 1},
+		{"s3", "gcs", 3},
+		{"", "abc", 3},
+	} {
+		if got := editDistance(tt.a, tt.b); got != tt.want {
+			t.Errorf("editDistance(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,213 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrVulnerable = errors.New("version with a critical advisory")
+)
+
+// Severities of the advisories.
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Advisory is a security advisory published in the integration index.
+type Advisory struct {
+	ID       string `json:"id"`
+	Severity string `json:"severity"`
+	Summary  string `json:"summary,omitempty"`
+
+	// Constraint on the affected versions, in the [Spec.Version]
+	// syntax.
+	Affected string `json:"affected"`
+
+	// First release with the fix, if there's one yet.
+	Fixed string `json:"fixed,omitempty"`
+}
+
+// affects returns whether the given version is affected by the
+// advisory.  Advisories that can't be parsed affect every version.
+func (adv *Advisory) affects(scheme VersionScheme, version string) bool {
+	cmps, err := parseConstraint(scheme, adv.Affected)
+	if err != nil {
+		return true
+	}
+	return satisfies(scheme, cmps, version)
+}
+
+// SecurityPolicy tells what [Manager.Sync] does with the versions
+// affected by a critical advisory.
+type SecurityPolicy int
+
+const (
+	// SecurityReport only reports them, as a [EventSecurity].
+	SecurityReport SecurityPolicy = iota
+
+	// SecurityUpgrade replaces them with the fixed release.  The
+	// ones without a fix are left alone and the step fails.
+	SecurityUpgrade
+
+	// SecurityRemove replaces them with the fixed release, and
+	// removes them when there is no fix yet.
+	SecurityRemove
+)
+
+// Vulnerability is an installed version affected by an advisory.
+type Vulnerability struct {
+	Name     string   `json:"name"`
+	Version  string   `json:"version"`
+	Advisory Advisory `json:"advisory"`
+}
+
+// integrations returns the entries of the index for the negotiated API
+// version, by name.
+func (p *Manager) integrations() (map[string]*Integration, error) {
+	index, err := p.cachedIndex()
+	if err != nil {
+		return nil, err
+	}
+	apiversion, err := p.APIVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	ret := make(map[string]*Integration)
+	for i := range index.Integrations {
+		plug := &index.Integrations[i]
+		if plug.API == apiversion {
+			ret[plug.Name] = plug
+		}
+	}
+	return ret, nil
+}
+
+// Vulnerabilities returns the advisories affecting the installed
+// versions, ordered by name and version.
+func (p *Manager) Vulnerabilities() ([]Vulnerability, error) {
+	if p.api == nil {
+		return nil, fmt.Errorf("%w: no API URL", ErrInvalidOptions)
+	}
+
+	plugs, err := p.integrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []Vulnerability
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+		plug, ok := plugs[pkg.Name]
+		if !ok {
+			continue
+		}
+		for _, adv := range plug.Advisories {
+			if adv.affects(p.versions, pkg.Version) {
+				ret = append(ret, Vulnerability{
+					Name:     pkg.Name,
+					Version:  pkg.Version,
+					Advisory: adv,
+				})
+			}
+		}
+	}
+
+	slices.SortStableFunc(ret, func(a, b Vulnerability) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return p.versions.Compare(a.Version, b.Version)
+	})
+	return ret, nil
+}
+
+// criticalAdvisory returns the first critical advisory affecting the
+// given version, if any.
+func (p *Manager) criticalAdvisory(plug *Integration, version string) *Advisory {
+	for i := range plug.Advisories {
+		adv := &plug.Advisories[i]
+		if adv.Severity == SeverityCritical && adv.affects(p.versions, version) {
+			return adv
+		}
+	}
+	return nil
+}
+
+// secure rewrites the steps of the plan that would leave a version
+// with a critical advisory installed, according to the security
+// policy, and reports them.
+func (p *Manager) secure(plan []SyncAction) error {
+	if p.securitypolicy == SecurityReport || p.api == nil {
+		return nil
+	}
+
+	plugs, err := p.integrations()
+	if err != nil {
+		return err
+	}
+
+	for i := range plan {
+		a := &plan[i]
+		if a.Err != nil || a.To == "" {
+			continue
+		}
+		plug, ok := plugs[a.Name]
+		if !ok {
+			continue
+		}
+		adv := p.criticalAdvisory(plug, a.To)
+		if adv == nil {
+			continue
+		}
+
+		a.Advisory = adv.ID
+		ev := &Event{
+			Type:     EventSecurity,
+			Package:  Package{Name: a.Name, Version: a.To},
+			Advisory: adv,
+		}
+
+		switch {
+		case a.Action == SyncHold:
+			ev.Message = "on hold, left alone"
+		case adv.Fixed != "" && !adv.affects(p.versions, adv.Fixed):
+			a.To, a.checksum, a.allowYanked = adv.Fixed, "", false
+			p.classify(a)
+			ev.Message = "replaced with " + adv.Fixed
+		case p.securitypolicy == SecurityRemove && a.From != "":
+			a.Action, a.To = SyncRemove, ""
+			ev.Message = "removed, no fixed release"
+		default:
+			a.Err = fmt.Errorf("%w: %s", ErrVulnerable, adv.ID)
+			ev.Message = "no fixed release"
+		}
+		p.emit(ev)
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func newAdvisoryServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"version":"v1","integrations":[
+			{"name":"s3","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.1.0",
+			 "advisories":[
+				{"id":"PKA-1","severity":"critical","affected":"<v1.0.2","fixed":"v1.0.2"},
+				{"id":"PKA-2","severity":"low","affected":"v1.0"}]},
+			{"name":"ftp","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0",
+			 "advisories":[{"id":"PKA-3","severity":"critical","affected":">=v1.0.0"}]}]}`)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestVulnerabilities(t *testing.T) {
+	srv := newAdvisoryServer(t)
+	be := newFakeBackend(pkgVer("s3", "v1.0.1"), pkgVer("ftp", "v1.0.0"), pkgVer("gcs", "v1.0.0"))
+	m, _ := New(be, &Options{ApiURL: srv.URL})
+
+	got, err := m.Vulnerabilities()
+	if err != nil {
+		t.Fatalf("Vulnerabilities: %v", err)
+	}
+	var ids []string
+	for _, v := range got {
+		ids = append(ids, v.Name+":"+v.Advisory.ID)
+	}
+	want := []string{"ftp:PKA-3", "s3:PKA-1", "s3:PKA-2"}
+	if len(ids) != len(want) || ids[0] != want[0] || ids[1] != want[1] || ids[2] != want[2] {
+		t.Errorf("Vulnerabilities = %v, want %v", ids, want)
+	}
+}
+
+func TestSyncSecurityPolicy(t *testing.T) {
+	srv := newAdvisoryServer(t)
+	specs := []Spec{
+		{Name: "s3", Version: "v1.0", Pinned: true},
+		{Name: "ftp", Pinned: true},
+	}
+
+	tests := []struct {
+		policy SecurityPolicy
+		s3     string // action on s3
+		ftp    string // action on ftp
+		ftperr bool
+	}{
+		{SecurityReport, SyncKeep, SyncKeep, false},
+		{SecurityUpgrade, SyncUpgrade, SyncKeep, true},
+		{SecurityRemove, SyncUpgrade, SyncRemove, false},
+	}
+	for _, tt := range tests {
+		var events []*Event
+		be := newFakeBackend(pkgVer("s3", "v1.0.1"), pkgVer("ftp", "v1.0.0"))
+		m, _ := New(be, &Options{
+			ApiURL:         srv.URL,
+			SecurityPolicy: tt.policy,
+			EventHook: func(ev *Event) {
+				if ev.Type == EventSecurity {
+					events = append(events, ev)
+				}
+			},
+		})
+
+		plan, err := m.Sync(specs, &SyncOptions{DryRun: true})
+		if tt.ftperr != errors.Is(err, ErrVulnerable) {
+			t.Errorf("policy %d: Sync = %v", tt.policy, err)
+		}
+		if len(plan) != 2 {
+			t.Fatalf("policy %d: plan = %+v", tt.policy, plan)
+		}
+		ftp, s3 := plan[0], plan[1]
+		if s3.Action != tt.s3 || ftp.Action != tt.ftp {
+			t.Errorf("policy %d: actions s3 %s, ftp %s, want %s, %s",
+				tt.policy, s3.Action, ftp.Action, tt.s3, tt.ftp)
+		}
+		if tt.policy == SecurityReport {
+			if len(events) != 0 {
+				t.Errorf("policy %d: %d security events", tt.policy, len(events))
+			}
+			continue
+		}
+		if s3.To != "v1.0.2" || s3.Advisory != "PKA-1" {
+			t.Errorf("policy %d: s3 step = %+v", tt.policy, s3)
+		}
+		if len(events) != 2 || events[0].Advisory == nil {
+			t.Errorf("policy %d: security events = %+v", tt.policy, events)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+)
+
+// Suffix of the checksum files published next to the packages, in
+// the sha256sum(1) format.
+const sidecarSuffix = ".sha256"
+
+// parseSidecar returns the checksum the content of a sha256sum(1)
+// file gives for the named file.  Lines are a digest optionally
+// followed by a file name, possibly prefixed with '*'; a lone digest
+// applies to any file.
+func parseSidecar(data []byte, name string) (string, error) {
+	var found string
+	for line := range strings.Lines(string(data)) {
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+
+		digest := fields[0]
+		if len(fields) > 1 {
+			file := strings.TrimPrefix(strings.Join(fields[1:], " "), "*")
+			if path.Base(file) != name {
+				continue
+			}
+		}
+		if found != "" {
+			return "", fmt.Errorf("%w: more than one digest for %s", ErrBadChecksum, name)
+		}
+		found = "sha256:" + strings.ToLower(digest)
+	}
+
+	if found == "" {
+		return "", fmt.Errorf("%w: no digest for %s", ErrBadChecksum, name)
+	}
+	if _, _, err := parseChecksum(found); err != nil {
+		return "", err
+	}
+	return found, nil
+}
+
+// openLocal opens the package file, verified against the sidecar
+// checksum file next to it if there is one, and against its detached
+// signature as the policy says.
+func (p *Manager) openLocal(file string) (io.ReadCloser, error) {
+	data, err := os.ReadFile(file + sidecarSuffix)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, err
+	}
+
+	var checksum string
+	if err == nil {
+		checksum, err = parseSidecar(data, path.Base(file))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file+sidecarSuffix, err)
+		}
+	}
+
+	sig, err := p.gpgSignature(file, func(suffix string) ([]byte, error) {
+		sig, err := os.ReadFile(file + suffix)
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return sig, err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	fp, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	rc, err := p.verifySidecar(fp, checksum)
+	if err != nil {
+		return nil, err
+	}
+	return p.verifyGPG(rc, sig)
+}
+
+// fetchSidecar returns the checksum the sidecar file published next
+// to the given URL has for it, or the empty string if there is none.
+func (p *Manager) fetchSidecar(artifact string) (string, error) {
+	data, err := p.fetchOptional(artifact + sidecarSuffix)
+	if err != nil || data == nil {
+		return "", err
+	}
+
+	checksum, err := parseSidecar(data, path.Base(artifact))
+	if err != nil {
+		return "", fmt.Errorf("%s: %w", artifact+sidecarSuffix, err)
+	}
+	return checksum, nil
+}
+
+// fetchOptional returns the content at the given URL, or nil if it
+// doesn't exist.
+func (p *Manager) fetchOptional(url string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := p.do(req, http.StatusOK)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	return io.ReadAll(resp.Body)
+}
+
+// verifySidecar wraps rc so that it's checked against the checksum,
+// if not empty.
+func (p *Manager) verifySidecar(rc io.ReadCloser, checksum string) (io.ReadCloser, error) {
+	if checksum == "" {
+		return rc, nil
+	}
+
+	rd, err := newVerifyingReader(rc, checksum, p.minstrength)
+	if err != nil {
+		rc.Close()
+		return nil, err
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{rd, rc}, nil
+}
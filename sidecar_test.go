@@ -0,0 +1,69 @@
+package pkg
+
+import (
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestParseSidecar(t *testing.T) {
+	digest := fmt.Sprintf("%x", sha256.Sum256([]byte("PTARDATA")))
+	other := fmt.Sprintf("%x", sha256.Sum256([]byte("other")))
+
+	for _, tt := range []struct {
+		data string
+		want string
+	}{
+		{digest + "\n", digest},
+		{strings.ToUpper(digest), digest},
+		{digest + "  s3.ptar\n", digest},
+		{digest + " *dist/s3.ptar\n", digest},
+		{other + "  fs.ptar\n" + digest + "  s3.ptar\n", digest},
+		{other + "  fs.ptar\n", ""},
+		{digest + "\n" + other + "\n", ""},
+		{"deadbeef  s3.ptar\n", ""},
+		{"", ""},
+	} {
+		got, err := parseSidecar([]byte(tt.data), "s3.ptar")
+		if tt.want == "" {
+			if !errors.Is(err, ErrBadChecksum) {
+				t.Errorf("parseSidecar(%q) = %q, %v, want ErrBadChecksum", tt.data, got, err)
+			}
+			continue
+		}
+		if err != nil || got != "sha256:"+tt.want {
+			t.Errorf("parseSidecar(%q) = %q, %v", tt.data, got, err)
+		}
+	}
+}
+
+func TestAddVerifiesSidecar(t *testing.T) {
+	dir := t.TempDir()
+	ptar := filepath.Join(dir, "s3_v1.0.0_"+runtime.GOOS+"_"+runtime.GOARCH+".ptar")
+	if err := os.WriteFile(ptar, []byte("PTARDATA"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	sidecar := fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("tampered")), filepath.Base(ptar))
+	if err := os.WriteFile(ptar+sidecarSuffix, []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	be := newFakeBackend()
+	m, _ := New(be, nil)
+	if err := m.Add(ptar, nil); !errors.Is(err, ErrChecksumMismatch) {
+		t.Fatalf("Add with a mismatching sidecar err = %v, want ErrChecksumMismatch", err)
+	}
+
+	sidecar = fmt.Sprintf("%x  %s\n", sha256.Sum256([]byte("PTARDATA")), filepath.Base(ptar))
+	if err := os.WriteFile(ptar+sidecarSuffix, []byte(sidecar), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Add(ptar, nil); err != nil {
+		t.Fatalf("Add with a matching sidecar: %v", err)
+	}
+}
@@ -0,0 +1,123 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var (
+	ErrBadKey       = errors.New("invalid public key")
+	ErrBadSignature = errors.New("invalid signature")
+)
+
+// signify(1) blobs are a two bytes algorithm tag, an eight bytes key
+// number, and the key or signature itself.
+const (
+	signifyAlgo   = "Ed"
+	signifyKeyNum = 8
+)
+
+// PublicKey is a signify(1) public key used to verify what the
+// repository serves.
+type PublicKey struct {
+	KeyNum [signifyKeyNum]byte
+	Key    ed25519.PublicKey
+}
+
+// signifyDecode parses the two lines signify(1) format: an untrusted
+// comment followed by the base64 encoded blob.
+func signifyDecode(data []byte) ([]byte, error) {
+	comment, rest, ok := strings.Cut(string(data), "\n")
+	if !ok || !strings.HasPrefix(comment, "untrusted comment: ") {
+		return nil, errors.New("missing untrusted comment")
+	}
+
+	line, _, _ := strings.Cut(rest, "\n")
+	blob, err := base64.StdEncoding.DecodeString(strings.TrimSpace(line))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(blob) < len(signifyAlgo)+signifyKeyNum ||
+		string(blob[:len(signifyAlgo)]) != signifyAlgo {
+		return nil, errors.New("unsupported algorithm")
+	}
+	return blob, nil
+}
+
+// ParsePublicKey parses a signify(1) public key.
+func ParsePublicKey(data []byte) (*PublicKey, error) {
+	blob, err := signifyDecode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrBadKey, err)
+	}
+
+	const off = len(signifyAlgo) + signifyKeyNum
+	if len(blob) != off+ed25519.PublicKeySize {
+		return nil, fmt.Errorf("%w: bad length", ErrBadKey)
+	}
+
+	var pk PublicKey
+	copy(pk.KeyNum[:], blob[len(signifyAlgo):off])
+	pk.Key = ed25519.PublicKey(bytes.Clone(blob[off:]))
+	return &pk, nil
+}
+
+// LoadPublicKey reads a signify(1) public key from the given file.
+func LoadPublicKey(path string) (*PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return ParsePublicKey(data)
+}
+
+// verifySignature checks that sig is a valid signify(1) signature
+// of msg by one of the given keys.
+func verifySignature(keys []*PublicKey, msg, sig []byte) error {
+	blob, err := signifyDecode(sig)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrBadSignature, err)
+	}
+
+	const off = len(signifyAlgo) + signifyKeyNum
+	if len(blob) != off+ed25519.SignatureSize {
+		return fmt.Errorf("%w: bad length", ErrBadSignature)
+	}
+
+	keynum := blob[len(signifyAlgo):off]
+	for _, k := range keys {
+		if !bytes.Equal(k.KeyNum[:], keynum) {
+			continue
+		}
+		if ed25519.Verify(k.Key, msg, blob[off:]) {
+			return nil
+		}
+		return fmt.Errorf("%w: verification failed", ErrBadSignature)
+	}
+
+	return fmt.Errorf("%w: signed by an unknown key", ErrBadSignature)
+}
@@ -0,0 +1,145 @@
+package pkg
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type testSigner struct {
+	keynum [signifyKeyNum]byte
+	priv   ed25519.PrivateKey
+	pub    ed25519.PublicKey
+}
+
+func newTestSigner(t *testing.T, keynum byte) *testSigner {
+	t.Helper()
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := &testSigner{priv: priv, pub: pub}
+	s.keynum[0] = keynum
+	return s
+}
+
+func (s *testSigner) blob(data []byte) string {
+	b := append([]byte(signifyAlgo), s.keynum[:]...)
+	b = append(b, data...)
+	return base64.StdEncoding.EncodeToString(b) + "\n"
+}
+
+func (s *testSigner) publicKey() string {
+	return "untrusted comment: test public key\n" + s.blob(s.pub)
+}
+
+func (s *testSigner) sign(msg []byte) string {
+	return "untrusted comment: verify with test.pub\n" + s.blob(ed25519.Sign(s.priv, msg))
+}
+
+func (s *testSigner) key(t *testing.T) *PublicKey {
+	t.Helper()
+	pk, err := ParsePublicKey([]byte(s.publicKey()))
+	if err != nil {
+		t.Fatalf("ParsePublicKey: %v", err)
+	}
+	return pk
+}
+
+func TestParsePublicKey(t *testing.T) {
+	s := newTestSigner(t, 42)
+	pk := s.key(t)
+	if pk.KeyNum != s.keynum || !pk.Key.Equal(s.pub) {
+		t.Errorf("parsed key = %+v", pk)
+	}
+
+	path := filepath.Join(t.TempDir(), "test.pub")
+	if err := os.WriteFile(path, []byte(s.publicKey()), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadPublicKey(path); err != nil {
+		t.Errorf("LoadPublicKey: %v", err)
+	}
+
+	for _, bad := range []string{
+		"",
+		"no comment\n" + s.blob(s.pub),
+		"untrusted comment: x\nnot base64!\n",
+		"untrusted comment: x\n" + s.blob(s.pub[:10]),
+	} {
+		if _, err := ParsePublicKey([]byte(bad)); !errors.Is(err, ErrBadKey) {
+			t.Errorf("ParsePublicKey(%q) err = %v, want ErrBadKey", bad, err)
+		}
+	}
+}
+
+func TestVerifySignature(t *testing.T) {
+	good := newTestSigner(t, 1)
+	other := newTestSigner(t, 2)
+	msg := []byte("name: s3\nversion: v1.0.0\n")
+	keys := []*PublicKey{good.key(t)}
+
+	if err := verifySignature(keys, msg, []byte(good.sign(msg))); err != nil {
+		t.Errorf("valid signature: %v", err)
+	}
+	if err := verifySignature(keys, []byte("tampered"), []byte(good.sign(msg))); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("tampered message err = %v, want ErrBadSignature", err)
+	}
+	if err := verifySignature(keys, msg, []byte(other.sign(msg))); !errors.Is(err, ErrBadSignature) {
+		t.Errorf("unknown key err = %v, want ErrBadSignature", err)
+	}
+}
+
+func TestFetchRecipeSigned(t *testing.T) {
+	signer := newTestSigner(t, 1)
+	const recipe = "name: s3\nversion: v1.2.3\n"
+
+	for _, tt := range []struct {
+		name    string
+		sig     string
+		wantErr bool
+	}{
+		{"valid", signer.sign([]byte(recipe)), false},
+		{"tampered", signer.sign([]byte("name: evil\n")), true},
+		{"missing", "", true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				switch {
+				case strings.HasSuffix(r.URL.Path, "recipe.yaml"):
+					w.Write([]byte(recipe))
+				case strings.HasSuffix(r.URL.Path, "recipe.yaml.sig") && tt.sig != "":
+					w.Write([]byte(tt.sig))
+				default:
+					http.NotFound(w, r)
+				}
+			}))
+			defer srv.Close()
+
+			m, _ := New(newFakeBackend(), &Options{
+				InstallURL:  srv.URL,
+				TrustedKeys: []*PublicKey{signer.key(t)},
+			})
+			r, err := m.FetchRecipe("s3")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatal("FetchRecipe accepted a recipe without a valid signature")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("FetchRecipe: %v", err)
+			}
+			if r.Version != "v1.2.3" {
+				t.Errorf("recipe = %+v", r)
+			}
+		})
+	}
+}
@@ -0,0 +1,95 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+var (
+	ErrStageNotAllowed = errors.New("integration stage not allowed")
+)
+
+// The release stages of the integrations, from the most to the least
+// stable, derived from the prerelease part of their version.
+const (
+	StageStable  = "stable"
+	StageTesting = "testing"
+	StageBeta    = "beta"
+	StageDevel   = "devel"
+)
+
+var stages = []string{StageStable, StageTesting, StageBeta, StageDevel}
+
+// versionStage returns the release stage of the given version.  The
+// unknown prereleases are reported as is.
+func versionStage(version string) string {
+	pr := semver.Prerelease(version)
+	switch {
+	case pr == "":
+		return StageStable
+	case strings.HasPrefix(pr, "-devel."):
+		return StageDevel
+	case strings.HasPrefix(pr, "-beta."):
+		return StageBeta
+	case strings.HasPrefix(pr, "-rc."):
+		return StageTesting
+	default:
+		return pr
+	}
+}
+
+// stageRank orders the stages from the most stable one.  The unknown
+// prereleases are ranked with the least stable.
+func stageRank(stage string) int {
+	for i, s := range stages {
+		if s == stage {
+			return i
+		}
+	}
+	return len(stages) - 1
+}
+
+func checkStageName(stage string) error {
+	for _, s := range stages {
+		if s == stage {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown stage %q", ErrInvalidOptions, stage)
+}
+
+// checkStage refuses to install the version if it's less stable than
+// what the policy, or the AllowStage of the operation, permits.
+func (p *Manager) checkStage(name, version string, opts *AddOptions) error {
+	allowed := p.stagepolicy
+	if opts.AllowStage != "" && stageRank(opts.AllowStage) > stageRank(allowed) {
+		allowed = opts.AllowStage
+	}
+
+	stage := versionStage(version)
+	if stageRank(stage) > stageRank(allowed) {
+		return fmt.Errorf("%s %s: %w: %s, only %s and above are installed",
+			name, version, ErrStageNotAllowed, stage, allowed)
+	}
+	return nil
+}
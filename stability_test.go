@@ -0,0 +1,76 @@
+package pkg
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestVersionStage(t *testing.T) {
+	for version, want := range map[string]string{
+		"v1.0.0":         StageStable,
+		"v1.0.0-rc.2":    StageTesting,
+		"v1.0.0-beta.1":  StageBeta,
+		"v1.0.0-devel.3": StageDevel,
+		"v1.0.0-foo":     "-foo",
+	} {
+		if got := versionStage(version); got != want {
+			t.Errorf("versionStage(%s) = %q, want %q", version, got, want)
+		}
+	}
+}
+
+func TestCheckStage(t *testing.T) {
+	tests := []struct {
+		policy, allow, version string
+		ok                     bool
+	}{
+		{"", "", "v1.0.0", true},
+		{"", "", "v1.0.0-rc.1", false},
+		{"", StageBeta, "v1.0.0-rc.1", true},
+		{"", StageBeta, "v1.0.0-beta.1", true},
+		{"", StageBeta, "v1.0.0-devel.1", false},
+		{"", StageDevel, "v1.0.0-foo", true},
+		{StageTesting, "", "v1.0.0-rc.1", true},
+		{StageTesting, "", "v1.0.0-beta.1", false},
+		// AllowStage can't be stricter than the policy.
+		{StageBeta, StageStable, "v1.0.0-beta.1", true},
+	}
+	for _, tt := range tests {
+		m, err := New(newFakeBackend(), &Options{StagePolicy: tt.policy})
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		err = m.checkStage("s3", tt.version, &AddOptions{AllowStage: tt.allow})
+		if tt.ok && err != nil {
+			t.Errorf("%+v: checkStage = %v", tt, err)
+		}
+		if !tt.ok && !errors.Is(err, ErrStageNotAllowed) {
+			t.Errorf("%+v: checkStage = %v, want %v", tt, err, ErrStageNotAllowed)
+		}
+	}
+}
+
+func TestStagePolicyOptions(t *testing.T) {
+	if _, err := New(newFakeBackend(), &Options{StagePolicy: "alpha"}); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("New = %v, want %v", err, ErrInvalidOptions)
+	}
+
+	m, _ := New(newFakeBackend(), nil)
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0", AllowStage: "alpha"})
+	if !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Add = %v, want %v", err, ErrInvalidOptions)
+	}
+}
+
+func TestAddRefusesPrerelease(t *testing.T) {
+	be := newFakeBackend()
+	m, _ := New(be, nil)
+
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0-beta.1"})
+	if !errors.Is(err, ErrStageNotAllowed) {
+		t.Fatalf("Add = %v, want %v", err, ErrStageNotAllowed)
+	}
+	if len(be.loaded) != 0 {
+		t.Errorf("loaded = %v, want nothing", be.loaded)
+	}
+}
@@ -0,0 +1,251 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Name of the directory, in the package directory, holding the
+// staged packages.
+const stagedDir = ".staged"
+
+// Stager is implemented by the backends that can keep packages ready
+// to be installed later.
+type Stager interface {
+	// Stage stores the package and checks that it can be
+	// extracted and loaded, without loading it.
+	Stage(*Package, io.Reader) error
+
+	// Staged returns the paths to the staged .ptar files.
+	Staged() iter.Seq2[string, error]
+
+	// Unstage drops a staged package.
+	Unstage(*Package) error
+}
+
+// Stage downloads, verifies and extracts a package without making it
+// live: it's installed by the next call to Commit.  It's meant to
+// fetch the updates ahead of a maintenance window.  The target and
+// the options are as for Add, with ImplicitFetch implied.
+func (p *Manager) Stage(target string, opts *AddOptions) error {
+	if opts == nil {
+		opts = &AddOptions{}
+	}
+
+	s, ok := p.store.(Stager)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	if capability, ok := strings.CutPrefix(target, capabilityPrefix); ok {
+		name, err := p.resolveCapability(capability)
+		if err != nil {
+			return err
+		}
+		target = name
+	}
+
+	base := NormalizeName(filepath.Base(target))
+
+	if strings.HasSuffix(base, ".ptar") {
+		var pkg Package
+		if err := pkg.parseNameWith(base, p.versions); err != nil {
+			return err
+		}
+
+		if pkg.OperatingSystem != runtime.GOOS || pkg.Architecture != runtime.GOARCH {
+			if !opts.AllowOSArchMismatch {
+				return ErrBadOSArch
+			}
+		}
+
+		fp, err := os.Open(target)
+		if err != nil {
+			return err
+		}
+		defer fp.Close()
+
+		return s.Stage(&pkg, fp)
+	}
+
+	pkg := Package{
+		Name:            base,
+		Version:         opts.Version,
+		Architecture:    runtime.GOARCH,
+		OperatingSystem: runtime.GOOS,
+	}
+	checksum := opts.Checksum
+	if pkg.Version == "" {
+		r, err := p.FetchRecipe(base)
+		if err != nil {
+			return err
+		}
+		pkg.Name, pkg.Version = r.Name, r.Semver()
+		checksum = r.Checksum(runtime.GOOS, runtime.GOARCH)
+	}
+
+	rd, _, err := p.openbinary(context.Background(), &pkg, checksum)
+	if err != nil {
+		return err
+	}
+	defer rd.Close()
+
+	if err := s.Stage(&pkg, rd); err != nil {
+		return err
+	}
+	keepDownload(rd)
+	return nil
+}
+
+// Commit installs all the staged packages, replacing the versions
+// currently installed.  A package failing to install doesn't prevent
+// the others from being installed, and stays staged: the failures are
+// reported together in a *MultiError.
+func (p *Manager) Commit() error {
+	s, ok := p.store.(Stager)
+	if !ok {
+		return errors.ErrUnsupported
+	}
+
+	var staged []string
+	for path, err := range s.Staged() {
+		if err != nil {
+			return err
+		}
+		staged = append(staged, path)
+	}
+
+	var merr MultiError
+	for _, path := range staged {
+		var pkg Package
+		if err := pkg.parseNameWith(filepath.Base(path), p.versions); err != nil {
+			// the others can still be committed.
+			merr.add(&Package{Name: filepath.Base(path)}, err)
+			continue
+		}
+
+		err := p.Add(path, &AddOptions{
+			Replace:             true,
+			AllowOSArchMismatch: true,
+		})
+
+		// once on disk, the package is no longer staged, even if
+		// what followed its installation failed.
+		if _, lerr := p.lookup(pkg.Name, pkg.Version); lerr == nil {
+			err = errors.Join(err, s.Unstage(&pkg))
+		}
+		merr.add(&pkg, err)
+	}
+	return merr.err()
+}
+
+// Stage keeps the package in a hidden directory, once it was
+// extracted in a temporary location and its manifest was checked.
+func (f *FlatBackend) Stage(pkg *Package, rd io.Reader) error {
+	dir := filepath.Join(f.pkgdir, stagedDir)
+	if err := f.fsys.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+
+	fp, err := f.fsys.CreateTemp(f.staging(f.pkgdir), stagingPrefix+pkg.Name+"-*")
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(fp, rd)
+	fp.Close()
+	if err != nil {
+		f.fsys.Remove(fp.Name())
+		return err
+	}
+
+	if err := f.checkStaged(pkg, fp.Name()); err != nil {
+		f.fsys.Remove(fp.Name())
+		return fmt.Errorf("%s: %w", pkg.Filename(), err)
+	}
+
+	if err := move(f.fsys, fp.Name(), filepath.Join(dir, pkg.Filename())); err != nil {
+		f.fsys.Remove(fp.Name())
+		return err
+	}
+	return nil
+}
+
+// checkStaged extracts the given ptar in a temporary directory and
+// validates its manifest.
+func (f *FlatBackend) checkStaged(pkg *Package, ptar string) error {
+	tmpdir, err := f.fsys.MkdirTemp(f.staging(f.cachedir), stagingPrefix+"stage-*")
+	if err != nil {
+		return err
+	}
+	defer f.fsys.RemoveAll(tmpdir)
+
+	content := filepath.Join(tmpdir, "content")
+	if err := f.extractor.Extract(ptar, content); err != nil {
+		return err
+	}
+
+	m, err := f.loadmanifest(pkg, filepath.Join(content, "manifest.yaml"))
+	if err != nil {
+		return err
+	}
+
+	if f.preloadhook != nil {
+		return f.preloadhook(f.kcontext, m)
+	}
+	return nil
+}
+
+func (f *FlatBackend) Staged() iter.Seq2[string, error] {
+	return func(yield func(string, error) bool) {
+		dir := filepath.Join(f.pkgdir, stagedDir)
+		dirents, err := f.fsys.ReadDir(dir)
+		if err != nil {
+			if !errors.Is(err, os.ErrNotExist) {
+				yield("", err)
+			}
+			return
+		}
+
+		for _, d := range dirents {
+			if strings.HasPrefix(d.Name(), ".") || !strings.HasSuffix(d.Name(), ".ptar") {
+				continue
+			}
+			if !yield(filepath.Join(dir, d.Name()), nil) {
+				return
+			}
+		}
+	}
+}
+
+func (f *FlatBackend) Unstage(pkg *Package) error {
+	err := f.fsys.Remove(filepath.Join(f.pkgdir, stagedDir, pkg.Filename()))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,129 @@
+package pkg
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func listVersions(t *testing.T, be Backend, name string) []string {
+	t.Helper()
+	var versions []string
+	for pkg, err := range be.List(name) {
+		if err != nil {
+			t.Fatalf("List: %v", err)
+		}
+		versions = append(versions, pkg.Version)
+	}
+	return versions
+}
+
+func TestStageCommit(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.1.0"})
+
+	var loaded []string
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{
+		Extractor: &fakeExtractor{},
+		LoadHook: func(ctx context.Context, m *Manifest, p *Package, dir string) error {
+			loaded = append(loaded, p.Version)
+			return nil
+		},
+	})
+	installExtracted(t, pkgdir, cachedir, pkgVer("s3", "v1.0.0"))
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Stage("s3", nil); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+
+	// nothing is live yet.
+	if got := listVersions(t, be, "s3"); !slices.Equal(got, []string{"v1.0.0"}) {
+		t.Fatalf("installed = %v, want [v1.0.0]", got)
+	}
+	if len(loaded) != 0 {
+		t.Fatalf("loaded = %v before Commit", loaded)
+	}
+	var staged []string
+	for path, err := range be.Staged() {
+		if err != nil {
+			t.Fatalf("Staged: %v", err)
+		}
+		staged = append(staged, filepath.Base(path))
+	}
+	if !slices.Equal(staged, []string{pkgVer("s3", "v1.1.0").Filename()}) {
+		t.Fatalf("staged = %v", staged)
+	}
+
+	if err := m.Commit(); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if got := listVersions(t, be, "s3"); !slices.Equal(got, []string{"v1.1.0"}) {
+		t.Errorf("installed = %v, want [v1.1.0]", got)
+	}
+	if !slices.Equal(loaded, []string{"v1.1.0"}) {
+		t.Errorf("loaded = %v, want [v1.1.0]", loaded)
+	}
+	for range be.Staged() {
+		t.Error("package still staged after Commit")
+	}
+}
+
+func TestCommitSkipsBadNames(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.1.0"})
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Stage("s3", nil); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	touch(t, filepath.Join(pkgdir, stagedDir), "garbage.ptar")
+
+	var merr *MultiError
+	if err := m.Commit(); !errors.As(err, &merr) || len(merr.Errors) != 1 {
+		t.Fatalf("Commit = %v, want the bad name reported", err)
+	}
+	if merr.Errors[0].Package.Name != "garbage.ptar" {
+		t.Errorf("failed package = %+v", merr.Errors[0].Package)
+	}
+	if got := listVersions(t, be, "s3"); !slices.Equal(got, []string{"v1.1.0"}) {
+		t.Errorf("installed = %v, want [v1.1.0]", got)
+	}
+}
+
+func TestStageRejectsInvalidPackage(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.1.0"})
+
+	errRefused := errors.New("refused")
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{
+		Extractor: &fakeExtractor{},
+		PreLoadHook: func(context.Context, *Manifest) error {
+			return errRefused
+		},
+	})
+
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+	if err := m.Stage("s3", nil); !errors.Is(err, errRefused) {
+		t.Fatalf("Stage = %v, want %v", err, errRefused)
+	}
+
+	dirents, err := os.ReadDir(filepath.Join(pkgdir, stagedDir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dirents) != 0 {
+		t.Errorf("left in the staging area: %v", dirents)
+	}
+}
+
+func TestStageUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if err := m.Stage("s3", nil); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Stage = %v, want %v", err, errors.ErrUnsupported)
+	}
+	if err := m.Commit(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Commit = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
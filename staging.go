@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// All the temporary files and directories are named after this
+// prefix, so that leftovers from an interrupted run can be told
+// apart and swept.
+const stagingPrefix = ".staging-"
+
+// staging returns where to create temporary files meant to end up in
+// dir.
+func (f *FlatBackend) staging(dir string) string {
+	if f.stagingdir != "" {
+		return f.stagingdir
+	}
+	return dir
+}
+
+// TempDir returns where the manager keeps the parallel downloads
+// while they are reassembled.  They only live on the local disk.
+func (f *FlatBackend) TempDir() string {
+	if _, ok := f.fsys.(OSFS); !ok {
+		return ""
+	}
+	return f.staging(f.pkgdir)
+}
+
+// sweepStaging removes the leftovers of previous runs.
+func (f *FlatBackend) sweepStaging() error {
+	for _, dir := range []string{f.stagingdir, f.pkgdir, f.cachedir} {
+		if dir == "" {
+			continue
+		}
+
+		dirents, err := f.fsys.ReadDir(dir)
+		if err != nil {
+			return err
+		}
+		for _, d := range dirents {
+			if strings.HasPrefix(d.Name(), stagingPrefix) {
+				f.fsys.RemoveAll(filepath.Join(dir, d.Name()))
+			}
+		}
+	}
+	return nil
+}
+
+// move renames src to dst, falling back to a copy when they are not
+// on the same filesystem.
+func move(fsys FS, src, dst string) error {
+	err := fsys.Rename(src, dst)
+	if err == nil {
+		return nil
+	}
+
+	fi, serr := fsys.Lstat(src)
+	if serr != nil {
+		return err
+	}
+
+	// copy next to the destination first so that it appears
+	// atomically.
+	tmp := filepath.Join(filepath.Dir(dst), stagingPrefix+filepath.Base(dst))
+	if fi.IsDir() {
+		serr = copyTree(fsys, src, tmp)
+	} else {
+		serr = copyFile(fsys, src, tmp, fi.Mode())
+	}
+	if serr != nil {
+		fsys.RemoveAll(tmp)
+		return err
+	}
+
+	if err := fsys.Rename(tmp, dst); err != nil {
+		fsys.RemoveAll(tmp)
+		return err
+	}
+	return fsys.RemoveAll(src)
+}
+
+func copyFile(fsys FS, src, dst string, mode fs.FileMode) error {
+	in, err := fsys.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	out, err := fsys.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, mode.Perm())
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func copyTree(fsys FS, src, dst string) error {
+	return walkDir(fsys, src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case d.IsDir():
+			return fsys.Mkdir(target, info.Mode().Perm())
+		case d.Type()&fs.ModeSymlink != 0:
+			link, err := fsys.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return fsys.Symlink(link, target)
+		case d.Type().IsRegular():
+			return copyFile(fsys, path, target, info.Mode())
+		}
+		return nil
+	})
+}
+
+// writeFile atomically replaces the content of the given file.
+func writeFile(fsys FS, path string, data []byte) error {
+	fp, err := fsys.CreateTemp(filepath.Dir(path), stagingPrefix+"*")
+	if err != nil {
+		return err
+	}
+
+	if _, err := fp.Write(data); err != nil {
+		fp.Close()
+		fsys.Remove(fp.Name())
+		return err
+	}
+	if err := fp.Sync(); err != nil {
+		fp.Close()
+		fsys.Remove(fp.Name())
+		return err
+	}
+	fp.Close()
+
+	if err := fsys.Rename(fp.Name(), path); err != nil {
+		fsys.Remove(fp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestFlatBackendSweepsStaging(t *testing.T) {
+	root := t.TempDir()
+	staging := filepath.Join(root, "staging")
+	pkgdir := filepath.Join(root, "pkgs")
+	for _, dir := range []string{staging, pkgdir} {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	touch(t, staging, stagingPrefix+"s3-1234")
+	touch(t, staging, "unrelated")
+	touch(t, pkgdir, stagingPrefix+"ftp-5678")
+	if err := os.Mkdir(filepath.Join(staging, stagingPrefix+"extract-42"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	newTestFlatBackendAt(t, pkgdir, filepath.Join(root, "cache"), &FlatBackendOptions{
+		StagingDir: staging,
+	})
+
+	for _, gone := range []string{
+		filepath.Join(staging, stagingPrefix+"s3-1234"),
+		filepath.Join(staging, stagingPrefix+"extract-42"),
+		filepath.Join(pkgdir, stagingPrefix+"ftp-5678"),
+	} {
+		if _, err := os.Stat(gone); !os.IsNotExist(err) {
+			t.Errorf("%s survived the sweep: %v", gone, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(staging, "unrelated")); err != nil {
+		t.Errorf("unrelated file was removed: %v", err)
+	}
+}
+
+func TestStagingDefaultsToDestination(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, nil)
+	if got := be.staging(pkgdir); got != pkgdir {
+		t.Errorf("staging(%q) = %q", pkgdir, got)
+	}
+
+	be.stagingdir = "/elsewhere"
+	if got := be.staging(pkgdir); got != "/elsewhere" {
+		t.Errorf("staging(%q) = %q, want /elsewhere", pkgdir, got)
+	}
+}
+
+func TestCopyTree(t *testing.T) {
+	src := filepath.Join(t.TempDir(), "src")
+	if err := os.MkdirAll(filepath.Join(src, "bin"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(src, "bin", "tool"), []byte("exe"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if runtime.GOOS != "windows" {
+		if err := os.Symlink("bin/tool", filepath.Join(src, "link")); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	dst := filepath.Join(t.TempDir(), "dst")
+	if err := copyTree(OSFS{}, src, dst); err != nil {
+		t.Fatalf("copyTree: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dst, "bin", "tool"))
+	if err != nil || string(data) != "exe" {
+		t.Errorf("copied file = %q, %v", data, err)
+	}
+	if runtime.GOOS != "windows" {
+		if link, err := os.Readlink(filepath.Join(dst, "link")); err != nil || link != "bin/tool" {
+			t.Errorf("copied link = %q, %v", link, err)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// StoreStats summarizes the content of a backend.
+type StoreStats struct {
+	// Number of packages installed, counting every version.
+	Packages int `json:"packages"`
+
+	// Bytes taken by the stored packages.
+	StoredBytes int64 `json:"stored_bytes"`
+
+	// Bytes taken by the extracted packages.
+	ExtractedBytes int64 `json:"extracted_bytes"`
+
+	// When a package was last installed or removed.
+	LastModified time.Time `json:"last_modified,omitzero"`
+}
+
+// StatsReporter is implemented by the backends that can summarize
+// their content cheaply.
+type StatsReporter interface {
+	Stats() (*StoreStats, error)
+}
+
+// Stats summarizes the packages installed in the backend.
+func (p *Manager) Stats() (*StoreStats, error) {
+	sr, ok := p.store.(StatsReporter)
+	if !ok {
+		return nil, errors.ErrUnsupported
+	}
+	return sr.Stats()
+}
+
+// Stats sums the sizes of the ptars and those recorded in the file
+// indexes of the extracted trees; only the trees extracted before the
+// index was kept are walked.
+func (f *FlatBackend) Stats() (*StoreStats, error) {
+	f.oplock.RLock()
+	defer f.oplock.RUnlock()
+
+	st := &StoreStats{}
+	if info, err := f.fsys.Stat(f.pkgdir); err == nil {
+		st.LastModified = info.ModTime()
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+
+	for pkg, err := range f.List("") {
+		if err != nil {
+			return nil, err
+		}
+
+		info, err := f.fsys.Stat(f.ptarPath(pkg))
+		if err != nil {
+			if errors.Is(err, os.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		st.Packages++
+		st.StoredBytes += info.Size()
+		if info.ModTime().After(st.LastModified) {
+			st.LastModified = info.ModTime()
+		}
+
+		size, err := f.extractedSize(f.extractedPath(pkg))
+		if err != nil {
+			return nil, err
+		}
+		st.ExtractedBytes += size
+	}
+	return st, nil
+}
+
+// extractedSize returns the size of the tree extracted in dir, zero if
+// it isn't extracted.
+func (f *FlatBackend) extractedSize(dir string) (int64, error) {
+	if !f.isExtracted(dir) {
+		return 0, nil
+	}
+
+	data, err := readFile(f.fsys, filepath.Join(dir, fileIndexFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return diskUsage(f.fsys, dir)
+		}
+		return 0, err
+	}
+
+	var index []FileEntry
+	if err := json.Unmarshal(data, &index); err != nil {
+		return 0, err
+	}
+
+	var total int64
+	for _, e := range index {
+		total += e.Size
+	}
+	return total, nil
+}
@@ -0,0 +1,59 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFlatBackendStats(t *testing.T) {
+	be, pkgdir, cachedir := newTestFlatBackend(t, &FlatBackendOptions{Extractor: &fakeExtractor{}})
+	m, _ := New(be, nil)
+
+	st, err := m.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if st.Packages != 0 || st.StoredBytes != 0 || st.ExtractedBytes != 0 {
+		t.Errorf("Stats of an empty store = %+v", st)
+	}
+
+	var extracted int64
+	for _, pkg := range []*Package{pkgVer("s3", "v1.0.0"), pkgVer("s3", "v1.1.0")} {
+		if err := be.Load(pkg, strings.NewReader("PTARDATA")); err != nil {
+			t.Fatalf("Load: %v", err)
+		}
+		info, err := os.Stat(filepath.Join(cachePath(cachedir, pkg), "manifest.yaml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		extracted += info.Size()
+	}
+
+	// extracted before the file index was kept: walked instead.
+	legacy := installExtracted(t, pkgdir, cachedir, pkgVer("ftp", "v1.0.0"))
+	info, err := os.Stat(filepath.Join(legacy, "manifest.yaml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	extracted += info.Size()
+
+	st, err = m.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if st.Packages != 3 || st.StoredBytes != int64(2*len("PTARDATA")) ||
+		st.ExtractedBytes != extracted || st.LastModified.IsZero() {
+		t.Errorf("Stats = %+v, want 3 packages, %d stored bytes, %d extracted",
+			st, int64(2*len("PTARDATA")), extracted)
+	}
+}
+
+func TestStatsUnsupported(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if _, err := m.Stats(); !errors.Is(err, errors.ErrUnsupported) {
+		t.Errorf("Stats = %v, want %v", err, errors.ErrUnsupported)
+	}
+}
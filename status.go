@@ -0,0 +1,207 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+)
+
+// PackageStatus joins what's installed of an integration with what the
+// repository offers.
+type PackageStatus struct {
+	Name string `json:"name"`
+
+	// The active version, if installed, and all those installed.
+	Installed bool     `json:"installed"`
+	Version   string   `json:"version,omitempty"`
+	Versions  []string `json:"versions,omitempty"`
+
+	// The latest version in the integration index, unknown for
+	// the packages missing from it.
+	Latest          string `json:"latest,omitempty"`
+	UpdateAvailable bool   `json:"update_available"`
+
+	// Whether the latest version, or the installed one for the
+	// packages missing from the index, runs on this platform.
+	Supported bool `json:"supported"`
+
+	Held bool `json:"held"`
+}
+
+// Status returns, ordered by name, the status of every integration
+// either installed or in the integration index.  Without an API URL
+// only the installed ones are reported.
+func (p *Manager) Status() ([]PackageStatus, error) {
+	var plugs map[string]*Integration
+	if p.api != nil {
+		var err error
+		if plugs, err = p.integrations(); err != nil {
+			return nil, err
+		}
+	}
+
+	held, err := p.held()
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make(map[string]*PackageStatus)
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+		st, ok := statuses[pkg.Name]
+		if !ok {
+			st = &PackageStatus{Name: pkg.Name, Installed: true}
+			statuses[pkg.Name] = st
+		}
+		st.Versions = append(st.Versions, pkg.Version)
+		if pkg.OperatingSystem == runtime.GOOS && pkg.Architecture == runtime.GOARCH {
+			st.Supported = true
+		}
+	}
+
+	a, _ := p.store.(Activator)
+	for _, st := range statuses {
+		slices.SortFunc(st.Versions, p.versions.Compare)
+		st.Versions = slices.Compact(st.Versions)
+		st.Version = st.Versions[len(st.Versions)-1]
+		if a != nil {
+			active, err := a.ActiveVersion(st.Name)
+			if err != nil {
+				return nil, err
+			}
+			if slices.Contains(st.Versions, active) {
+				st.Version = active
+			}
+		}
+	}
+
+	for name, plug := range plugs {
+		st, ok := statuses[name]
+		if !ok {
+			st = &PackageStatus{Name: name}
+			statuses[name] = st
+		}
+		st.Latest = plug.Version
+		st.Supported = plug.Supports(plug.Version, runtime.GOOS, runtime.GOARCH)
+		st.UpdateAvailable = st.Installed && p.versions.Compare(st.Latest, st.Version) > 0
+	}
+
+	ret := make([]PackageStatus, 0, len(statuses))
+	for _, st := range statuses {
+		st.Held = slices.Contains(held, st.Name)
+		ret = append(ret, *st)
+	}
+	slices.SortFunc(ret, func(a, b PackageStatus) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return ret, nil
+}
+
+// setState records that an operation on the named package started.
+func (p *Manager) setState(name string, status InstallationStatus, version string) {
+	now := time.Now()
+
+	p.statemu.Lock()
+	defer p.statemu.Unlock()
+
+	if p.states == nil {
+		p.states = make(map[string]*IntegrationInstallation)
+	}
+	p.states[name] = &IntegrationInstallation{
+		Status:    status,
+		Version:   version,
+		StartedAt: now,
+		UpdatedAt: now,
+	}
+}
+
+// setDone records the outcome of the operation on the named package.
+// Failures are kept around for the UI to show, successes are simply
+// reflected by what's installed.
+func (p *Manager) setDone(name string, err error) {
+	p.statemu.Lock()
+	defer p.statemu.Unlock()
+
+	st, ok := p.states[name]
+	if !ok {
+		return
+	}
+	if err == nil || errors.Is(err, ErrAlreadyInstalled) {
+		delete(p.states, name)
+		return
+	}
+	st.Status = StatusFailed
+	st.Error = err.Error()
+	st.UpdatedAt = time.Now()
+}
+
+// setProgress updates the state of the package being installed.
+func (p *Manager) setProgress(ev *Event) {
+	p.statemu.Lock()
+	defer p.statemu.Unlock()
+
+	st, ok := p.states[ev.Package.Name]
+	if !ok || st.Status != StatusInstalling {
+		return
+	}
+	st.Progress = -1
+	if ev.Total > 0 {
+		st.Progress = int(ev.Bytes * 100 / ev.Total)
+	}
+	st.UpdatedAt = time.Now()
+}
+
+// mergeStates overrides the installation of the integrations with
+// the state of the ongoing and failed operations.
+func (p *Manager) mergeStates(integrations map[string]*Integration, apiversion string) {
+	p.statemu.Lock()
+	defer p.statemu.Unlock()
+
+	for name, st := range p.states {
+		in, ok := integrations[name]
+		if !ok {
+			in = &Integration{
+				Id:          name,
+				Name:        name,
+				DisplayName: name,
+				Tags:        []string{},
+				API:         apiversion,
+			}
+			integrations[name] = in
+		}
+
+		// a failed upgrade or removal leaves the package installed.
+		if st.Status == StatusFailed && in.Installation.Status == StatusInstalled {
+			in.Installation.Error = st.Error
+			in.Installation.StartedAt = st.StartedAt
+			in.Installation.UpdatedAt = st.UpdatedAt
+			continue
+		}
+
+		available := in.Installation.Available
+		in.Installation = *st
+		in.Installation.Available = available
+	}
+}
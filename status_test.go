@@ -0,0 +1,146 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func localStatus(t *testing.T, m *Manager, name string) *IntegrationInstallation {
+	t.Helper()
+	got, err := m.Query(&QueryOptions{OnlyLocal: true})
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	for _, in := range got {
+		if in.Name == name {
+			return &in.Installation
+		}
+	}
+	return nil
+}
+
+func TestInstallationStatusLive(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+
+	var m *Manager
+	var seen []IntegrationInstallation
+	m, _ = New(newFakeBackend(), &Options{
+		InstallURL: repo.URL,
+		EventHook: func(ev *Event) {
+			if ev.Type == EventProgress {
+				seen = append(seen, *localStatus(t, m, "s3"))
+			}
+		},
+	})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	if len(seen) == 0 {
+		t.Fatal("no progress event")
+	}
+	last := seen[len(seen)-1]
+	if last.Status != StatusInstalling || last.Version != "v1.3.0" || last.StartedAt.IsZero() {
+		t.Errorf("status while installing = %+v", last)
+	}
+	if last.Progress != 100 && last.Progress != -1 {
+		t.Errorf("progress at the end of the download = %d", last.Progress)
+	}
+
+	st := localStatus(t, m, "s3")
+	if st == nil || st.Status != StatusInstalled || st.Error != "" {
+		t.Errorf("status after the install = %+v", st)
+	}
+}
+
+func TestInstallationStatusFailed(t *testing.T) {
+	repo := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := newFakeBackend()
+	be.loadErr = errors.New("disk full")
+	m, _ := New(be, &Options{InstallURL: repo.URL})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err == nil {
+		t.Fatal("Add succeeded")
+	}
+
+	st := localStatus(t, m, "s3")
+	if st == nil || st.Status != StatusFailed || st.Error != "disk full" {
+		t.Fatalf("status after a failed install = %+v", st)
+	}
+	if st.UpdatedAt.Before(st.StartedAt) {
+		t.Errorf("updated %v before started %v", st.UpdatedAt, st.StartedAt)
+	}
+
+	// a successful retry clears the failure.
+	be.loadErr = nil
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if st := localStatus(t, m, "s3"); st == nil || st.Status != StatusInstalled || st.Error != "" {
+		t.Errorf("status after the retry = %+v", st)
+	}
+}
+
+func TestInstallationStatusFailedRemoval(t *testing.T) {
+	be := &failingBackend{fakeBackend: newFakeBackend(pkgVer("s3", "v1.0.0")), fail: "s3"}
+	m, _ := New(be, nil)
+
+	if err := m.Del("s3", nil); err == nil {
+		t.Fatal("Del succeeded")
+	}
+
+	st := localStatus(t, m, "s3")
+	if st == nil || st.Status != StatusInstalled || st.Version != "v1.0.0" || st.Error != "busy" {
+		t.Errorf("status after a failed removal = %+v", st)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, `{"version":"v1","integrations":[
+			{"name":"s3","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.2.0"},
+			{"name":"ftp","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0"},
+			{"name":"imap","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.0.0",
+			 "artifacts":[{"version":"v1.0.0","os":"plan9","arch":"mips"}]}]}`)
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"), pkgVer("s3", "v1.1.0"), pkgVer("ftp", "v1.0.0"),
+		pkgVer("local", "v0.1.0"))
+	m, _ := New(be, &Options{ApiURL: srv.URL})
+
+	got, err := m.Status()
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	want := []PackageStatus{
+		{Name: "ftp", Installed: true, Version: "v1.0.0", Versions: []string{"v1.0.0"},
+			Latest: "v1.0.0", Supported: true},
+		{Name: "imap", Latest: "v1.0.0"},
+		{Name: "local", Installed: true, Version: "v0.1.0", Versions: []string{"v0.1.0"},
+			Supported: true},
+		{Name: "s3", Installed: true, Version: "v1.1.0", Versions: []string{"v1.0.0", "v1.1.0"},
+			Latest: "v1.2.0", UpdateAvailable: true, Supported: true},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Status = %+v", got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Name != w.Name || g.Installed != w.Installed || g.Version != w.Version ||
+			len(g.Versions) != len(w.Versions) || g.Latest != w.Latest ||
+			g.UpdateAvailable != w.UpdateAvailable || g.Supported != w.Supported {
+			t.Errorf("Status[%d] = %+v, want %+v", i, g, w)
+		}
+	}
+
+	// offline, only what's installed.
+	m, _ = New(be, nil)
+	if got, err := m.Status(); err != nil || len(got) != 3 || got[2].Latest != "" {
+		t.Errorf("Status without API = %+v, %v", got, err)
+	}
+}
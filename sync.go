@@ -0,0 +1,376 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"slices"
+	"strings"
+	"time"
+
+	"golang.org/x/mod/semver"
+)
+
+var (
+	ErrBadConstraint  = errors.New("invalid version constraint")
+	ErrUnsatisfiable  = errors.New("no version satisfies the constraint")
+	ErrDuplicatedSpec = errors.New("package listed more than once")
+)
+
+const (
+	SyncKeep      = "keep"
+	SyncInstall   = "install"
+	SyncUpgrade   = "upgrade"
+	SyncDowngrade = "downgrade"
+	SyncRemove    = "remove"
+	SyncHold      = "hold"
+)
+
+// Spec describes a package that should be installed.
+type Spec struct {
+	Name string `yaml:"name"`
+
+	// Constraint on the version: empty for any version, a full
+	// version like "v1.2.3" for that exact one, a partial version
+	// like "v1" or "v1.2" for any version with that prefix, or a
+	// comma-separated list of comparisons like ">=v1.2.0,<v2.0.0".
+	Version string `yaml:"version,omitempty"`
+
+	// Keep the installed version as long as it satisfies the
+	// constraint instead of upgrading it.
+	Pinned bool `yaml:"pinned,omitempty"`
+}
+
+// SyncAction is a step of the plan computed by [Manager.Sync].
+type SyncAction struct {
+	Action string // SyncKeep, SyncInstall, SyncUpgrade, ...
+	Name   string
+	From   string // installed version, if any
+	To     string // version to install, if any
+
+	checksum    string
+	exact       bool
+	allowYanked bool
+
+	// The critical advisory that changed the step, if any.
+	Advisory string
+
+	// Why the step couldn't be planned or done.
+	Err error
+}
+
+type SyncOptions struct {
+	// Only compute the plan, don't change anything.
+	DryRun bool
+
+	// Don't remove the installed packages that are not listed.
+	KeepUnlisted bool
+
+	// When the changes are allowed.  Outside of it, Sync fails
+	// with ErrOutsideWindow, unless DryRun is set.
+	Schedule *Schedule
+
+	// Install the yanked versions the specs pin exactly, e.g. when
+	// they come from a lockfile.  Yanked versions are never picked
+	// otherwise.
+	AllowYanked bool
+}
+
+type comparison struct {
+	op      string
+	version string
+}
+
+// parseConstraint parses a version constraint as documented in
+// [Spec.Version].
+func parseConstraint(scheme VersionScheme, constraint string) ([]comparison, error) {
+	var ret []comparison
+	if constraint == "" {
+		return nil, nil
+	}
+
+	for c := range strings.SplitSeq(constraint, ",") {
+		c = strings.TrimSpace(c)
+		op := "="
+		for _, o := range []string{">=", "<=", ">", "<", "="} {
+			if v, ok := strings.CutPrefix(c, o); ok {
+				op, c = o, strings.TrimSpace(v)
+				break
+			}
+		}
+
+		if !scheme.Valid(c) {
+			return nil, fmt.Errorf("%w %q", ErrBadConstraint, constraint)
+		}
+		// partial semantic versions are only meaningful as a
+		// prefix.
+		if _, ok := scheme.(semverScheme); ok && op == "=" && strings.Count(c, ".") < 2 {
+			op = "^"
+		}
+		ret = append(ret, comparison{op: op, version: c})
+	}
+	return ret, nil
+}
+
+// exactVersion returns the version the constraint pins, if any.
+func exactVersion(cmps []comparison) string {
+	if len(cmps) == 1 && cmps[0].op == "=" {
+		return cmps[0].version
+	}
+	return ""
+}
+
+func satisfies(scheme VersionScheme, cmps []comparison, version string) bool {
+	for _, c := range cmps {
+		cmp := scheme.Compare(version, c.version)
+		var ok bool
+		switch c.op {
+		case "=":
+			// a build metadata, if given, must match too.
+			ok = cmp == 0 && (semver.Build(c.version) == "" || version == c.version)
+		case "^":
+			ok = version == c.version || strings.HasPrefix(version, c.version+".")
+		case ">=":
+			ok = cmp >= 0
+		case "<=":
+			ok = cmp <= 0
+		case ">":
+			ok = cmp > 0
+		case "<":
+			ok = cmp < 0
+		}
+		if !ok {
+			return false
+		}
+	}
+	return true
+}
+
+// Sync converges the installed packages to the desired ones: the
+// missing ones are installed, the ones with a version not satisfying
+// the constraint are upgraded or downgraded, and the ones not listed
+// are removed.  The packages on hold are left untouched.  The
+// versions with a critical advisory are dealt with according to
+// Options.SecurityPolicy.  It returns
+// the plan, with the error of every step that failed.
+func (p *Manager) Sync(desired []Spec, opts *SyncOptions) ([]SyncAction, error) {
+	if opts == nil {
+		opts = &SyncOptions{}
+	}
+
+	if opts.Schedule != nil && !opts.DryRun {
+		now := time.Now()
+		if !opts.Schedule.Allows(now) {
+			if next := opts.Schedule.Next(now); !next.IsZero() {
+				return nil, fmt.Errorf("%w: next one at %s", ErrOutsideWindow,
+					next.Format(time.RFC3339))
+			}
+			return nil, ErrOutsideWindow
+		}
+	}
+
+	plan, err := p.plan(desired, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var errs []error
+	for i := range plan {
+		a := &plan[i]
+		if a.Err == nil && !opts.DryRun {
+			a.Err = p.apply(a)
+		}
+		if a.Err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", a.Name, a.Err))
+		}
+	}
+	return plan, errors.Join(errs...)
+}
+
+func (p *Manager) plan(desired []Spec, opts *SyncOptions) ([]SyncAction, error) {
+	// reject the invalid specs before going to the network.
+	seen := make(map[string]bool)
+	for _, spec := range desired {
+		if seen[spec.Name] {
+			return nil, fmt.Errorf("%w: %s", ErrDuplicatedSpec, spec.Name)
+		}
+		seen[spec.Name] = true
+
+		if _, err := parseConstraint(p.versions, spec.Version); err != nil {
+			return nil, fmt.Errorf("%s: %w", spec.Name, err)
+		}
+	}
+
+	held, err := p.held()
+	if err != nil {
+		return nil, err
+	}
+
+	installed := make(map[string][]string)
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+		installed[pkg.Name] = append(installed[pkg.Name], pkg.Version)
+	}
+
+	var plan []SyncAction
+	for _, spec := range desired {
+		if slices.Contains(held, spec.Name) {
+			plan = append(plan, p.holdAction(spec.Name, installed[spec.Name]))
+			continue
+		}
+
+		a := p.planSpec(&spec, installed[spec.Name])
+		a.allowYanked = opts.AllowYanked && a.exact
+		plan = append(plan, a)
+	}
+
+	if !opts.KeepUnlisted {
+		for name, versions := range installed {
+			if seen[name] {
+				continue
+			}
+			if slices.Contains(held, name) {
+				plan = append(plan, p.holdAction(name, versions))
+				continue
+			}
+			for _, v := range versions {
+				plan = append(plan, SyncAction{
+					Action: SyncRemove,
+					Name:   name,
+					From:   v,
+				})
+			}
+		}
+	}
+
+	if err := p.secure(plan); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(plan, func(a, b SyncAction) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return plan, nil
+}
+
+// holdAction returns the step leaving a package on hold alone.
+func (p *Manager) holdAction(name string, versions []string) SyncAction {
+	a := SyncAction{Action: SyncHold, Name: name}
+	if len(versions) != 0 {
+		slices.SortFunc(versions, p.versions.Compare)
+		a.From = versions[len(versions)-1]
+		a.To = a.From
+	}
+	return a
+}
+
+func (p *Manager) planSpec(spec *Spec, versions []string) SyncAction {
+	a := SyncAction{Name: spec.Name}
+
+	cmps, err := parseConstraint(p.versions, spec.Version)
+	if err != nil {
+		a.Err = err
+		return a
+	}
+
+	// the newest installed version satisfying the constraint, or
+	// the newest one.
+	slices.SortFunc(versions, p.versions.Compare)
+	for _, v := range slices.Backward(versions) {
+		if satisfies(p.versions, cmps, v) {
+			a.From = v
+			break
+		}
+	}
+	if a.From == "" && len(versions) != 0 {
+		a.From = versions[len(versions)-1]
+	}
+	current := a.From != "" && satisfies(p.versions, cmps, a.From)
+
+	if exact := exactVersion(cmps); exact != "" {
+		a.To, a.exact = exact, true
+		if a.To != a.From {
+			// verified like the latest version is, when it's
+			// the one pinned.
+			checksum, err := p.versionChecksum(spec.Name, exact)
+			if err != nil {
+				a.Err = err
+				return a
+			}
+			a.checksum = checksum
+		}
+	} else if current && spec.Pinned {
+		a.To = a.From
+	} else {
+		r, err := p.FetchRecipe(spec.Name)
+		if err != nil {
+			a.Err = err
+			return a
+		}
+		switch latest := r.Semver(); {
+		case satisfies(p.versions, cmps, latest):
+			a.To = latest
+			a.checksum = r.Checksum(runtime.GOOS, runtime.GOARCH)
+		case current:
+			a.To = a.From
+		default:
+			a.Err = fmt.Errorf("%w %q: latest is %s", ErrUnsatisfiable,
+				spec.Version, latest)
+			return a
+		}
+	}
+
+	p.classify(&a)
+	return a
+}
+
+// classify sets the action of the step from the versions it goes
+// from and to.
+func (p *Manager) classify(a *SyncAction) {
+	switch cmp := p.versions.Compare(a.To, a.From); {
+	case a.From == "":
+		a.Action = SyncInstall
+	case a.To == a.From:
+		a.Action = SyncKeep
+	case cmp < 0:
+		a.Action = SyncDowngrade
+	default:
+		a.Action = SyncUpgrade
+	}
+}
+
+func (p *Manager) apply(a *SyncAction) error {
+	switch a.Action {
+	case SyncInstall, SyncUpgrade, SyncDowngrade:
+		return p.Add(a.Name, &AddOptions{
+			ImplicitFetch: true,
+			Version:       a.To,
+			Checksum:      a.checksum,
+			Replace:       a.From != "",
+			AllowYanked:   a.allowYanked,
+		})
+	case SyncRemove:
+		return p.Del(a.Name, &DelOptions{Version: a.From})
+	}
+	return nil
+}
@@ -0,0 +1,220 @@
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"runtime"
+	"testing"
+)
+
+// newTestRepository serves the recipes of the given packages, mapped
+// to their latest version, and any binary.
+func newTestRepository(t *testing.T, latest map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "recipe.yaml" {
+			name := path.Base(path.Dir(r.URL.Path))
+			version, ok := latest[name]
+			if !ok {
+				http.NotFound(w, r)
+				return
+			}
+			fmt.Fprintf(w, "name: %s\nversion: %s\n", name, version)
+			return
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestSatisfies(t *testing.T) {
+	tests := []struct {
+		constraint string
+		version    string
+		want       bool
+	}{
+		{"", "v1.0.0", true},
+		{"v1.2.3", "v1.2.3", true},
+		{"v1.2.3", "v1.2.4", false},
+		{"=v1.2.3", "v1.2.3", true},
+		{"v1", "v1.9.0", true},
+		{"v1", "v10.0.0", false},
+		{"v1.2", "v1.2.7", true},
+		{"v1.2", "v1.3.0", false},
+		{">=v1.2.0,<v2.0.0", "v1.5.0", true},
+		{">=v1.2.0,<v2.0.0", "v2.0.0", false},
+		{">v1.0.0", "v1.0.0", false},
+		{"<=v1.0.0", "v1.0.0", true},
+		{"v1.0.7+build.2", "v1.0.7+build.2", true},
+		{"v1.0.7+build.2", "v1.0.7+build.1", false},
+		{"v1.0.7", "v1.0.7+build.1", true},
+	}
+	for _, tt := range tests {
+		cmps, err := parseConstraint(SemverScheme, tt.constraint)
+		if err != nil {
+			t.Fatalf("parseConstraint(%q): %v", tt.constraint, err)
+		}
+		if got := satisfies(SemverScheme, cmps, tt.version); got != tt.want {
+			t.Errorf("%q satisfies %q = %v, want %v", tt.version, tt.constraint, got, tt.want)
+		}
+	}
+}
+
+func TestParseConstraintInvalid(t *testing.T) {
+	for _, c := range []string{"1.0.0", ">=", "v1.0.0,foo", "~v1.0.0"} {
+		if _, err := parseConstraint(SemverScheme, c); !errors.Is(err, ErrBadConstraint) {
+			t.Errorf("parseConstraint(%q) err = %v, want ErrBadConstraint", c, err)
+		}
+	}
+}
+
+func TestSyncPlan(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{
+		"s3":    "v1.3.0",
+		"sftp":  "v2.1.0",
+		"ftp":   "v1.0.0",
+		"pinme": "v1.5.0",
+		"down":  "v2.0.0",
+	})
+
+	be := newFakeBackend(
+		pkgVer("s3", "v1.0.0"),
+		pkgVer("sftp", "v2.1.0"),
+		pkgVer("pinme", "v1.1.0"),
+		pkgVer("extra", "v0.1.0"),
+		pkgVer("down", "v2.0.0"),
+	)
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	plan, err := m.Sync([]Spec{
+		{Name: "s3", Version: "v1"},
+		{Name: "sftp"},
+		{Name: "ftp"},
+		{Name: "pinme", Version: "v1", Pinned: true},
+		{Name: "down", Version: "v1.9.0"},
+	}, &SyncOptions{DryRun: true})
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	want := []SyncAction{
+		{Action: SyncDowngrade, Name: "down", From: "v2.0.0", To: "v1.9.0"},
+		{Action: SyncRemove, Name: "extra", From: "v0.1.0"},
+		{Action: SyncInstall, Name: "ftp", To: "v1.0.0"},
+		{Action: SyncKeep, Name: "pinme", From: "v1.1.0", To: "v1.1.0"},
+		{Action: SyncUpgrade, Name: "s3", From: "v1.0.0", To: "v1.3.0"},
+		{Action: SyncKeep, Name: "sftp", From: "v2.1.0", To: "v2.1.0"},
+	}
+	if len(plan) != len(want) {
+		t.Fatalf("plan = %+v", plan)
+	}
+	for i, a := range plan {
+		w := want[i]
+		if a.Action != w.Action || a.Name != w.Name || a.From != w.From || a.To != w.To {
+			t.Errorf("plan[%d] = %+v, want %+v", i, a, w)
+		}
+	}
+
+	if len(be.loaded) != 0 || len(be.unloaded) != 0 {
+		t.Error("dry run changed the installed packages")
+	}
+}
+
+func TestSyncApply(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.3.0", "ftp": "v1.0.0"})
+
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"), pkgVer("extra", "v0.1.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	if _, err := m.Sync([]Spec{{Name: "s3"}, {Name: "ftp"}}, nil); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+
+	got := map[string]string{}
+	for _, p := range be.pkgs {
+		got[p.Name] = p.Version
+	}
+	if len(got) != 2 || got["s3"] != "v1.3.0" || got["ftp"] != "v1.0.0" {
+		t.Errorf("installed = %v", got)
+	}
+}
+
+func TestSyncExactChecksum(t *testing.T) {
+	checksum := sha256sum("PTARDATA")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if path.Base(r.URL.Path) == "recipe.yaml" {
+			fmt.Fprintf(w, "name: s3\nversion: v1.3.0\nchecksums:\n  %s/%s: %s\n",
+				runtime.GOOS, runtime.GOARCH, checksum)
+			return
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL, MinChecksumStrength: 128})
+
+	// the pinned version is the latest one, whose checksum is known.
+	if _, err := m.Sync([]Spec{{Name: "s3", Version: "v1.3.0"}}, nil); err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Version != "v1.3.0" {
+		t.Errorf("loaded = %v, want s3 v1.3.0", be.loaded)
+	}
+
+	// an older one can't be verified.
+	_, err := m.Sync([]Spec{{Name: "s3", Version: "v1.2.0"}}, nil)
+	if !errors.Is(err, ErrWeakChecksum) {
+		t.Errorf("Sync of an older version = %v, want %v", err, ErrWeakChecksum)
+	}
+}
+
+func TestSyncUnsatisfiable(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v2.0.0"})
+
+	be := newFakeBackend()
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	plan, err := m.Sync([]Spec{{Name: "s3", Version: "<v2.0.0"}}, nil)
+	if !errors.Is(err, ErrUnsatisfiable) {
+		t.Fatalf("Sync err = %v, want ErrUnsatisfiable", err)
+	}
+	if len(plan) != 1 || !errors.Is(plan[0].Err, ErrUnsatisfiable) {
+		t.Errorf("plan = %+v", plan)
+	}
+	if len(be.loaded) != 0 {
+		t.Error("unsatisfiable spec got installed")
+	}
+}
+
+func TestSyncDuplicatedSpec(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	_, err := m.Sync([]Spec{{Name: "s3"}, {Name: "s3"}}, nil)
+	if !errors.Is(err, ErrDuplicatedSpec) {
+		t.Errorf("Sync err = %v, want ErrDuplicatedSpec", err)
+	}
+}
+
+func TestSyncInvalidSpecsBeforeFetch(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	for _, specs := range [][]Spec{
+		{{Name: "fs"}, {Name: "s3"}, {Name: "s3"}},
+		{{Name: "fs"}, {Name: "s3", Version: ">>1"}},
+	} {
+		if _, err := m.Sync(specs, nil); errors.Is(err, ErrInvalidOptions) || err == nil {
+			t.Errorf("Sync(%v) err = %v, want it to fail before fetching", specs, err)
+		}
+	}
+}
+
+func TestFetchWithoutRepository(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if _, err := m.FetchRecipe("s3"); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("FetchRecipe err = %v, want ErrInvalidOptions", err)
+	}
+}
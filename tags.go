@@ -0,0 +1,138 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"cmp"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// defaultIndexTTL is how long the integration index is reused before
+// being fetched again.
+const defaultIndexTTL = 5 * time.Minute
+
+// cachedIndex returns the integration index, fetching it only if the
+// cached one is older than the configured TTL.  If the index can't be
+// fetched, the last one known is used, possibly from the cache file.
+// The integrations are copied, so the callers may change them.
+func (p *Manager) cachedIndex() (*IntegrationIndex, error) {
+	p.indexmu.Lock()
+	defer p.indexmu.Unlock()
+
+	if p.index == nil || p.indexttl < 0 || time.Since(p.indexfetched) >= p.indexttl {
+		index, err := p.fetchIndex()
+		switch {
+		case err == nil:
+			p.index, p.indexfetched = index, time.Now()
+			p.saveIndex(index)
+		case p.index != nil:
+			p.emit(newWarning(nil, WarnStaleIndex,
+				"using the index fetched at %s: %v", p.indexfetched.Format(time.RFC3339), err))
+		default:
+			index, ferr := p.loadIndex()
+			if ferr != nil || index == nil {
+				return nil, err
+			}
+			p.emit(newWarning(nil, WarnStaleIndex,
+				"using the cached index of %s: %v", index.Timestamp.Format(time.RFC3339), err))
+			// retry the fetch on the next call.
+			p.index, p.indexfetched = index, time.Time{}
+		}
+	}
+
+	index := *p.index
+	index.Integrations = slices.Clone(p.index.Integrations)
+	return &index, nil
+}
+
+// TagCount is a tag with the number of integrations carrying it.
+type TagCount struct {
+	Tag   string `json:"tag"`
+	Count int    `json:"count"`
+}
+
+// Tags returns the tags found in the integration index, with how
+// many integrations carry each, ordered by name.
+func (p *Manager) Tags() ([]TagCount, error) {
+	integrations, err := p.Query(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	for _, integration := range integrations {
+		for _, tag := range integration.Tags {
+			counts[tag]++
+		}
+	}
+
+	ret := make([]TagCount, 0, len(counts))
+	for tag, n := range counts {
+		ret = append(ret, TagCount{Tag: tag, Count: n})
+	}
+	slices.SortFunc(ret, func(a, b TagCount) int {
+		return cmp.Compare(a.Tag, b.Tag)
+	})
+	return ret, nil
+}
+
+// ByTag returns the integrations carrying the given tag.
+func (p *Manager) ByTag(tag string) ([]*Integration, error) {
+	return p.Query(&QueryOptions{Tag: tag})
+}
+
+// saveIndex writes the index to the cache file, if one is configured.
+// Failing to do so only costs the offline fallback.
+func (p *Manager) saveIndex(index *IntegrationIndex) {
+	if p.indexcache == "" {
+		return
+	}
+
+	data, err := json.Marshal(index)
+	if err != nil {
+		return
+	}
+
+	dir := filepath.Dir(p.indexcache)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return
+	}
+	writeFile(OSFS{}, p.indexcache, data)
+}
+
+// loadIndex reads the cache file.  It returns nil if there is none.
+func (p *Manager) loadIndex() (*IntegrationIndex, error) {
+	if p.indexcache == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(p.indexcache)
+	if err != nil {
+		return nil, err
+	}
+
+	var index IntegrationIndex
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, err
+	}
+	return &index, nil
+}
@@ -0,0 +1,94 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+)
+
+const tagsIndex = `{
+	"version":"v1",
+	"integrations":[
+		{"name":"s3","edition":"community","api":"v1.1.0","version":"v1.0.0","tags":["cloud","object-storage"]},
+		{"name":"gcs","edition":"community","api":"v1.1.0","version":"v1.0.0","tags":["cloud"]},
+		{"name":"ftp","edition":"community","api":"v1.1.0","version":"v1.0.0","tags":["legacy"]}
+	]
+}`
+
+func newTagsAPI(t *testing.T, hits *int) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*hits++
+		io.WriteString(w, tagsIndex)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestTags(t *testing.T) {
+	var hits int
+	srv := newTagsAPI(t, &hits)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	tags, err := m.Tags()
+	if err != nil {
+		t.Fatalf("Tags: %v", err)
+	}
+	want := []TagCount{{"cloud", 2}, {"legacy", 1}, {"object-storage", 1}}
+	if !slices.Equal(tags, want) {
+		t.Errorf("tags = %v, want %v", tags, want)
+	}
+
+	cloud, err := m.ByTag("cloud")
+	if err != nil {
+		t.Fatalf("ByTag: %v", err)
+	}
+	var names []string
+	for _, i := range cloud {
+		names = append(names, i.Name)
+	}
+	if !slices.Equal(names, []string{"gcs", "s3"}) {
+		t.Errorf("ByTag(cloud) = %v", names)
+	}
+
+	if hits != 1 {
+		t.Errorf("index fetched %d times, want 1", hits)
+	}
+}
+
+func TestIndexCacheDisabled(t *testing.T) {
+	var hits int
+	srv := newTagsAPI(t, &hits)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, IndexTTL: -1})
+
+	for range 2 {
+		if _, err := m.Query(nil); err != nil {
+			t.Fatalf("Query: %v", err)
+		}
+	}
+	if hits != 2 {
+		t.Errorf("index fetched %d times, want 2", hits)
+	}
+}
+
+func TestIndexCacheIsolation(t *testing.T) {
+	var hits int
+	srv := newTagsAPI(t, &hits)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL})
+
+	first, err := m.Query(nil)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	first[0].Installation.Status = StatusInstalled
+
+	again, err := m.Query(nil)
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if again[0].Installation.Status != StatusNotInstalled {
+		t.Errorf("status = %q, the cache was modified", again[0].Installation.Status)
+	}
+}
@@ -0,0 +1,58 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+const (
+	TelemetryInstall = "install"
+	TelemetryUpgrade = "upgrade"
+)
+
+// TelemetryEvent describes the outcome of an installation.  It only
+// carries the package and the platform, never anything identifying
+// the user or the host.
+type TelemetryEvent struct {
+	Operation       string // TelemetryInstall or TelemetryUpgrade
+	Name            string
+	Version         string
+	OperatingSystem string
+	Architecture    string
+
+	// Whether the operation failed, and why.
+	Failed bool
+	Error  string
+}
+
+// TelemetryReporter is implemented by the host to collect anonymous
+// install statistics.  The manager never reports anything unless one
+// is given in [Options.Telemetry].
+type TelemetryReporter interface {
+	Report(*TelemetryEvent)
+}
+
+func (p *Manager) report(ev *TelemetryEvent, err error) {
+	if p.telemetry == nil || ev.Name == "" {
+		return
+	}
+
+	if err != nil {
+		ev.Failed = true
+		ev.Error = err.Error()
+	}
+	p.telemetry.Report(ev)
+}
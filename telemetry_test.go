@@ -0,0 +1,73 @@
+package pkg
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+type recordingReporter struct {
+	events []TelemetryEvent
+}
+
+func (r *recordingReporter) Report(ev *TelemetryEvent) {
+	r.events = append(r.events, *ev)
+}
+
+func TestTelemetryReportsInstallAndUpgrade(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer srv.Close()
+
+	rep := &recordingReporter{}
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL, Telemetry: rep})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.1.0", Upgrade: true}); err != nil {
+		t.Fatalf("Add (upgrade): %v", err)
+	}
+
+	if len(rep.events) != 2 {
+		t.Fatalf("got %d events, want 2", len(rep.events))
+	}
+	if ev := rep.events[0]; ev.Operation != TelemetryInstall || ev.Version != "v1.0.0" || ev.Failed {
+		t.Errorf("first event = %+v", ev)
+	}
+	if ev := rep.events[1]; ev.Operation != TelemetryUpgrade || ev.Version != "v1.1.0" || ev.Failed {
+		t.Errorf("second event = %+v", ev)
+	}
+	if ev := rep.events[0]; ev.OperatingSystem != runtime.GOOS || ev.Architecture != runtime.GOARCH {
+		t.Errorf("platform = %s/%s", ev.OperatingSystem, ev.Architecture)
+	}
+}
+
+func TestTelemetryReportsFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "no such build", http.StatusNotFound)
+	}))
+	defer srv.Close()
+
+	rep := &recordingReporter{}
+	m, _ := New(newFakeBackend(), &Options{InstallURL: srv.URL, Telemetry: rep})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err == nil {
+		t.Fatal("expected Add to fail")
+	}
+	if len(rep.events) != 1 || !rep.events[0].Failed || rep.events[0].Error == "" {
+		t.Errorf("events = %+v", rep.events)
+	}
+}
+
+func TestTelemetryDisabledByDefault(t *testing.T) {
+	m, _ := New(newFakeBackend(), nil)
+	if m.telemetry != nil {
+		t.Error("telemetry enabled by default")
+	}
+	// must not panic without a reporter
+	m.report(&TelemetryEvent{Name: "s3"}, nil)
+}
@@ -0,0 +1,64 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"io"
+	"time"
+)
+
+// throttledReader caps the average throughput of the underlying
+// reader to the given number of bytes per second.
+type throttledReader struct {
+	rd    io.Reader
+	rate  int64
+	start time.Time
+	n     int64
+
+	// the clock, replaced in the tests.
+	now   func() time.Time
+	sleep func(time.Duration)
+}
+
+func newThrottledReader(rd io.Reader, rate int64) *throttledReader {
+	return &throttledReader{
+		rd:    rd,
+		rate:  rate,
+		start: time.Now(),
+		now:   time.Now,
+		sleep: time.Sleep,
+	}
+}
+
+func (t *throttledReader) Read(p []byte) (int, error) {
+	// don't read more than a second worth of data at a time, so
+	// the throughput stays smooth.
+	if int64(len(p)) > t.rate {
+		p = p[:t.rate]
+	}
+
+	n, err := t.rd.Read(p)
+	t.n += int64(n)
+
+	expected := time.Duration(float64(t.n) / float64(t.rate) * float64(time.Second))
+	if elapsed := t.now().Sub(t.start); elapsed < expected {
+		t.sleep(expected - elapsed)
+	}
+	return n, err
+}
@@ -0,0 +1,41 @@
+package pkg
+
+import (
+	"bytes"
+	"io"
+	"testing"
+	"time"
+)
+
+func TestThrottledReader(t *testing.T) {
+	data := bytes.Repeat([]byte("x"), 300)
+	tr := newThrottledReader(bytes.NewReader(data), 100)
+
+	var slept time.Duration
+	clock := time.Unix(0, 0)
+	tr.start = clock
+	tr.now = func() time.Time { return clock.Add(slept) }
+	tr.sleep = func(d time.Duration) { slept += d }
+
+	buf := make([]byte, 1024)
+	n, err := tr.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 100 {
+		t.Errorf("first read = %d bytes, want at most one second worth (100)", n)
+	}
+
+	rest, err := io.ReadAll(tr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n+len(rest) != len(data) {
+		t.Errorf("read %d bytes, want %d", n+len(rest), len(data))
+	}
+
+	// 300 bytes at 100 B/s should take about three seconds.
+	if slept < 2900*time.Millisecond || slept > 3*time.Second {
+		t.Errorf("slept %v, want about 3s", slept)
+	}
+}
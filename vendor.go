@@ -0,0 +1,84 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Bootstrap installs the packages of the vendor directory that are
+// missing from the backend, so that a system image can ship with a
+// default set of plugins.  Only the packages built for the current
+// platform are considered, the newest version if there are many,
+// and those already installed, in whatever version, are left alone.
+func (p *Manager) Bootstrap() error {
+	if p.vendordir == "" {
+		return nil
+	}
+
+	entries, err := os.ReadDir(p.vendordir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+
+	vendored := map[string]*Package{}
+	paths := map[string]string{}
+	var names []string
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".ptar") {
+			continue
+		}
+
+		var pkg Package
+		if err := pkg.parseNameWith(e.Name(), p.versions); err != nil {
+			continue
+		}
+		if pkg.OperatingSystem != runtime.GOOS || pkg.Architecture != runtime.GOARCH {
+			continue
+		}
+
+		prev, ok := vendored[pkg.Name]
+		if !ok {
+			names = append(names, pkg.Name)
+		}
+		if !ok || p.versions.Compare(pkg.Version, prev.Version) > 0 {
+			vendored[pkg.Name] = &pkg
+			paths[pkg.Name] = filepath.Join(p.vendordir, e.Name())
+		}
+	}
+
+	var merr MultiError
+	for _, name := range names {
+		if p.installedVersion(name) != "" {
+			continue
+		}
+
+		err := p.Add(paths[name], &AddOptions{})
+		merr.add(vendored[name], err)
+	}
+	return merr.err()
+}
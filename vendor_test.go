@@ -0,0 +1,47 @@
+package pkg
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestBootstrap(t *testing.T) {
+	dir := t.TempDir()
+	platform := "_" + runtime.GOOS + "_" + runtime.GOARCH + ".ptar"
+	for _, name := range []string{
+		"s3_v1.0.0" + platform,
+		"s3_v1.2.0" + platform,
+		"fs_v1.0.0" + platform,
+		"sftp_v1.0.0_plan9_sparc64.ptar",
+		"README",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte("PTARDATA"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	be := newFakeBackend(pkgOf(t, "fs"))
+	m, _ := New(be, &Options{VendorDir: dir})
+	if err := m.Bootstrap(); err != nil {
+		t.Fatalf("Bootstrap: %v", err)
+	}
+	if len(be.loaded) != 1 || be.loaded[0].Name != "s3" || be.loaded[0].Version != "v1.2.0" {
+		t.Fatalf("loaded = %v, want only s3 v1.2.0", be.loaded)
+	}
+
+	// nothing left to install the second time.
+	be.loaded = nil
+	if err := m.Bootstrap(); err != nil {
+		t.Fatalf("second Bootstrap: %v", err)
+	}
+	if len(be.loaded) != 0 {
+		t.Errorf("second Bootstrap loaded %v", be.loaded)
+	}
+
+	m, _ = New(newFakeBackend(), &Options{VendorDir: filepath.Join(dir, "missing")})
+	if err := m.Bootstrap(); err != nil {
+		t.Errorf("Bootstrap without vendor directory: %v", err)
+	}
+}
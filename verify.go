@@ -0,0 +1,185 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"path/filepath"
+
+	"github.com/PlakarKorp/kloset/snapshot"
+)
+
+var (
+	ErrCorrupted = errors.New("package corrupted")
+)
+
+// Verify checks the integrity of the installed packages with the
+// given name, or of all of them if name is empty.  All the packages
+// are checked, and the failures are reported in a *MultiError.
+func (p *Manager) Verify(name string) error {
+	var merr MultiError
+	for pkg, err := range p.store.List(name) {
+		if err != nil {
+			return err
+		}
+		merr.add(pkg, p.store.Verify(pkg))
+	}
+	return merr.err()
+}
+
+// Verify checks that the stored ptar can be opened and that the
+// extracted tree matches its content.
+func (f *FlatBackend) Verify(pkg *Package) error {
+	ptar := f.ptarPath(pkg)
+	if _, err := f.fsys.Stat(ptar); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%s: %w", pkg.Filename(), ErrNotInstalled)
+		}
+		return err
+	}
+
+	if f.isolated() {
+		return f.verifyExtracted(pkg, ptar)
+	}
+
+	snap, base, release, err := f.openSnapshot(ptar)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	defer release()
+
+	files, err := snapshotFiles(snap, base)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+
+	extracted := f.extractedPath(pkg)
+	if !f.isExtracted(extracted) {
+		return fmt.Errorf("%w: %s is not extracted", ErrCorrupted, pkg.Filename())
+	}
+
+	for _, fe := range files {
+		if err := verifyFile(f.fsys, snap, base, extracted, fe); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// verifyExtracted is Verify for the backends that decode the packages
+// in a helper: the ptar is extracted again by the helper, and the
+// fresh tree compared with the installed one.
+func (f *FlatBackend) verifyExtracted(pkg *Package, ptar string) error {
+	extracted := f.extractedPath(pkg)
+	if !f.isExtracted(extracted) {
+		return fmt.Errorf("%w: %s is not extracted", ErrCorrupted, pkg.Filename())
+	}
+
+	tmpdir, err := f.fsys.MkdirTemp(f.staging(f.cachedir), stagingPrefix+"verify-*")
+	if err != nil {
+		return err
+	}
+	defer f.fsys.RemoveAll(tmpdir)
+
+	dir := filepath.Join(tmpdir, "content")
+	if err := f.unpack(pkg, ptar, dir); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if err := f.pruneForeign(pkg, dir); err != nil {
+		return err
+	}
+	return verifyTree(f.fsys, dir, extracted)
+}
+
+// verifyTree checks that every file of the reference tree is found
+// unchanged in the extracted one.
+func verifyTree(fsys FS, reference, extracted string) error {
+	want, err := scanFiles(fsys, reference, true)
+	if err != nil {
+		return err
+	}
+	got, err := scanFiles(fsys, extracted, true)
+	if err != nil {
+		return err
+	}
+
+	have := make(map[string]FileEntry, len(got))
+	for _, fe := range got {
+		have[fe.Path] = fe
+	}
+	for _, fe := range want {
+		cur, ok := have[fe.Path]
+		switch {
+		case !ok:
+			return fmt.Errorf("%w: %s is missing", ErrCorrupted, fe.Path)
+		case cur.Size != fe.Size:
+			return fmt.Errorf("%w: %s has size %d, want %d", ErrCorrupted,
+				fe.Path, cur.Size, fe.Size)
+		case cur.Checksum != fe.Checksum:
+			return fmt.Errorf("%w: %s was modified", ErrCorrupted, fe.Path)
+		}
+	}
+	return nil
+}
+
+// verifyFile compares the extracted copy of a file with the one in
+// the snapshot.
+func verifyFile(fsys FS, snap *snapshot.Snapshot, base, extracted string, fe FileEntry) error {
+	fp, err := fsys.Open(filepath.Join(extracted, filepath.FromSlash(fe.Path)))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("%w: %s is missing", ErrCorrupted, fe.Path)
+		}
+		return err
+	}
+	defer fp.Close()
+
+	fi, err := fp.Stat()
+	if err != nil {
+		return err
+	}
+	if fi.Size() != fe.Size {
+		return fmt.Errorf("%w: %s has size %d, want %d", ErrCorrupted,
+			fe.Path, fi.Size(), fe.Size)
+	}
+
+	rd, err := snap.NewReader(path.Join(base, fe.Path))
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	defer rd.Close()
+
+	want, got := sha256.New(), sha256.New()
+	if _, err := io.Copy(want, rd); err != nil {
+		return fmt.Errorf("%w: %w", ErrCorrupted, err)
+	}
+	if _, err := io.Copy(got, fp); err != nil {
+		return err
+	}
+	if !bytes.Equal(want.Sum(nil), got.Sum(nil)) {
+		return fmt.Errorf("%w: %s was modified", ErrCorrupted, fe.Path)
+	}
+	return nil
+}
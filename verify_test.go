@@ -0,0 +1,80 @@
+package pkg
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestManagerVerify(t *testing.T) {
+	be := newFakeBackend(pkgVer("fs", "v1.0.0"), pkgVer("s3", "v1.0.0"), pkgVer("sftp", "v1.0.0"))
+	m, err := New(be, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := m.Verify(""); err != nil {
+		t.Fatalf("Verify: %v", err)
+	}
+
+	be.corrupted = []string{"s3", "sftp"}
+	if err := m.Verify("fs"); err != nil {
+		t.Errorf("Verify(fs): %v", err)
+	}
+
+	err = m.Verify("")
+	var merr *MultiError
+	if !errors.As(err, &merr) || len(merr.Errors) != 2 {
+		t.Fatalf("Verify err = %v, want two failures", err)
+	}
+	if !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Verify err = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestFlatBackendVerifyNotInstalled(t *testing.T) {
+	be, _, _ := newTestFlatBackend(t, nil)
+	if err := be.Verify(pkgVer("s3", "v1.0.0")); !errors.Is(err, ErrNotInstalled) {
+		t.Errorf("Verify err = %v, want ErrNotInstalled", err)
+	}
+}
+
+func TestFlatBackendVerifyBogusPtar(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, nil)
+	pkg := pkgVer("s3", "v1.0.0")
+	if err := os.WriteFile(filepath.Join(pkgdir, pkg.Filename()), []byte("garbage"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := be.Verify(pkg); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("Verify err = %v, want ErrCorrupted", err)
+	}
+}
+
+func TestVerifyTree(t *testing.T) {
+	ref, extracted := t.TempDir(), t.TempDir()
+	for _, dir := range []string{ref, extracted} {
+		if err := os.WriteFile(filepath.Join(dir, "plugin"), []byte("binary"), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// extra files in the installed tree, e.g. the index, are fine.
+	touch(t, extracted, fileIndexFile)
+	if err := verifyTree(OSFS{}, ref, extracted); err != nil {
+		t.Fatalf("verifyTree: %v", err)
+	}
+
+	if err := os.WriteFile(filepath.Join(extracted, "plugin"), []byte("BINARY"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTree(OSFS{}, ref, extracted); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("verifyTree = %v, want ErrCorrupted for a modified file", err)
+	}
+
+	if err := os.Remove(filepath.Join(extracted, "plugin")); err != nil {
+		t.Fatal(err)
+	}
+	if err := verifyTree(OSFS{}, ref, extracted); !errors.Is(err, ErrCorrupted) {
+		t.Errorf("verifyTree = %v, want ErrCorrupted for a missing file", err)
+	}
+}
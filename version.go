@@ -0,0 +1,162 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"cmp"
+	"strconv"
+	"strings"
+
+	"golang.org/x/mod/semver"
+)
+
+// VersionScheme defines which versions are valid and how they
+// compare.
+type VersionScheme interface {
+	Valid(version string) bool
+
+	// Compare returns -1, 0 or +1 depending on whether a is older,
+	// the same as, or newer than b.
+	Compare(a, b string) int
+}
+
+var (
+	// SemverScheme accepts only semantic versions like "v1.2.3".
+	// It's the default.
+	SemverScheme VersionScheme = semverScheme{}
+
+	// LooseScheme accepts any version made of alphanumeric
+	// components separated by dots, dashes or pluses, like
+	// "2024.01.15" or "1.2.3.4".  Components are compared in
+	// order, numerically when both are numbers.
+	LooseScheme VersionScheme = looseScheme{}
+)
+
+// EpochScheme accepts versions optionally prefixed by an epoch, as
+// in "epoch:version", and compares the epochs first, then the rest
+// with the given scheme.  Since ':' can't appear in filenames on
+// every system, "epoch!version" is accepted as well.
+func EpochScheme(inner VersionScheme) VersionScheme {
+	return epochScheme{inner: inner}
+}
+
+type semverScheme struct{}
+
+func (semverScheme) Valid(v string) bool     { return semver.IsValid(v) }
+func (semverScheme) Compare(a, b string) int { return semver.Compare(a, b) }
+
+type looseScheme struct{}
+
+func isLooseChar(c byte) bool {
+	return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' || '0' <= c && c <= '9' ||
+		c == '.' || c == '-' || c == '+'
+}
+
+func (looseScheme) Valid(v string) bool {
+	v = strings.TrimPrefix(v, "v")
+	if v == "" || v[0] < '0' || v[0] > '9' {
+		return false
+	}
+	for i := 0; i < len(v); i++ {
+		if !isLooseChar(v[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// looseComponents splits a version into runs of digits and runs of
+// letters.
+func looseComponents(v string) []string {
+	v = strings.TrimPrefix(v, "v")
+
+	var ret []string
+	for i := 0; i < len(v); {
+		if !isNumeric(v[i]) && !isAlpha(v[i]) {
+			i++
+			continue
+		}
+		j := i + 1
+		for j < len(v) && isNumeric(v[j]) == isNumeric(v[i]) && (isNumeric(v[j]) || isAlpha(v[j])) {
+			j++
+		}
+		ret = append(ret, v[i:j])
+		i = j
+	}
+	return ret
+}
+
+func isNumeric(c byte) bool { return '0' <= c && c <= '9' }
+func isAlpha(c byte) bool   { return 'A' <= c && c <= 'Z' || 'a' <= c && c <= 'z' }
+
+func (looseScheme) Compare(a, b string) int {
+	ca, cb := looseComponents(a), looseComponents(b)
+	for i := 0; i < len(ca) && i < len(cb); i++ {
+		na, aerr := strconv.ParseUint(ca[i], 10, 64)
+		nb, berr := strconv.ParseUint(cb[i], 10, 64)
+		switch {
+		case aerr == nil && berr == nil:
+			if c := cmp.Compare(na, nb); c != 0 {
+				return c
+			}
+		case aerr == nil:
+			// numbers are newer than letters
+			return 1
+		case berr == nil:
+			return -1
+		default:
+			if c := strings.Compare(ca[i], cb[i]); c != 0 {
+				return c
+			}
+		}
+	}
+	return cmp.Compare(len(ca), len(cb))
+}
+
+type epochScheme struct {
+	inner VersionScheme
+}
+
+// splitEpoch returns the epoch of the version, zero if not given,
+// and the rest.
+func splitEpoch(v string) (uint64, string, bool) {
+	i := strings.IndexAny(v, ":!")
+	if i == -1 {
+		return 0, v, true
+	}
+	epoch, err := strconv.ParseUint(v[:i], 10, 64)
+	if err != nil {
+		return 0, "", false
+	}
+	return epoch, v[i+1:], true
+}
+
+func (e epochScheme) Valid(v string) bool {
+	_, rest, ok := splitEpoch(v)
+	return ok && e.inner.Valid(rest)
+}
+
+func (e epochScheme) Compare(a, b string) int {
+	ea, ra, _ := splitEpoch(a)
+	eb, rb, _ := splitEpoch(b)
+	if c := cmp.Compare(ea, eb); c != 0 {
+		return c
+	}
+	return e.inner.Compare(ra, rb)
+}
@@ -0,0 +1,107 @@
+package pkg
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestLooseScheme(t *testing.T) {
+	for _, v := range []string{"2024.01.15", "1.2.3.4", "v1.2", "3-beta", "1.0+build"} {
+		if !LooseScheme.Valid(v) {
+			t.Errorf("LooseScheme.Valid(%q) = false", v)
+		}
+	}
+	for _, v := range []string{"", "v", "beta", "1.0_1", "1.0/2"} {
+		if LooseScheme.Valid(v) {
+			t.Errorf("LooseScheme.Valid(%q) = true", v)
+		}
+	}
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"2024.01.15", "2024.01.15", 0},
+		{"2024.01.15", "2024.2.1", -1},
+		{"1.2.3.4", "1.2.3", 1},
+		{"1.10", "1.9", 1},
+		{"v1.2", "1.2", 0},
+		{"1.0a", "1.0.1", -1},
+	}
+	for _, tt := range tests {
+		if got := LooseScheme.Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("LooseScheme.Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestEpochScheme(t *testing.T) {
+	s := EpochScheme(LooseScheme)
+
+	for _, v := range []string{"1:2.0", "1!2.0", "2.0"} {
+		if !s.Valid(v) {
+			t.Errorf("Valid(%q) = false", v)
+		}
+	}
+	if s.Valid("x:2.0") {
+		t.Error("Valid(x:2.0) = true")
+	}
+
+	tests := []struct {
+		a, b string
+		want int
+	}{
+		{"1:1.0", "9.0", 1},
+		{"1:1.0", "1!1.0", 0},
+		{"0:2.0", "2.0", 0},
+		{"2!1.0", "1:5.0", 1},
+	}
+	for _, tt := range tests {
+		if got := s.Compare(tt.a, tt.b); got != tt.want {
+			t.Errorf("Compare(%q, %q) = %d, want %d", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestParseNameWithScheme(t *testing.T) {
+	name := "s3_2024.01.15_" + runtime.GOOS + "_" + runtime.GOARCH + ".ptar"
+
+	var pkg Package
+	if err := pkg.parseName(name); err == nil {
+		t.Error("parseName accepted a non-semver version")
+	}
+	if err := pkg.parseNameWith(name, LooseScheme); err != nil {
+		t.Fatalf("parseNameWith: %v", err)
+	}
+	if pkg.Version != "2024.01.15" {
+		t.Errorf("Version = %q", pkg.Version)
+	}
+}
+
+func TestFlatBackendListLooseVersions(t *testing.T) {
+	be, pkgdir, _ := newTestFlatBackend(t, &FlatBackendOptions{VersionScheme: LooseScheme})
+	touch(t, pkgdir, "s3_1.2.3.4_"+runtime.GOOS+"_"+runtime.GOARCH+".ptar")
+
+	var got []string
+	for pkg, err := range be.List("") {
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, pkg.Version)
+	}
+	if len(got) != 1 || got[0] != "1.2.3.4" {
+		t.Errorf("List = %v", got)
+	}
+}
+
+func TestPreaddLooseUpgrade(t *testing.T) {
+	be := newFakeBackend(pkgVer("s3", "2024.01.15"))
+	m, _ := New(be, &Options{VersionScheme: LooseScheme})
+
+	if err := m.preadd("s3", "2024.2.1", &AddOptions{Upgrade: true}); err != nil {
+		t.Fatalf("preadd: %v", err)
+	}
+	if len(be.unloaded) != 1 {
+		t.Errorf("older version was not removed")
+	}
+}
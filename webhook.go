@@ -0,0 +1,101 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+
+package pkg
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"runtime"
+	"time"
+)
+
+// Header carrying the HMAC-SHA256 of the payload, in the
+// "sha256=<hex>" form.
+const WebhookSignatureHeader = "X-Plakar-Signature"
+
+const webhookTimeout = 10 * time.Second
+
+// Webhook is an endpoint notified of the installs and removals.
+type Webhook struct {
+	URL string
+
+	// Key used to sign the payloads.  The signature is sent in
+	// the WebhookSignatureHeader header so that the receiver can
+	// authenticate them.
+	Secret string
+}
+
+// WebhookPayload is the JSON document posted to the webhooks.
+type WebhookPayload struct {
+	Host            string `json:"host"`
+	OperatingSystem string `json:"os"`
+	Architecture    string `json:"arch"`
+	AuditRecord
+}
+
+// SignWebhookPayload returns the value of the WebhookSignatureHeader
+// header for the given payload.
+func SignWebhookPayload(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// notify posts the record to the configured webhooks.  Delivery is
+// best effort and failures are ignored.
+func (p *Manager) notify(rec *AuditRecord) {
+	if len(p.webhooks) == 0 {
+		return
+	}
+
+	host, _ := os.Hostname()
+	data, err := json.Marshal(&WebhookPayload{
+		Host:            host,
+		OperatingSystem: runtime.GOOS,
+		Architecture:    runtime.GOARCH,
+		AuditRecord:     *rec,
+	})
+	if err != nil {
+		return
+	}
+
+	client := &http.Client{Timeout: webhookTimeout}
+	for _, hook := range p.webhooks {
+		req, err := http.NewRequest("POST", hook.URL, bytes.NewReader(data))
+		if err != nil {
+			continue
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", p.useragent)
+		if hook.Secret != "" {
+			req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(hook.Secret, data))
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			continue
+		}
+		resp.Body.Close()
+	}
+}
@@ -0,0 +1,78 @@
+package pkg
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"testing"
+)
+
+func TestWebhookNotifications(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer repo.Close()
+
+	var payloads []WebhookPayload
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, err := io.ReadAll(r.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := r.Header.Get(WebhookSignatureHeader), SignWebhookPayload("s3cr3t", data); got != want {
+			t.Errorf("signature = %q, want %q", got, want)
+		}
+
+		var p WebhookPayload
+		if err := json.Unmarshal(data, &p); err != nil {
+			t.Errorf("bad payload %q: %v", data, err)
+		}
+		payloads = append(payloads, p)
+	}))
+	defer hook.Close()
+
+	m, _ := New(newFakeBackend(), &Options{
+		InstallURL: repo.URL,
+		Webhooks:   []Webhook{{URL: hook.URL, Secret: "s3cr3t"}},
+	})
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if err := m.Del("s3", nil); err != nil {
+		t.Fatalf("Del: %v", err)
+	}
+
+	if len(payloads) != 2 {
+		t.Fatalf("got %d payloads, want 2", len(payloads))
+	}
+	if p := payloads[0]; p.Operation != AuditAdd || p.Name != "s3" || p.To != "v1.0.0" ||
+		p.OperatingSystem != runtime.GOOS || p.Architecture != runtime.GOARCH {
+		t.Errorf("first payload = %+v", p)
+	}
+	if p := payloads[1]; p.Operation != AuditDel || p.From != "v1.0.0" {
+		t.Errorf("second payload = %+v", p)
+	}
+}
+
+func TestWebhookFailureIgnored(t *testing.T) {
+	repo := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "PTARDATA")
+	}))
+	defer repo.Close()
+
+	hook := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "boom", http.StatusInternalServerError)
+	}))
+	defer hook.Close()
+
+	m, _ := New(newFakeBackend(), &Options{
+		InstallURL: repo.URL,
+		Webhooks:   []Webhook{{URL: hook.URL}},
+	})
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.0"}); err != nil {
+		t.Errorf("Add with a failing webhook: %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+)
+
+var (
+	ErrOutsideWindow = errors.New("outside of the maintenance windows")
+	ErrBadWindow     = errors.New("bad maintenance window")
+)
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Window is a recurring period of time during which the packages
+// may be changed.
+type Window struct {
+	// Days the window opens on.  Empty means every day.
+	Days []time.Weekday
+
+	// Opening and closing times, as offsets from midnight.  The
+	// window goes past midnight when End is before Start.
+	Start time.Duration
+	End   time.Duration
+
+	// Defaults to UTC.
+	Location *time.Location
+}
+
+// ParseWindow parses a window written as days, a time range and an
+// optional time zone, e.g. "sun 02:00-04:00 UTC",
+// "mon-fri 22:00-01:00 Europe/Paris" or "* 03:00-04:00".  The days
+// are a comma-separated list of names or ranges of names, or "*" for
+// every day.
+func ParseWindow(s string) (*Window, error) {
+	fields := strings.Fields(s)
+	if len(fields) != 2 && len(fields) != 3 {
+		return nil, fmt.Errorf("%w: %q", ErrBadWindow, s)
+	}
+
+	w := &Window{Location: time.UTC}
+
+	if fields[0] != "*" {
+		for part := range strings.SplitSeq(strings.ToLower(fields[0]), ",") {
+			from, to, isrange := strings.Cut(part, "-")
+			first, ok1 := weekdays[from]
+			last, ok2 := weekdays[to]
+			if !ok1 || (isrange && !ok2) {
+				return nil, fmt.Errorf("%w: bad day %q", ErrBadWindow, part)
+			}
+			if !isrange {
+				last = first
+			}
+			for d := first; ; d = (d + 1) % 7 {
+				if !slices.Contains(w.Days, d) {
+					w.Days = append(w.Days, d)
+				}
+				if d == last {
+					break
+				}
+			}
+		}
+	}
+
+	from, to, ok := strings.Cut(fields[1], "-")
+	if !ok {
+		return nil, fmt.Errorf("%w: bad time range %q", ErrBadWindow, fields[1])
+	}
+	var err error
+	if w.Start, err = parseClock(from); err != nil {
+		return nil, err
+	}
+	if w.End, err = parseClock(to); err != nil {
+		return nil, err
+	}
+	if w.Start == w.End {
+		return nil, fmt.Errorf("%w: empty time range %q", ErrBadWindow, fields[1])
+	}
+
+	if len(fields) == 3 {
+		loc, err := time.LoadLocation(fields[2])
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrBadWindow, err)
+		}
+		w.Location = loc
+	}
+	return w, nil
+}
+
+// parseClock parses a HH:MM time of the day.
+func parseClock(s string) (time.Duration, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("%w: bad time %q", ErrBadWindow, s)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+func (w *Window) location() *time.Location {
+	if w.Location == nil {
+		return time.UTC
+	}
+	return w.Location
+}
+
+func (w *Window) opensOn(day time.Weekday) bool {
+	return len(w.Days) == 0 || slices.Contains(w.Days, day)
+}
+
+// opening returns when the window opens on the day of t.
+func (w *Window) opening(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location()).Add(w.Start)
+}
+
+// Contains returns whether t falls in the window.
+func (w *Window) Contains(t time.Time) bool {
+	t = t.In(w.location())
+	for _, days := range []int{0, -1} {
+		day := t.AddDate(0, 0, days)
+		if !w.opensOn(day.Weekday()) {
+			continue
+		}
+		start := w.opening(day)
+		length := w.End - w.Start
+		if length < 0 {
+			length += 24 * time.Hour
+		}
+		if !t.Before(start) && t.Before(start.Add(length)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Blackout is a period of time during which the packages must not be
+// changed, e.g. while the backups run.
+type Blackout struct {
+	From time.Time
+	To   time.Time
+}
+
+func (b *Blackout) Contains(t time.Time) bool {
+	return !t.Before(b.From) && t.Before(b.To)
+}
+
+// Schedule tells when unattended changes to the packages may happen:
+// during one of the windows, if any, and outside of the blackouts.
+type Schedule struct {
+	Windows   []*Window
+	Blackouts []*Blackout
+}
+
+// Allows returns whether the packages may be changed at t.
+func (s *Schedule) Allows(t time.Time) bool {
+	for _, b := range s.Blackouts {
+		if b.Contains(t) {
+			return false
+		}
+	}
+	if len(s.Windows) == 0 {
+		return true
+	}
+	for _, w := range s.Windows {
+		if w.Contains(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Next returns the first time from t on when the packages may be
+// changed, or the zero time if there is none.
+func (s *Schedule) Next(t time.Time) time.Time {
+	if s.Allows(t) {
+		return t
+	}
+
+	// the changes become allowed either when a window opens or
+	// when a blackout ends.
+	bases := []time.Time{t}
+	for _, b := range s.Blackouts {
+		if b.To.After(t) {
+			bases = append(bases, b.To)
+		}
+	}
+
+	var candidates []time.Time
+	for _, base := range bases {
+		candidates = append(candidates, base)
+		for _, w := range s.Windows {
+			local := base.In(w.location())
+			for days := 0; days <= 7; days++ {
+				day := local.AddDate(0, 0, days)
+				if start := w.opening(day); w.opensOn(day.Weekday()) && start.After(t) {
+					candidates = append(candidates, start)
+				}
+			}
+		}
+	}
+
+	slices.SortFunc(candidates, func(a, b time.Time) int {
+		return a.Compare(b)
+	})
+	for _, c := range candidates {
+		if !c.Before(t) && s.Allows(c) {
+			return c
+		}
+	}
+	return time.Time{}
+}
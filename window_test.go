@@ -0,0 +1,141 @@
+package pkg
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+func mustWindow(t *testing.T, s string) *Window {
+	t.Helper()
+	w, err := ParseWindow(s)
+	if err != nil {
+		t.Fatalf("ParseWindow(%q): %v", s, err)
+	}
+	return w
+}
+
+func TestParseWindow(t *testing.T) {
+	w := mustWindow(t, "sun 02:00-04:00 UTC")
+	if !slices.Equal(w.Days, []time.Weekday{time.Sunday}) ||
+		w.Start != 2*time.Hour || w.End != 4*time.Hour || w.Location != time.UTC {
+		t.Errorf("window = %+v", w)
+	}
+
+	w = mustWindow(t, "fri-mon,wed 22:30-01:00")
+	want := []time.Weekday{time.Friday, time.Saturday, time.Sunday, time.Monday, time.Wednesday}
+	if !slices.Equal(w.Days, want) {
+		t.Errorf("days = %v, want %v", w.Days, want)
+	}
+
+	if w := mustWindow(t, "* 03:00-04:00"); len(w.Days) != 0 {
+		t.Errorf("days = %v, want every day", w.Days)
+	}
+
+	for _, s := range []string{
+		"",
+		"sun",
+		"sunday 02:00-04:00",
+		"sun 02:00",
+		"sun 2-4",
+		"sun 25:00-26:00",
+		"sun 02:00-02:00",
+		"sun 02:00-04:00 Nowhere/Town",
+	} {
+		if _, err := ParseWindow(s); !errors.Is(err, ErrBadWindow) {
+			t.Errorf("ParseWindow(%q) = %v, want %v", s, err, ErrBadWindow)
+		}
+	}
+}
+
+func TestWindowContains(t *testing.T) {
+	// 2026-10-18 is a Sunday.
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		window string
+		t      time.Time
+		want   bool
+	}{
+		{"sun 02:00-04:00 UTC", at(18, 2, 0), true},
+		{"sun 02:00-04:00 UTC", at(18, 3, 59), true},
+		{"sun 02:00-04:00 UTC", at(18, 4, 0), false},
+		{"sun 02:00-04:00 UTC", at(18, 1, 59), false},
+		{"sun 02:00-04:00 UTC", at(19, 3, 0), false},
+		{"sat 23:00-01:00 UTC", at(18, 0, 30), true},
+		{"sat 23:00-01:00 UTC", at(17, 23, 30), true},
+		{"sat 23:00-01:00 UTC", at(18, 23, 30), false},
+		{"* 03:00-04:00", at(21, 3, 30), true},
+	}
+	for _, tt := range tests {
+		if got := mustWindow(t, tt.window).Contains(tt.t); got != tt.want {
+			t.Errorf("%q contains %v = %v, want %v", tt.window, tt.t, got, tt.want)
+		}
+	}
+}
+
+func TestSchedule(t *testing.T) {
+	at := func(day, hour, min int) time.Time {
+		return time.Date(2026, 10, day, hour, min, 0, 0, time.UTC)
+	}
+
+	s := &Schedule{
+		Windows: []*Window{mustWindow(t, "sun 02:00-04:00 UTC")},
+		Blackouts: []*Blackout{
+			{From: at(18, 1, 0), To: at(18, 3, 0)},
+		},
+	}
+
+	if s.Allows(at(18, 2, 30)) {
+		t.Error("allowed during a blackout")
+	}
+	if !s.Allows(at(18, 3, 30)) {
+		t.Error("not allowed in the window after the blackout")
+	}
+
+	tests := []struct {
+		from, want time.Time
+	}{
+		{at(16, 12, 0), at(18, 3, 0)},
+		{at(18, 3, 30), at(18, 3, 30)},
+		{at(18, 4, 0), at(25, 2, 0)},
+	}
+	for _, tt := range tests {
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Next(%v) = %v, want %v", tt.from, got, tt.want)
+		}
+	}
+
+	always := &Schedule{}
+	if !always.Allows(at(18, 12, 0)) {
+		t.Error("empty schedule doesn't allow changes")
+	}
+}
+
+func TestSyncOutsideWindow(t *testing.T) {
+	srv := newTestRepository(t, map[string]string{"s3": "v1.3.0"})
+	be := newFakeBackend(pkgVer("s3", "v1.0.0"))
+	m, _ := New(be, &Options{InstallURL: srv.URL})
+
+	now := time.Now()
+	blackout := &Schedule{
+		Blackouts: []*Blackout{{From: now.Add(-time.Hour), To: now.Add(time.Hour)}},
+	}
+
+	_, err := m.Sync([]Spec{{Name: "s3"}}, &SyncOptions{Schedule: blackout})
+	if !errors.Is(err, ErrOutsideWindow) {
+		t.Fatalf("Sync = %v, want %v", err, ErrOutsideWindow)
+	}
+	if len(be.loaded) != 0 || len(be.unloaded) != 0 {
+		t.Fatal("packages changed outside of the window")
+	}
+
+	// a dry run still computes the plan.
+	plan, err := m.Sync([]Spec{{Name: "s3"}}, &SyncOptions{Schedule: blackout, DryRun: true})
+	if err != nil || len(plan) != 1 {
+		t.Fatalf("dry run = %v, %v", plan, err)
+	}
+}
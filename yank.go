@@ -0,0 +1,128 @@
+/*
+ * Copyright (c) 2025, 2026 Gilles Chehade <gilles@poolp.org>
+ * Copyright (c) 2025, 2026 Eric Faurot <eric.faurot@plakar.io>
+ * Copyright (c) 2025, 2026 Omar Polo <op@omarpolo.com>
+ *
+ * Permission to use, copy, modify, and distribute this software for any
+ * purpose with or without fee is hereby granted, provided that the above
+ * copyright notice and this permission notice appear in all copies.
+ *
+ * THE SOFTWARE IS PROVIDED "AS IS" AND THE AUTHOR DISCLAIMS ALL WARRANTIES
+ * WITH REGARD TO THIS SOFTWARE INCLUDING ALL IMPLIED WARRANTIES OF
+ * MERCHANTABILITY AND FITNESS. IN NO EVENT SHALL THE AUTHOR BE LIABLE FOR
+ * ANY SPECIAL, DIRECT, INDIRECT, OR CONSEQUENTIAL DAMAGES OR ANY DAMAGES
+ * WHATSOEVER RESULTING FROM LOSS OF USE, DATA OR PROFITS, WHETHER IN AN
+ * ACTION OF CONTRACT, NEGLIGENCE OR OTHER TORTIOUS ACTION, ARISING OUT OF
+ * OR IN CONNECTION WITH THE USE OR PERFORMANCE OF THIS SOFTWARE.
+ */
+package pkg
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+)
+
+var (
+	ErrYanked = errors.New("version yanked")
+)
+
+// YankedVersion is a version withdrawn from the repository, listed in
+// the integration index.
+type YankedVersion struct {
+	Version string `json:"version"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// YankedPackage is an installed version that was yanked since.
+type YankedPackage struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// yanked returns the entry of the index yanking the given version,
+// if any.
+func (int *Integration) yanked(version string) *YankedVersion {
+	for i := range int.Yanked {
+		if int.Yanked[i].Version == version {
+			return &int.Yanked[i]
+		}
+	}
+	return nil
+}
+
+func yankError(name string, y *YankedVersion) error {
+	if y.Reason == "" {
+		return fmt.Errorf("%s %s: %w", name, y.Version, ErrYanked)
+	}
+	return fmt.Errorf("%s %s: %w: %s", name, y.Version, ErrYanked, y.Reason)
+}
+
+// checkYanked refuses the versions yanked from the repository, unless
+// explicitly allowed.  Without an integration index there's nothing
+// to check against.
+func (p *Manager) checkYanked(name, version string, opts *AddOptions) error {
+	if p.api == nil {
+		return nil
+	}
+
+	plug, err := p.integration(name)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			return nil
+		}
+		return err
+	}
+
+	y := plug.yanked(version)
+	if y == nil {
+		return nil
+	}
+	if !opts.AllowYanked {
+		return yankError(name, y)
+	}
+	p.emit(newWarning(&Package{Name: name, Version: version}, WarnYanked,
+		"installing a yanked version: %v", yankError(name, y)))
+	return nil
+}
+
+// Yanked returns the installed versions that were yanked from the
+// repository, ordered by name and version.
+func (p *Manager) Yanked() ([]YankedPackage, error) {
+	if p.api == nil {
+		return nil, fmt.Errorf("%w: no API URL", ErrInvalidOptions)
+	}
+
+	plugs, err := p.integrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var ret []YankedPackage
+	for pkg, err := range p.store.List("") {
+		if err != nil {
+			return nil, err
+		}
+		plug, ok := plugs[pkg.Name]
+		if !ok {
+			continue
+		}
+		if y := plug.yanked(pkg.Version); y != nil {
+			ret = append(ret, YankedPackage{
+				Name:    pkg.Name,
+				Version: pkg.Version,
+				Reason:  y.Reason,
+			})
+		}
+	}
+
+	slices.SortFunc(ret, func(a, b YankedPackage) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return p.versions.Compare(a.Version, b.Version)
+	})
+	return ret, nil
+}
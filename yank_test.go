@@ -0,0 +1,91 @@
+package pkg
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func newYankServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, ".json") {
+			io.WriteString(w, `{"version":"v1","integrations":[
+				{"name":"s3","edition":"community","api":"`+PLUGIN_API_VERSION+`","version":"v1.1.0",
+				 "yanked":[{"version":"v1.0.1","reason":"corrupts snapshots"}]}]}`)
+			return
+		}
+		if strings.HasSuffix(r.URL.Path, "/recipe.yaml") {
+			io.WriteString(w, "name: s3\nversion: v1.1.0\n")
+			return
+		}
+		io.WriteString(w, "PTARDATA")
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestAddRefusesYanked(t *testing.T) {
+	srv := newYankServer(t)
+	var warned bool
+	be := newFakeBackend()
+	m, _ := New(be, &Options{
+		ApiURL:     srv.URL,
+		InstallURL: srv.URL,
+		EventHook: func(ev *Event) {
+			warned = warned || ev.Code == WarnYanked
+		},
+	})
+
+	err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.1"})
+	if !errors.Is(err, ErrYanked) || !strings.Contains(err.Error(), "corrupts snapshots") {
+		t.Fatalf("Add = %v, want %v", err, ErrYanked)
+	}
+	if len(be.loaded) != 0 {
+		t.Errorf("loaded = %v, want nothing", be.loaded)
+	}
+
+	if err := m.Add("s3", &AddOptions{ImplicitFetch: true, Version: "v1.0.1", AllowYanked: true}); err != nil {
+		t.Fatalf("Add (allowed): %v", err)
+	}
+	if !warned {
+		t.Error("no warning about the yanked version")
+	}
+}
+
+func TestSyncAllowYanked(t *testing.T) {
+	srv := newYankServer(t)
+	m, _ := New(newFakeBackend(), &Options{ApiURL: srv.URL, InstallURL: srv.URL})
+
+	// only the exact versions may be yanked ones.
+	specs := []Spec{{Name: "s3", Version: "v1.0.1"}}
+	if _, err := m.Sync(specs, nil); !errors.Is(err, ErrYanked) {
+		t.Fatalf("Sync = %v, want %v", err, ErrYanked)
+	}
+	if _, err := m.Sync(specs, &SyncOptions{AllowYanked: true}); err != nil {
+		t.Fatalf("Sync (allowed): %v", err)
+	}
+}
+
+func TestYanked(t *testing.T) {
+	srv := newYankServer(t)
+	be := newFakeBackend(pkgVer("s3", "v1.0.1"), pkgVer("s3", "v1.1.0"), pkgVer("ftp", "v1.0.0"))
+	m, _ := New(be, &Options{ApiURL: srv.URL})
+
+	got, err := m.Yanked()
+	if err != nil {
+		t.Fatalf("Yanked: %v", err)
+	}
+	want := YankedPackage{Name: "s3", Version: "v1.0.1", Reason: "corrupts snapshots"}
+	if len(got) != 1 || got[0] != want {
+		t.Errorf("Yanked = %+v, want [%+v]", got, want)
+	}
+
+	m, _ = New(be, nil)
+	if _, err := m.Yanked(); !errors.Is(err, ErrInvalidOptions) {
+		t.Errorf("Yanked without API = %v, want %v", err, ErrInvalidOptions)
+	}
+}